@@ -1,13 +1,19 @@
 package internal
 
 import (
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/node"
 	"coreum_processor/modules/service"
 	"coreum_processor/modules/service/processor-coreum"
 	"coreum_processor/modules/storage"
 	"database/sql"
 	"github.com/CoreumFoundation/coreum/v2/pkg/config/constant"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
 	"log"
+	"time"
 )
 
 const (
@@ -19,22 +25,65 @@ const (
 func InitProcessorCoreum(blockchain string, db *sql.DB, callBack *service.CallBacks) service.CryptoProcessor {
 	var (
 		chainID                  = GetString("COREUM_CHAIN_ID", string(constant.ChainIDTest))
-		nodeAddress              = GetString("COREUM_NODE_ADDRESS", testNodeAddress)
+		nodeConfig               = GetNodeConfig(testNodeAddress)
+		rpcAddress               = GetString("COREUM_RPC_ADDRESS", "")
+		minConfirmation          = GetInt("COREUM_MIN_CONFIRMATIONS", 1)
+		depositMode              = GetString("COREUM_DEPOSIT_MODE", "blocks")
+		depositShards            = GetInt("COREUM_DEPOSIT_SHARDS", 1)
+		scannerStart             = GetInt("COREUM_SCANNER_START_HEIGHT", 0)
+		sweepInterval            = GetInt("COREUM_SWEEP_INTERVAL", 3600)
+		sweepDust                = GetAmount("COREUM_SWEEP_DUST", amount.NewFromInt64(100000))
+		feeMultiplier            = GetAmount("COREUM_FEE_MULTIPLIER", amount.NewFromInt64(13).Quo(amount.NewFromInt64(10)))
 		addressPrefix            = GetString("COREUM_ADDRESS_PREFIX", constant.AddressPrefixTest)
 		denom                    = GetString("COREUM_ADDRESS_PREFIX", constant.DenomTest)
-		minValue                 = GetFloat("MIN_VALUE", 10.0)
+		minValue                 = GetAmount("MIN_VALUE", amount.NewFromInt64(10))
 		WalletReceiverAddressStr = MustString("WALLET_RECEIVER_ADDRESS")
-		WalletReceiverSeedStr    = MustString("WALLET_RECEIVER_SEED")
 		WalletSenderAddressStr   = MustString("WALLET_SENDER_ADDRESS")
-		WalletSenderSeedStr      = MustString("WALLET_SENDER_SEED")
+		signerType               = GetString("COREUM_SIGNER", "in-process")
+		WalletReceiverSeedStr    string
+		WalletSenderSeedStr      string
+		signer                   processor_coreum.Signer
+		cursors                  *storage.CursorsPSQL
+		err                      error
 	)
 
+	// Initializing signer of the hot wallets, mnemonics are required only to sign in the process
+	switch signerType {
+	case "in-process":
+		WalletReceiverSeedStr = MustString("WALLET_RECEIVER_SEED")
+		WalletSenderSeedStr = MustString("WALLET_SENDER_SEED")
+		signer = processor_coreum.NewInProcessSigner()
+	case "keyring":
+		signer, err = processor_coreum.NewKeyringSigner("coreum_processor",
+			MustString("COREUM_SIGNER_KEYRING_DIR"), MustString("COREUM_SIGNER_KEYRING_PASSPHRASE"))
+		if err != nil {
+			log.Fatalf("could not make keyring signer, error: %v", err)
+		}
+	case "remote":
+		signer = processor_coreum.NewRemoteSigner(MustString("COREUM_SIGNER_URL"),
+			GetString("COREUM_SIGNER_TOKEN", ""))
+	default:
+		log.Fatalf("unknown signer: %v", signerType)
+	}
+
 	// Initializing store for Coreum wallets
-	store, err := storage.NewKeys("coreum_wallets", db)
+	store, err := storage.NewKeys("coreum_wallets", db, GetKeyProvider())
 	if err != nil {
 		log.Fatalf("could not make store for Wallets, error: %v", err)
 	}
 
+	// Initializing store for cursor of the block scanner, deposits are found by balance without it
+	switch depositMode {
+	case "blocks":
+		cursors, err = storage.NewCursorStorage("scanner_cursors", db)
+		if err != nil {
+			log.Fatalf("could not make store for scanner cursors, error: %v", err)
+		}
+	case "balance":
+	default:
+		log.Fatalf("unknown deposit mode: %v", depositMode)
+	}
+
 	// Initializing Coreum receiver as a structure
 	WalletReceiver := service.Wallet{
 		WalletAddress: WalletReceiverAddressStr,
@@ -48,6 +97,22 @@ func InitProcessorCoreum(blockchain string, db *sql.DB, callBack *service.CallBa
 		WalletSeed:    WalletSenderSeedStr,
 		Blockchain:    blockchain,
 	}
-	return processor_coreum.NewCoreumCryptoProcessor(WalletSender, WalletReceiver, blockchain, store, float64(minValue),
-		constant.ChainID(chainID), nodeAddress, addressPrefix, denom, signMode, callBack)
+	// Initializing connection to the nodes, Tendermint RPC is used to find transactions which are still
+	// in the mempool, transactions are recorded before broadcast only with it
+	grpcClient, err := node.Dial(nodeConfig)
+	if err != nil {
+		log.Fatalf("could not connect to Coreum nodes, error: %v", err)
+	}
+	if rpcAddress == "" {
+		log.Fatal("COREUM_RPC_ADDRESS env variable must be set")
+	}
+	mempool, err := rpchttp.New(rpcAddress, "/websocket")
+	if err != nil {
+		log.Fatalf("could not connect to Coreum RPC, error: %v", err)
+	}
+	return processor_coreum.NewCoreumCryptoProcessor(WalletSender, WalletReceiver, blockchain, store,
+		InitElector(db), int64(depositShards), cursors, int64(scannerStart), minValue,
+		int64(minConfirmation), time.Duration(sweepInterval)*time.Second, sweepDust, feeMultiplier,
+		constant.ChainID(chainID), grpcClient, txtypes.NewServiceClient(grpcClient),
+		tmservice.NewServiceClient(grpcClient), mempool, addressPrefix, denom, signMode, signer, callBack)
 }
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"coreum_processor/modules/encryption"
+	"log"
+)
+
+// GetKeyProvider initialize provider of master keys to encrypt wallets at rest,
+// the application is stopped if the provider is not configured
+func GetKeyProvider() encryption.KeyProvider {
+	var (
+		provider encryption.KeyProvider
+		err      error
+	)
+	switch kind := GetString("KEYS_PROVIDER", ""); kind {
+	case "":
+		log.Fatalf("KEYS_PROVIDER must be set to encrypt wallets")
+	case "env":
+		provider, err = encryption.NewEnvKeyProvider("KEYS_MASTER_KEY_ID", "KEYS_MASTER_KEY",
+			"KEYS_PREVIOUS_MASTER_KEYS")
+	case "file":
+		provider, err = encryption.NewFileKeyProvider(MustString("KEYS_MASTER_KEYS_FILE"))
+	default:
+		log.Fatalf("unknown provider of master keys: %v", kind)
+	}
+	if err != nil {
+		log.Fatalf("could not make provider of master keys, error: %v", err)
+	}
+	return provider
+}
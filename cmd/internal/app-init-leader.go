@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"coreum_processor/modules/leader"
+	"coreum_processor/modules/storage"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// InitElector initialize leader election between replicas of the processing
+func InitElector(db *sql.DB) *leader.Elector {
+	hostname, _ := os.Hostname()
+	var (
+		holder   = GetString("LEADER_ID", fmt.Sprintf("%s-%d", hostname, os.Getpid()))
+		leaseTTL = GetInt("LEADER_LEASE_TTL", 30)
+	)
+	if leaseTTL < 3 {
+		log.Fatalf("lease time to live must be at least 3 seconds, got: %v", leaseTTL)
+	}
+	store, err := storage.NewLeaseStorage("leader_leases", db)
+	if err != nil {
+		log.Fatalf("could not make store for leader leases, error: %v", err)
+	}
+	return leader.NewElector(store, holder, time.Duration(leaseTTL)*time.Second)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
 func LoadMultiSignEnv() MultiSignConfig {
@@ -20,6 +21,7 @@ func LoadMultiSignEnv() MultiSignConfig {
 		publicKeyPath = MustString("PUBLIC_KEY")
 		networkType   = MustString("NETWORK_TYPE")
 		threshold     = GetInt("THRESHOLD", 1)
+		nodeConfig    = GetNodeConfig(networkNodeAddress(networkType))
 	)
 
 	if len(publicKeyPath) < 1 {
@@ -50,5 +52,11 @@ func LoadMultiSignEnv() MultiSignConfig {
 		Mnemonics:   mnemonics,
 		PublicKey:   public,
 		NetworkType: networkType,
+		Node:        nodeConfig,
 	}
 }
+
+// networkNodeAddress returns address of the public node for the type of Coreum network
+func networkNodeAddress(networkType string) string {
+	return fmt.Sprintf("full-node.%s-1.coreum.dev:9090", strings.ToLower(networkType))
+}
@@ -26,9 +26,11 @@ func MakeConfigFromEnv() AppConfig {
 		// Initializing number of retry for callbacks
 		retryCount = GetInt("RETRY_COUNT", 15)
 		// Initializing time waite interval in sec betweend callbacks
-		retryWait = GetInt("RETRY_COUNT", 30)
+		retryWait = GetInt("RETRY_WAIT", 30)
 		// Initializing ENV variable for kratos url
 		kratosURL = MustString("KRATOS_URL")
+		// Initializing how deposits are processed if limits can't be checked: closed holds them, open processes them
+		limitsFailMode = GetString("LIMITS_FAIL_MODE", "closed")
 	)
 
 	if len(publicKeyPath) < 1 {
@@ -37,6 +39,9 @@ func MakeConfigFromEnv() AppConfig {
 	if len(privateKeyPath) < 1 {
 		log.Fatal("PRIVATE_KEY env variable must be set")
 	}
+	if limitsFailMode != "closed" && limitsFailMode != "open" {
+		log.Fatalf("unknown LIMITS_FAIL_MODE: %v, must be closed or open", limitsFailMode)
+	}
 
 	// Parsing public key
 	publicKey, err := os.ReadFile(publicKeyPath)
@@ -79,5 +84,6 @@ func MakeConfigFromEnv() AppConfig {
 		RetryCount:      retryCount,
 		RetryWait:       retryWait,
 		KratosURL:       kratosURL,
+		LimitsFailOpen:  limitsFailMode == "open",
 	}
 }
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/node"
 	"crypto/rsa"
 	"log"
 	"os"
@@ -14,6 +16,7 @@ const (
 
 type MultiSignConfig struct {
 	Port            string
+	Node            node.Config
 	Threshold       int
 	TokenTimeToLive int
 	Mnemonics       string
@@ -30,6 +33,7 @@ type AppConfig struct {
 	RetryCount      int
 	RetryWait       int
 	KratosURL       string
+	LimitsFailOpen  bool
 }
 
 // MustString func returns environment variable value as a string value,
@@ -99,3 +103,29 @@ func GetFloat(key string, fallback int) int {
 	}
 	return int(res)
 }
+
+// GetAmount func returns environment variable value as an exact decimal amount,
+// If variable doesn't exist or is not a number, returns fallback value
+func GetAmount(key string, fallback amount.Amount) amount.Amount {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	res, err := amount.Parse(value)
+	if err != nil {
+		return fallback
+	}
+	return res
+}
+
+// GetNodeConfig func returns configuration of connection to blockchain nodes from environment variables,
+// a comma separated list of node addresses in COREUM_NODE_ADDRESS is used in order for failover
+func GetNodeConfig(fallbackAddress string) node.Config {
+	return node.Config{
+		Addresses: node.ParseAddresses(GetString("COREUM_NODE_ADDRESS", fallbackAddress)),
+		Transport: node.Transport(GetString("COREUM_NODE_TRANSPORT", string(node.TransportTLS))),
+		CAFile:    GetString("COREUM_NODE_CA_FILE", ""),
+		CertFile:  GetString("COREUM_NODE_CERT_FILE", ""),
+		KeyFile:   GetString("COREUM_NODE_KEY_FILE", ""),
+	}
+}
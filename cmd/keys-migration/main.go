@@ -0,0 +1,32 @@
+package main
+
+import (
+	internalApp "coreum_processor/cmd/internal"
+	"coreum_processor/modules/storage"
+	"log"
+)
+
+// keys-migration encrypts wallets which are stored as plaintext and
+// re-wraps encrypted wallets with the current master key after its rotation
+func main() {
+	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.Llongfile)
+
+	provider := internalApp.GetKeyProvider()
+	db := internalApp.DBConnect()
+	store, err := storage.NewKeys("coreum_wallets", db, provider)
+	if err != nil {
+		log.Fatalf("could not make store for Wallets, error: %v", err)
+	}
+
+	encrypted, err := store.EncryptLegacy()
+	if err != nil {
+		log.Fatalf("could not encrypt plaintext wallets, encrypted: %d, error: %v", encrypted, err)
+	}
+	log.Printf("encrypted plaintext wallets: %d", encrypted)
+
+	rewrapped, err := store.Rewrap()
+	if err != nil {
+		log.Fatalf("could not re-wrap wallets, re-wrapped: %d, error: %v", rewrapped, err)
+	}
+	log.Printf("re-wrapped wallets with master key %v: %d", provider.KeyID(), rewrapped)
+}
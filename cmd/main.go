@@ -31,11 +31,61 @@ func main() {
 	if err != nil {
 		panic(fmt.Errorf("cant open merchant storage: %v", err))
 	}
-	transactionStore, err := storage.NewTransactionStorage("transactions", db)
+	webhookStore, err := storage.NewWebhookStorage("webhook_outbox", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open webhook outbox storage: %v", err))
+	}
+
+	endpointStore, err := storage.NewEndpointStorage("webhook_endpoints", db, internalApp.GetKeyProvider())
+	if err != nil {
+		panic(fmt.Errorf("cant open webhook endpoints storage: %v", err))
+	}
+
+	ledgerStore, err := storage.NewLedgerStorage("ledger_entries", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open ledger storage: %v", err))
+	}
+
+	approvalStore, err := storage.NewApprovalStorage("withdraw_approvals", "withdraw_approval_decisions", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open withdraw approvals storage: %v", err))
+	}
+
+	addressStore, err := storage.NewAddressStorage("address_book", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open address book storage: %v", err))
+	}
+
+	limitStore, err := storage.NewLimitStorage("limit_rules", "transaction_holds", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open limits storage: %v", err))
+	}
+
+	transactionStore, err := storage.NewTransactionStorage("transactions", db, webhookStore, ledgerStore)
 	if err != nil {
 		panic(fmt.Errorf("cant open transactions storage: %v", err))
 	}
 
+	idempotencyStore, err := storage.NewIdempotencyStorage("idempotency_keys", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open idempotency keys storage: %v", err))
+	}
+
+	jobStore, err := storage.NewJobStorage("processing_jobs", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open processing jobs storage: %v", err))
+	}
+
+	memoStore, err := storage.NewMemoStorage("deposit_memos", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open deposit memos storage: %v", err))
+	}
+
+	quarantineStore, err := storage.NewQuarantineStorage("quarantined_deposits", db)
+	if err != nil {
+		panic(fmt.Errorf("cant open quarantined deposits storage: %v", err))
+	}
+
 	userStore, err := storage.NewUserStorage(storage.UserRegistered,
 		"users", "merchant_users", "merchant_list",
 		db)
@@ -56,7 +106,7 @@ func main() {
 
 	// Initializing callback service
 	callBack := service.NewCallBackService(cfg.PrivateKey,
-		cfg.TokenTimeToLive, cfg.RetryCount, cfg.RetryWait, merchants)
+		cfg.TokenTimeToLive, cfg.RetryCount, cfg.RetryWait, merchants, endpointStore)
 
 	// Adding processors to the unified structure
 	processors := map[string]service.CryptoProcessor{
@@ -65,7 +115,9 @@ func main() {
 
 	// Initializing processing services
 	processingService := service.NewProcessingService(cfg.PublicKey, cfg.PrivateKey,
-		cfg.TokenTimeToLive, processors, merchants, callBack, transactionStore)
+		cfg.TokenTimeToLive, processors, merchants, callBack, transactionStore, idempotencyStore, jobStore,
+		memoStore, quarantineStore, webhookStore, endpointStore, assetsStore, ledgerStore,
+		approvalStore, addressStore, limitStore, cfg.LimitsFailOpen)
 
 	// Initializing user management service
 	userService := user.NewService(userStore, merchants)
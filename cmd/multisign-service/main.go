@@ -19,10 +19,10 @@ func main() {
 	cfg := internal.LoadMultiSignEnv()
 
 	processingService := service.NewProcessingService(cfg.PublicKey, nil,
-		3600, nil, nil, nil, nil)
+		3600, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false)
 
 	// @ToDo write transaction check callback function and find client context
-	multiSignService := MultiSignService.NewMultiSignService(ctx, nil, cfg.NetworkType, cfg.Mnemonics)
+	multiSignService := MultiSignService.NewMultiSignService(ctx, nil, cfg.NetworkType, cfg.Node, cfg.Mnemonics)
 
 	router := httprouter.New()
 	urlPath := ""
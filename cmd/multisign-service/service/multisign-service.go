@@ -2,7 +2,7 @@ package service
 
 import (
 	"context"
-	"crypto/tls"
+	"coreum_processor/modules/node"
 	"fmt"
 	"github.com/CoreumFoundation/coreum/v2/pkg/client"
 	"github.com/CoreumFoundation/coreum/v2/pkg/config/constant"
@@ -17,8 +17,6 @@ import (
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	"github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/dvsekhvalnov/jose2go/base64url"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	"log"
 	"strings"
 )
@@ -44,17 +42,17 @@ type MultiSignService struct {
 //   - fn - is a transaction verification function, that returns true if transaction verified and should be executed
 //     otherwise return false and signature will not be created for the transaction
 //   - networkType - is a string that defines type of blockchain network can be ['devnet','testnet','mainnet']
+//   - nodeConfig - defines addresses of the blockchain nodes and transport to connect to them
 //   - mnemonics - a set of mnemonics to generate coreum keys for multi sign accounts
 //
 // the function panic in case it is not possible to create private keys from the provided mnemonics
 func NewMultiSignService(ctx context.Context, fn FuncTrxIDVerification,
-	networkType string, mnemonics ...string) *MultiSignService {
+	networkType string, nodeConfig node.Config, mnemonics ...string) *MultiSignService {
 	algo := hd.Secp256k1
 	hdPath := sdk.GetConfig().GetFullBIP44Path()
 	privateKey := map[string]types.PrivKey{}
 	addressPrefix := ""
 	var chainID constant.ChainID
-	nodeAddress := "full-node.testnet-1.coreum.dev:9090"
 	networkType = strings.ToLower(networkType)
 	switch networkType {
 	case "devnet":
@@ -80,8 +78,7 @@ func NewMultiSignService(ctx context.Context, fn FuncTrxIDVerification,
 	)
 
 	// Configure client context and tx factory
-	// If you don't use TLS then replace `grpc.WithTransportCredentials()` with `grpc.WithInsecure()`
-	grpcClient, err := grpc.Dial(nodeAddress, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+	grpcClient, err := node.Dial(nodeConfig)
 	if err != nil {
 		panic(err)
 	}
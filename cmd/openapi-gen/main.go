@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"coreum_processor/modules/openapi"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// openapi-gen writes OpenAPI document of the backend REST API and Go client of the API made from it,
+// it is run by go generate in modules/client
+func main() {
+	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.Llongfile)
+
+	clientPath := flag.String("client", "", "path of Go file with the client")
+	specPath := flag.String("spec", "", "path of JSON file with OpenAPI document")
+	flag.Parse()
+
+	document := openapi.Backend()
+	if *specPath != "" {
+		spec, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			log.Fatalf("could not marshal OpenAPI document, error: %v", err)
+		}
+		if err = os.WriteFile(*specPath, append(spec, '\n'), 0644); err != nil {
+			log.Fatalf("could not write OpenAPI document, error: %v", err)
+		}
+	}
+	if *clientPath != "" {
+		source, err := generateClient(document)
+		if err != nil {
+			log.Fatalf("could not generate client, error: %v", err)
+		}
+		if err = os.WriteFile(*clientPath, source, 0644); err != nil {
+			log.Fatalf("could not write client, error: %v", err)
+		}
+	}
+}
+
+// generator writes Go code of types and methods of the client and collects packages it uses
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+func generateClient(document *openapi.Document) ([]byte, error) {
+	g := &generator{imports: map[string]bool{"context": true, "net/http": true}}
+	names := make([]string, 0, len(document.Components.Schemas))
+	for name := range document.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.writeType(name, document.Components.Schemas[name])
+	}
+	operations := document.Operations()
+	sort.Slice(operations, func(i, j int) bool { return operations[i].OperationID < operations[j].OperationID })
+	for _, operation := range operations {
+		if err := g.writeOperation(operation); err != nil {
+			return nil, err
+		}
+	}
+
+	var source bytes.Buffer
+	source.WriteString("// Code generated by openapi-gen from OpenAPI document of the backend. DO NOT EDIT.\n\n")
+	source.WriteString("package client\n\nimport (\n")
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		fmt.Fprintf(&source, "\t%q\n", path)
+	}
+	source.WriteString(")\n")
+	source.Write(g.buf.Bytes())
+	return format.Source(source.Bytes())
+}
+
+// writeType writes struct of the component schema with fields ordered by name
+func (g *generator) writeType(name string, schema *openapi.Schema) {
+	required := map[string]bool{}
+	for _, field := range schema.Required {
+		required[field] = true
+	}
+	properties := make([]string, 0, len(schema.Properties))
+	for property := range schema.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+	fmt.Fprintf(&g.buf, "\n// %s is a schema of the API\ntype %s struct {\n", name, name)
+	for _, property := range properties {
+		tag := property
+		if !required[property] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&g.buf, "\t%s %s `json:%q`\n", goName(property), g.goType(schema.Properties[property]), tag)
+	}
+	g.buf.WriteString("}\n")
+}
+
+// writeOperation writes method of the client for the operation and the struct of its query and header parameters
+func (g *generator) writeOperation(operation *openapi.Operation) error {
+	name := goName(operation.OperationID)
+	args := []string{"ctx context.Context"}
+	var pathParameters, otherParameters []openapi.Parameter
+	for _, parameter := range operation.Parameters {
+		if parameter.In == "path" {
+			pathParameters = append(pathParameters, parameter)
+			args = append(args, lowerName(parameter.Name)+" "+g.goType(parameter.Schema))
+		} else {
+			otherParameters = append(otherParameters, parameter)
+		}
+	}
+	if len(otherParameters) > 0 {
+		fmt.Fprintf(&g.buf, "\n// %sParams are query and header parameters of %s\ntype %sParams struct {\n",
+			name, name, name)
+		for _, parameter := range otherParameters {
+			if parameter.Description != "" {
+				fmt.Fprintf(&g.buf, "\t// %s is %s\n", goName(parameter.Name), parameter.Description)
+			}
+			fmt.Fprintf(&g.buf, "\t%s %s\n", goName(parameter.Name), g.goType(parameter.Schema))
+		}
+		g.buf.WriteString("}\n")
+		args = append(args, "params "+name+"Params")
+	}
+	bodyArg := "nil"
+	if schema := operation.RequestSchema(); schema != nil {
+		args = append(args, "body "+g.goType(schema))
+		bodyArg = "body"
+	}
+	response := operation.ResponseSchema()
+	result := g.goType(response)
+	if response.Type != "array" {
+		result = "*" + result
+	}
+
+	path, err := g.pathExpression(operation.Path, pathParameters)
+	if err != nil {
+		return err
+	}
+	method := "http.Method" + strings.ToUpper(operation.Method[:1]) + strings.ToLower(operation.Method[1:])
+	fmt.Fprintf(&g.buf, "\n// %s calls %s %s to %s\nfunc (c *Client) %s(%s) (%s, error) {\n", name,
+		operation.Method, operation.Path, lowerFirst(operation.Summary), name, strings.Join(args, ", "), result)
+	g.buf.WriteString("\tquery := url.Values{}\n\theader := http.Header{}\n")
+	g.imports["net/url"] = true
+	for _, parameter := range otherParameters {
+		target := "query"
+		if parameter.In == "header" {
+			target = "header"
+		}
+		field := "params." + goName(parameter.Name)
+		if g.goType(parameter.Schema) == "int64" {
+			g.imports["strconv"] = true
+			fmt.Fprintf(&g.buf, "\tif %s != 0 {\n\t\t%s.Set(%q, strconv.FormatInt(%s, 10))\n\t}\n",
+				field, target, parameter.Name, field)
+		} else {
+			fmt.Fprintf(&g.buf, "\tif %s != \"\" {\n\t\t%s.Set(%q, %s)\n\t}\n", field, target, parameter.Name, field)
+		}
+	}
+	if response.Type == "array" {
+		fmt.Fprintf(&g.buf, "\tvar res %s\n", result)
+		fmt.Fprintf(&g.buf, "\tif err := c.do(ctx, %s, %s, query, header, %s, &res); err != nil {\n"+
+			"\t\treturn nil, err\n\t}\n\treturn res, nil\n}\n", method, path, bodyArg)
+	} else {
+		fmt.Fprintf(&g.buf, "\tres := %s{}\n", strings.TrimPrefix(result, "*"))
+		fmt.Fprintf(&g.buf, "\tif err := c.do(ctx, %s, %s, query, header, %s, &res); err != nil {\n"+
+			"\t\treturn nil, err\n\t}\n\treturn &res, nil\n}\n", method, path, bodyArg)
+	}
+	return nil
+}
+
+// pathExpression returns Go expression which makes the path of the operation from its path parameters
+func (g *generator) pathExpression(path string, parameters []openapi.Parameter) (string, error) {
+	types := map[string]string{}
+	for _, parameter := range parameters {
+		types[parameter.Name] = g.goType(parameter.Schema)
+	}
+	var parts []string
+	literal := ""
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		literal += "/"
+		if !strings.HasPrefix(segment, ":") {
+			literal += segment
+			continue
+		}
+		name := strings.TrimPrefix(segment, ":")
+		parts = append(parts, fmt.Sprintf("%q", literal))
+		literal = ""
+		switch types[name] {
+		case "int64":
+			g.imports["strconv"] = true
+			parts = append(parts, fmt.Sprintf("strconv.FormatInt(%s, 10)", lowerName(name)))
+		case "string":
+			parts = append(parts, fmt.Sprintf("url.PathEscape(%s)", lowerName(name)))
+		default:
+			return "", fmt.Errorf("path parameter %v of %v has unsupported type", name, path)
+		}
+	}
+	if literal != "" {
+		parts = append(parts, fmt.Sprintf("%q", literal))
+	}
+	return strings.Join(parts, " + "), nil
+}
+
+// goType returns Go type of values of the schema
+func (g *generator) goType(schema *openapi.Schema) string {
+	if schema.Ref != "" {
+		return openapi.RefName(schema)
+	}
+	switch schema.Type {
+	case "array":
+		return "[]" + g.goType(schema.Items)
+	case "object":
+		if values, ok := schema.AdditionalProperties.(*openapi.Schema); ok {
+			return "map[string]" + g.goType(values)
+		}
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		return "int64"
+	case "number":
+		if schema.Format == "decimal" {
+			g.imports["encoding/json"] = true
+			return "json.Number"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	}
+	g.imports["encoding/json"] = true
+	return "json.RawMessage"
+}
+
+// initialisms are written in upper case in Go names
+var initialisms = map[string]bool{"id": true, "url": true, "guid": true, "uuid": true, "api": true}
+
+// goName converts name of the API like wallet_address or Idempotency-Key to exported Go name
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	for i, part := range parts {
+		if initialisms[strings.ToLower(part)] {
+			parts[i] = strings.ToUpper(part)
+		} else {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// lowerName converts name of the API to unexported Go name
+func lowerName(name string) string {
+	return lowerFirst(goName(name))
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	if strings.ToUpper(s) == s {
+		return strings.ToLower(s)
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,7 @@
+-- amounts are kept as exact decimals, existing double precision values are converted
+-- with their shortest decimal representation, commission keeps its fraction of subunits
+alter table transactions
+    alter column amount type numeric(78, 18) using amount::numeric(78, 18),
+    alter column amount set default 0,
+    alter column commission type numeric(78, 18) using commission::numeric(78, 18),
+    alter column commission set default 0;
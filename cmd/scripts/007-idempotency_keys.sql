@@ -0,0 +1,13 @@
+create table if not exists idempotency_keys
+(
+    id              bigserial primary key,
+    created_at      timestamp with time zone not null,
+    updated_at      timestamp with time zone not null,
+    merchant_id     varchar(64)              not null,
+    idempotency_key varchar(255)             not null,
+    request_hash    varchar(64)              not null,
+    status_code     integer default 0        not null,
+    response        bytea   default ''::bytea not null,
+    constraint idempotency_merchant_key_uq
+        unique (merchant_id, idempotency_key)
+);
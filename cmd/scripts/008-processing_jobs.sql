@@ -0,0 +1,22 @@
+create table if not exists processing_jobs
+(
+    id          bigserial primary key,
+    created_at  timestamp with time zone  not null,
+    updated_at  timestamp with time zone  not null,
+    kind        varchar(64)               not null,
+    key         varchar(255)              not null,
+    payload     bytea   default ''::bytea not null,
+    attempts    integer default 0         not null,
+    next_run_at timestamp with time zone  not null,
+    last_error  text    default ''        not null,
+    constraint processing_jobs_kind_key_uq
+        unique (kind, key)
+);
+
+create index if not exists processing_jobs_next_run_at_idx
+    on processing_jobs (next_run_at);
+
+-- blockchain transaction recorded before broadcast can be included in a block up to the height,
+-- it is made again only after the height is passed
+alter table transactions
+    add column if not exists timeout_height bigint default 0 not null;
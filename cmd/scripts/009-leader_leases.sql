@@ -0,0 +1,7 @@
+create table if not exists leader_leases
+(
+    name       varchar(255) primary key,
+    holder     varchar(255)             not null,
+    expires_at timestamp with time zone not null,
+    updated_at timestamp with time zone not null
+);
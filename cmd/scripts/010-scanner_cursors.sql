@@ -0,0 +1,6 @@
+create table if not exists scanner_cursors
+(
+    name       varchar(255) primary key,
+    height     bigint                   not null,
+    updated_at timestamp with time zone not null
+);
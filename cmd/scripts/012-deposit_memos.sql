@@ -0,0 +1,11 @@
+create table if not exists deposit_memos
+(
+    blockchain  varchar(64)              not null,
+    memo        varchar(64)              not null,
+    created_at  timestamp with time zone not null,
+    merchant_id varchar(64)              not null,
+    external_id varchar(64)              not null,
+    primary key (blockchain, memo),
+    constraint deposit_memos_user_uq
+        unique (blockchain, merchant_id, external_id)
+);
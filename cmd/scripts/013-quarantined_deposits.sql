@@ -0,0 +1,21 @@
+create table if not exists quarantined_deposits
+(
+    id          bigserial primary key,
+    created_at  timestamp with time zone not null,
+    updated_at  timestamp with time zone not null,
+    merchant_id varchar(64)              not null,
+    blockchain  varchar(64)              not null,
+    hash        varchar(128)             not null,
+    memo        text    default ''       not null,
+    sender      varchar(128)             not null,
+    asset       varchar(128)             not null,
+    issuer      varchar(128)             not null,
+    amount      numeric(78, 18)          not null,
+    status      varchar(32)              not null,
+    external_id varchar(64) default ''   not null,
+    constraint quarantined_deposits_hash_uq
+        unique (merchant_id, blockchain, hash, sender, asset, issuer)
+);
+
+create index if not exists quarantined_deposits_merchant_idx
+    on quarantined_deposits (merchant_id, status);
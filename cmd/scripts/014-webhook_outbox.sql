@@ -0,0 +1,19 @@
+create table if not exists webhook_outbox
+(
+    id              bigserial primary key,
+    created_at      timestamp with time zone  not null,
+    updated_at      timestamp with time zone  not null,
+    merchant_id     varchar(64)               not null,
+    event           varchar(64)               not null,
+    payload         bytea   default ''::bytea not null,
+    status          varchar(32)               not null,
+    attempts        integer default 0         not null,
+    next_attempt_at timestamp with time zone  not null,
+    last_error      text    default ''        not null
+);
+
+create index if not exists webhook_outbox_next_attempt_at_idx
+    on webhook_outbox (next_attempt_at) where status = 'pending';
+
+create index if not exists webhook_outbox_merchant_idx
+    on webhook_outbox (merchant_id, status);
@@ -0,0 +1,26 @@
+create table if not exists webhook_endpoints
+(
+    id          bigserial primary key,
+    created_at  timestamp with time zone not null,
+    updated_at  timestamp with time zone not null,
+    merchant_id varchar(64)              not null,
+    name        varchar(128) default ''  not null,
+    url         text                     not null,
+    purpose     varchar(32)              not null,
+    secret      text                     not null,
+    enabled     boolean      default true not null,
+    events      text[]       default '{}' not null
+);
+
+-- secret is sealed by the key provider, the sealed envelope doesn't fit the former varchar column
+alter table webhook_endpoints
+    alter column secret type text;
+
+create index if not exists webhook_endpoints_merchant_idx
+    on webhook_endpoints (merchant_id, purpose);
+
+-- an event is put for the merchant and fanned out to a copy per endpoint, the copy keeps id of the event
+alter table webhook_outbox
+    add column if not exists endpoint_id bigint default 0 not null;
+alter table webhook_outbox
+    add column if not exists event_id bigint default 0 not null;
@@ -0,0 +1,9 @@
+-- least and largest amounts of an asset in subunits a merchant can withdraw at once, null means no limit
+alter table assets
+    add column if not exists min_withdraw numeric(78, 18) default null;
+alter table assets
+    add column if not exists max_withdraw numeric(78, 18) default null;
+
+-- pending withdraws of a merchant are summed up to reserve their amounts in the sending wallet
+create index if not exists transactions_merchant_action_status_idx
+    on transactions (merchant_id, action, status);
@@ -0,0 +1,73 @@
+-- double-entry ledger of merchant assets, lines of a posting made for a transaction status sum up to zero.
+-- merchant, reserved, held and fees are funds of the merchant, incoming, incoming_held and refunding are funds
+-- of transactions in progress, external is the other side of funds coming in and out
+create table if not exists ledger_entries
+(
+    id               bigserial primary key,
+    created_at       timestamp with time zone not null,
+    merchant_id      varchar(64)              not null,
+    blockchain       varchar(32)              not null,
+    asset            varchar(32)              not null,
+    issuer           varchar                  not null,
+    account          varchar(32)              not null,
+    amount           numeric(78, 18)          not null,
+    transaction_guid uuid                     not null,
+    posting          varchar(32)              not null
+);
+
+create index if not exists ledger_entries_transaction_idx
+    on ledger_entries (transaction_guid);
+create index if not exists ledger_entries_merchant_idx
+    on ledger_entries (merchant_id, blockchain, asset, issuer, account);
+
+-- opening entries for transactions made before the ledger
+insert into ledger_entries (created_at, merchant_id, blockchain, asset, issuer, account, amount, transaction_guid,
+                            posting)
+select now(), t.merchant_id, t.blockchain, t.asset, t.issuer, l.account, l.amount, t.guid, t.status
+from transactions t
+         cross join lateral (values ('external', -t.amount),
+                                    ('merchant', t.amount - t.commission),
+                                    ('fees', t.commission)) as l(account, amount)
+where t.deleted_at is null
+  and t.action = 'deposit'
+  and t.status = 'done'
+  and l.amount <> 0
+  and not exists(select 1 from ledger_entries e where e.transaction_guid = t.guid);
+
+insert into ledger_entries (created_at, merchant_id, blockchain, asset, issuer, account, amount, transaction_guid,
+                            posting)
+select now(), t.merchant_id, t.blockchain, t.asset, t.issuer, l.account, l.amount, t.guid, 'settle'
+from transactions t
+         cross join lateral (values ('merchant', -t.amount - t.commission),
+                                    ('external', t.amount),
+                                    ('fees', t.commission)) as l(account, amount)
+where t.deleted_at is null
+  and t.action = 'withdraw'
+  and t.status in ('settle', 'done')
+  and l.amount <> 0
+  and not exists(select 1 from ledger_entries e where e.transaction_guid = t.guid);
+
+insert into ledger_entries (created_at, merchant_id, blockchain, asset, issuer, account, amount, transaction_guid,
+                            posting)
+select now(), t.merchant_id, t.blockchain, t.asset, t.issuer, l.account, l.amount, t.guid, 'init'
+from transactions t
+         cross join lateral (values ('merchant', -t.amount - t.commission),
+                                    ('reserved', t.amount + t.commission)) as l(account, amount)
+where t.deleted_at is null
+  and t.action = 'withdraw'
+  and t.status in ('init', 'processed')
+  and l.amount <> 0
+  and not exists(select 1 from ledger_entries e where e.transaction_guid = t.guid);
+
+insert into ledger_entries (created_at, merchant_id, blockchain, asset, issuer, account, amount, transaction_guid,
+                            posting)
+select now(), t.merchant_id, t.blockchain, t.asset, t.issuer, l.account, l.amount, t.guid, t.status
+from transactions t
+         cross join lateral (values ('external', -t.amount),
+                                    (case when t.action = 'deposit' then 'incoming' else 'refunding' end,
+                                     t.amount)) as l(account, amount)
+where t.deleted_at is null
+  and t.action in ('deposit', 'refund')
+  and t.status in ('init', 'processed', 'settle')
+  and l.amount <> 0
+  and not exists(select 1 from ledger_entries e where e.transaction_guid = t.guid);
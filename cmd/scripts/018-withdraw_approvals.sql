@@ -0,0 +1,32 @@
+-- withdraws confirmed by the merchant which wait for approvals of merchant users by the approval policy,
+-- requested_by is the identity of the user confirmed the withdraw in UI and empty for the merchant token
+create table if not exists withdraw_approvals
+(
+    transaction_guid uuid primary key,
+    created_at       timestamp with time zone not null,
+    updated_at       timestamp with time zone not null,
+    expires_at       timestamp with time zone not null,
+    merchant_id      varchar(64)              not null,
+    requested_by     varchar                  not null default '',
+    hash             varchar                  not null default '',
+    required         integer                  not null,
+    status           varchar(32)              not null
+);
+
+create index if not exists withdraw_approvals_merchant_idx
+    on withdraw_approvals (merchant_id, status);
+create index if not exists withdraw_approvals_expires_idx
+    on withdraw_approvals (status, expires_at);
+
+-- approve and reject decisions of merchant users, a user decides on a withdraw once
+create table if not exists withdraw_approval_decisions
+(
+    id               bigserial primary key,
+    created_at       timestamp with time zone not null,
+    transaction_guid uuid                     not null references withdraw_approvals (transaction_guid),
+    identity         varchar                  not null,
+    decision         varchar(32)              not null
+);
+
+create unique index if not exists withdraw_approval_decisions_identity_idx
+    on withdraw_approval_decisions (transaction_guid, identity);
@@ -0,0 +1,15 @@
+-- addresses merchants allow to withdraw to, an address added with cooling-off period can be used from active_from
+create table if not exists address_book
+(
+    id          bigserial primary key,
+    created_at  timestamp with time zone not null,
+    updated_at  timestamp with time zone not null,
+    active_from timestamp with time zone not null,
+    merchant_id varchar(64)              not null,
+    blockchain  varchar(32)              not null,
+    address     varchar                  not null,
+    label       varchar(128) default ''  not null
+);
+
+create unique index if not exists address_book_address_idx
+    on address_book (merchant_id, blockchain, address);
@@ -0,0 +1,44 @@
+-- limits of deposits and withdraws of a merchant asset, a rule of user scope is counted for every user
+-- (external_id) and a rule of merchant scope for all users of the merchant together.
+-- Amounts are in subunits of the asset and null means no limit
+create table if not exists limit_rules
+(
+    id                 bigserial primary key,
+    created_at         timestamp with time zone not null,
+    updated_at         timestamp with time zone not null,
+    merchant_id        varchar(64)              not null,
+    blockchain         varchar(32)              not null,
+    asset              varchar                  not null,
+    issuer             varchar                  not null default '',
+    action             varchar(32)              not null,
+    scope              varchar(32)              not null,
+    max_per_tx         numeric(78, 18) default null,
+    max_per_day        numeric(78, 18) default null,
+    max_per_month      numeric(78, 18) default null,
+    max_count_per_hour integer         default null
+);
+
+create index if not exists limit_rules_merchant_idx
+    on limit_rules (merchant_id, blockchain, action);
+
+-- transactions put on hold for breaching a limit rule, held_status is the status a transaction
+-- returns to when it is released by an admin
+create table if not exists transaction_holds
+(
+    transaction_guid uuid primary key,
+    created_at       timestamp with time zone not null,
+    updated_at       timestamp with time zone not null,
+    merchant_id      varchar(64)              not null,
+    rule_id          bigint                   not null,
+    reason           varchar                  not null,
+    held_status      varchar(32)              not null,
+    decision         varchar(32)              not null default '',
+    decided_by       varchar                  not null default ''
+);
+
+create index if not exists transaction_holds_decision_idx
+    on transaction_holds (decision, created_at);
+
+-- volumes of users and merchants are summed up over transactions created in a period
+create index if not exists transactions_volume_idx
+    on transactions (merchant_id, blockchain, action, asset, created_at);
@@ -0,0 +1,199 @@
+package amount
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// decimalPattern is a plain decimal number, fractions like 1/3 and exponent notation like 1e6 are not accepted
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Amount is an exact decimal amount of an asset expressed in the asset subunits,
+// it is stored in the database as numeric and marshaled to JSON as a number without rounding
+type Amount struct {
+	dec sdk.Dec
+}
+
+// Zero returns zero amount
+func Zero() Amount {
+	return Amount{dec: sdk.ZeroDec()}
+}
+
+// NewFromInt64 returns an amount for the given number of subunits
+func NewFromInt64(value int64) Amount {
+	return Amount{dec: sdk.NewDec(value)}
+}
+
+// NewFromInt returns an amount for the given number of subunits from the Cosmos integer
+func NewFromInt(value sdk.Int) Amount {
+	return Amount{dec: sdk.NewDecFromInt(value)}
+}
+
+// Parse returns an amount from a plain decimal string, an error is returned
+// if the amount has more decimal places than it can be kept with
+func Parse(value string) (Amount, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Zero(), nil
+	}
+	if !decimalPattern.MatchString(value) {
+		return Amount{}, fmt.Errorf("could not parse amount: %q, it must be a plain decimal", value)
+	}
+	dec, err := sdk.NewDecFromStr(value)
+	if err != nil {
+		return Amount{}, fmt.Errorf("could not parse amount: %q, err: %w", value, err)
+	}
+	return Amount{dec: dec}, nil
+}
+
+// Add returns sum of the amounts
+func (a Amount) Add(b Amount) Amount {
+	return Amount{dec: a.value().Add(b.value())}
+}
+
+// Sub returns difference of the amounts
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{dec: a.value().Sub(b.value())}
+}
+
+// Mul returns product of the amounts
+func (a Amount) Mul(b Amount) Amount {
+	return Amount{dec: a.value().Mul(b.value())}
+}
+
+// Quo returns quotient of the amounts, b must not be zero
+func (a Amount) Quo(b Amount) Amount {
+	return Amount{dec: a.value().Quo(b.value())}
+}
+
+// Percent returns the given percent of the amount
+func (a Amount) Percent(percent Amount) Amount {
+	return Amount{dec: a.value().Mul(percent.value()).QuoInt64(100)}
+}
+
+// Round returns the amount rounded to whole subunits, half is rounded to even
+func (a Amount) Round() Amount {
+	return Amount{dec: sdk.NewDecFromInt(a.value().RoundInt())}
+}
+
+// Ceil returns the amount rounded up to whole subunits
+func (a Amount) Ceil() Amount {
+	return Amount{dec: a.value().Ceil()}
+}
+
+// Int returns the amount as the Cosmos integer, an error is returned if the amount isn't whole subunits
+func (a Amount) Int() (sdk.Int, error) {
+	if !a.value().IsInteger() {
+		return sdk.Int{}, fmt.Errorf("amount %v is not whole subunits", a)
+	}
+	return a.value().TruncateInt(), nil
+}
+
+// IsZero reports whether the amount is zero
+func (a Amount) IsZero() bool {
+	return a.value().IsZero()
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (a Amount) IsPositive() bool {
+	return a.value().IsPositive()
+}
+
+// IsNegative reports whether the amount is less than zero
+func (a Amount) IsNegative() bool {
+	return a.value().IsNegative()
+}
+
+// Equal reports whether the amounts are equal
+func (a Amount) Equal(b Amount) bool {
+	return a.value().Equal(b.value())
+}
+
+// LT reports whether the amount is less than b
+func (a Amount) LT(b Amount) bool {
+	return a.value().LT(b.value())
+}
+
+// GT reports whether the amount is greater than b
+func (a Amount) GT(b Amount) bool {
+	return a.value().GT(b.value())
+}
+
+// String returns the amount as decimal string without trailing zeros
+func (a Amount) String() string {
+	s := a.value().String()
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// MarshalJSON writes the amount as JSON number
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON reads the amount from JSON number or string, amounts in JSON can't be negative
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(bytes.TrimSpace(data), `"`)
+	if string(data) == "null" {
+		*a = Zero()
+		return nil
+	}
+	res, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+	if res.IsNegative() {
+		return fmt.Errorf("amount can't be negative: %v", res)
+	}
+	*a = res
+	return nil
+}
+
+// Scan implements sql.Scanner for numeric columns
+func (a *Amount) Scan(src interface{}) error {
+	var (
+		res Amount
+		err error
+	)
+	switch v := src.(type) {
+	case nil:
+		res = Zero()
+	case []byte:
+		res, err = Parse(string(v))
+	case string:
+		res, err = Parse(v)
+	case int64:
+		res = NewFromInt64(v)
+	case float64:
+		res, err = Parse(big.NewFloat(v).Text('f', -1))
+	default:
+		err = fmt.Errorf("could not scan amount from: %T", src)
+	}
+	if err != nil {
+		return err
+	}
+	*a = res
+	return nil
+}
+
+// Value implements driver.Valuer, the amount is passed to the database as decimal string
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a Amount) value() sdk.Dec {
+	if a.dec.IsNil() {
+		return sdk.ZeroDec()
+	}
+	return a.dec
+}
@@ -0,0 +1,66 @@
+package amount
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		fails bool
+	}{
+		{value: "100", want: "100"},
+		{value: " 0.5 ", want: "0.5"},
+		{value: "-2.25", want: "-2.25"},
+		{value: "", want: "0"},
+		{value: "0.000000000000000001", want: "0.000000000000000001"},
+		{value: "0.0000000000000000001", fails: true},
+		{value: "1/3", fails: true},
+		{value: "1e6", fails: true},
+		{value: "1E-2", fails: true},
+		{value: ".5", fails: true},
+		{value: "+1", fails: true},
+		{value: "0x10", fails: true},
+		{value: "NaN", fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			res, err := Parse(tt.value)
+			if tt.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, res.String())
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var res struct {
+		Amount Amount `json:"amount"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"12.5"}`), &res))
+	require.Equal(t, "12.5", res.Amount.String())
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":7}`), &res))
+	require.Equal(t, "7", res.Amount.String())
+	require.Error(t, json.Unmarshal([]byte(`{"amount":-7}`), &res))
+	require.Error(t, json.Unmarshal([]byte(`{"amount":"1e3"}`), &res))
+}
+
+func TestInt(t *testing.T) {
+	res, err := NewFromInt64(42).Int()
+	require.NoError(t, err)
+	require.Equal(t, int64(42), res.Int64())
+
+	half, err := Parse("2.5")
+	require.NoError(t, err)
+	_, err = half.Int()
+	require.Error(t, err)
+	res, err = half.Round().Int()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), res.Int64())
+}
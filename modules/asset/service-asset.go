@@ -4,6 +4,7 @@ import (
 	"coreum_processor/modules/service"
 	"coreum_processor/modules/storage"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -37,6 +38,13 @@ func (s *Service) CreateAssetRequest(blockchain, code, smartContractAddress, nam
 	code = strings.ToLower(code)
 	return s.assetStorage.CreateAsset(blockchain, code, smartContractAddress, name, description, assetType, merchantOwnerID, features)
 }
+func (s *Service) SetWithdrawLimits(blockchain, code, issuer string, limits storage.WithdrawLimits) error {
+	if limits.Min != nil && limits.Max != nil && limits.Min.GT(*limits.Max) {
+		return fmt.Errorf("minimum withdraw: %v is greater than maximum: %v", *limits.Min, *limits.Max)
+	}
+	code = strings.ToLower(code)
+	return s.assetStorage.SetWithdrawLimits(blockchain, code, issuer, limits)
+}
 func (s *Service) IssueAsset(blockchain, code, merchantID string) error {
 	// TODO: issue asset in processing
 	issuer := ""
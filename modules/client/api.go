@@ -0,0 +1,964 @@
+// Code generated by openapi-gen from OpenAPI document of the backend. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AddressBookEntry is a schema of the API
+type AddressBookEntry struct {
+	ActiveFrom time.Time `json:"active_from,omitempty"`
+	Address    string    `json:"address,omitempty"`
+	Blockchain string    `json:"blockchain,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	ID         int64     `json:"id,omitempty"`
+	Label      string    `json:"label,omitempty"`
+	MerchantID string    `json:"merchant_id,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+}
+
+// AddressBookPolicy is a schema of the API
+type AddressBookPolicy struct {
+	AllowListOnly     bool  `json:"allow_list_only,omitempty"`
+	CoolingOffSeconds int64 `json:"cooling_off_seconds,omitempty"`
+}
+
+// AddressBookSettings is a schema of the API
+type AddressBookSettings struct {
+	AllowListOnly     bool   `json:"allow_list_only"`
+	Blockchain        string `json:"blockchain"`
+	CoolingOffSeconds int64  `json:"cooling_off_seconds,omitempty"`
+}
+
+// ApprovalPolicy is a schema of the API
+type ApprovalPolicy struct {
+	Enabled       bool                 `json:"enabled"`
+	ExpirySeconds int64                `json:"expiry_seconds,omitempty"`
+	Thresholds    []ApprovalThresholds `json:"thresholds,omitempty"`
+}
+
+// ApprovalThresholds is a schema of the API
+type ApprovalThresholds struct {
+	Asset            string      `json:"asset"`
+	AutoApproveBelow json.Number `json:"auto_approve_below"`
+	Issuer           string      `json:"issuer"`
+	TwoApprovalsFrom json.Number `json:"two_approvals_from,omitempty"`
+}
+
+// AssetWithdrawLimits is a schema of the API
+type AssetWithdrawLimits struct {
+	Blockchain  string      `json:"blockchain"`
+	Code        string      `json:"code"`
+	Issuer      string      `json:"issuer,omitempty"`
+	MaxWithdraw json.Number `json:"max_withdraw,omitempty"`
+	MinWithdraw json.Number `json:"min_withdraw,omitempty"`
+}
+
+// Balance is a schema of the API
+type Balance struct {
+	Amount     json.Number `json:"amount,omitempty"`
+	Asset      string      `json:"asset,omitempty"`
+	Blockchain string      `json:"blockchain,omitempty"`
+	Issuer     string      `json:"issuer,omitempty"`
+}
+
+// Commission is a schema of the API
+type Commission struct {
+	Fix     json.Number `json:"fix,omitempty"`
+	Percent json.Number `json:"percent,omitempty"`
+}
+
+// CreatedWebhookEndpoint is a schema of the API
+type CreatedWebhookEndpoint struct {
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	Enabled    bool      `json:"enabled,omitempty"`
+	Events     []string  `json:"events,omitempty"`
+	ID         int64     `json:"id,omitempty"`
+	MerchantID string    `json:"merchant_id,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Purpose    string    `json:"purpose,omitempty"`
+	Secret     string    `json:"secret,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+	URL        string    `json:"url,omitempty"`
+}
+
+// CredentialDeposit is a schema of the API
+type CredentialDeposit struct {
+	Amount     json.Number `json:"amount,omitempty"`
+	Asset      string      `json:"asset,omitempty"`
+	Blockchain string      `json:"blockchain"`
+	Issuer     string      `json:"issuer,omitempty"`
+}
+
+// CredentialWithdraw is a schema of the API
+type CredentialWithdraw struct {
+	Amount        json.Number `json:"amount"`
+	Asset         string      `json:"asset"`
+	Blockchain    string      `json:"blockchain"`
+	Issuer        string      `json:"issuer,omitempty"`
+	Memo          string      `json:"memo,omitempty"`
+	WalletAddress string      `json:"wallet_address"`
+}
+
+// DeleteWithdrawResponse is a schema of the API
+type DeleteWithdrawResponse struct {
+	Status string `json:"status,omitempty"`
+}
+
+// DepositResponse is a schema of the API
+type DepositResponse struct {
+	ID            string `json:"id,omitempty"`
+	Memo          string `json:"memo,omitempty"`
+	URL           string `json:"url,omitempty"`
+	WalletAddress string `json:"wallet_address,omitempty"`
+}
+
+// HeldTransaction is a schema of the API
+type HeldTransaction struct {
+	CreatedAt       time.Time        `json:"created_at,omitempty"`
+	DecidedBy       string           `json:"decided_by,omitempty"`
+	Decision        string           `json:"decision,omitempty"`
+	HeldStatus      string           `json:"held_status,omitempty"`
+	MerchantID      string           `json:"merchant_id,omitempty"`
+	Reason          string           `json:"reason,omitempty"`
+	RuleID          int64            `json:"rule_id,omitempty"`
+	Transaction     TransactionStore `json:"transaction,omitempty"`
+	TransactionGUID string           `json:"transaction_guid,omitempty"`
+	UpdatedAt       time.Time        `json:"updated_at,omitempty"`
+}
+
+// HoldResolution is a schema of the API
+type HoldResolution struct {
+	Decision string `json:"decision"`
+}
+
+// LedgerBalance is a schema of the API
+type LedgerBalance struct {
+	Asset      string      `json:"asset,omitempty"`
+	Available  json.Number `json:"available,omitempty"`
+	Blockchain string      `json:"blockchain,omitempty"`
+	Fees       json.Number `json:"fees,omitempty"`
+	Held       json.Number `json:"held,omitempty"`
+	Incoming   json.Number `json:"incoming,omitempty"`
+	Issuer     string      `json:"issuer,omitempty"`
+	Reserved   json.Number `json:"reserved,omitempty"`
+}
+
+// LimitRule is a schema of the API
+type LimitRule struct {
+	Action          string      `json:"action,omitempty"`
+	Asset           string      `json:"asset,omitempty"`
+	Blockchain      string      `json:"blockchain,omitempty"`
+	CreatedAt       time.Time   `json:"created_at,omitempty"`
+	ID              int64       `json:"id,omitempty"`
+	Issuer          string      `json:"issuer,omitempty"`
+	MaxCountPerHour int64       `json:"max_count_per_hour,omitempty"`
+	MaxPerDay       json.Number `json:"max_per_day,omitempty"`
+	MaxPerMonth     json.Number `json:"max_per_month,omitempty"`
+	MaxPerTx        json.Number `json:"max_per_tx,omitempty"`
+	MerchantID      string      `json:"merchant_id,omitempty"`
+	Scope           string      `json:"scope,omitempty"`
+	UpdatedAt       time.Time   `json:"updated_at,omitempty"`
+}
+
+// MerchantData is a schema of the API
+type MerchantData struct {
+	CallBackURL   string             `json:"call_back_url,omitempty"`
+	ID            string             `json:"id,omitempty"`
+	Name          string             `json:"name,omitempty"`
+	PublicKey     string             `json:"public_key,omitempty"`
+	Wallets       map[string]Wallets `json:"wallets,omitempty"`
+	WebhookEvents []string           `json:"webhook_events,omitempty"`
+}
+
+// MerchantResponse is a schema of the API
+type MerchantResponse struct {
+	ID string `json:"id,omitempty"`
+}
+
+// MessageResponse is a schema of the API
+type MessageResponse struct {
+	Message string `json:"message,omitempty"`
+}
+
+// MintTokenRequest is a schema of the API
+type MintTokenRequest struct {
+	Amount            string `json:"amount,omitempty"`
+	Blockchain        string `json:"blockchain"`
+	ClassID           string `json:"class_id,omitempty"`
+	Code              string `json:"code"`
+	Issuer            string `json:"issuer,omitempty"`
+	NftID             string `json:"nft_id,omitempty"`
+	ReceivingWalletID string `json:"receiving_wallet_id,omitempty"`
+	Type              string `json:"type,omitempty"`
+}
+
+// NewAddressBookEntry is a schema of the API
+type NewAddressBookEntry struct {
+	Address    string `json:"address"`
+	Blockchain string `json:"blockchain"`
+	Label      string `json:"label,omitempty"`
+}
+
+// NewLimitRule is a schema of the API
+type NewLimitRule struct {
+	Action          string      `json:"action"`
+	Asset           string      `json:"asset"`
+	Blockchain      string      `json:"blockchain"`
+	Issuer          string      `json:"issuer,omitempty"`
+	MaxCountPerHour int64       `json:"max_count_per_hour,omitempty"`
+	MaxPerDay       json.Number `json:"max_per_day,omitempty"`
+	MaxPerMonth     json.Number `json:"max_per_month,omitempty"`
+	MaxPerTx        json.Number `json:"max_per_tx,omitempty"`
+	MerchantID      string      `json:"merchant_id"`
+	Scope           string      `json:"scope"`
+}
+
+// NewMerchant is a schema of the API
+type NewMerchant struct {
+	Callback  string `json:"callback,omitempty"`
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// NewMerchantCommission is a schema of the API
+type NewMerchantCommission struct {
+	CommissionReceiving Commission `json:"commission_receiving"`
+	CommissionSending   Commission `json:"commission_sending"`
+}
+
+// NewMerchantDepositMemo is a schema of the API
+type NewMerchantDepositMemo struct {
+	DepositMemo bool `json:"deposit_memo"`
+}
+
+// NewTokenRequest is a schema of the API
+type NewTokenRequest struct {
+	Issuer        string `json:"Issuer,omitempty"`
+	Blockchain    string `json:"blockchain"`
+	Code          string `json:"code"`
+	Description   string `json:"description,omitempty"`
+	InitialAmount int64  `json:"initial_amount,omitempty"`
+	Symbol        string `json:"symbol,omitempty"`
+	Type          string `json:"type"`
+}
+
+// NewTokenResponse is a schema of the API
+type NewTokenResponse struct {
+	Issuer string `json:"Issuer,omitempty"`
+	TxHash string `json:"TxHash,omitempty"`
+}
+
+// NewWebhookEndpoint is a schema of the API
+type NewWebhookEndpoint struct {
+	Events  []string `json:"events,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Purpose string   `json:"purpose"`
+	URL     string   `json:"url"`
+}
+
+// PendingAddressBookPolicy is a schema of the API
+type PendingAddressBookPolicy struct {
+	AllowListOnly     bool      `json:"allow_list_only,omitempty"`
+	CoolingOffSeconds int64     `json:"cooling_off_seconds,omitempty"`
+	EffectiveFrom     time.Time `json:"effective_from,omitempty"`
+}
+
+// QuarantineResolution is a schema of the API
+type QuarantineResolution struct {
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// QuarantinedDeposit is a schema of the API
+type QuarantinedDeposit struct {
+	Amount     json.Number `json:"amount,omitempty"`
+	Asset      string      `json:"asset,omitempty"`
+	Blockchain string      `json:"blockchain,omitempty"`
+	CreatedAt  time.Time   `json:"created_at,omitempty"`
+	ExternalID string      `json:"external_id,omitempty"`
+	Hash       string      `json:"hash,omitempty"`
+	ID         int64       `json:"id,omitempty"`
+	Issuer     string      `json:"issuer,omitempty"`
+	Memo       string      `json:"memo,omitempty"`
+	MerchantID string      `json:"merchant_id,omitempty"`
+	Sender     string      `json:"sender,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	UpdatedAt  time.Time   `json:"updated_at,omitempty"`
+}
+
+// TokenRequest is a schema of the API
+type TokenRequest struct {
+	Amount     string `json:"amount"`
+	Blockchain string `json:"blockchain"`
+	Code       string `json:"code"`
+	Issuer     string `json:"issuer"`
+}
+
+// TransactionHold is a schema of the API
+type TransactionHold struct {
+	CreatedAt       time.Time `json:"created_at,omitempty"`
+	DecidedBy       string    `json:"decided_by,omitempty"`
+	Decision        string    `json:"decision,omitempty"`
+	HeldStatus      string    `json:"held_status,omitempty"`
+	MerchantID      string    `json:"merchant_id,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	RuleID          int64     `json:"rule_id,omitempty"`
+	TransactionGUID string    `json:"transaction_guid,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at,omitempty"`
+}
+
+// TransactionStore is a schema of the API
+type TransactionStore struct {
+	GUID       string      `json:"GUID,omitempty"`
+	Action     string      `json:"action,omitempty"`
+	Amount     json.Number `json:"amount,omitempty"`
+	Asset      string      `json:"asset,omitempty"`
+	Blockchain string      `json:"blockchain,omitempty"`
+	CreatedAt  time.Time   `json:"created_at,omitempty"`
+	ExtWallet  string      `json:"ext_wallet,omitempty"`
+	ExternalID string      `json:"external_id,omitempty"`
+	Issuer     string      `json:"issuer,omitempty"`
+	MerchantID string      `json:"merchant_id,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	UpdatedAt  time.Time   `json:"updated_at,omitempty"`
+}
+
+// UpdateAddressBookEntry is a schema of the API
+type UpdateAddressBookEntry struct {
+	Label string `json:"label"`
+}
+
+// UpdateWebhookEndpoint is a schema of the API
+type UpdateWebhookEndpoint struct {
+	Enabled bool     `json:"enabled"`
+	Events  []string `json:"events,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	URL     string   `json:"url"`
+}
+
+// Wallets is a schema of the API
+type Wallets struct {
+	AddressBook         AddressBookPolicy        `json:"address_book,omitempty"`
+	AddressBookPending  PendingAddressBookPolicy `json:"address_book_pending,omitempty"`
+	CommissionReceiving Commission               `json:"commission_receiving,omitempty"`
+	CommissionSending   Commission               `json:"commission_sending,omitempty"`
+	DepositMemo         bool                     `json:"deposit_memo,omitempty"`
+	ReceivingID         string                   `json:"receiving_id,omitempty"`
+	SendingID           string                   `json:"sending_id,omitempty"`
+	SignPublicKey       string                   `json:"sign_public_key,omitempty"`
+	WithdrawApproval    ApprovalPolicy           `json:"withdraw_approval,omitempty"`
+}
+
+// WebhookEndpoint is a schema of the API
+type WebhookEndpoint struct {
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	Enabled    bool      `json:"enabled,omitempty"`
+	Events     []string  `json:"events,omitempty"`
+	ID         int64     `json:"id,omitempty"`
+	MerchantID string    `json:"merchant_id,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Purpose    string    `json:"purpose,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+	URL        string    `json:"url,omitempty"`
+}
+
+// WebhookEventsResponse is a schema of the API
+type WebhookEventsResponse struct {
+	Events     []string `json:"events,omitempty"`
+	Subscribed []string `json:"subscribed,omitempty"`
+	Version    int64    `json:"version,omitempty"`
+}
+
+// WebhookSubscription is a schema of the API
+type WebhookSubscription struct {
+	Events []string `json:"events"`
+}
+
+// WithdrawResponse is a schema of the API
+type WithdrawResponse struct {
+	Result string `json:"result,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// WithdrawValidationError is a schema of the API
+type WithdrawValidationError struct {
+	Message    string              `json:"message"`
+	Violations []WithdrawViolation `json:"violations"`
+}
+
+// WithdrawViolation is a schema of the API
+type WithdrawViolation struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// BurnToken calls POST /token_burn to burn token of the user of the token
+func (c *Client) BurnToken(ctx context.Context, body TokenRequest) (*NewTokenResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := NewTokenResponse{}
+	if err := c.do(ctx, http.MethodPost, "/token_burn", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CreateAddressBookEntry calls POST /address-book/entries to add address to the address book
+func (c *Client) CreateAddressBookEntry(ctx context.Context, body NewAddressBookEntry) (*AddressBookEntry, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := AddressBookEntry{}
+	if err := c.do(ctx, http.MethodPost, "/address-book/entries", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CreateLimitRule calls POST /limits/rules to add limit rule of deposits or withdraws of the merchant asset
+func (c *Client) CreateLimitRule(ctx context.Context, body NewLimitRule) (*LimitRule, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := LimitRule{}
+	if err := c.do(ctx, http.MethodPost, "/limits/rules", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CreateMerchant calls POST /merchant to create merchant
+func (c *Client) CreateMerchant(ctx context.Context, body NewMerchant) (*MerchantData, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MerchantData{}
+	if err := c.do(ctx, http.MethodPost, "/merchant", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CreateWebhookEndpoint calls POST /webhooks/endpoints to register webhook endpoint
+func (c *Client) CreateWebhookEndpoint(ctx context.Context, body NewWebhookEndpoint) (*CreatedWebhookEndpoint, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := CreatedWebhookEndpoint{}
+	if err := c.do(ctx, http.MethodPost, "/webhooks/endpoints", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DeleteAddressBookEntry calls DELETE /address-book/entries/:id to remove address from the address book
+func (c *Client) DeleteAddressBookEntry(ctx context.Context, id int64) (*MessageResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MessageResponse{}
+	if err := c.do(ctx, http.MethodDelete, "/address-book/entries/"+strconv.FormatInt(id, 10), query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DeleteLimitRule calls DELETE /limits/rules/:id to remove limit rule
+func (c *Client) DeleteLimitRule(ctx context.Context, id int64) (*MessageResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MessageResponse{}
+	if err := c.do(ctx, http.MethodDelete, "/limits/rules/"+strconv.FormatInt(id, 10), query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DeleteWebhookEndpoint calls DELETE /webhooks/endpoints/:id to remove webhook endpoint
+func (c *Client) DeleteWebhookEndpoint(ctx context.Context, id int64) (*MessageResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MessageResponse{}
+	if err := c.do(ctx, http.MethodDelete, "/webhooks/endpoints/"+strconv.FormatInt(id, 10), query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DeleteWithdraw calls DELETE /withdraw/:guid to reject withdrawal which isn't processed yet
+func (c *Client) DeleteWithdraw(ctx context.Context, guid string) (*DeleteWithdrawResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := DeleteWithdrawResponse{}
+	if err := c.do(ctx, http.MethodDelete, "/withdraw/"+url.PathEscape(guid), query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DepositParams are query and header parameters of Deposit
+type DepositParams struct {
+	// IdempotencyKey is key to repeat the request safely, the first response is returned for the same key
+	IdempotencyKey string
+}
+
+// Deposit calls POST /deposit to get address for deposit of the user of the token
+func (c *Client) Deposit(ctx context.Context, params DepositParams, body CredentialDeposit) (*DepositResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.IdempotencyKey != "" {
+		header.Set("Idempotency-Key", params.IdempotencyKey)
+	}
+	res := DepositResponse{}
+	if err := c.do(ctx, http.MethodPost, "/deposit", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetAddressBookParams are query and header parameters of GetAddressBook
+type GetAddressBookParams struct {
+	// Blockchain is blockchain of addresses
+	Blockchain string
+}
+
+// GetAddressBook calls GET /address-book/entries to get addresses the merchant allows to withdraw to
+func (c *Client) GetAddressBook(ctx context.Context, params GetAddressBookParams) ([]AddressBookEntry, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.Blockchain != "" {
+		query.Set("blockchain", params.Blockchain)
+	}
+	var res []AddressBookEntry
+	if err := c.do(ctx, http.MethodGet, "/address-book/entries", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetBalanceParams are query and header parameters of GetBalance
+type GetBalanceParams struct {
+	// Blockchain is blockchain of the balance
+	Blockchain string
+	// Asset is code of the asset
+	Asset string
+}
+
+// GetBalance calls GET /get_balance to get balance of the user of the token
+func (c *Client) GetBalance(ctx context.Context, params GetBalanceParams) (*Balance, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.Blockchain != "" {
+		query.Set("blockchain", params.Blockchain)
+	}
+	if params.Asset != "" {
+		query.Set("asset", params.Asset)
+	}
+	res := Balance{}
+	if err := c.do(ctx, http.MethodGet, "/get_balance", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetHeldTransactionsParams are query and header parameters of GetHeldTransactions
+type GetHeldTransactionsParams struct {
+	// MerchantID is merchant of transactions
+	MerchantID string
+}
+
+// GetHeldTransactions calls GET /limits/holds to get transactions on hold for breaching limits
+func (c *Client) GetHeldTransactions(ctx context.Context, params GetHeldTransactionsParams) ([]HeldTransaction, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.MerchantID != "" {
+		query.Set("merchant_id", params.MerchantID)
+	}
+	var res []HeldTransaction
+	if err := c.do(ctx, http.MethodGet, "/limits/holds", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetLimitRulesParams are query and header parameters of GetLimitRules
+type GetLimitRulesParams struct {
+	// MerchantID is merchant of limit rules
+	MerchantID string
+}
+
+// GetLimitRules calls GET /limits/rules to get limit rules of deposits and withdraws of merchants
+func (c *Client) GetLimitRules(ctx context.Context, params GetLimitRulesParams) ([]LimitRule, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.MerchantID != "" {
+		query.Set("merchant_id", params.MerchantID)
+	}
+	var res []LimitRule
+	if err := c.do(ctx, http.MethodGet, "/limits/rules", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetMerchant calls GET /merchant/:id to get merchant
+func (c *Client) GetMerchant(ctx context.Context, id string) (*MerchantData, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MerchantData{}
+	if err := c.do(ctx, http.MethodGet, "/merchant/"+url.PathEscape(id), query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetMerchantBalancesParams are query and header parameters of GetMerchantBalances
+type GetMerchantBalancesParams struct {
+	// Blockchain is blockchain of balances
+	Blockchain string
+}
+
+// GetMerchantBalances calls GET /balances to get available, reserved, held, incoming and paid as fees funds of the merchant assets
+func (c *Client) GetMerchantBalances(ctx context.Context, params GetMerchantBalancesParams) ([]LedgerBalance, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.Blockchain != "" {
+		query.Set("blockchain", params.Blockchain)
+	}
+	var res []LedgerBalance
+	if err := c.do(ctx, http.MethodGet, "/balances", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetMerchants calls GET /merchants to get all merchants
+func (c *Client) GetMerchants(ctx context.Context) ([]MerchantData, error) {
+	query := url.Values{}
+	header := http.Header{}
+	var res []MerchantData
+	if err := c.do(ctx, http.MethodGet, "/merchants", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetQuarantinedDepositsParams are query and header parameters of GetQuarantinedDeposits
+type GetQuarantinedDepositsParams struct {
+	// Blockchain is blockchain of deposits
+	Blockchain string
+}
+
+// GetQuarantinedDeposits calls GET /deposits/quarantined to get deposits with unknown memo
+func (c *Client) GetQuarantinedDeposits(ctx context.Context, params GetQuarantinedDepositsParams) ([]QuarantinedDeposit, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.Blockchain != "" {
+		query.Set("blockchain", params.Blockchain)
+	}
+	var res []QuarantinedDeposit
+	if err := c.do(ctx, http.MethodGet, "/deposits/quarantined", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetTransactionStatus calls GET /get_transaction_status/:id to get transaction of the merchant by its GUID
+func (c *Client) GetTransactionStatus(ctx context.Context, id string) (*TransactionStore, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := TransactionStore{}
+	if err := c.do(ctx, http.MethodGet, "/get_transaction_status/"+url.PathEscape(id), query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetTransactionsParams are query and header parameters of GetTransactions
+type GetTransactionsParams struct {
+	// Blockchain is blockchain of transactions
+	Blockchain string
+	// From is unix time of the earliest transaction
+	From int64
+	// To is unix time of the latest transaction
+	To int64
+	// Action is comma separated actions of transactions
+	Action string
+	// Status is comma separated statuses of transactions
+	Status string
+}
+
+// GetTransactions calls GET /transactions to get transactions of the merchant
+func (c *Client) GetTransactions(ctx context.Context, params GetTransactionsParams) ([]TransactionStore, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.Blockchain != "" {
+		query.Set("blockchain", params.Blockchain)
+	}
+	if params.From != 0 {
+		query.Set("from", strconv.FormatInt(params.From, 10))
+	}
+	if params.To != 0 {
+		query.Set("to", strconv.FormatInt(params.To, 10))
+	}
+	if params.Action != "" {
+		query.Set("action", params.Action)
+	}
+	if params.Status != "" {
+		query.Set("status", params.Status)
+	}
+	var res []TransactionStore
+	if err := c.do(ctx, http.MethodGet, "/transactions", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetWalletByIdParams are query and header parameters of GetWalletById
+type GetWalletByIdParams struct {
+	// Blockchain is blockchain of the wallet
+	Blockchain string
+}
+
+// GetWalletById calls GET /get_wallet_by_id to get wallet address of the user of the token
+func (c *Client) GetWalletById(ctx context.Context, params GetWalletByIdParams) (*MerchantResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.Blockchain != "" {
+		query.Set("blockchain", params.Blockchain)
+	}
+	res := MerchantResponse{}
+	if err := c.do(ctx, http.MethodGet, "/get_wallet_by_id", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetWebhookEndpoints calls GET /webhooks/endpoints to get webhook endpoints of the merchant
+func (c *Client) GetWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
+	query := url.Values{}
+	header := http.Header{}
+	var res []WebhookEndpoint
+	if err := c.do(ctx, http.MethodGet, "/webhooks/endpoints", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetWebhookEvents calls GET /webhooks/events to get types of webhook events
+func (c *Client) GetWebhookEvents(ctx context.Context) (*WebhookEventsResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := WebhookEventsResponse{}
+	if err := c.do(ctx, http.MethodGet, "/webhooks/events", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// IssueToken calls POST /token_issue to issue token by the wallet of the user of the token
+func (c *Client) IssueToken(ctx context.Context, body NewTokenRequest) (*NewTokenResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := NewTokenResponse{}
+	if err := c.do(ctx, http.MethodPost, "/token_issue", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// MintToken calls POST /token_mint to mint token issued by the merchant
+func (c *Client) MintToken(ctx context.Context, body MintTokenRequest) (*NewTokenResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := NewTokenResponse{}
+	if err := c.do(ctx, http.MethodPost, "/token_mint", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ResolveHeldTransaction calls PUT /limits/holds/:guid to release transaction on hold to processing or reject it
+func (c *Client) ResolveHeldTransaction(ctx context.Context, guid string, body HoldResolution) (*TransactionHold, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := TransactionHold{}
+	if err := c.do(ctx, http.MethodPut, "/limits/holds/"+url.PathEscape(guid), query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ResolveQuarantinedDeposit calls PUT /deposits/quarantined/:id to assign quarantined deposit to the user or dismiss it
+func (c *Client) ResolveQuarantinedDeposit(ctx context.Context, id int64, body QuarantineResolution) (*QuarantinedDeposit, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := QuarantinedDeposit{}
+	if err := c.do(ctx, http.MethodPut, "/deposits/quarantined/"+strconv.FormatInt(id, 10), query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// TestWebhookEndpoint calls POST /webhooks/endpoints/:id/test to send test event to webhook endpoint
+func (c *Client) TestWebhookEndpoint(ctx context.Context, id int64) (*MessageResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MessageResponse{}
+	if err := c.do(ctx, http.MethodPost, "/webhooks/endpoints/"+strconv.FormatInt(id, 10)+"/test", query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateAddressBookEntry calls PUT /address-book/entries/:id to change label of address in the address book
+func (c *Client) UpdateAddressBookEntry(ctx context.Context, id int64, body UpdateAddressBookEntry) (*MessageResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MessageResponse{}
+	if err := c.do(ctx, http.MethodPut, "/address-book/entries/"+strconv.FormatInt(id, 10), query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateAddressBookPolicy calls PUT /address-book/policy to set allow-list only mode and cooling-off of new addresses
+func (c *Client) UpdateAddressBookPolicy(ctx context.Context, body AddressBookSettings) (*MerchantResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MerchantResponse{}
+	if err := c.do(ctx, http.MethodPut, "/address-book/policy", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateAssetWithdrawLimits calls PUT /asset/withdraw-limits to set the least and the largest amounts of the asset to withdraw
+func (c *Client) UpdateAssetWithdrawLimits(ctx context.Context, body AssetWithdrawLimits) (*MessageResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MessageResponse{}
+	if err := c.do(ctx, http.MethodPut, "/asset/withdraw-limits", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateLimitRule calls PUT /limits/rules/:id to replace limit rule
+func (c *Client) UpdateLimitRule(ctx context.Context, id int64, body NewLimitRule) (*MessageResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MessageResponse{}
+	if err := c.do(ctx, http.MethodPut, "/limits/rules/"+strconv.FormatInt(id, 10), query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateMerchant calls PUT /merchant/:id to update public key, name and callback url of the merchant
+func (c *Client) UpdateMerchant(ctx context.Context, id string, body NewMerchant) (*MerchantResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MerchantResponse{}
+	if err := c.do(ctx, http.MethodPut, "/merchant/"+url.PathEscape(id), query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateMerchantCommission calls PUT /merchant/:id/:blockchain/commission to update commissions of the merchant
+func (c *Client) UpdateMerchantCommission(ctx context.Context, id string, blockchain string, body NewMerchantCommission) (*MerchantResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MerchantResponse{}
+	if err := c.do(ctx, http.MethodPut, "/merchant/"+url.PathEscape(id)+"/"+url.PathEscape(blockchain)+"/commission", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateMerchantDepositMemo calls PUT /merchant/:id/:blockchain/deposit-memo to enable deposits with memo to the receiving wallet
+func (c *Client) UpdateMerchantDepositMemo(ctx context.Context, id string, blockchain string, body NewMerchantDepositMemo) (*MerchantResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MerchantResponse{}
+	if err := c.do(ctx, http.MethodPut, "/merchant/"+url.PathEscape(id)+"/"+url.PathEscape(blockchain)+"/deposit-memo", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateMerchantWithdrawApproval calls PUT /merchant/:id/:blockchain/withdraw-approval to set approvals of withdraws by merchant users
+func (c *Client) UpdateMerchantWithdrawApproval(ctx context.Context, id string, blockchain string, body ApprovalPolicy) (*MerchantResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MerchantResponse{}
+	if err := c.do(ctx, http.MethodPut, "/merchant/"+url.PathEscape(id)+"/"+url.PathEscape(blockchain)+"/withdraw-approval", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateWebhookEndpoint calls PUT /webhooks/endpoints/:id to update webhook endpoint
+func (c *Client) UpdateWebhookEndpoint(ctx context.Context, id int64, body UpdateWebhookEndpoint) (*MessageResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MessageResponse{}
+	if err := c.do(ctx, http.MethodPut, "/webhooks/endpoints/"+strconv.FormatInt(id, 10), query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateWebhookSubscription calls PUT /webhooks/subscription to subscribe the merchant to types of webhook events
+func (c *Client) UpdateWebhookSubscription(ctx context.Context, body WebhookSubscription) (*MerchantResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	res := MerchantResponse{}
+	if err := c.do(ctx, http.MethodPut, "/webhooks/subscription", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UpdateWithdrawParams are query and header parameters of UpdateWithdraw
+type UpdateWithdrawParams struct {
+	// Hash is hash of the transaction
+	Hash string
+}
+
+// UpdateWithdraw calls PUT /withdraw/:guid to confirm withdrawal by hash of the transaction
+func (c *Client) UpdateWithdraw(ctx context.Context, guid string, params UpdateWithdrawParams) (*DeleteWithdrawResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.Hash != "" {
+		query.Set("hash", params.Hash)
+	}
+	res := DeleteWithdrawResponse{}
+	if err := c.do(ctx, http.MethodPut, "/withdraw/"+url.PathEscape(guid), query, header, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// WithdrawParams are query and header parameters of Withdraw
+type WithdrawParams struct {
+	// IdempotencyKey is key to repeat the request safely, the first response is returned for the same key
+	IdempotencyKey string
+}
+
+// Withdraw calls POST /withdraw to request withdrawal of the user of the token
+func (c *Client) Withdraw(ctx context.Context, params WithdrawParams, body CredentialWithdraw) (*WithdrawResponse, error) {
+	query := url.Values{}
+	header := http.Header{}
+	if params.IdempotencyKey != "" {
+		header.Set("Idempotency-Key", params.IdempotencyKey)
+	}
+	res := WithdrawResponse{}
+	if err := c.do(ctx, http.MethodPost, "/withdraw", query, header, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
@@ -0,0 +1,86 @@
+// Package client is Go client of the backend REST API of the processing,
+// its types and methods in api.go are generated from OpenAPI document of the API
+package client
+
+//go:generate go run ../../cmd/openapi-gen -client api.go -spec ../../documentation/openapi.json
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the backend REST API with JWT of the merchant or the admin
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Error is returned if the API responds with status other than 2xx
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("processing returned status: %v, message: %v", e.StatusCode, e.Message)
+}
+
+// NewClient creates client of the API at the base url which authorizes requests by the token,
+// http.DefaultClient is used if the http client is nil
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// do sends the request with the body as JSON and reads JSON response to res
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header,
+	body interface{}, res interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("can't marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		header.Set("Content-Type", "application/json")
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("can't create request: %w", err)
+	}
+	req.Header = header
+	req.Header.Set("Authorization", c.token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("can't read response: %w", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if err = json.Unmarshal(data, res); err != nil {
+		return fmt.Errorf("can't unmarshal response: %w", err)
+	}
+	return nil
+}
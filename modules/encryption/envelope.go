@@ -0,0 +1,124 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const keySize = 32
+
+// envelope is stored instead of plaintext data, the data is encrypted by its own data key
+// which is wrapped by the master key
+type envelope struct {
+	KeyID      string `json:"kid"`
+	DataKey    []byte `json:"dk"`
+	Ciphertext []byte `json:"ct"`
+}
+
+// Seal encrypts the data with a new data key wrapped by the current master key of the provider,
+// the additional data binds the result to its record, the same additional data must be passed to Open
+func Seal(provider KeyProvider, data, additional []byte) ([]byte, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("could not generate data key: %w", err)
+	}
+	ciphertext, err := seal(dataKey, data, additional)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := provider.Wrap(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not wrap data key: %w", err)
+	}
+	return json.Marshal(envelope{KeyID: provider.KeyID(), DataKey: wrapped, Ciphertext: ciphertext})
+}
+
+// Open decrypts the data sealed by Seal with the same additional data, data which is not sealed is returned as is
+func Open(provider KeyProvider, data, additional []byte) ([]byte, error) {
+	env, ok := parse(data)
+	if !ok {
+		return data, nil
+	}
+	dataKey, err := provider.Unwrap(env.KeyID, env.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not unwrap data key: %w", err)
+	}
+	return open(dataKey, env.Ciphertext, additional)
+}
+
+// Rewrap wraps data key of the sealed data with the current master key of the provider,
+// the data itself is not decrypted, it returns false if the data is already wrapped by the current key
+func Rewrap(provider KeyProvider, data []byte) ([]byte, bool, error) {
+	env, ok := parse(data)
+	if !ok {
+		return nil, false, errors.New("data is not sealed")
+	}
+	if env.KeyID == provider.KeyID() {
+		return data, false, nil
+	}
+	dataKey, err := provider.Unwrap(env.KeyID, env.DataKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not unwrap data key: %w", err)
+	}
+	if env.DataKey, err = provider.Wrap(dataKey); err != nil {
+		return nil, false, fmt.Errorf("could not wrap data key: %w", err)
+	}
+	env.KeyID = provider.KeyID()
+	res, err := json.Marshal(env)
+	return res, err == nil, err
+}
+
+// IsSealed reports whether the data is sealed by Seal
+func IsSealed(data []byte) bool {
+	_, ok := parse(data)
+	return ok
+}
+
+func parse(data []byte) (envelope, bool) {
+	env := envelope{}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return env, false
+	}
+	return env, env.KeyID != "" && len(env.DataKey) > 0 && len(env.Ciphertext) > 0
+}
+
+// seal encrypts the data with AES-GCM, nonce is prepended to the result
+func seal(key, data, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("could not generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, data, additional), nil
+}
+
+func open(key, data, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	res, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additional)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt data: %w", err)
+	}
+	return res, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
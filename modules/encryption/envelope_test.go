@@ -0,0 +1,80 @@
+package encryption
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestProvider(t *testing.T, current string, ids ...string) *StaticKeyProvider {
+	keys := map[string][]byte{}
+	for i, id := range append(ids, current) {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, keySize)
+	}
+	provider, err := NewStaticKeyProvider(current, keys)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestSealOpen(t *testing.T) {
+	provider := newTestProvider(t, "key-1")
+	data := []byte("wallet mnemonic")
+	sealed, err := Seal(provider, data, []byte("merchant:user"))
+	require.NoError(t, err)
+	require.True(t, IsSealed(sealed))
+	require.NotContains(t, string(sealed), string(data))
+
+	opened, err := Open(provider, sealed, []byte("merchant:user"))
+	require.NoError(t, err)
+	require.Equal(t, data, opened)
+}
+
+func TestOpenWithWrongKey(t *testing.T) {
+	sealed, err := Seal(newTestProvider(t, "key-1"), []byte("wallet mnemonic"), nil)
+	require.NoError(t, err)
+
+	// master key of the same id which differs from the sealing one
+	other, err := NewStaticKeyProvider("key-1", map[string][]byte{"key-1": bytes.Repeat([]byte{9}, keySize)})
+	require.NoError(t, err)
+	_, err = Open(other, sealed, nil)
+	require.Error(t, err)
+
+	_, err = Open(newTestProvider(t, "key-2"), sealed, nil)
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestOpenWithWrongAdditionalData(t *testing.T) {
+	provider := newTestProvider(t, "key-1")
+	sealed, err := Seal(provider, []byte("wallet mnemonic"), []byte("merchant:alice"))
+	require.NoError(t, err)
+
+	// sealed data copied to another record can't be opened
+	_, err = Open(provider, sealed, []byte("merchant:bob"))
+	require.Error(t, err)
+}
+
+func TestOpenNotSealed(t *testing.T) {
+	data := []byte(`{"wallet_address": "core1user", "wallet_seed": "seed"}`)
+	require.False(t, IsSealed(data))
+
+	opened, err := Open(newTestProvider(t, "key-1"), data, []byte("merchant:user"))
+	require.NoError(t, err)
+	require.Equal(t, data, opened)
+}
+
+func TestRewrap(t *testing.T) {
+	sealed, err := Seal(newTestProvider(t, "key-1"), []byte("wallet mnemonic"), nil)
+	require.NoError(t, err)
+
+	rotated := newTestProvider(t, "key-2", "key-1")
+	rewrapped, changed, err := Rewrap(rotated, sealed)
+	require.NoError(t, err)
+	require.True(t, changed)
+	opened, err := Open(rotated, rewrapped, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("wallet mnemonic"), opened)
+
+	_, changed, err = Rewrap(rotated, rewrapped)
+	require.NoError(t, err)
+	require.False(t, changed)
+}
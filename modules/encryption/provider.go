@@ -0,0 +1,104 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrUnknownKey is returned when a record is wrapped by master key the provider doesn't have
+var ErrUnknownKey = errors.New("unknown master key")
+
+// KeyProvider wraps data keys of records with a master key, it can be implemented by KMS or Vault,
+// the current key is used to wrap and any known key is used to unwrap, so the master key can be rotated
+type KeyProvider interface {
+	// KeyID returns id of the current master key
+	KeyID() string
+	// Wrap encrypts the data key with the current master key
+	Wrap(dataKey []byte) ([]byte, error)
+	// Unwrap decrypts the data key with the master key of the given id
+	Unwrap(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeyProvider keeps master keys in memory
+type StaticKeyProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewStaticKeyProvider creates a provider with master keys by their ids, every key must be 32 bytes
+func NewStaticKeyProvider(current string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownKey, current)
+	}
+	for id, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("master key: %v must be %d bytes", id, keySize)
+		}
+	}
+	return &StaticKeyProvider{current: current, keys: keys}, nil
+}
+
+// NewEnvKeyProvider creates a provider from base64 encoded master key in the environment variable,
+// previous keys for rotation are set as comma separated list of id:key pairs in the second variable
+func NewEnvKeyProvider(idEnv, keyEnv, previousEnv string) (*StaticKeyProvider, error) {
+	current := os.Getenv(idEnv)
+	if current == "" {
+		return nil, fmt.Errorf("master key id is not set in %v", idEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(os.Getenv(keyEnv))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode master key from %v: %w", keyEnv, err)
+	}
+	keys := map[string][]byte{current: key}
+	for _, pair := range strings.Split(os.Getenv(previousEnv), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("previous master key must be set as id:key in %v", previousEnv)
+		}
+		if keys[id], err = base64.StdEncoding.DecodeString(value); err != nil {
+			return nil, fmt.Errorf("could not decode master key: %v from %v: %w", id, previousEnv, err)
+		}
+	}
+	return NewStaticKeyProvider(current, keys)
+}
+
+// NewFileKeyProvider creates a provider from JSON file with base64 encoded master keys:
+// {"current": "key-2", "keys": {"key-1": "...", "key-2": "..."}}
+func NewFileKeyProvider(path string) (*StaticKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read master keys file: %v, err: %w", path, err)
+	}
+	file := struct {
+		Current string            `json:"current"`
+		Keys    map[string][]byte `json:"keys"`
+	}{}
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("could not parse master keys file: %v, err: %w", path, err)
+	}
+	return NewStaticKeyProvider(file.Current, file.Keys)
+}
+
+func (p *StaticKeyProvider) KeyID() string {
+	return p.current
+}
+
+func (p *StaticKeyProvider) Wrap(dataKey []byte) ([]byte, error) {
+	return seal(p.keys[p.current], dataKey, nil)
+}
+
+func (p *StaticKeyProvider) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownKey, keyID)
+	}
+	return open(key, wrapped, nil)
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"coreum_processor/modules/internal"
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// GetAddressBook method for getting addresses the merchant allows to withdraw to
+func GetAddressBook(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.GetAddressBook(merchantID, r.URL.Query().Get("blockchain"))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not get address book", http.StatusInternalServerError)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// CreateAddressBookEntry method for adding address to the address book of the merchant
+func CreateAddressBookEntry(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		entry := service.NewAddressBookEntry{}
+		err = json.NewDecoder(r.Body).Decode(&entry)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.CreateAddressBookEntry(merchantID, entry)
+		if errors.Is(err, storage.ErrAddressExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not add address: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// UpdateAddressBookEntry method for changing label of address in the address book of the merchant
+func UpdateAddressBookEntry(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, id, err := getAddressRequest(r, ps)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		entry := service.UpdateAddressBookEntry{}
+		err = json.NewDecoder(r.Body).Decode(&entry)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = processing.UpdateAddressBookEntry(merchantID, id, entry)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find address", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not update address", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"message":"Updated successfully"}`))
+	}
+}
+
+// DeleteAddressBookEntry method for removing address from the address book of the merchant
+func DeleteAddressBookEntry(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, id, err := getAddressRequest(r, ps)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = processing.DeleteAddressBookEntry(merchantID, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find address", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not delete address", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"message":"Deleted successfully"}`))
+	}
+}
+
+// UpdateAddressBookPolicy method for switching allow-list only mode and cooling-off period of the merchant
+func UpdateAddressBookPolicy(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		settings := service.AddressBookSettings{}
+		err = json.NewDecoder(r.Body).Decode(&settings)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		_, err = processing.UpdateAddressBookPolicy(merchantID, settings)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not update address book policy: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		merchantReturn := service.MerchantResponse{MerchantId: merchantID}
+		err = json.NewEncoder(w).Encode(merchantReturn)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// getAddressRequest returns the merchant and id of address in its address book from the request
+func getAddressRequest(r *http.Request, ps httprouter.Params) (string, int64, error) {
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	merchantID, err := internal.GetMerchantID(r.Context())
+	if err != nil {
+		return "", 0, err
+	}
+	return merchantID, id, nil
+}
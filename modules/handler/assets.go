@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"coreum_processor/modules/asset"
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// UpdateAssetWithdrawLimits method for setting the least and the largest amounts of the asset to withdraw at once
+func UpdateAssetWithdrawLimits(processing *service.ProcessingService, assetService *asset.Service) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		limits := service.AssetWithdrawLimits{}
+		err := json.NewDecoder(r.Body).Decode(&limits)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = assetService.SetWithdrawLimits(strings.ToLower(limits.Blockchain), limits.Code, limits.Issuer,
+			storage.WithdrawLimits{Min: limits.MinWithdraw, Max: limits.MaxWithdraw})
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "asset is not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not update withdraw limits of asset", http.StatusBadRequest)
+			return
+		}
+		err = json.NewEncoder(w).Encode(service.MessageResponse{Message: "Updated successfully"})
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -45,3 +45,30 @@ func GetBalance(ctx context.Context, processing *service.ProcessingService) http
 		}
 	}
 }
+
+// GetMerchantBalances is a method for getting available and reserved funds of the merchant assets
+func GetMerchantBalances(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		blockchain := strings.ToLower(r.URL.Query().Get("blockchain"))
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not find merchant", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.GetMerchantBalances(r.Context(), merchantID, blockchain)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not get balances of merchant", http.StatusBadRequest)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"coreum_processor/modules/internal"
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// GetWebhookEndpoints method for getting webhook endpoints of the merchant
+func GetWebhookEndpoints(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.GetWebhookEndpoints(merchantID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not get webhook endpoints", http.StatusInternalServerError)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// CreateWebhookEndpoint method for registering webhook endpoint of the merchant, the response has its secret
+func CreateWebhookEndpoint(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		endpoint := service.NewWebhookEndpoint{}
+		err = json.NewDecoder(r.Body).Decode(&endpoint)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.CreateWebhookEndpoint(merchantID, endpoint)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not create webhook endpoint: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// UpdateWebhookEndpoint method for changing url, name, enable flag and event filter of webhook endpoint
+func UpdateWebhookEndpoint(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, id, err := getEndpointRequest(r, ps)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		endpoint := service.UpdateWebhookEndpoint{}
+		err = json.NewDecoder(r.Body).Decode(&endpoint)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = processing.UpdateWebhookEndpoint(merchantID, id, endpoint)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find webhook endpoint", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not update webhook endpoint: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"message":"Updated successfully"}`))
+	}
+}
+
+// DeleteWebhookEndpoint method for removing webhook endpoint of the merchant
+func DeleteWebhookEndpoint(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, id, err := getEndpointRequest(r, ps)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = processing.DeleteWebhookEndpoint(merchantID, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find webhook endpoint", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not delete webhook endpoint", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"message":"Deleted successfully"}`))
+	}
+}
+
+// TestWebhookEndpoint method for sending test event to webhook endpoint of the merchant
+func TestWebhookEndpoint(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, id, err := getEndpointRequest(r, ps)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = processing.SendTestWebhook(merchantID, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find webhook endpoint", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "test event is not delivered: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"message":"Test event delivered"}`))
+	}
+}
+
+// getEndpointRequest returns the merchant and id of webhook endpoint from the request
+func getEndpointRequest(r *http.Request, ps httprouter.Params) (string, int64, error) {
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	merchantID, err := internal.GetMerchantID(r.Context())
+	if err != nil {
+		return "", 0, err
+	}
+	return merchantID, id, nil
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// GetLimitRules method for getting limit rules of deposits and withdraws of merchants
+func GetLimitRules(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		res, err := processing.GetLimitRules(r.URL.Query().Get("merchant_id"))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not get limit rules", http.StatusInternalServerError)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// CreateLimitRule method for adding limit rule of deposits or withdraws of the merchant asset
+func CreateLimitRule(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		rule := service.NewLimitRule{}
+		err := json.NewDecoder(r.Body).Decode(&rule)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.CreateLimitRule(rule)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not add limit rule: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// UpdateLimitRule method for replacing limit rule
+func UpdateLimitRule(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		rule := service.NewLimitRule{}
+		err = json.NewDecoder(r.Body).Decode(&rule)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = processing.UpdateLimitRule(id, rule)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find limit rule", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not update limit rule: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"message":"Updated successfully"}`))
+	}
+}
+
+// DeleteLimitRule method for removing limit rule
+func DeleteLimitRule(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = processing.DeleteLimitRule(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find limit rule", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not delete limit rule", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"message":"Deleted successfully"}`))
+	}
+}
+
+// GetHeldTransactions method for getting transactions on hold for breaching limits
+func GetHeldTransactions(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		res, err := processing.GetHeldTransactions(r.URL.Query().Get("merchant_id"))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not fetch a list of transactions on hold", http.StatusInternalServerError)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// ResolveHeldTransaction method for releasing transaction on hold to processing or rejecting it
+func ResolveHeldTransaction(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		resolution := service.HoldResolution{}
+		err := json.NewDecoder(r.Body).Decode(&resolution)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.ResolveHeldTransaction(ps.ByName("guid"), "", resolution)
+		writeHoldResolution(w, res, err)
+	}
+}
+
+// writeHoldResolution writes the resolved hold or the error of its resolution
+func writeHoldResolution(w http.ResponseWriter, res *storage.TransactionHold, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "could not find transaction on hold", http.StatusNotFound)
+		return
+	} else if errors.Is(err, storage.ErrInvalidTransition) {
+		http.Error(w, "transaction on hold is already resolved", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		http.Error(w, "could not resolve transaction on hold", http.StatusBadRequest)
+		return
+	}
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+		return
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"coreum_processor/modules/amount"
 	"coreum_processor/modules/internal"
 	"coreum_processor/modules/service"
 	"coreum_processor/modules/storage"
@@ -132,8 +133,8 @@ func CreateWallet(ctx context.Context, processing *service.ProcessingService) ht
 		}
 		// TODO define default commission
 		commission := service.Commission{
-			Fix:     1,
-			Percent: 1,
+			Fix:     amount.NewFromInt64(1),
+			Percent: amount.NewFromInt64(1),
 		}
 		wallets := service.Wallets{
 			CommissionReceiving: commission,
@@ -289,6 +290,68 @@ func UpdateMerchantCommission(ctx context.Context, processing *service.Processin
 	}
 }
 
+// UpdateMerchantDepositMemo method for switching deposits of the merchant to the receiving wallet with memo
+func UpdateMerchantDepositMemo(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		newMerchantDepositMemo := service.NewMerchantDepositMemo{}
+		merchantID := ps.ByName("id")
+		blockchain := strings.ToLower(ps.ByName("blockchain"))
+
+		err := json.NewDecoder(r.Body).Decode(&newMerchantDepositMemo)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		_, err = processing.UpdateMerchantDepositMemo(merchantID, blockchain, newMerchantDepositMemo)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not update merchants deposit memo", http.StatusBadRequest)
+			return
+		}
+		merchantReturn := service.MerchantResponse{MerchantId: merchantID}
+		err = json.NewEncoder(w).Encode(merchantReturn)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// UpdateMerchantWithdrawApproval method for setting the policy of approvals of withdraws of the merchant
+func UpdateMerchantWithdrawApproval(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		policy := service.ApprovalPolicy{}
+		merchantID := ps.ByName("id")
+		blockchain := strings.ToLower(ps.ByName("blockchain"))
+
+		err := json.NewDecoder(r.Body).Decode(&policy)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		_, err = processing.UpdateWithdrawApproval(merchantID, blockchain, policy)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not update merchants withdraw approval", http.StatusBadRequest)
+			return
+		}
+		merchantReturn := service.MerchantResponse{MerchantId: merchantID}
+		err = json.NewEncoder(w).Encode(merchantReturn)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // GetWalletById method for getting a wallet data on given blockchain by its id
 func GetWalletById(processing *service.ProcessingService) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"coreum_processor/modules/internal"
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetQuarantinedDeposits method for getting deposits of the merchant with unknown memo
+func GetQuarantinedDeposits(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		blockchain := strings.ToLower(r.URL.Query().Get("blockchain"))
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.GetQuarantinedDeposits(merchantID, blockchain)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not fetch a list of quarantined deposits", http.StatusBadRequest)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// ResolveQuarantinedDeposit method for assigning quarantined deposit to the user or dismissing it
+func ResolveQuarantinedDeposit(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		resolution := service.QuarantineResolution{}
+		err = json.NewDecoder(r.Body).Decode(&resolution)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		res, err := processing.ResolveQuarantinedDeposit(merchantID, id, resolution)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find quarantined deposit", http.StatusNotFound)
+			return
+		} else if errors.Is(err, storage.ErrInvalidTransition) {
+			http.Error(w, "quarantined deposit is already resolved", http.StatusConflict)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not resolve quarantined deposit", http.StatusBadRequest)
+			return
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"coreum_processor/modules/internal"
 	"coreum_processor/modules/service"
 	"encoding/json"
+	"errors"
 	"github.com/julienschmidt/httprouter"
 	"log"
 	"net/http"
@@ -55,7 +56,7 @@ func Deposit(ctx context.Context, processing *service.ProcessingService) httprou
 	}
 }
 
-func Withdraw(processing *service.ProcessingService) httprouter.Handle {
+func Withdraw(ctx context.Context, processing *service.ProcessingService) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		w = processing.SetHeaders(w)
 		credentialsWithdraw := service.CredentialWithdraw{}
@@ -81,8 +82,17 @@ func Withdraw(processing *service.ProcessingService) httprouter.Handle {
 		}
 
 		credentialsWithdraw.Blockchain = strings.ToLower(credentialsWithdraw.Blockchain)
-		res, err := processing.InitWithdraw(credentialsWithdraw, merchantID, externalId)
-		if err != nil {
+		res, err := processing.InitWithdraw(ctx, credentialsWithdraw, merchantID, externalId)
+		validation := &service.WithdrawValidationError{}
+		if errors.As(err, &validation) {
+			log.Println(err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			if err = json.NewEncoder(w).Encode(validation); err != nil {
+				log.Println(err)
+			}
+			return
+		} else if err != nil {
 			log.Println(err)
 			http.Error(w, "could not perform withdraw", http.StatusBadRequest)
 			return
@@ -116,14 +126,14 @@ func UpdateWithdraw(processing *service.ProcessingService) httprouter.Handle {
 			return
 		}
 
-		err = processing.UpdateWithdraw(transactionGuid, merchantID, externalId, hash)
+		status, err := processing.UpdateWithdraw(transactionGuid, merchantID, externalId, hash, "")
 		if err != nil {
 			log.Println(err)
 			http.Error(w, "could not update withdraw", http.StatusBadRequest)
 			return
 		}
 
-		deleteWithdrawReturn := service.DeleteWithdrawResponse{Status: "success"}
+		deleteWithdrawReturn := service.DeleteWithdrawResponse{Status: status}
 		err = json.NewEncoder(w).Encode(deleteWithdrawReturn)
 		if err != nil {
 			log.Println(err)
@@ -5,8 +5,10 @@ import (
 	"coreum_processor/modules/asset"
 	"coreum_processor/modules/internal"
 	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
 	"coreum_processor/modules/user"
 	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 	"html/template"
@@ -183,6 +185,100 @@ func PageAssetRequestsAdmin(ctx context.Context, assetService *asset.Service, pr
 	}
 }
 
+func PageRefundsAdmin(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		t, err := template.ParseFiles("./templates/lite/refunds/refunds.html", "./templates/lite/admin-sidebar.html")
+		if err != nil {
+			w.WriteHeader(http.StatusNoContent)
+			w.Write([]byte(`{"message":"` + `template parsing error` + `"}`))
+			return
+		}
+
+		refunds, err := processing.GetPendingRefunds()
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not get refunds", http.StatusInternalServerError)
+			return
+		}
+
+		err = t.Execute(w, refunds)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"` + `template parsing error` + `"}`))
+			return
+		}
+	}
+}
+
+// PageHoldsAdmin shows transactions on hold for breaching limits of merchants
+func PageHoldsAdmin(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if _, ok := requireSysAdmin(w, r); !ok {
+			return
+		}
+		t, err := template.ParseFiles("./templates/lite/holds/holds.html", "./templates/lite/admin-sidebar.html")
+		if err != nil {
+			w.WriteHeader(http.StatusNoContent)
+			w.Write([]byte(`{"message":"` + `template parsing error` + `"}`))
+			return
+		}
+
+		holds, err := processing.GetHeldTransactions("")
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not get transactions on hold", http.StatusInternalServerError)
+			return
+		}
+
+		err = t.Execute(w, holds)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"` + `template parsing error` + `"}`))
+			return
+		}
+	}
+}
+
+// ResolveHoldAdmin releases the transaction on hold to processing or rejects it by decision of the admin
+func ResolveHoldAdmin(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		userStore, ok := requireSysAdmin(w, r)
+		if !ok {
+			return
+		}
+		resolution := service.HoldResolution{}
+		err := json.NewDecoder(r.Body).Decode(&resolution)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+
+		hold, err := processing.ResolveHeldTransaction(ps.ByName("guid"), userStore.Identity, resolution)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "could not find transaction on hold", http.StatusNotFound)
+			return
+		} else if errors.Is(err, storage.ErrInvalidTransition) {
+			log.Println(err)
+			http.Error(w, "transaction on hold is already resolved", http.StatusConflict)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not resolve transaction on hold", http.StatusBadRequest)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(hold)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 func PageAssetRequestsAdminUpdate(ctx context.Context, assetService *asset.Service, processing *service.ProcessingService) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		//Getting data from the request
@@ -257,3 +353,19 @@ func PageAssetRequestsAdminUpdate(ctx context.Context, assetService *asset.Servi
 		}
 	}
 }
+
+// requireSysAdmin returns the user of the session if the user is a system admin,
+// otherwise it responds with forbidden status
+func requireSysAdmin(w http.ResponseWriter, r *http.Request) (*storage.UserStore, bool) {
+	userStore, err := internal.GetUserStore(r.Context())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "could not find user", http.StatusForbidden)
+		return nil, false
+	}
+	if !user.IsSysAdmin(userStore.Access) {
+		http.Error(w, "access is allowed only to admins", http.StatusForbidden)
+		return nil, false
+	}
+	return userStore, true
+}
@@ -2,12 +2,14 @@ package ui
 
 import (
 	"context"
+	"coreum_processor/modules/amount"
 	"coreum_processor/modules/asset"
 	"coreum_processor/modules/internal"
 	"coreum_processor/modules/service"
 	"coreum_processor/modules/storage"
 	"coreum_processor/modules/user"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"html/template"
@@ -119,7 +121,7 @@ func generateTransactionTable(res []storage.TransactionStore) template.HTML {
 			"<td>" + res[i].ExtWallet + "</td>" +
 			"<td class=\"action_btn" + string(res[i].Status) + "\">" + string(res[i].Status) + "</td>" +
 			"<td>" + res[i].Asset + "</td>" +
-			"<td>" + fmt.Sprintf("%v", res[i].Amount) + "</td>" +
+			"<td>" + res[i].Amount.String() + "</td>" +
 			"</tr></tbody>"
 	}
 	return template.HTML(htmlBlock)
@@ -231,10 +233,21 @@ func PageMerchantSettings(processing *service.ProcessingService, assetService *a
 			w.Write([]byte(`{"message":"` + `data parsing error` + `"}`))
 			return
 		}
+		endpoints, err := processing.GetWebhookEndpoints(merchantID)
+		if err != nil {
+			log.Println(err)
+		}
+		addresses, err := processing.GetAddressBook(merchantID, "")
+		if err != nil {
+			log.Println(err)
+		}
 		varmap := map[string]interface{}{
 			"tokens":       generateAssetsTable(res),
 			"key":          merchantData.PublicKey,
 			"callback_url": merchantData.CallBackURL,
+			"endpoints":    endpoints,
+			"addresses":    addresses,
+			"wallets":      merchantData.Wallets,
 		}
 
 		err = t.ExecuteTemplate(w, "settings.html", varmap)
@@ -376,7 +389,6 @@ func Deposit(ctx context.Context, processing *service.ProcessingService) httprou
 		}
 		err := json.NewDecoder(r.Body).Decode(&raw)
 		CredentialsDeposit := service.CredentialDeposit{
-			Amount:     0,
 			Blockchain: raw.Blockchain,
 			Asset:      "",
 			Issuer:     "",
@@ -412,10 +424,10 @@ func TransferMerchantWallets(ctx context.Context, processing *service.Processing
 		w = processing.SetHeaders(w)
 
 		var raw struct {
-			Amount     float64 `json:"amount"`
-			Blockchain string  `json:"blockchain"`
-			Asset      string  `json:"asset"`
-			Issuer     string  `json:"issuer"`
+			Amount     amount.Amount `json:"amount"`
+			Blockchain string        `json:"blockchain"`
+			Asset      string        `json:"asset"`
+			Issuer     string        `json:"issuer"`
 		}
 		err := json.NewDecoder(r.Body).Decode(&raw)
 		if err != nil {
@@ -455,17 +467,17 @@ func TransferMerchantWallets(ctx context.Context, processing *service.Processing
 	}
 }
 
-func Withdraw(processing *service.ProcessingService) httprouter.Handle {
+func Withdraw(ctx context.Context, processing *service.ProcessingService) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		w = processing.SetHeaders(w)
 
 		var raw struct {
-			Amount        float64 `json:"amount"`
-			Blockchain    string  `json:"blockchain"`
-			WalletAddress string  `json:"wallet_address"`
-			Asset         string  `json:"asset"`
-			Issuer        string  `json:"issuer"`
-			ExternalID    string  `json:"externalID"`
+			Amount        amount.Amount `json:"amount"`
+			Blockchain    string        `json:"blockchain"`
+			WalletAddress string        `json:"wallet_address"`
+			Asset         string        `json:"asset"`
+			Issuer        string        `json:"issuer"`
+			ExternalID    string        `json:"externalID"`
 		}
 		err := json.NewDecoder(r.Body).Decode(&raw)
 		if err != nil {
@@ -491,8 +503,13 @@ func Withdraw(processing *service.ProcessingService) httprouter.Handle {
 		}
 
 		credentialsWithdraw.Blockchain = strings.ToLower(credentialsWithdraw.Blockchain)
-		res, err := processing.InitWithdraw(credentialsWithdraw, merchantID, raw.ExternalID)
-		if err != nil {
+		res, err := processing.InitWithdraw(ctx, credentialsWithdraw, merchantID, raw.ExternalID)
+		validation := &service.WithdrawValidationError{}
+		if errors.As(err, &validation) {
+			log.Println(err)
+			http.Error(w, validation.Error(), http.StatusUnprocessableEntity)
+			return
+		} else if err != nil {
 			log.Println(err)
 			http.Error(w, "could not perform withdraw", http.StatusBadRequest)
 			return
@@ -529,14 +546,21 @@ func UpdateWithdraw(processing *service.ProcessingService) httprouter.Handle {
 			return
 		}
 
-		err = processing.UpdateWithdraw(raw.Guid, merchantID, raw.ExternalID, "")
+		userStore, err := internal.GetUserStore(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not find user", http.StatusBadRequest)
+			return
+		}
+
+		status, err := processing.UpdateWithdraw(raw.Guid, merchantID, raw.ExternalID, "", userStore.Identity)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, "could not update withdraw", http.StatusBadRequest)
 			return
 		}
 
-		deleteWithdrawReturn := service.DeleteWithdrawResponse{Status: "success"}
+		deleteWithdrawReturn := service.DeleteWithdrawResponse{Status: status}
 		err = json.NewEncoder(w).Encode(deleteWithdrawReturn)
 		if err != nil {
 			log.Println(err)
@@ -545,3 +569,153 @@ func UpdateWithdraw(processing *service.ProcessingService) httprouter.Handle {
 		}
 	}
 }
+
+// PageMerchantWebhooks shows webhooks of the merchant which exhausted their delivery attempts
+func PageMerchantWebhooks(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		t, err := template.ParseFiles("./templates/lite/default/webhooks.html", "./templates/lite/sidebar.html")
+		if err != nil {
+			w.WriteHeader(http.StatusNoContent)
+			w.Write([]byte(`{"message":"` + `template parsing error` + `"}`))
+			return
+		}
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"` + `data parsing error` + `"}`))
+			return
+		}
+		webhooks, err := processing.GetDeadWebhooks(merchantID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not get webhooks", http.StatusInternalServerError)
+			return
+		}
+		err = t.Execute(w, webhooks)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"` + `template parsing error` + `"}`))
+			return
+		}
+	}
+}
+
+// ReplayWebhook returns the failed webhook of the merchant to delivery
+func ReplayWebhook(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		var raw struct {
+			ID int64 `json:"id"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&raw)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not find merchant", http.StatusBadRequest)
+			return
+		}
+		err = processing.ReplayWebhook(merchantID, raw.ID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not replay webhook", http.StatusBadRequest)
+			return
+		}
+
+		response := map[string]string{"message": "Updated successfully"}
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// PageMerchantApprovals shows withdraws of the merchant waiting for approvals of merchant users
+func PageMerchantApprovals(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		t, err := template.ParseFiles("./templates/lite/default/approvals.html", "./templates/lite/sidebar.html")
+		if err != nil {
+			w.WriteHeader(http.StatusNoContent)
+			w.Write([]byte(`{"message":"` + `template parsing error` + `"}`))
+			return
+		}
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"` + `data parsing error` + `"}`))
+			return
+		}
+		approvals, err := processing.GetWithdrawApprovals(merchantID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not get withdraw approvals", http.StatusInternalServerError)
+			return
+		}
+		err = t.Execute(w, approvals)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"` + `template parsing error` + `"}`))
+			return
+		}
+	}
+}
+
+// DecideWithdraw records approval or rejection of the withdraw by the merchant user of the session,
+// a user can't decide on the withdraw the user confirmed
+func DecideWithdraw(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		var raw struct {
+			Decision storage.ApprovalDecision `json:"decision"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&raw)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		if raw.Decision != storage.ApprovalDecisionApprove && raw.Decision != storage.ApprovalDecisionReject {
+			http.Error(w, "decision must be approve or reject", http.StatusBadRequest)
+			return
+		}
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not find merchant", http.StatusBadRequest)
+			return
+		}
+		userStore, err := internal.GetUserStore(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not find user", http.StatusBadRequest)
+			return
+		}
+
+		request, err := processing.DecideWithdraw(merchantID, ps.ByName("guid"), userStore.Identity, raw.Decision)
+		if errors.Is(err, storage.ErrSelfApproval) || errors.Is(err, storage.ErrAlreadyDecided) ||
+			errors.Is(err, storage.ErrInvalidTransition) {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not decide on withdraw", http.StatusBadRequest)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(request)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
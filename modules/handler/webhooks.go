@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"coreum_processor/modules/internal"
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+)
+
+// GetWebhookEvents method for getting types of webhook events and the ones the merchant is subscribed to
+func GetWebhookEvents(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		merchData, err := processing.GetMerchantData(merchantID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not find merchant", http.StatusBadRequest)
+			return
+		}
+		response := service.WebhookEventsResponse{
+			Version:    storage.WebhookEventVersion,
+			Events:     storage.WebhookEventTypes,
+			Subscribed: merchData.WebhookEvents,
+		}
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// UpdateWebhookSubscription method for subscribing the merchant to types of webhook events
+func UpdateWebhookSubscription(processing *service.ProcessingService) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w = processing.SetHeaders(w)
+
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		subscription := service.WebhookSubscription{}
+		err = json.NewDecoder(r.Body).Decode(&subscription)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse request data", http.StatusBadRequest)
+			return
+		}
+		err = processing.UpdateWebhookSubscription(merchantID, subscription)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not update webhook subscription", http.StatusBadRequest)
+			return
+		}
+		merchantReturn := service.MerchantResponse{MerchantId: merchantID}
+		err = json.NewEncoder(w).Encode(merchantReturn)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not parse response from server", http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package leader
+
+import (
+	"context"
+	"coreum_processor/modules/storage"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Elector elects the replica which runs a named task, leadership is kept by renewing the lease of the task
+// and is taken over by another replica when the leader dies and its lease expires
+type Elector struct {
+	store  *storage.LeasesPSQL
+	holder string
+	ttl    time.Duration
+	mu     sync.RWMutex
+	leases map[string]time.Time
+}
+
+// NewElector creates elector for the replica identified by the holder
+func NewElector(store *storage.LeasesPSQL, holder string, ttl time.Duration) *Elector {
+	return &Elector{
+		store:  store,
+		holder: holder,
+		ttl:    ttl,
+		leases: map[string]time.Time{},
+	}
+}
+
+// Run tries to take and renew leases of the names every third of the lease time until the context is done,
+// the held leases are released on exit
+func (e *Elector) Run(ctx context.Context, names ...string) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		for _, name := range names {
+			e.acquire(name)
+		}
+		select {
+		case <-ctx.Done():
+			for _, name := range names {
+				e.release(name)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IsLeader reports whether the replica holds the lease of the name
+func (e *Elector) IsLeader(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	expiresAt, ok := e.leases[name]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (e *Elector) acquire(name string) {
+	// lease is counted from the moment before the request, so the replica stops before the lease expires
+	expiresAt := time.Now().Add(e.ttl)
+	ok, err := e.store.Acquire(name, e.holder, e.ttl)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't acquire lease: %v, err: %v", name, err))
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		// the held lease is kept until it expires
+		return
+	}
+	if !ok {
+		if _, held := e.leases[name]; held {
+			log.Println(fmt.Sprintf("lease: %v is taken by another replica", name))
+		}
+		delete(e.leases, name)
+		return
+	}
+	if _, held := e.leases[name]; !held {
+		log.Println(fmt.Sprintf("replica: %v is elected as leader for: %v", e.holder, name))
+	}
+	e.leases[name] = expiresAt
+}
+
+func (e *Elector) release(name string) {
+	e.mu.Lock()
+	_, held := e.leases[name]
+	delete(e.leases, name)
+	e.mu.Unlock()
+	if !held {
+		return
+	}
+	if err := e.store.Release(name, e.holder); err != nil {
+		log.Println(fmt.Sprintf("can't release lease: %v, err: %v", name, err))
+	}
+}
@@ -0,0 +1,91 @@
+package leader
+
+import (
+	"context"
+	"coreum_processor/modules/storage"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	acquireQuery = regexp.QuoteMeta("INSERT INTO leader_leases")
+	releaseQuery = regexp.QuoteMeta("DELETE FROM leader_leases")
+)
+
+func newElectorMock(t *testing.T) (*Elector, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT 1 FROM "leader_leases"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	store, err := storage.NewLeaseStorage("leader_leases", db)
+	require.NoError(t, err)
+	return NewElector(store, "replica-1", time.Minute), mock
+}
+
+func TestElectorAcquiresLease(t *testing.T) {
+	e, mock := newElectorMock(t)
+	mock.ExpectExec(acquireQuery).WithArgs("deposits", "replica-1", time.Minute.Milliseconds()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.False(t, e.IsLeader("deposits"))
+	e.acquire("deposits")
+	require.True(t, e.IsLeader("deposits"))
+	require.False(t, e.IsLeader("sweep"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestElectorRenewsLease(t *testing.T) {
+	e, mock := newElectorMock(t)
+	mock.ExpectExec(acquireQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(acquireQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	e.acquire("deposits")
+	acquired := e.leases["deposits"]
+	e.acquire("deposits")
+	require.True(t, e.IsLeader("deposits"))
+	require.False(t, e.leases["deposits"].Before(acquired))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestElectorLosesLeaseTakenByAnotherReplica(t *testing.T) {
+	e, mock := newElectorMock(t)
+	mock.ExpectExec(acquireQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+	// the lease has expired and another replica has taken it
+	mock.ExpectExec(acquireQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	e.acquire("deposits")
+	e.acquire("deposits")
+	require.False(t, e.IsLeader("deposits"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestElectorKeepsLeaseUntilExpiryOnError(t *testing.T) {
+	e, mock := newElectorMock(t)
+	mock.ExpectExec(acquireQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(acquireQuery).WillReturnError(errors.New("connection refused"))
+
+	e.acquire("deposits")
+	e.acquire("deposits")
+	require.True(t, e.IsLeader("deposits"))
+
+	// the lease isn't renewed, so it expires
+	e.leases["deposits"] = time.Now().Add(-time.Second)
+	require.False(t, e.IsLeader("deposits"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestElectorReleasesLeaseOnExit(t *testing.T) {
+	e, mock := newElectorMock(t)
+	mock.ExpectExec(acquireQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(releaseQuery).WithArgs("deposits", "replica-1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e.Run(ctx, "deposits")
+	require.False(t, e.IsLeader("deposits"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
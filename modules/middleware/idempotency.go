@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"coreum_processor/modules/internal"
+	"coreum_processor/modules/service"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"log"
+	"net/http"
+)
+
+const (
+	// IdempotencyHeader is a header with a key which makes a backend request to be processed only once
+	IdempotencyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to the response replayed for a request with an already used key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// responseRecorder keeps status and body of a response to be stored for an idempotency key
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware replays the stored response for a merchant request repeated with the same
+// Idempotency-Key header, a request with the same key and another body is rejected with conflict status.
+// It must be used after AuthMiddleware which puts merchant to the request context
+func IdempotencyMiddleware(ProcessingService *service.ProcessingService, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		key := r.Header.Get(IdempotencyHeader)
+		if key == "" {
+			next(w, r, ps)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "idempotency key is too long", http.StatusBadRequest)
+			return
+		}
+		merchantID, err := internal.GetMerchantID(r.Context())
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not find merchant", http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "could not read request data", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(append([]byte(r.Method+" "+r.URL.RequestURI()+"\n"), body...))
+		requestHash := hex.EncodeToString(hash[:])
+
+		stored, err := ProcessingService.StartIdempotentRequest(merchantID, key, requestHash)
+		if errors.Is(err, service.ErrIdempotencyKeyConflict) || errors.Is(err, service.ErrIdempotencyKeyInProgress) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			log.Println(err)
+			http.Error(w, "could not check idempotency key", http.StatusInternalServerError)
+			return
+		}
+		if stored != nil {
+			w = ProcessingService.SetHeaders(w)
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(stored.StatusCode)
+			_, _ = w.Write(stored.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(recorder, r, ps)
+		err = ProcessingService.FinishIdempotentRequest(merchantID, key, service.IdempotentResponse{
+			StatusCode: recorder.statusCode,
+			Body:       recorder.body.Bytes(),
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
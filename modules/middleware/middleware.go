@@ -94,5 +94,5 @@ func CorsResponse(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, OPTIONS_CORS, PUT, DELETE, PATCH")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Authorization, Authorization")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Authorization, Authorization, Idempotency-Key")
 }
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"bytes"
+	"coreum_processor/modules/openapi"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"net/http"
+)
+
+// maxRequestBodySize limits body of requests validated against OpenAPI document
+const maxRequestBodySize = 1 << 20
+
+// ValidationMiddleware rejects requests which parameters or body don't match the operation of OpenAPI document,
+// the body is read and passed to the next handler as is
+func ValidationMiddleware(document *openapi.Document, operation *openapi.Operation,
+	next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if err := validateRequest(document, operation, r, ps); err != nil {
+			http.Error(w, fmt.Sprintf("request does not match API specification: %v", err), http.StatusBadRequest)
+			return
+		}
+		next(w, r, ps)
+	}
+}
+
+func validateRequest(document *openapi.Document, operation *openapi.Operation, r *http.Request,
+	ps httprouter.Params) error {
+	for _, parameter := range operation.Parameters {
+		var value string
+		switch parameter.In {
+		case "path":
+			value = ps.ByName(parameter.Name)
+		case "query":
+			value = r.URL.Query().Get(parameter.Name)
+		case "header":
+			value = r.Header.Get(parameter.Name)
+		}
+		if err := document.ValidateParameter(parameter, value); err != nil {
+			return err
+		}
+	}
+	schema := operation.RequestSchema()
+	if schema == nil {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize+1))
+	if err != nil {
+		return fmt.Errorf("can't read body: %w", err)
+	}
+	if len(body) > maxRequestBodySize {
+		return fmt.Errorf("body is larger than %d bytes", maxRequestBodySize)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return document.ValidateBody(schema, body)
+}
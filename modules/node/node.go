@@ -0,0 +1,128 @@
+package node
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"net"
+	"os"
+	"strings"
+)
+
+// Transport defines how connection to a node is secured
+type Transport string
+
+const (
+	TransportTLS      Transport = "tls"
+	TransportMTLS     Transport = "mtls"
+	TransportInsecure Transport = "insecure"
+)
+
+const (
+	resolverScheme = "nodes"
+	// requests go to the first available node, the next node is used if the current one fails
+	serviceConfig = `{"loadBalancingConfig": [{"pick_first":{}}]}`
+)
+
+// Config defines a list of node addresses in order of priority and transport to connect to them,
+// CAFile is optional for TLS, CertFile and KeyFile are required for mTLS
+type Config struct {
+	Addresses []string
+	Transport Transport
+	CAFile    string
+	CertFile  string
+	KeyFile   string
+}
+
+// ParseAddresses returns a list of addresses from a comma separated string
+func ParseAddresses(addresses string) []string {
+	var res []string
+	for _, address := range strings.Split(addresses, ",") {
+		address = strings.TrimSpace(address)
+		if address != "" {
+			res = append(res, address)
+		}
+	}
+	return res
+}
+
+// Dial creates gRPC connection to the nodes of the config with failover between them
+func Dial(cfg Config) (*grpc.ClientConn, error) {
+	if len(cfg.Addresses) == 0 {
+		return nil, errors.New("node address is not set")
+	}
+	creds, err := cfg.credentials()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]resolver.Address, 0, len(cfg.Addresses))
+	for _, address := range cfg.Addresses {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node address: %v, err: %w", address, err)
+		}
+		// every node is verified by its own host name
+		addresses = append(addresses, resolver.Address{Addr: address, ServerName: host})
+	}
+	r := manual.NewBuilderWithScheme(resolverScheme)
+	r.InitialState(resolver.State{Addresses: addresses})
+
+	return grpc.Dial(fmt.Sprintf("%s:///%s", r.Scheme(), cfg.Addresses[0]),
+		grpc.WithResolvers(r),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+	)
+}
+
+func (cfg Config) credentials() (credentials.TransportCredentials, error) {
+	switch cfg.Transport {
+	case TransportInsecure:
+		return insecure.NewCredentials(), nil
+	case TransportTLS, "":
+		tlsConfig, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		return credentials.NewTLS(tlsConfig), nil
+	case TransportMTLS:
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, errors.New("client certificate and key are required for mtls")
+		}
+		tlsConfig, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		return credentials.NewTLS(tlsConfig), nil
+	default:
+		return nil, fmt.Errorf("unsupported node transport: %v", cfg.Transport)
+	}
+}
+
+// tlsConfig returns TLS config which trusts custom CA if it is set or system root CAs otherwise
+func (cfg Config) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile == "" {
+		return tlsConfig, nil
+	}
+	ca, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA file: %v, err: %w", cfg.CAFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("could not parse CA file: %v", cfg.CAFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
@@ -0,0 +1,177 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Version is version of OpenAPI specification the document follows
+const Version = "3.0.3"
+
+// Document is OpenAPI document of the REST API
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	operations map[string]*Operation `json:"-"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem lists operations of a path by lower case http method
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	// Method and Path are http method and path of the operation in notation of the router like /merchant/:id
+	Method string `json:"-"`
+	Path   string `json:"-"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a subset of JSON schema used by OpenAPI. AdditionalProperties is either false
+// or the schema of values of a map
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+const (
+	contentJSON = "application/json"
+	refPrefix   = "#/components/schemas/"
+)
+
+// Operation returns the operation registered for the method and path in notation of the router
+func (d *Document) Operation(method, path string) (*Operation, bool) {
+	operation, ok := d.operations[operationKey(method, path)]
+	return operation, ok
+}
+
+// Operations returns all operations of the document ordered by path and method
+func (d *Document) Operations() []*Operation {
+	res := make([]*Operation, 0, len(d.operations))
+	for _, operation := range d.operations {
+		res = append(res, operation)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].Method < res[j].Method
+	})
+	return res
+}
+
+// Resolve returns the component schema the schema refers to or the schema itself
+func (d *Document) Resolve(schema *Schema) (*Schema, error) {
+	if schema == nil || schema.Ref == "" {
+		return schema, nil
+	}
+	component, ok := d.Components.Schemas[strings.TrimPrefix(schema.Ref, refPrefix)]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema: %v", schema.Ref)
+	}
+	return component, nil
+}
+
+// RequestSchema returns schema of JSON body of the operation or nil if it doesn't have body
+func (o *Operation) RequestSchema() *Schema {
+	if o.RequestBody == nil {
+		return nil
+	}
+	return o.RequestBody.Content[contentJSON].Schema
+}
+
+// ResponseSchema returns schema of JSON body of successful response of the operation
+func (o *Operation) ResponseSchema() *Schema {
+	return o.Responses[okResponse].Content[contentJSON].Schema
+}
+
+// RefName returns name of the component schema the schema refers to
+func RefName(schema *Schema) string {
+	return strings.TrimPrefix(schema.Ref, refPrefix)
+}
+
+// addOperation adds the operation to paths of the document, path parameters of the router
+// like :id are written as {id}
+func (d *Document) addOperation(operation *Operation) {
+	key := operationKey(operation.Method, operation.Path)
+	if _, ok := d.operations[key]; ok {
+		panic(fmt.Sprintf("operation %v is described twice", key))
+	}
+	d.operations[key] = operation
+	path := specPath(operation.Path)
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][strings.ToLower(operation.Method)] = operation
+}
+
+// specPath converts path of the router to the path of OpenAPI document
+func specPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + strings.TrimPrefix(part, ":") + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func operationKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
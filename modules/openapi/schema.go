@@ -0,0 +1,152 @@
+package openapi
+
+import (
+	"coreum_processor/modules/amount"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	amountType     = reflect.TypeOf(amount.Amount{})
+	timeType       = reflect.TypeOf(time.Time{})
+	uuidType       = reflect.TypeOf(uuid.UUID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaBuilder makes schemas of Go types by their json tags, named structs become component schemas
+type schemaBuilder struct {
+	schemas  map[string]*Schema
+	types    map[string]reflect.Type
+	required map[reflect.Type][]string
+	enums    map[reflect.Type][]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schemas:  map[string]*Schema{},
+		types:    map[string]reflect.Type{},
+		required: map[reflect.Type][]string{},
+		enums:    map[reflect.Type][]string{},
+	}
+}
+
+// require marks fields of the struct by their json names as required
+func (b *schemaBuilder) require(v interface{}, fields ...string) {
+	b.required[reflect.TypeOf(v)] = fields
+}
+
+// enum sets values allowed for the string type
+func (b *schemaBuilder) enum(v interface{}, values ...string) {
+	b.enums[reflect.TypeOf(v)] = values
+}
+
+// schemaOf returns schema of the value, nil value means the operation doesn't have the body
+func (b *schemaBuilder) schemaOf(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	return b.schemaOfType(reflect.TypeOf(v))
+}
+
+func (b *schemaBuilder) schemaOfType(t reflect.Type) *Schema {
+	switch t {
+	case amountType:
+		return &Schema{Type: "number", Format: "decimal",
+			Description: "decimal number, requests accept it as a string as well"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawMessageType:
+		return &Schema{Description: "any JSON value"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := b.schemaOfType(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.String:
+		return &Schema{Type: "string", Enum: b.enums[t]}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := 0.
+		return &Schema{Type: "integer", Format: "int64", Minimum: &minimum}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schemaOfType(t.Elem()), Nullable: true}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOfType(t.Elem()), Nullable: true}
+	case reflect.Interface:
+		return &Schema{Description: "any JSON value"}
+	case reflect.Struct:
+		return b.component(t)
+	}
+	panic(fmt.Sprintf("type %v can't be described by schema", t))
+}
+
+// component returns reference to the schema of the named struct and adds the schema to components
+func (b *schemaBuilder) component(t reflect.Type) *Schema {
+	name := t.Name()
+	if name == "" {
+		panic(fmt.Sprintf("anonymous struct %v can't be a component", t))
+	}
+	ref := &Schema{Ref: refPrefix + name}
+	if known, ok := b.types[name]; ok {
+		if known != t {
+			panic(fmt.Sprintf("schemas of %v and %v have the same name", known, t))
+		}
+		return ref
+	}
+	b.types[name] = t
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	// the schema is added before its fields, so a recursive type refers to itself
+	b.schemas[name] = s
+	b.addFields(s, t)
+	for _, field := range b.required[t] {
+		if _, ok := s.Properties[field]; !ok {
+			panic(fmt.Sprintf("required field %v is not found in %v", field, t))
+		}
+	}
+	s.Required = b.required[t]
+	return ref
+}
+
+// addFields adds exported fields of the struct to properties by rules of encoding/json
+func (b *schemaBuilder) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.addFields(s, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = b.schemaOfType(field.Type)
+	}
+}
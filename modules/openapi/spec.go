@@ -0,0 +1,335 @@
+package openapi
+
+import (
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"net/http"
+	"strings"
+)
+
+const (
+	okResponse      = "200"
+	failureResponse = "422"
+
+	securityMerchant = "merchantToken"
+	securityAdmin    = "adminToken"
+
+	tagTransactions = "transactions"
+	tagDeposits     = "deposits"
+	tagTokens       = "tokens"
+	tagMerchants    = "merchants"
+	tagWebhooks     = "webhooks"
+	tagAddressBook  = "address book"
+	tagLimits       = "limits"
+)
+
+// operationSpec describes the operation by Go types of its body and response, failure is JSON body
+// of the response refusing the request, parameters of the path are strings unless they are listed with other schema
+type operationSpec struct {
+	method     string
+	path       string
+	id         string
+	summary    string
+	tag        string
+	security   string
+	parameters []Parameter
+	body       interface{}
+	response   interface{}
+	failure    interface{}
+}
+
+// backendOperations lists operations of the backend REST API, the router refuses to start
+// if a backend route is missing here or an operation here has no route
+var backendOperations = []operationSpec{
+	{method: http.MethodGet, path: "/get_balance", id: "getBalance", tag: tagTransactions,
+		summary: "Get balance of the user of the token", security: securityMerchant,
+		parameters: []Parameter{
+			query("blockchain", "blockchain of the balance", true),
+			query("asset", "code of the asset", false),
+		},
+		response: service.Balance{}},
+	{method: http.MethodGet, path: "/balances", id: "getMerchantBalances", tag: tagTransactions,
+		summary: "Get available, reserved, held, incoming and paid as fees funds of the merchant assets", security: securityMerchant,
+		parameters: []Parameter{query("blockchain", "blockchain of balances", false)},
+		response:   []storage.LedgerBalance{}},
+	{method: http.MethodGet, path: "/transactions", id: "getTransactions", tag: tagTransactions,
+		summary: "Get transactions of the merchant", security: securityMerchant,
+		parameters: []Parameter{
+			query("blockchain", "blockchain of transactions", false),
+			queryInt("from", "unix time of the earliest transaction"),
+			queryInt("to", "unix time of the latest transaction"),
+			query("action", "comma separated actions of transactions", false),
+			query("status", "comma separated statuses of transactions", false),
+		},
+		response: []storage.TransactionStore{}},
+	{method: http.MethodGet, path: "/get_transaction_status/:id", id: "getTransactionStatus", tag: tagTransactions,
+		summary: "Get transaction of the merchant by its GUID", security: securityMerchant,
+		parameters: []Parameter{pathUUID("id")},
+		response:   storage.TransactionStore{}},
+	{method: http.MethodGet, path: "/get_wallet_by_id", id: "getWalletById", tag: tagTransactions,
+		summary: "Get wallet address of the user of the token", security: securityMerchant,
+		parameters: []Parameter{query("blockchain", "blockchain of the wallet", true)},
+		response:   service.MerchantResponse{}},
+	{method: http.MethodPost, path: "/deposit", id: "deposit", tag: tagTransactions,
+		summary: "Get address for deposit of the user of the token", security: securityMerchant,
+		parameters: []Parameter{idempotencyKey()},
+		body:       service.CredentialDeposit{}, response: service.DepositResponse{}},
+	{method: http.MethodPost, path: "/withdraw", id: "withdraw", tag: tagTransactions,
+		summary: "Request withdrawal of the user of the token", security: securityMerchant,
+		parameters: []Parameter{idempotencyKey()},
+		body:       service.CredentialWithdraw{}, response: service.WithdrawResponse{},
+		failure: service.WithdrawValidationError{}},
+	{method: http.MethodPut, path: "/withdraw/:guid", id: "updateWithdraw", tag: tagTransactions,
+		summary: "Confirm withdrawal by hash of the transaction", security: securityMerchant,
+		parameters: []Parameter{query("hash", "hash of the transaction", true)},
+		response:   service.DeleteWithdrawResponse{}},
+	{method: http.MethodDelete, path: "/withdraw/:guid", id: "deleteWithdraw", tag: tagTransactions,
+		summary: "Reject withdrawal which isn't processed yet", security: securityMerchant,
+		response: service.DeleteWithdrawResponse{}},
+	{method: http.MethodGet, path: "/deposits/quarantined", id: "getQuarantinedDeposits", tag: tagDeposits,
+		summary: "Get deposits with unknown memo", security: securityMerchant,
+		parameters: []Parameter{query("blockchain", "blockchain of deposits", false)},
+		response:   []storage.QuarantinedDeposit{}},
+	{method: http.MethodPut, path: "/deposits/quarantined/:id", id: "resolveQuarantinedDeposit", tag: tagDeposits,
+		summary: "Assign quarantined deposit to the user or dismiss it", security: securityMerchant,
+		parameters: []Parameter{pathInt("id")},
+		body:       service.QuarantineResolution{}, response: storage.QuarantinedDeposit{}},
+	{method: http.MethodPost, path: "/token_issue", id: "issueToken", tag: tagTokens,
+		summary: "Issue token by the wallet of the user of the token", security: securityMerchant,
+		body: service.NewTokenRequest{}, response: service.NewTokenResponse{}},
+	{method: http.MethodPost, path: "/token_mint", id: "mintToken", tag: tagTokens,
+		summary: "Mint token issued by the merchant", security: securityMerchant,
+		body: service.MintTokenRequest{}, response: service.NewTokenResponse{}},
+	{method: http.MethodPost, path: "/token_burn", id: "burnToken", tag: tagTokens,
+		summary: "Burn token of the user of the token", security: securityMerchant,
+		body: service.TokenRequest{}, response: service.NewTokenResponse{}},
+	{method: http.MethodPut, path: "/asset/withdraw-limits", id: "updateAssetWithdrawLimits", tag: tagTokens,
+		summary: "Set the least and the largest amounts of the asset to withdraw", security: securityAdmin,
+		body: service.AssetWithdrawLimits{}, response: service.MessageResponse{}},
+	{method: http.MethodGet, path: "/merchant/:id", id: "getMerchant", tag: tagMerchants,
+		summary: "Get merchant", security: securityMerchant,
+		response: service.MerchantData{}},
+	{method: http.MethodGet, path: "/merchants", id: "getMerchants", tag: tagMerchants,
+		summary: "Get all merchants", security: securityMerchant,
+		response: []service.MerchantData{}},
+	{method: http.MethodPost, path: "/merchant", id: "createMerchant", tag: tagMerchants,
+		summary: "Create merchant", security: securityAdmin,
+		body: service.NewMerchant{}, response: service.MerchantData{}},
+	{method: http.MethodPut, path: "/merchant/:id", id: "updateMerchant", tag: tagMerchants,
+		summary: "Update public key, name and callback url of the merchant", security: securityMerchant,
+		body: service.NewMerchant{}, response: service.MerchantResponse{}},
+	{method: http.MethodPut, path: "/merchant/:id/:blockchain/commission", id: "updateMerchantCommission",
+		tag: tagMerchants, summary: "Update commissions of the merchant", security: securityAdmin,
+		body: service.NewMerchantCommission{}, response: service.MerchantResponse{}},
+	{method: http.MethodPut, path: "/merchant/:id/:blockchain/deposit-memo", id: "updateMerchantDepositMemo",
+		tag: tagMerchants, summary: "Enable deposits with memo to the receiving wallet", security: securityAdmin,
+		body: service.NewMerchantDepositMemo{}, response: service.MerchantResponse{}},
+	{method: http.MethodPut, path: "/merchant/:id/:blockchain/withdraw-approval", id: "updateMerchantWithdrawApproval",
+		tag: tagMerchants, summary: "Set approvals of withdraws by merchant users", security: securityAdmin,
+		body: service.ApprovalPolicy{}, response: service.MerchantResponse{}},
+	{method: http.MethodGet, path: "/webhooks/events", id: "getWebhookEvents", tag: tagWebhooks,
+		summary: "Get types of webhook events", security: securityMerchant,
+		response: service.WebhookEventsResponse{}},
+	{method: http.MethodPut, path: "/webhooks/subscription", id: "updateWebhookSubscription", tag: tagWebhooks,
+		summary: "Subscribe the merchant to types of webhook events", security: securityMerchant,
+		body: service.WebhookSubscription{}, response: service.MerchantResponse{}},
+	{method: http.MethodGet, path: "/webhooks/endpoints", id: "getWebhookEndpoints", tag: tagWebhooks,
+		summary: "Get webhook endpoints of the merchant", security: securityMerchant,
+		response: []storage.WebhookEndpoint{}},
+	{method: http.MethodPost, path: "/webhooks/endpoints", id: "createWebhookEndpoint", tag: tagWebhooks,
+		summary: "Register webhook endpoint", security: securityMerchant,
+		body: service.NewWebhookEndpoint{}, response: service.CreatedWebhookEndpoint{}},
+	{method: http.MethodPut, path: "/webhooks/endpoints/:id", id: "updateWebhookEndpoint", tag: tagWebhooks,
+		summary: "Update webhook endpoint", security: securityMerchant,
+		parameters: []Parameter{pathInt("id")},
+		body:       service.UpdateWebhookEndpoint{}, response: service.MessageResponse{}},
+	{method: http.MethodDelete, path: "/webhooks/endpoints/:id", id: "deleteWebhookEndpoint", tag: tagWebhooks,
+		summary: "Remove webhook endpoint", security: securityMerchant,
+		parameters: []Parameter{pathInt("id")},
+		response:   service.MessageResponse{}},
+	{method: http.MethodPost, path: "/webhooks/endpoints/:id/test", id: "testWebhookEndpoint", tag: tagWebhooks,
+		summary: "Send test event to webhook endpoint", security: securityMerchant,
+		parameters: []Parameter{pathInt("id")},
+		response:   service.MessageResponse{}},
+	{method: http.MethodGet, path: "/address-book/entries", id: "getAddressBook", tag: tagAddressBook,
+		summary: "Get addresses the merchant allows to withdraw to", security: securityMerchant,
+		parameters: []Parameter{query("blockchain", "blockchain of addresses", false)},
+		response:   []storage.AddressBookEntry{}},
+	{method: http.MethodPost, path: "/address-book/entries", id: "createAddressBookEntry", tag: tagAddressBook,
+		summary: "Add address to the address book", security: securityMerchant,
+		body: service.NewAddressBookEntry{}, response: storage.AddressBookEntry{}},
+	{method: http.MethodPut, path: "/address-book/entries/:id", id: "updateAddressBookEntry", tag: tagAddressBook,
+		summary: "Change label of address in the address book", security: securityMerchant,
+		parameters: []Parameter{pathInt("id")},
+		body:       service.UpdateAddressBookEntry{}, response: service.MessageResponse{}},
+	{method: http.MethodDelete, path: "/address-book/entries/:id", id: "deleteAddressBookEntry", tag: tagAddressBook,
+		summary: "Remove address from the address book", security: securityMerchant,
+		parameters: []Parameter{pathInt("id")},
+		response:   service.MessageResponse{}},
+	{method: http.MethodPut, path: "/address-book/policy", id: "updateAddressBookPolicy", tag: tagAddressBook,
+		summary: "Set allow-list only mode and cooling-off of new addresses", security: securityMerchant,
+		body: service.AddressBookSettings{}, response: service.MerchantResponse{}},
+	{method: http.MethodGet, path: "/limits/rules", id: "getLimitRules", tag: tagLimits,
+		summary: "Get limit rules of deposits and withdraws of merchants", security: securityAdmin,
+		parameters: []Parameter{query("merchant_id", "merchant of limit rules", false)},
+		response:   []storage.LimitRule{}},
+	{method: http.MethodPost, path: "/limits/rules", id: "createLimitRule", tag: tagLimits,
+		summary: "Add limit rule of deposits or withdraws of the merchant asset", security: securityAdmin,
+		body: service.NewLimitRule{}, response: storage.LimitRule{}},
+	{method: http.MethodPut, path: "/limits/rules/:id", id: "updateLimitRule", tag: tagLimits,
+		summary: "Replace limit rule", security: securityAdmin,
+		parameters: []Parameter{pathInt("id")},
+		body:       service.NewLimitRule{}, response: service.MessageResponse{}},
+	{method: http.MethodDelete, path: "/limits/rules/:id", id: "deleteLimitRule", tag: tagLimits,
+		summary: "Remove limit rule", security: securityAdmin,
+		parameters: []Parameter{pathInt("id")},
+		response:   service.MessageResponse{}},
+	{method: http.MethodGet, path: "/limits/holds", id: "getHeldTransactions", tag: tagLimits,
+		summary: "Get transactions on hold for breaching limits", security: securityAdmin,
+		parameters: []Parameter{query("merchant_id", "merchant of transactions", false)},
+		response:   []service.HeldTransaction{}},
+	{method: http.MethodPut, path: "/limits/holds/:guid", id: "resolveHeldTransaction", tag: tagLimits,
+		summary: "Release transaction on hold to processing or reject it", security: securityAdmin,
+		body: service.HoldResolution{}, response: storage.TransactionHold{}},
+}
+
+// Backend returns OpenAPI document of the backend REST API made from Go types of its requests and responses
+func Backend() *Document {
+	b := newSchemaBuilder()
+	b.require(service.CredentialDeposit{}, "blockchain")
+	b.require(service.CredentialWithdraw{}, "amount", "blockchain", "wallet_address", "asset")
+	b.require(service.NewTokenRequest{}, "code", "blockchain", "type")
+	b.require(service.MintTokenRequest{}, "code", "blockchain")
+	b.require(service.TokenRequest{}, "code", "blockchain", "amount", "issuer")
+	b.require(service.AssetWithdrawLimits{}, "blockchain", "code")
+	b.require(service.WithdrawValidationError{}, "message", "violations")
+	b.require(service.WithdrawViolation{}, "code", "field", "message")
+	b.require(service.NewMerchant{}, "name")
+	b.require(service.NewMerchantCommission{}, "commission_receiving", "commission_sending")
+	b.require(service.NewMerchantDepositMemo{}, "deposit_memo")
+	b.require(service.ApprovalPolicy{}, "enabled")
+	b.require(service.ApprovalThresholds{}, "asset", "issuer", "auto_approve_below")
+	b.require(service.NewAddressBookEntry{}, "blockchain", "address")
+	b.require(service.UpdateAddressBookEntry{}, "label")
+	b.require(service.AddressBookSettings{}, "blockchain", "allow_list_only")
+	b.require(service.NewLimitRule{}, "merchant_id", "blockchain", "asset", "action", "scope")
+	b.require(service.HoldResolution{}, "decision")
+	b.require(service.WebhookSubscription{}, "events")
+	b.require(service.NewWebhookEndpoint{}, "url", "purpose")
+	b.require(service.UpdateWebhookEndpoint{}, "url", "enabled")
+	b.enum(storage.ActionTx(""), string(storage.DepositTransaction), string(storage.WithdrawTransaction),
+		string(storage.RefundTransaction), string(storage.SweepTransaction))
+	b.enum(storage.StatusTx(""), string(storage.InitTransaction), string(storage.ProcessedTransaction),
+		string(storage.SettledTransaction), string(storage.DoneTransaction), string(storage.RejectedTransaction),
+		string(storage.OnHoldTransaction))
+	b.enum(storage.LimitScope(""), string(storage.LimitScopeUser), string(storage.LimitScopeMerchant))
+	b.enum(storage.HoldDecision(""), string(storage.HoldReleased), string(storage.HoldRejected))
+	b.enum(storage.EndpointPurpose(""), string(storage.EndpointNotifications), string(storage.EndpointMultiSign))
+	b.enum(service.ViolationCode(""), string(service.ViolationInvalidAmount),
+		string(service.ViolationInvalidAddress), string(service.ViolationAssetNotFound),
+		string(service.ViolationAssetInactive), string(service.ViolationAmountTooSmall),
+		string(service.ViolationAmountTooLarge), string(service.ViolationInsufficientBalance),
+		string(service.ViolationAddressNotAllowed), string(service.ViolationAddressCoolingOff))
+	b.enum(storage.QuarantineStatus(""), string(storage.QuarantineHeld), string(storage.QuarantineAssigned),
+		string(storage.QuarantineDismissed))
+
+	d := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       "Coreum processor",
+			Description: "Backend REST API of the processing for merchants",
+			Version:     "1.0.0",
+		},
+		Paths: map[string]PathItem{},
+		Components: Components{
+			Schemas: b.schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				securityMerchant: {Type: "apiKey", In: "header", Name: "Authorization",
+					Description: "JWT with merchant_id and external_id signed by the merchant"},
+				securityAdmin: {Type: "apiKey", In: "header", Name: "Authorization",
+					Description: "JWT signed by the processing"},
+			},
+		},
+		operations: map[string]*Operation{},
+	}
+	for _, spec := range backendOperations {
+		d.addOperation(spec.operation(b))
+	}
+	return d
+}
+
+// operation makes the operation of the document with schemas of its Go types
+func (spec operationSpec) operation(b *schemaBuilder) *Operation {
+	operation := &Operation{
+		OperationID: spec.id,
+		Summary:     spec.summary,
+		Tags:        []string{spec.tag},
+		Parameters:  pathParameters(spec.path, spec.parameters),
+		Responses: map[string]Response{
+			okResponse: {
+				Description: "successful response",
+				Content:     map[string]MediaType{contentJSON: {Schema: b.schemaOf(spec.response)}},
+			},
+			"default": {Description: "error message in plain text"},
+		},
+		Security: []map[string][]string{{spec.security: {}}},
+		Method:   spec.method,
+		Path:     spec.path,
+	}
+	if spec.failure != nil {
+		operation.Responses[failureResponse] = Response{
+			Description: "request is refused",
+			Content:     map[string]MediaType{contentJSON: {Schema: b.schemaOf(spec.failure)}},
+		}
+	}
+	if spec.body != nil {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentJSON: {Schema: b.schemaOf(spec.body)}},
+		}
+	}
+	return operation
+}
+
+// pathParameters adds string parameters for segments of the path which are not listed in the parameters
+func pathParameters(path string, parameters []Parameter) []Parameter {
+	res := append([]Parameter{}, parameters...)
+	for _, part := range strings.Split(path, "/") {
+		if !strings.HasPrefix(part, ":") {
+			continue
+		}
+		name := strings.TrimPrefix(part, ":")
+		listed := false
+		for _, parameter := range parameters {
+			listed = listed || (parameter.In == "path" && parameter.Name == name)
+		}
+		if !listed {
+			res = append(res, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return res
+}
+
+func query(name, description string, required bool) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Required: required,
+		Schema: &Schema{Type: "string"}}
+}
+
+func queryInt(name, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description,
+		Schema: &Schema{Type: "integer", Format: "int64"}}
+}
+
+func pathInt(name string) Parameter {
+	return Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}}
+}
+
+func pathUUID(name string) Parameter {
+	return Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}}
+}
+
+func idempotencyKey() Parameter {
+	return Parameter{Name: "Idempotency-Key", In: "header",
+		Description: "key to repeat the request safely, the first response is returned for the same key",
+		Schema:      &Schema{Type: "string"}}
+}
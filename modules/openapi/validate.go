@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"bytes"
+	"coreum_processor/modules/amount"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ValidateBody checks that the body is a JSON value matching the schema
+func (d *Document) ValidateBody(schema *Schema, body []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("body is not valid JSON: %w", err)
+	}
+	if decoder.More() {
+		return errors.New("body has data after JSON value")
+	}
+	return d.validate(schema, value, "body")
+}
+
+// ValidateParameter checks that the value of query, path or header parameter matches its schema
+func (d *Document) ValidateParameter(parameter Parameter, value string) error {
+	if value == "" {
+		if parameter.Required {
+			return fmt.Errorf("%v parameter %v is required", parameter.In, parameter.Name)
+		}
+		return nil
+	}
+	var parsed interface{} = value
+	if parameter.Schema.Type == "integer" || parameter.Schema.Type == "number" {
+		parsed = json.Number(value)
+	}
+	return d.validate(parameter.Schema, parsed, parameter.In+" parameter "+parameter.Name)
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, path string) error {
+	schema, err := d.Resolve(schema)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" || schema.Format == "decimal" {
+			return nil
+		}
+		return fmt.Errorf("%v must not be null", path)
+	}
+	switch schema.Type {
+	case "object":
+		return d.validateObject(schema, value, path)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v must be an array", path)
+		}
+		for i, item := range items {
+			if err = d.validate(schema.Items, item, fmt.Sprintf("%v[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		return validateString(schema, value, path)
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%v must be an integer", path)
+		}
+		n, err := number.Int64()
+		if err != nil {
+			return fmt.Errorf("%v must be an integer", path)
+		}
+		if schema.Minimum != nil && float64(n) < *schema.Minimum {
+			return fmt.Errorf("%v must not be less than %v", path, *schema.Minimum)
+		}
+	case "number":
+		return validateNumber(schema, value, path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%v must be a boolean", path)
+		}
+	}
+	return nil
+}
+
+func (d *Document) validateObject(schema *Schema, value interface{}, path string) error {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%v must be an object", path)
+	}
+	for _, name := range schema.Required {
+		if _, ok = object[name]; !ok {
+			return fmt.Errorf("%v.%v is required", path, name)
+		}
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			additional, isSchema := schema.AdditionalProperties.(*Schema)
+			if !isSchema {
+				return fmt.Errorf("%v.%v is not expected", path, name)
+			}
+			property = additional
+		}
+		if err := d.validate(property, object[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateString(schema *Schema, value interface{}, path string) error {
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%v must be a string", path)
+	}
+	if len(schema.Enum) > 0 {
+		known := false
+		for _, e := range schema.Enum {
+			known = known || e == s
+		}
+		if !known {
+			return fmt.Errorf("%v must be one of %v", path, schema.Enum)
+		}
+	}
+	var err error
+	switch schema.Format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, s)
+	case "uuid":
+		_, err = uuid.Parse(s)
+	case "byte":
+		_, err = base64.StdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return fmt.Errorf("%v must be %v string", path, schema.Format)
+	}
+	return nil
+}
+
+func validateNumber(schema *Schema, value interface{}, path string) error {
+	var n float64
+	switch v := value.(type) {
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("%v must be a number", path)
+		}
+		n = parsed
+	case string:
+		if schema.Format != "decimal" {
+			return fmt.Errorf("%v must be a number", path)
+		}
+		if _, err := amount.Parse(v); err != nil {
+			return fmt.Errorf("%v must be a decimal number", path)
+		}
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%v must be a decimal number", path)
+		}
+		n = parsed
+	default:
+		return fmt.Errorf("%v must be a number", path)
+	}
+	if schema.Minimum != nil && n < *schema.Minimum {
+		return fmt.Errorf("%v must not be less than %v", path, *schema.Minimum)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package routing
+
+import (
+	"coreum_processor/modules/middleware"
+	"coreum_processor/modules/openapi"
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps the handler of a route, it is used to authenticate requests of the route
+type Middleware func(next httprouter.Handle) httprouter.Handle
+
+// APIRouterWrap registers routes of the backend REST API, each route must be described by OpenAPI document
+// and its authenticated requests are validated against the document
+type APIRouterWrap struct {
+	routerWrap *RouterWrap
+	document   *openapi.Document
+	registered map[*openapi.Operation]bool
+}
+
+// NewAPIRouterWrap is constructor for APIRouterWrap
+func NewAPIRouterWrap(routerWrap *RouterWrap, document *openapi.Document) *APIRouterWrap {
+	return &APIRouterWrap{
+		routerWrap: routerWrap,
+		document:   document,
+		registered: map[*openapi.Operation]bool{},
+	}
+}
+
+// GET is wrap method over adding GET method handler described by OpenAPI document
+func (a *APIRouterWrap) GET(path string, auth Middleware, handle httprouter.Handle) {
+	a.routerWrap.GET(path, a.validated(http.MethodGet, path, auth, handle))
+}
+
+// POST is wrap method over adding POST method handler described by OpenAPI document
+func (a *APIRouterWrap) POST(path string, auth Middleware, handle httprouter.Handle) {
+	a.routerWrap.POST(path, a.validated(http.MethodPost, path, auth, handle))
+}
+
+// PUT is wrap method over adding PUT method handler described by OpenAPI document
+func (a *APIRouterWrap) PUT(path string, auth Middleware, handle httprouter.Handle) {
+	a.routerWrap.PUT(path, a.validated(http.MethodPut, path, auth, handle))
+}
+
+// DELETE is wrap method over adding DELETE method handler described by OpenAPI document
+func (a *APIRouterWrap) DELETE(path string, auth Middleware, handle httprouter.Handle) {
+	a.routerWrap.DELETE(path, a.validated(http.MethodDelete, path, auth, handle))
+}
+
+// ServeDocument adds GET handler which returns OpenAPI document
+func (a *APIRouterWrap) ServeDocument(path string) {
+	document, err := json.Marshal(a.document)
+	if err != nil {
+		panic(fmt.Errorf("can't marshal OpenAPI document: %v", err))
+	}
+	a.routerWrap.GET(path, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if _, err := w.Write(document); err != nil {
+			log.Println(err)
+		}
+	})
+}
+
+// Check returns error if an operation of OpenAPI document doesn't have a route
+func (a *APIRouterWrap) Check() error {
+	var missing []string
+	for _, operation := range a.document.Operations() {
+		if !a.registered[operation] {
+			missing = append(missing, operation.Method+" "+operation.Path)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("operations of OpenAPI document don't have routes: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// validated wraps the handler with validation of requests by the operation inside the authentication,
+// so requests are validated only after they are authenticated, the route must be described
+func (a *APIRouterWrap) validated(method, path string, auth Middleware, handle httprouter.Handle) httprouter.Handle {
+	operation, ok := a.document.Operation(method, path)
+	if !ok {
+		panic(fmt.Errorf("route %v %v is not described by OpenAPI document", method, path))
+	}
+	a.registered[operation] = true
+	return auth(middleware.ValidationMiddleware(a.document, operation, handle))
+}
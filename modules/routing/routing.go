@@ -6,6 +6,7 @@ import (
 	"coreum_processor/modules/handler"
 	"coreum_processor/modules/handler/ui"
 	"coreum_processor/modules/middleware"
+	"coreum_processor/modules/openapi"
 	"coreum_processor/modules/service"
 	user "coreum_processor/modules/user"
 	"github.com/julienschmidt/httprouter"
@@ -48,6 +49,10 @@ func InitRouter(ctx context.Context, ory *client.APIClient,
 		userService, ui.PageMerchantUsers(ctx, userService, processing)))
 	routerWrap.GET("/ui/merchant/settings", middleware.AuthMiddlewareCookie(ctx, ory,
 		userService, ui.PageMerchantSettings(processing, assetService)))
+	routerWrap.GET("/ui/merchant/approvals", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, ui.PageMerchantApprovals(processing)))
+	routerWrap.GET("/ui/merchant/webhooks", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, ui.PageMerchantWebhooks(processing)))
 	routerWrap.GET("/ui/admin/merchant-requests", middleware.AuthMiddlewareCookie(ctx, ory,
 		userService, ui.PageRequestsAdmin(ctx, userService, processing)))
 	routerWrap.POST("/ui/admin/merchant-requests", middleware.AuthMiddlewareCookie(ctx, ory,
@@ -56,6 +61,12 @@ func InitRouter(ctx context.Context, ory *client.APIClient,
 		userService, ui.PageAssetRequestsAdmin(ctx, assetService, processing)))
 	routerWrap.POST("/ui/admin/asset-requests", middleware.AuthMiddlewareCookie(ctx, ory,
 		userService, ui.PageAssetRequestsAdminUpdate(ctx, assetService, processing)))
+	routerWrap.GET("/ui/admin/refunds", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, ui.PageRefundsAdmin(processing)))
+	routerWrap.GET("/ui/admin/holds", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, ui.PageHoldsAdmin(processing)))
+	routerWrap.POST("/ui/admin/holds/:guid", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, ui.ResolveHoldAdmin(processing)))
 	routerWrap.GET("/ui/merchant/assets", middleware.AuthMiddlewareCookie(ctx, ory,
 		userService, ui.PageMerchantAssets(ctx, assetService, processing)))
 	routerWrap.POST("/ui/merchant/assets", middleware.AuthMiddlewareCookie(ctx, ory,
@@ -80,39 +91,95 @@ func InitRouter(ctx context.Context, ory *client.APIClient,
 	routerWrap.POST("/ui/merchant/deposit", middleware.AuthMiddlewareCookie(ctx, ory,
 		userService, ui.Deposit(ctx, processing)))
 	routerWrap.POST("/ui/merchant/withdraw", middleware.AuthMiddlewareCookie(ctx, ory,
-		userService, ui.Withdraw(processing)))
+		userService, ui.Withdraw(ctx, processing)))
 	routerWrap.POST("/ui/merchant/update_withdraw", middleware.AuthMiddlewareCookie(ctx, ory,
 		userService, ui.UpdateWithdraw(processing)))
+	routerWrap.POST("/ui/merchant/approvals/:guid", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, ui.DecideWithdraw(processing)))
+	routerWrap.POST("/ui/merchant/webhooks/replay", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, ui.ReplayWebhook(processing)))
+	routerWrap.POST("/ui/merchant/webhook-endpoints", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, handler.CreateWebhookEndpoint(processing)))
+	routerWrap.PUT("/ui/merchant/webhook-endpoints/:id", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, handler.UpdateWebhookEndpoint(processing)))
+	routerWrap.DELETE("/ui/merchant/webhook-endpoints/:id", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, handler.DeleteWebhookEndpoint(processing)))
+	routerWrap.POST("/ui/merchant/webhook-endpoints/:id/test", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, handler.TestWebhookEndpoint(processing)))
+	routerWrap.POST("/ui/merchant/address-book/entries", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, handler.CreateAddressBookEntry(processing)))
+	routerWrap.PUT("/ui/merchant/address-book/entries/:id", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, handler.UpdateAddressBookEntry(processing)))
+	routerWrap.DELETE("/ui/merchant/address-book/entries/:id", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, handler.DeleteAddressBookEntry(processing)))
+	routerWrap.PUT("/ui/merchant/address-book/policy", middleware.AuthMiddlewareCookie(ctx, ory,
+		userService, handler.UpdateAddressBookPolicy(processing)))
 	routerWrap.POST("/ui/merchant/transfer", middleware.AuthMiddlewareCookie(ctx, ory,
 		userService, ui.TransferMerchantWallets(ctx, processing)))
 
 	//GET routers for styles and assets
 	router.ServeFiles("/assets/*filepath", http.Dir("templates/assets"))
 
-	//GET routers for backend
-	routerWrap.GET("/get_balance", middleware.AuthMiddleware(processing, handler.GetBalance(ctx, processing)))               //Tested
-	routerWrap.GET("/transactions", middleware.AuthMiddleware(processing, handler.GetTransactionList(processing)))           //Tested
-	routerWrap.GET("/merchant/:id", middleware.AuthMiddleware(processing, handler.GetMerchantById(processing)))              //Tested
-	routerWrap.GET("/merchants", middleware.AuthMiddleware(processing, handler.GetMerchants(processing)))                    //Tested
-	routerWrap.GET("/get_wallet_by_id", middleware.AuthMiddleware(processing, handler.GetWalletById(processing)))            //Tested
-	routerWrap.GET("/get_transaction_status/:id", middleware.AuthMiddleware(processing, handler.GetTransaction(processing))) //Tested
+	//GET routers for backend, they are described by OpenAPI document
+	apiWrap := NewAPIRouterWrap(routerWrap, openapi.Backend())
+	merchantAuth := func(next httprouter.Handle) httprouter.Handle {
+		return middleware.AuthMiddleware(processing, next)
+	}
+	adminAuth := func(next httprouter.Handle) httprouter.Handle {
+		return middleware.AuthMiddlewareAdmin(processing, next)
+	}
+	apiWrap.ServeDocument("/openapi.json")
+	apiWrap.GET("/get_balance", merchantAuth, handler.GetBalance(ctx, processing))               //Tested
+	apiWrap.GET("/transactions", merchantAuth, handler.GetTransactionList(processing))           //Tested
+	apiWrap.GET("/merchant/:id", merchantAuth, handler.GetMerchantById(processing))              //Tested
+	apiWrap.GET("/merchants", merchantAuth, handler.GetMerchants(processing))                    //Tested
+	apiWrap.GET("/get_wallet_by_id", merchantAuth, handler.GetWalletById(processing))            //Tested
+	apiWrap.GET("/get_transaction_status/:id", merchantAuth, handler.GetTransaction(processing)) //Tested
+	apiWrap.GET("/balances", merchantAuth, handler.GetMerchantBalances(processing))
+	apiWrap.GET("/deposits/quarantined", merchantAuth, handler.GetQuarantinedDeposits(processing))
+	apiWrap.GET("/webhooks/events", merchantAuth, handler.GetWebhookEvents(processing))
+	apiWrap.GET("/webhooks/endpoints", merchantAuth, handler.GetWebhookEndpoints(processing))
+	apiWrap.GET("/address-book/entries", merchantAuth, handler.GetAddressBook(processing))
+	apiWrap.GET("/limits/rules", adminAuth, handler.GetLimitRules(processing))
+	apiWrap.GET("/limits/holds", adminAuth, handler.GetHeldTransactions(processing))
 	routerWrap.GET("/get_supply", middleware.AuthMiddlewareCookie(ctx, ory, userService, handler.GetTokenSupply(ctx, processing)))
 
 	//POST router for backend
-	routerWrap.POST("/deposit", middleware.AuthMiddleware(processing, handler.Deposit(ctx, processing))) //Tested
-	routerWrap.POST("/token_issue", middleware.AuthMiddleware(processing, handler.NewToken(ctx, processing, assetService)))
-	routerWrap.POST("/token_mint", middleware.AuthMiddleware(processing, handler.MintToken(ctx, processing, assetService)))
-	routerWrap.POST("/token_burn", middleware.AuthMiddleware(processing, handler.BurnTokenMerchant(ctx, processing))) //Tested
-	routerWrap.POST("/withdraw", middleware.AuthMiddleware(processing, handler.Withdraw(processing)))                 //Tested
-	routerWrap.POST("/merchant", middleware.AuthMiddlewareAdmin(processing, handler.CreateMerchant(processing)))      //Tested
+	apiWrap.POST("/deposit", merchantAuth,
+		middleware.IdempotencyMiddleware(processing, handler.Deposit(ctx, processing))) //Tested
+	apiWrap.POST("/token_issue", merchantAuth, handler.NewToken(ctx, processing, assetService))
+	apiWrap.POST("/token_mint", merchantAuth, handler.MintToken(ctx, processing, assetService))
+	apiWrap.POST("/token_burn", merchantAuth, handler.BurnTokenMerchant(ctx, processing)) //Tested
+	apiWrap.POST("/withdraw", merchantAuth,
+		middleware.IdempotencyMiddleware(processing, handler.Withdraw(ctx, processing))) //Tested
+	apiWrap.POST("/merchant", adminAuth, handler.CreateMerchant(processing)) //Tested
+	apiWrap.POST("/webhooks/endpoints", merchantAuth, handler.CreateWebhookEndpoint(processing))
+	apiWrap.POST("/webhooks/endpoints/:id/test", merchantAuth, handler.TestWebhookEndpoint(processing))
+	apiWrap.POST("/address-book/entries", merchantAuth, handler.CreateAddressBookEntry(processing))
+	apiWrap.POST("/limits/rules", adminAuth, handler.CreateLimitRule(processing))
 
 	// DELETE routers for backend
-	routerWrap.DELETE("/withdraw/:guid", middleware.AuthMiddleware(processing, handler.DeleteWithdraw(processing))) //Tested
+	apiWrap.DELETE("/withdraw/:guid", merchantAuth, handler.DeleteWithdraw(processing)) //Tested
+	apiWrap.DELETE("/webhooks/endpoints/:id", merchantAuth, handler.DeleteWebhookEndpoint(processing))
+	apiWrap.DELETE("/address-book/entries/:id", merchantAuth, handler.DeleteAddressBookEntry(processing))
+	apiWrap.DELETE("/limits/rules/:id", adminAuth, handler.DeleteLimitRule(processing))
 
 	// PUT routers for backend
-	routerWrap.PUT("/withdraw/:guid", middleware.AuthMiddleware(processing, handler.UpdateWithdraw(processing)))
-	routerWrap.PUT("/merchant/:id", middleware.AuthMiddleware(processing, handler.UpdateMerchant(processing)))
-	routerWrap.PUT("/merchant/:id/:blockchain/commission",
-		middleware.AuthMiddlewareAdmin(processing, handler.UpdateMerchantCommission(ctx, processing)))
-
+	apiWrap.PUT("/withdraw/:guid", merchantAuth, handler.UpdateWithdraw(processing))
+	apiWrap.PUT("/merchant/:id", merchantAuth, handler.UpdateMerchant(processing))
+	apiWrap.PUT("/merchant/:id/:blockchain/commission", adminAuth, handler.UpdateMerchantCommission(ctx, processing))
+	apiWrap.PUT("/merchant/:id/:blockchain/deposit-memo", adminAuth, handler.UpdateMerchantDepositMemo(processing))
+	apiWrap.PUT("/merchant/:id/:blockchain/withdraw-approval",
+		adminAuth, handler.UpdateMerchantWithdrawApproval(processing))
+	apiWrap.PUT("/asset/withdraw-limits", adminAuth, handler.UpdateAssetWithdrawLimits(processing, assetService))
+	apiWrap.PUT("/webhooks/subscription", merchantAuth, handler.UpdateWebhookSubscription(processing))
+	apiWrap.PUT("/webhooks/endpoints/:id", merchantAuth, handler.UpdateWebhookEndpoint(processing))
+	apiWrap.PUT("/address-book/entries/:id", merchantAuth, handler.UpdateAddressBookEntry(processing))
+	apiWrap.PUT("/address-book/policy", merchantAuth, handler.UpdateAddressBookPolicy(processing))
+	apiWrap.PUT("/deposits/quarantined/:id", merchantAuth, handler.ResolveQuarantinedDeposit(processing))
+	apiWrap.PUT("/limits/rules/:id", adminAuth, handler.UpdateLimitRule(processing))
+	apiWrap.PUT("/limits/holds/:guid", adminAuth, handler.ResolveHeldTransaction(processing))
+	if err := apiWrap.Check(); err != nil {
+		panic(err)
+	}
 }
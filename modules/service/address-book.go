@@ -0,0 +1,116 @@
+package service
+
+import (
+	"coreum_processor/modules/storage"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetAddressBook returns addresses the merchant allows to withdraw to, blockchain filter is not applied if it is empty
+func (s ProcessingService) GetAddressBook(merchantID, blockchain string) ([]storage.AddressBookEntry, error) {
+	if s.addressStore == nil {
+		return nil, errors.New("address book is not available")
+	}
+	return s.addressStore.GetByMerchant(merchantID, strings.ToLower(blockchain))
+}
+
+// CreateAddressBookEntry validates the address and adds it to the address book of the merchant,
+// the address can be used after the cooling-off period of the merchant in the blockchain
+func (s ProcessingService) CreateAddressBookEntry(merchantID string,
+	entry NewAddressBookEntry) (storage.AddressBookEntry, error) {
+	if s.addressStore == nil {
+		return storage.AddressBookEntry{}, errors.New("address book is not available")
+	}
+	blockchain := strings.ToLower(entry.Blockchain)
+	processor, ok := s.processors[blockchain]
+	if !ok {
+		return storage.AddressBookEntry{}, fmt.Errorf("%s blockchain not found", entry.Blockchain)
+	}
+	address, err := processor.ValidateAddress(entry.Address)
+	if err != nil {
+		return storage.AddressBookEntry{}, fmt.Errorf("%v is not a valid %v address: %w",
+			entry.Address, entry.Blockchain, err)
+	}
+	_, wallet, err := s.getMerchantWallet(merchantID, blockchain)
+	if err != nil {
+		return storage.AddressBookEntry{}, err
+	}
+	now := time.Now()
+	activeFrom := now.Add(time.Duration(wallet.AddressBookAt(now).CoolingOffSeconds) * time.Second)
+	return s.addressStore.Create(merchantID, blockchain, address, entry.Label, activeFrom)
+}
+
+// UpdateAddressBookEntry changes label of the address in the address book of the merchant
+func (s ProcessingService) UpdateAddressBookEntry(merchantID string, id int64, entry UpdateAddressBookEntry) error {
+	if s.addressStore == nil {
+		return errors.New("address book is not available")
+	}
+	return s.addressStore.UpdateLabel(merchantID, id, entry.Label)
+}
+
+// DeleteAddressBookEntry removes the address from the address book of the merchant
+func (s ProcessingService) DeleteAddressBookEntry(merchantID string, id int64) error {
+	if s.addressStore == nil {
+		return errors.New("address book is not available")
+	}
+	return s.addressStore.Delete(merchantID, id)
+}
+
+// UpdateAddressBookPolicy sets how the address book of the merchant restricts withdraws in the blockchain.
+// A stricter policy is effective at once, a relaxed one becomes effective after the current cooling-off period,
+// so a stolen token or session can't be used to withdraw to a new address earlier than the address could be used,
+// until then the policy is not relaxed in any part
+func (s ProcessingService) UpdateAddressBookPolicy(merchantID string, settings AddressBookSettings) (Wallets, error) {
+	if settings.CoolingOffSeconds < 0 {
+		return Wallets{}, errors.New("cooling-off period can't be negative")
+	}
+	blockchain := strings.ToLower(settings.Blockchain)
+	_, wallet, err := s.getMerchantWallet(merchantID, blockchain)
+	if err != nil {
+		return Wallets{}, err
+	}
+	now := time.Now()
+	current := wallet.AddressBookAt(now)
+	policy := settings.AddressBookPolicy
+	if !current.relaxedBy(policy) || current.CoolingOffSeconds == 0 {
+		return s.merchants.UpdateAddressBookPolicy(merchantID, blockchain, policy, nil)
+	}
+	pending := &PendingAddressBookPolicy{
+		AddressBookPolicy: policy,
+		EffectiveFrom:     now.Add(time.Duration(current.CoolingOffSeconds) * time.Second).UTC(),
+	}
+	// parts of the policy which are stricter than the current one are effective at once
+	current.AllowListOnly = current.AllowListOnly || policy.AllowListOnly
+	if policy.CoolingOffSeconds > current.CoolingOffSeconds {
+		current.CoolingOffSeconds = policy.CoolingOffSeconds
+	}
+	return s.merchants.UpdateAddressBookPolicy(merchantID, blockchain, current, pending)
+}
+
+// checkDestination adds a violation to the validation if the merchant allows withdraws only to its address book
+// and the address of the withdraw in canonical form is not in the book or is cooling off
+func (s ProcessingService) checkDestination(validation *WithdrawValidationError, withdraw CredentialWithdraw,
+	address, merchantID string, wallet Wallets) error {
+	if !wallet.AddressBookAt(time.Now()).AllowListOnly {
+		return nil
+	}
+	if s.addressStore == nil {
+		return errors.New("address book is not available")
+	}
+	entry, err := s.addressStore.Find(merchantID, strings.ToLower(withdraw.Blockchain), address)
+	if errors.Is(err, storage.ErrNotFound) {
+		validation.add(ViolationAddressNotAllowed, "wallet_address", "address %v is not in address book",
+			withdraw.WalletAddress)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't get address: %v from address book of merchant: %v, err: %w",
+			withdraw.WalletAddress, merchantID, err)
+	}
+	if !entry.IsActive(time.Now()) {
+		validation.add(ViolationAddressCoolingOff, "wallet_address", "address %v can be used from %v",
+			withdraw.WalletAddress, entry.ActiveFrom.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
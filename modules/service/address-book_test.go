@@ -0,0 +1,30 @@
+package service
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAddressBookPolicyRelaxedBy(t *testing.T) {
+	current := AddressBookPolicy{AllowListOnly: true, CoolingOffSeconds: 3600}
+
+	require.True(t, current.relaxedBy(AddressBookPolicy{CoolingOffSeconds: 3600}))
+	require.True(t, current.relaxedBy(AddressBookPolicy{AllowListOnly: true}))
+	require.False(t, current.relaxedBy(AddressBookPolicy{AllowListOnly: true, CoolingOffSeconds: 7200}))
+	require.False(t, AddressBookPolicy{}.relaxedBy(AddressBookPolicy{AllowListOnly: true}))
+}
+
+func TestWalletsAddressBookAt(t *testing.T) {
+	now := time.Now()
+	wallet := Wallets{
+		AddressBook: AddressBookPolicy{AllowListOnly: true, CoolingOffSeconds: 3600},
+		AddressBookPending: &PendingAddressBookPolicy{
+			AddressBookPolicy: AddressBookPolicy{},
+			EffectiveFrom:     now.Add(time.Hour),
+		},
+	}
+
+	require.Equal(t, wallet.AddressBook, wallet.AddressBookAt(now))
+	require.Equal(t, AddressBookPolicy{}, wallet.AddressBookAt(now.Add(time.Hour)))
+}
@@ -2,18 +2,31 @@ package service
 
 import (
 	"coreum_processor/modules/storage"
+	"crypto/hmac"
 	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-resty/resty/v2"
 	"github.com/golang-jwt/jwt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type CallBacks struct {
 	client          *resty.Client
+	webhookClient   *resty.Client
 	privateKey      *rsa.PrivateKey
 	merchantService *Merchants
+	endpoints       *storage.EndpointsPSQL
 	tokenTimeToLive int
+	retryCount      int
+	retryWaitTime   time.Duration
 }
 
 const (
@@ -21,15 +34,53 @@ const (
 	callBackSign         = "/sign"
 	callBackTransactions = "/transactions"
 	minLengthCallBackURL = 9
+	webhookTimeout       = 30 * time.Second
+	// headers of webhook request, the signature is made over timestamp and body joined by a dot,
+	// by secret of the endpoint with HMAC-SHA256 or by private key of the processing for callback url
+	headerWebhookID        = "X-Webhook-Id"
+	headerWebhookTimestamp = "X-Webhook-Timestamp"
+	headerWebhookSignature = "X-Webhook-Signature"
+)
+
+var (
+	// ErrNoCallBackURL is returned if the merchant doesn't have callback url to deliver webhook
+	ErrNoCallBackURL = errors.New("merchant doesn't have callback url")
+	// ErrNotSubscribed is returned if the merchant isn't subscribed to the type of webhook event
+	ErrNotSubscribed = errors.New("merchant isn't subscribed to the event")
+	// ErrEndpointDisabled is returned if the endpoint of the webhook is disabled or removed
+	ErrEndpointDisabled = errors.New("webhook endpoint is disabled or removed")
+	// ErrPrivateAddress is returned if the host of callback url is resolved to not public address
+	ErrPrivateAddress = errors.New("host of url is resolved to private address")
 )
 
 func NewCallBackService(privateKey *rsa.PrivateKey, tokenTimeToLive, retryCount, retryWaitTime int,
-	merchantService *Merchants) *CallBacks {
+	merchantService *Merchants, endpoints *storage.EndpointsPSQL) *CallBacks {
 	return &CallBacks{
 		// Create a Resty Client
-		client: resty.New().SetRetryCount(retryCount).
+		client: resty.New().SetTransport(newPublicTransport()).SetRetryCount(retryCount).
 			SetRetryWaitTime(time.Duration(retryWaitTime) * time.Second),
-		privateKey: privateKey, tokenTimeToLive: tokenTimeToLive, merchantService: merchantService}
+		// webhooks are retried by the dispatcher with backoff
+		webhookClient:   resty.New().SetTransport(newPublicTransport()).SetTimeout(webhookTimeout),
+		privateKey:      privateKey,
+		tokenTimeToLive: tokenTimeToLive,
+		merchantService: merchantService,
+		endpoints:       endpoints,
+		retryCount:      retryCount,
+		retryWaitTime:   time.Duration(retryWaitTime) * time.Second,
+	}
+}
+
+// newPublicTransport returns transport which connects only to public addresses, callbacks are sent to urls
+// of merchants directly, so they can't reach internal services of the processing
+func newPublicTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   webhookTimeout,
+		KeepAlive: webhookTimeout,
+		Control:   publicAddressControl,
+	}).DialContext
+	return transport
 }
 
 func (s *CallBacks) createJWTAuthorization() (string, error) {
@@ -41,13 +92,47 @@ func (s *CallBacks) createJWTAuthorization() (string, error) {
 	return t.SignedString(s.privateKey)
 }
 
-func (s *CallBacks) GetMultiSignAddressesFn(merchantID string) (FuncMultiSignAddrCallback, error) {
+// getMultiSignURL returns url of the first enabled multisign endpoint of the merchant or its callback url,
+// empty url is returned if the merchant has neither
+func (s *CallBacks) getMultiSignURL(merchantID string) (string, error) {
+	if s.endpoints != nil {
+		endpoints, err := s.endpoints.GetEnabled(merchantID, storage.EndpointMultiSign)
+		if err != nil {
+			return "", err
+		}
+		if len(endpoints) > 0 {
+			return endpoints[0].URL, nil
+		}
+	}
 	merchant, err := s.merchantService.GetMerchantData(merchantID)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	if len(merchant.CallBackURL) < minLengthCallBackURL {
-		return nil, nil
+		return "", nil
+	}
+	return merchant.CallBackURL, nil
+}
+
+// IsMerchantWallet checks if the wallet with the external id is the sending or receiving wallet of the merchant
+// in the blockchain
+func (s *CallBacks) IsMerchantWallet(blockchain, merchantID, externalID string) (bool, error) {
+	merchant, err := s.merchantService.GetMerchantData(merchantID)
+	if err != nil {
+		return false, err
+	}
+	for name, wallet := range merchant.Wallets {
+		if strings.EqualFold(name, blockchain) {
+			return externalID == wallet.SendingID || externalID == wallet.ReceivingID, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *CallBacks) GetMultiSignAddressesFn(merchantID string) (FuncMultiSignAddrCallback, error) {
+	callBackURL, err := s.getMultiSignURL(merchantID)
+	if err != nil || callBackURL == "" {
+		return nil, err
 	}
 	return func(blockChain, externalId string) (MultiSignAddress, float64, error) {
 		threshold := 0.
@@ -55,7 +140,7 @@ func (s *CallBacks) GetMultiSignAddressesFn(merchantID string) (FuncMultiSignAdd
 		query := map[string]string{"blockchain": blockChain, "external_id": externalId}
 		resp, err := s.client.R().SetHeader("Authorization", authorization).SetQueryParams(query).
 			EnableTrace().
-			Get(merchant.CallBackURL + callBackAddresses)
+			Get(callBackURL + callBackAddresses)
 		if err != nil {
 			return MultiSignAddress{}, threshold, err
 		}
@@ -69,19 +154,16 @@ func (s *CallBacks) GetMultiSignAddressesFn(merchantID string) (FuncMultiSignAdd
 }
 
 func (s *CallBacks) GetMultiSignFn(merchantID string) (FuncMultiSignSignature, error) {
-	merchant, err := s.merchantService.GetMerchantData(merchantID)
-	if err != nil {
+	callBackURL, err := s.getMultiSignURL(merchantID)
+	if err != nil || callBackURL == "" {
 		return nil, err
 	}
-	if len(merchant.CallBackURL) < minLengthCallBackURL {
-		return nil, nil
-	}
 	return func(request MultiSignTransactionRequest) (map[string][]byte, error) {
 		authorization, err := s.createJWTAuthorization()
 
 		resp, err := s.client.R().SetHeader("Authorization", authorization).SetBody(request).
 			EnableTrace().
-			Post(merchant.CallBackURL + callBackSign)
+			Post(callBackURL + callBackSign)
 		if err != nil {
 			return nil, err
 		}
@@ -94,22 +176,22 @@ func (s *CallBacks) GetMultiSignFn(merchantID string) (FuncMultiSignSignature, e
 }
 
 func (s *CallBacks) GetTransactionFn(merchantID string) (FuncTransactionsCallback, error) {
-	merchant, err := s.merchantService.GetMerchantData(merchantID)
-	if err != nil {
+	callBackURL, err := s.getMultiSignURL(merchantID)
+	if err != nil || callBackURL == "" {
 		return nil, err
 	}
-	if len(merchant.CallBackURL) < minLengthCallBackURL {
-		return nil, nil
-	}
 	return func(trx storage.TransactionStore) error {
 		authorization, err := s.createJWTAuthorization()
 
 		resp, err := s.client.R().SetHeader("Authorization", authorization).SetBody(trx).
 			EnableTrace().
-			Post(merchant.CallBackURL + callBackTransactions)
+			Post(callBackURL + callBackTransactions)
 		if err != nil {
 			return err
 		}
+		if !resp.IsSuccess() {
+			return fmt.Errorf("merchant: %v callback returned status: %v", merchantID, resp.StatusCode())
+		}
 
 		res := MultiSignAddress{}
 		err = json.Unmarshal(resp.Body(), &res)
@@ -117,3 +199,112 @@ func (s *CallBacks) GetTransactionFn(merchantID string) (FuncTransactionsCallbac
 		return err
 	}, nil
 }
+
+// DeliverWebhook posts the event in the envelope to the endpoint it is fanned out to or to the callback url
+// of the merchant. The body is signed together with the timestamp, so the merchant can verify it and reject
+// replayed requests. Any status other than 2xx is a failure
+func (s *CallBacks) DeliverWebhook(event storage.WebhookEvent) error {
+	merchant, err := s.merchantService.GetMerchantData(event.MerchantID)
+	if err != nil {
+		return err
+	}
+	if !merchant.IsSubscribed(event.Event) {
+		return ErrNotSubscribed
+	}
+	envelope := WebhookEnvelope{
+		ID:        strconv.FormatInt(event.OriginID(), 10),
+		Type:      event.Event,
+		Version:   storage.WebhookEventVersion,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	}
+	if event.EndpointID == 0 {
+		if len(merchant.CallBackURL) < minLengthCallBackURL {
+			return ErrNoCallBackURL
+		}
+		return s.postWebhook(merchant.CallBackURL+callBackTransactions, "", envelope)
+	}
+	if s.endpoints == nil {
+		return ErrEndpointDisabled
+	}
+	endpoint, err := s.endpoints.Get(event.MerchantID, event.EndpointID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrEndpointDisabled
+	} else if err != nil {
+		return err
+	}
+	if !endpoint.Enabled {
+		return ErrEndpointDisabled
+	}
+	if !endpoint.IsSubscribed(event.Event) {
+		return ErrNotSubscribed
+	}
+	return s.postWebhook(endpoint.URL, endpoint.Secret, envelope)
+}
+
+// DeliverTestWebhook posts test event to the endpoint at once regardless of its filter and enable flag
+func (s *CallBacks) DeliverTestWebhook(endpoint storage.WebhookEndpoint) error {
+	now := time.Now().UTC()
+	data, err := json.Marshal(struct {
+		EndpointID int64 `json:"endpoint_id"`
+	}{EndpointID: endpoint.ID})
+	if err != nil {
+		return fmt.Errorf("can't marshal test webhook: %w", err)
+	}
+	return s.postWebhook(endpoint.URL, endpoint.Secret, WebhookEnvelope{
+		ID:        "test-" + strconv.FormatInt(now.UnixNano(), 10),
+		Type:      storage.EventWebhookTest,
+		Version:   storage.WebhookEventVersion,
+		CreatedAt: now,
+		Data:      data,
+	})
+}
+
+// postWebhook posts the envelope to the url signed by the secret or by the private key if the secret is empty
+func (s *CallBacks) postWebhook(url, secret string, envelope WebhookEnvelope) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("can't marshal webhook: %w", err)
+	}
+	authorization, err := s.createJWTAuthorization()
+	if err != nil {
+		return fmt.Errorf("can't create authorization for webhook: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().UTC().Unix(), 10)
+	signature, err := s.signWebhook(secret, timestamp+"."+string(body))
+	if err != nil {
+		return fmt.Errorf("can't sign webhook: %w", err)
+	}
+	resp, err := s.webhookClient.R().
+		SetHeader("Authorization", authorization).
+		SetHeader("Content-Type", "application/json").
+		SetHeader(headerWebhookID, envelope.ID).
+		SetHeader(headerWebhookTimestamp, timestamp).
+		SetHeader(headerWebhookSignature, signature).
+		SetBody(body).
+		Post(url)
+	if err != nil {
+		return err
+	}
+	if !resp.IsSuccess() {
+		return fmt.Errorf("merchant returned status: %v", resp.StatusCode())
+	}
+	return nil
+}
+
+// signWebhook returns hex encoded HMAC-SHA256 of the payload by the secret,
+// the payload is signed by the private key with RS256 if the secret is empty
+func (s *CallBacks) signWebhook(secret, payload string) (string, error) {
+	if secret == "" {
+		return jwt.SigningMethodRS256.Sign(payload, s.privateKey)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// NextWebhookAttempt returns delay before the next delivery of the webhook which failed the given number
+// of times, the webhook goes to dead letters if it has no attempts left
+func (s *CallBacks) NextWebhookAttempt(attempts int) (time.Duration, bool) {
+	return jobBackoff(s.retryWaitTime, attempts), attempts+1 >= s.retryCount
+}
@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"coreum_processor/modules/amount"
 	"coreum_processor/modules/storage"
+	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
+	"strings"
 	"time"
 )
 
@@ -25,10 +28,73 @@ type MerchantData struct {
 	MerchantName string             `json:"name"`
 	CallBackURL  string             `json:"call_back_url"`
 	Wallets      map[string]Wallets `json:"wallets"`
+	// WebhookEvents lists types of webhook events the merchant is subscribed to, empty list means all
+	WebhookEvents []string `json:"webhook_events,omitempty"`
+}
+
+// IsSubscribed checks if the merchant is subscribed to the type of webhook event
+func (m MerchantData) IsSubscribed(eventType string) bool {
+	if len(m.WebhookEvents) == 0 {
+		return true
+	}
+	for _, pattern := range m.WebhookEvents {
+		if storage.MatchEventType(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEnvelope wraps data of webhook event with its id, type and version of the data
+type WebhookEnvelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewWebhookEndpoint is an endpoint to register for the merchant, it is enabled once registered
+type NewWebhookEndpoint struct {
+	Name    string                  `json:"name"`
+	URL     string                  `json:"url"`
+	Purpose storage.EndpointPurpose `json:"purpose"`
+	Events  []string                `json:"events"`
+}
+
+// CreatedWebhookEndpoint is a registered endpoint with its secret, the secret is returned only once
+type CreatedWebhookEndpoint struct {
+	storage.WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+// UpdateWebhookEndpoint is a change of the endpoint, its purpose and secret are kept
+type UpdateWebhookEndpoint struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Enabled bool     `json:"enabled"`
+	Events  []string `json:"events"`
+}
+
+// WebhookEventsResponse lists types of webhook events and the ones the merchant is subscribed to
+type WebhookEventsResponse struct {
+	Version    int      `json:"version"`
+	Events     []string `json:"events"`
+	Subscribed []string `json:"subscribed"`
+}
+
+// WebhookSubscription lists types of webhook events, a type can end with wildcard like deposit.*
+type WebhookSubscription struct {
+	Events []string `json:"events"`
 }
 type Commission struct {
-	Fix     float64 `json:"fix"`
-	Percent float64 `json:"percent"`
+	Fix     amount.Amount `json:"fix"`
+	Percent amount.Amount `json:"percent"`
+}
+
+// Calculate returns commission for the amount rounded to whole subunits
+func (c Commission) Calculate(value amount.Amount) amount.Amount {
+	return c.Fix.Add(value.Percent(c.Percent)).Round()
 }
 
 type Wallets struct {
@@ -37,6 +103,82 @@ type Wallets struct {
 	ReceivingID         string     `json:"receiving_id"`
 	SendingID           string     `json:"sending_id"`
 	SignPublicKey       string     `json:"sign_public_key"`
+	// DepositMemo makes users deposit to the receiving wallet with their memo instead of own wallets
+	DepositMemo bool `json:"deposit_memo"`
+	// WithdrawApproval makes withdraws wait for approvals of merchant users before they are processed
+	WithdrawApproval ApprovalPolicy `json:"withdraw_approval"`
+	// AddressBook restricts destinations of withdraws to the address book of the merchant
+	AddressBook AddressBookPolicy `json:"address_book"`
+	// AddressBookPending is a relaxed address book policy which replaces AddressBook when it is effective
+	AddressBookPending *PendingAddressBookPolicy `json:"address_book_pending,omitempty"`
+}
+
+// AddressBookAt returns the address book policy of the wallet effective at the time
+func (w Wallets) AddressBookAt(at time.Time) AddressBookPolicy {
+	if w.AddressBookPending != nil && !w.AddressBookPending.EffectiveFrom.After(at) {
+		return w.AddressBookPending.AddressBookPolicy
+	}
+	return w.AddressBook
+}
+
+// AddressBookPolicy sets how the address book of the merchant restricts destinations of withdraws
+type AddressBookPolicy struct {
+	// AllowListOnly refuses withdraws to addresses which are not in the address book or are cooling off
+	AllowListOnly bool `json:"allow_list_only"`
+	// CoolingOffSeconds is time a newly added address can't be used for withdraws in allow-list only mode
+	CoolingOffSeconds int64 `json:"cooling_off_seconds"`
+}
+
+// PendingAddressBookPolicy is an address book policy which becomes effective from the time
+type PendingAddressBookPolicy struct {
+	AddressBookPolicy
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// relaxedBy checks if the policy allows withdraws the current one doesn't or allows them earlier
+func (p AddressBookPolicy) relaxedBy(policy AddressBookPolicy) bool {
+	return p.AllowListOnly && !policy.AllowListOnly || policy.CoolingOffSeconds < p.CoolingOffSeconds
+}
+
+// ApprovalPolicy sets how many approvals of distinct merchant users a withdraw needs by its asset and amount
+type ApprovalPolicy struct {
+	Enabled bool `json:"enabled"`
+	// Thresholds set amounts of assets which need approvals, a withdraw of an asset without thresholds
+	// needs one approval whatever its amount is
+	Thresholds []ApprovalThresholds `json:"thresholds"`
+	// ExpirySeconds is time a withdraw waits for approvals before it is rejected, a day if it is not set
+	ExpirySeconds int64 `json:"expiry_seconds"`
+}
+
+// ApprovalThresholds sets amounts of the asset which need approvals, amounts are in subunits of the asset,
+// the native coin of the blockchain is set with empty asset and issuer
+type ApprovalThresholds struct {
+	Asset  string `json:"asset"`
+	Issuer string `json:"issuer"`
+	// AutoApproveBelow is an amount, withdraws of less amount are processed without approvals
+	AutoApproveBelow amount.Amount `json:"auto_approve_below"`
+	// TwoApprovalsFrom is an amount, withdraws of it and larger need two approvals, one is enough if it is omitted
+	TwoApprovalsFrom *amount.Amount `json:"two_approvals_from,omitempty"`
+}
+
+// RequiredApprovals returns number of approvals the withdraw of the asset amount needs
+func (p ApprovalPolicy) RequiredApprovals(asset, issuer string, value amount.Amount) int {
+	if !p.Enabled {
+		return 0
+	}
+	for _, thresholds := range p.Thresholds {
+		if !strings.EqualFold(thresholds.Asset, asset) || thresholds.Issuer != issuer {
+			continue
+		}
+		if value.LT(thresholds.AutoApproveBelow) {
+			return 0
+		}
+		if thresholds.TwoApprovalsFrom != nil && !value.LT(*thresholds.TwoApprovalsFrom) {
+			return 2
+		}
+		return 1
+	}
+	return 1
 }
 
 type SmartContract struct {
@@ -64,23 +206,25 @@ type BalanceRequest struct {
 }
 
 type CredentialDeposit struct {
-	Amount     float64 `json:"amount"`
-	Blockchain string  `json:"blockchain"`
-	Asset      string  `json:"asset"`
-	Issuer     string  `json:"issuer"`
+	Amount     amount.Amount `json:"amount"`
+	Blockchain string        `json:"blockchain"`
+	Asset      string        `json:"asset"`
+	Issuer     string        `json:"issuer"`
 }
 
 type CredentialWithdraw struct {
-	Amount        float64 `json:"amount"`
-	Blockchain    string  `json:"blockchain"`
-	WalletAddress string  `json:"wallet_address"`
-	Asset         string  `json:"asset"`
-	Issuer        string  `json:"issuer"`
-	Memo          string  `json:"memo"`
+	Amount        amount.Amount `json:"amount"`
+	Blockchain    string        `json:"blockchain"`
+	WalletAddress string        `json:"wallet_address"`
+	Asset         string        `json:"asset"`
+	Issuer        string        `json:"issuer"`
+	Memo          string        `json:"memo"`
 }
 
 type WithdrawResponse struct {
 	TransactionHash string `json:"result"`
+	// Status is on_hold if the withdraw breaches limits of the merchant and waits for review of an admin
+	Status storage.StatusTx `json:"status,omitempty"`
 }
 
 type DepositResponse struct {
@@ -95,20 +239,26 @@ type TransferResponse struct {
 	TransferHash string
 }
 
+type RefundResponse struct {
+	TransferHash string
+	Address      string
+	Commission   amount.Amount
+}
+
 type NewTokenResponse struct {
 	Issuer string
 	TxHash string
 }
 
 type Balance struct {
-	Amount     float64 `json:"amount"`
-	Blockchain string  `json:"blockchain"`
-	Asset      string  `json:"asset"`
-	Issuer     string  `json:"issuer"`
+	Amount     amount.Amount `json:"amount"`
+	Blockchain string        `json:"blockchain"`
+	Asset      string        `json:"asset"`
+	Issuer     string        `json:"issuer"`
 }
 
 type TransferTokenRequest struct {
-	Amount              float64
+	Amount              amount.Amount
 	Blockchain          string
 	Subunit             string
 	Issuer              string
@@ -120,10 +270,12 @@ type TransferTokenRequest struct {
 }
 
 type TransferRequest struct {
-	Amount     float64
+	Amount     amount.Amount
 	Blockchain string
 	Asset      string
 	Issuer     string
+	// Address is the address a refund is returned to, it is found by the latest deposit if it is empty
+	Address string
 }
 
 type NewTokenRequest struct {
@@ -160,6 +312,82 @@ type NewMerchant struct {
 	Callback     string `json:"callback"`
 }
 
+// NewMerchantDepositMemo switches deposits of the merchant to the receiving wallet with memo
+type NewMerchantDepositMemo struct {
+	DepositMemo bool `json:"deposit_memo"`
+}
+
+// NewAddressBookEntry adds the address to the address book of the merchant
+type NewAddressBookEntry struct {
+	Blockchain string `json:"blockchain"`
+	Address    string `json:"address"`
+	Label      string `json:"label"`
+}
+
+// UpdateAddressBookEntry changes label of the address in the address book of the merchant
+type UpdateAddressBookEntry struct {
+	Label string `json:"label"`
+}
+
+// AddressBookSettings sets the address book policy of the merchant in the blockchain
+type AddressBookSettings struct {
+	Blockchain string `json:"blockchain"`
+	AddressBookPolicy
+}
+
+// WithdrawApprovalsResponse lists withdraws of the merchant waiting for approvals with their transactions
+type WithdrawApprovalsResponse struct {
+	Approvals []WithdrawApprovalItem `json:"approvals"`
+}
+
+// WithdrawApprovalItem is a withdraw waiting for approvals with decisions of users made on it
+type WithdrawApprovalItem struct {
+	storage.ApprovalRequest
+	Transaction storage.TransactionStore `json:"transaction"`
+	Decisions   []storage.ApprovalRecord `json:"decisions"`
+}
+
+// AssetWithdrawLimits sets the least and the largest amounts in subunits of the asset allowed to withdraw at once,
+// an omitted limit is removed
+type AssetWithdrawLimits struct {
+	Blockchain  string         `json:"blockchain"`
+	Code        string         `json:"code"`
+	Issuer      string         `json:"issuer"`
+	MinWithdraw *amount.Amount `json:"min_withdraw"`
+	MaxWithdraw *amount.Amount `json:"max_withdraw"`
+}
+
+// NewLimitRule limits deposits or withdraws of the merchant asset, amounts are in subunits of the asset
+// and an omitted limit is not checked
+type NewLimitRule struct {
+	MerchantID      string             `json:"merchant_id"`
+	Blockchain      string             `json:"blockchain"`
+	Asset           string             `json:"asset"`
+	Issuer          string             `json:"issuer"`
+	Action          storage.ActionTx   `json:"action"`
+	Scope           storage.LimitScope `json:"scope"`
+	MaxPerTx        *amount.Amount     `json:"max_per_tx"`
+	MaxPerDay       *amount.Amount     `json:"max_per_day"`
+	MaxPerMonth     *amount.Amount     `json:"max_per_month"`
+	MaxCountPerHour *int64             `json:"max_count_per_hour"`
+}
+
+// HeldTransaction is a transaction on hold for breaching limits with the hold
+type HeldTransaction struct {
+	storage.TransactionHold
+	Transaction storage.TransactionStore `json:"transaction"`
+}
+
+// HoldResolution releases the transaction on hold to processing or rejects it
+type HoldResolution struct {
+	Decision storage.HoldDecision `json:"decision"`
+}
+
+// QuarantineResolution assigns quarantined deposit to the user, the deposit is dismissed without a user
+type QuarantineResolution struct {
+	ExternalID string `json:"external_id"`
+}
+
 type NewMerchantCommission struct {
 	CommissionReceiving Commission `json:"commission_receiving"`
 	CommissionSending   Commission `json:"commission_sending"`
@@ -182,6 +410,11 @@ type DeleteWithdrawResponse struct {
 	Status string `json:"status"`
 }
 
+// MessageResponse is a result of request which doesn't return data
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
 type TransactionsResponse struct {
 	Transactions []Transaction `json:"transactions"`
 }
@@ -242,9 +475,16 @@ const (
 	SuccessfulTransaction CryptoTransactionStatus = 3
 )
 
-// FuncDepositCallback defines a callback function to inform main processing service about received deposit
-type FuncDepositCallback func(blockChain, merchantID, externalId, externalWallet, hash, asset, issuer string,
-	amount float64)
+// FuncDepositCallback defines a callback function to inform main processing service about received deposit,
+// hash and memo are empty if the deposit is found by balance of the wallet instead of its transaction.
+// An error is returned if the deposit isn't recorded, so it must be informed again
+type FuncDepositCallback func(blockChain, merchantID, externalId, externalWallet, hash, memo, asset, issuer string,
+	value amount.Amount) error
+
+// FuncSweepCallback defines a callback function to inform main processing service about gas returned
+// from user wallet to the sending wallet of the processing
+type FuncSweepCallback func(blockChain, merchantID, externalId, externalWallet, hash, asset string,
+	value amount.Amount)
 
 // FuncMultiSignAddrCallback defines a callback function to get a list of address to be added to multi sig account
 type FuncMultiSignAddrCallback func(blockChain, externalId string) (MultiSignAddress, float64, error)
@@ -252,6 +492,38 @@ type FuncMultiSignAddrCallback func(blockChain, externalId string) (MultiSignAdd
 // FuncMultiSignSignature defines a callback function to get a list of address to be added to multi sig account
 type FuncMultiSignSignature func(request MultiSignTransactionRequest) (map[string][]byte, error)
 
+// FuncBroadcastIntent defines a callback function to record hash of a signed blockchain transaction and the height
+// it can't be included in a block after before it is broadcast, the transaction is not broadcast if the hash
+// can't be recorded
+type FuncBroadcastIntent func(hash string, timeoutHeight int64) error
+
+// broadcastIntentKey is the context key of the broadcast intent
+type broadcastIntentKey struct{}
+
+// WithBroadcastIntent returns context which makes the processor record the hash of the transaction
+// it broadcasts with the context, nil callback disables the record
+func WithBroadcastIntent(ctx context.Context, callback FuncBroadcastIntent) context.Context {
+	return context.WithValue(ctx, broadcastIntentKey{}, callback)
+}
+
+// HasBroadcastIntent reports whether the transaction broadcast with the context is recorded before broadcast
+func HasBroadcastIntent(ctx context.Context) bool {
+	callback, _ := ctx.Value(broadcastIntentKey{}).(FuncBroadcastIntent)
+	return callback != nil
+}
+
+// RecordBroadcastIntent is called by the processor with hash of the signed transaction right before it is broadcast
+func RecordBroadcastIntent(ctx context.Context, hash string, timeoutHeight int64) error {
+	callback, _ := ctx.Value(broadcastIntentKey{}).(FuncBroadcastIntent)
+	if callback == nil {
+		return nil
+	}
+	if err := callback(hash, timeoutHeight); err != nil {
+		return fmt.Errorf("can't record transaction: %v before broadcast, err: %w", hash, err)
+	}
+	return nil
+}
+
 // FuncTransactionsCallback defines a callback function to post transaction for merchant
 type FuncTransactionsCallback func(trx storage.TransactionStore) error
 
@@ -267,6 +539,8 @@ type CryptoProcessor interface {
 	// Deposit create a
 	Deposit(ctx context.Context, request CredentialDeposit, merchantID, externalId string) (*DepositResponse, error)
 	StreamDeposit(ctx context.Context, callback FuncDepositCallback, interval time.Duration)
+	// StreamSweep returns gas left in user wallets to the processing
+	StreamSweep(ctx context.Context, callback FuncSweepCallback)
 
 	// Withdraw
 	Withdraw(ctx context.Context, request CredentialWithdraw,
@@ -293,8 +567,20 @@ type CryptoProcessor interface {
 	TransferNFT(ctx context.Context, request TransferTokenRequest,
 		merchantID string) (string, error)
 
+	// Refund returns funds deposited to the user wallet back to the address they were sent from
+	Refund(ctx context.Context, request TransferRequest, merchantID, externalId string) (*RefundResponse, error)
+
 	GetTokenSupply(ctx context.Context, request BalanceRequest) (int64, error)
 	GetBalance(ctx context.Context, merchantID, externalID string) (Balance, error)
 	GetAssetsBalance(ctx context.Context, request BalanceRequest, merchantID, externalId string) ([]Balance, error)
 	GetTransactionStatus(ctx context.Context, hash string) (CryptoTransactionStatus, error)
+	// GetBroadcastStatus returns status of the transaction recorded before broadcast with its timeout height,
+	// NoTransaction is returned only when the transaction can't be included in a block any more
+	GetBroadcastStatus(ctx context.Context, hash string, timeoutHeight int64) (CryptoTransactionStatus, error)
+
+	// ValidateAddress returns the address in canonical form or error if it is not a valid account address
+	// of the blockchain
+	ValidateAddress(address string) (string, error)
+	// MinimumValue returns the least amount in subunits the processor transfers
+	MinimumValue() amount.Amount
 }
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// idempotencyLease is time a request holds its idempotency key in progress, the key of a request lost
+// by a crashed instance can be reserved again by a retry after the lease
+const idempotencyLease = 5 * time.Minute
+
+var (
+	// ErrIdempotencyKeyConflict returned in case of the idempotency key was used with another request
+	ErrIdempotencyKeyConflict = errors.New("idempotency key is already used for another request")
+	// ErrIdempotencyKeyInProgress returned in case of a request with the idempotency key is still processed
+	ErrIdempotencyKeyInProgress = errors.New("request with the idempotency key is in progress")
+)
+
+// IdempotentResponse is a response stored for a request made with an idempotency key
+type IdempotentResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// StartIdempotentRequest reserves the idempotency key of merchant for the request with the hash,
+// if the request has been already completed the stored response is returned to be replayed
+func (s ProcessingService) StartIdempotentRequest(merchantID, key, requestHash string) (*IdempotentResponse, error) {
+	if s.idempotencyStore == nil {
+		return nil, nil
+	}
+	record, err := s.idempotencyStore.Reserve(merchantID, key, requestHash, idempotencyLease)
+	if err != nil {
+		return nil, fmt.Errorf("can't reserve idempotency key: %v for merchant: %v, err: %w", key, merchantID, err)
+	}
+	if record == nil {
+		return nil, nil
+	}
+	if record.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyConflict
+	}
+	if record.StatusCode == 0 {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	return &IdempotentResponse{StatusCode: record.StatusCode, Body: record.Response}, nil
+}
+
+// FinishIdempotentRequest stores successful response for the idempotency key of merchant,
+// the key of failed request is released to let merchant retry it
+func (s ProcessingService) FinishIdempotentRequest(merchantID, key string, response IdempotentResponse) error {
+	if s.idempotencyStore == nil {
+		return nil
+	}
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return s.idempotencyStore.Release(merchantID, key)
+	}
+	return s.idempotencyStore.Complete(merchantID, key, response.StatusCode, response.Body)
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/storage"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// limitBreach is the first limit rule a transaction breaches with the reason to put it on hold
+type limitBreach struct {
+	ruleID int64
+	reason string
+}
+
+// checkLimits returns the first limit rule of the merchant the new transaction of the user breaches
+// by volumes of transactions counted by the function, nil is returned if the transaction is within all limits
+func (s ProcessingService) checkLimits(volumes storage.VolumeFunc, merchantID, externalID, blockchain, asset,
+	issuer string, action storage.ActionTx, value amount.Amount) (*limitBreach, error) {
+	if s.limitStore == nil {
+		return nil, nil
+	}
+	rules, err := s.limitStore.GetMatchingRules(merchantID, strings.ToLower(blockchain), asset, issuer, action)
+	if err != nil {
+		return nil, fmt.Errorf("can't get limit rules of merchant: %v, err: %w", merchantID, err)
+	}
+	now := time.Now()
+	for _, rule := range rules {
+		if rule.MaxPerTx != nil && value.GT(*rule.MaxPerTx) {
+			return &limitBreach{ruleID: rule.ID,
+				reason: fmt.Sprintf("amount %v is over %v per transaction", value, *rule.MaxPerTx)}, nil
+		}
+		user := externalID
+		if rule.Scope == storage.LimitScopeMerchant {
+			user = ""
+		}
+		windows := []struct {
+			limit  *amount.Amount
+			period time.Duration
+			name   string
+		}{
+			{limit: rule.MaxPerDay, period: 24 * time.Hour, name: "24 hours"},
+			{limit: rule.MaxPerMonth, period: 30 * 24 * time.Hour, name: "30 days"},
+		}
+		for _, window := range windows {
+			if window.limit == nil {
+				continue
+			}
+			volume, _, err := volumes(merchantID, user, rule.Blockchain, asset, issuer, action,
+				now.Add(-window.period))
+			if err != nil {
+				return nil, err
+			}
+			if volume = volume.Add(value); volume.GT(*window.limit) {
+				return &limitBreach{ruleID: rule.ID, reason: fmt.Sprintf("%v %v in %v is over %v",
+					rule.Scope, action, window.name, *window.limit)}, nil
+			}
+		}
+		if rule.MaxCountPerHour != nil {
+			_, count, err := volumes(merchantID, user, rule.Blockchain, asset, issuer, action,
+				now.Add(-time.Hour))
+			if err != nil {
+				return nil, err
+			}
+			if count+1 > *rule.MaxCountPerHour {
+				return &limitBreach{ruleID: rule.ID, reason: fmt.Sprintf("%v %v count in an hour is over %v",
+					rule.Scope, action, *rule.MaxCountPerHour)}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// holdTransaction puts the new transaction in the status on hold for breaching the limit,
+// the hold is recorded first, so the transaction on hold is always found by admins
+func (s ProcessingService) holdTransaction(merchantID, guid string, status storage.StatusTx,
+	breach limitBreach) error {
+	err := s.limitStore.CreateHold(merchantID, guid, breach.ruleID, breach.reason, status)
+	if err != nil {
+		return err
+	}
+	if err = s.transactionStore.HoldTransaction(merchantID, guid); err != nil {
+		return fmt.Errorf("can't put transaction: %v on hold, err: %w", guid, err)
+	}
+	log.Println(fmt.Sprintf("transaction: %v of merchant: %v is on hold, %v", guid, merchantID, breach.reason))
+	return nil
+}
+
+// checkDepositLimits returns the limit the new deposit breaches. If limits can't be checked, the deposit is held
+// for admins to decide on it, or it is not held if the processing is configured to fail open
+func (s ProcessingService) checkDepositLimits(merchantID, externalId, blockChain, asset, issuer string,
+	value amount.Amount) *limitBreach {
+	breach, err := s.checkLimits(s.transactionStore.GetVolume, merchantID, externalId, blockChain, asset, issuer,
+		storage.DepositTransaction, value)
+	if err != nil {
+		log.Println(fmt.Sprintf("error in deposit callback to check limits, err: %v", err))
+		if s.limitsFailOpen {
+			return nil
+		}
+		return &limitBreach{reason: "limits could not be checked"}
+	}
+	return breach
+}
+
+// processDeposit puts the new deposit on hold if it breaches the limit, otherwise makes a job to process it.
+// If the deposit can't be held, it is processed if the processing is configured to fail open, otherwise it waits
+// in its status until the processing is restarted
+func (s ProcessingService) processDeposit(merchantID, guid string, status storage.StatusTx, breach *limitBreach) {
+	if breach == nil {
+		s.enqueueTransaction(merchantID, guid)
+		return
+	}
+	if err := s.holdTransaction(merchantID, guid, status, *breach); err != nil {
+		log.Println(fmt.Sprintf("error in deposit callback to hold transaction: %v, err: %v", guid, err))
+		if s.limitsFailOpen {
+			s.enqueueTransaction(merchantID, guid)
+		}
+	}
+}
+
+// GetLimitRules returns limit rules of the merchant, rules of all merchants are returned if merchantID is empty
+func (s ProcessingService) GetLimitRules(merchantID string) ([]storage.LimitRule, error) {
+	if s.limitStore == nil {
+		return nil, errors.New("limits are not available")
+	}
+	return s.limitStore.GetRules(merchantID)
+}
+
+// CreateLimitRule adds the limit rule for deposits or withdraws of the merchant asset
+func (s ProcessingService) CreateLimitRule(rule NewLimitRule) (storage.LimitRule, error) {
+	if s.limitStore == nil {
+		return storage.LimitRule{}, errors.New("limits are not available")
+	}
+	limit, err := s.newLimitRule(rule)
+	if err != nil {
+		return storage.LimitRule{}, err
+	}
+	return s.limitStore.CreateRule(limit)
+}
+
+// UpdateLimitRule replaces the limit rule with the id
+func (s ProcessingService) UpdateLimitRule(id int64, rule NewLimitRule) error {
+	if s.limitStore == nil {
+		return errors.New("limits are not available")
+	}
+	limit, err := s.newLimitRule(rule)
+	if err != nil {
+		return err
+	}
+	return s.limitStore.UpdateRule(id, limit)
+}
+
+// DeleteLimitRule removes the limit rule with the id
+func (s ProcessingService) DeleteLimitRule(id int64) error {
+	if s.limitStore == nil {
+		return errors.New("limits are not available")
+	}
+	return s.limitStore.DeleteRule(id)
+}
+
+// newLimitRule validates the requested limit rule
+func (s ProcessingService) newLimitRule(rule NewLimitRule) (storage.LimitRule, error) {
+	if _, err := s.merchants.GetMerchantData(rule.MerchantID); err != nil {
+		return storage.LimitRule{}, fmt.Errorf("can't get merchant: %v, err: %w", rule.MerchantID, err)
+	}
+	if rule.Blockchain == "" || rule.Asset == "" {
+		return storage.LimitRule{}, errors.New("blockchain and asset of limit rule are required")
+	}
+	if rule.Action != storage.DepositTransaction && rule.Action != storage.WithdrawTransaction {
+		return storage.LimitRule{}, fmt.Errorf("limits of %v are not supported", rule.Action)
+	}
+	if rule.Scope != storage.LimitScopeUser && rule.Scope != storage.LimitScopeMerchant {
+		return storage.LimitRule{}, fmt.Errorf("unknown scope of limit rule: %v", rule.Scope)
+	}
+	for _, limit := range []*amount.Amount{rule.MaxPerTx, rule.MaxPerDay, rule.MaxPerMonth} {
+		if limit != nil && limit.IsNegative() {
+			return storage.LimitRule{}, errors.New("amounts of limit rule can't be negative")
+		}
+	}
+	if rule.MaxCountPerHour != nil && *rule.MaxCountPerHour < 0 {
+		return storage.LimitRule{}, errors.New("count of limit rule can't be negative")
+	}
+	return storage.LimitRule{
+		MerchantID:      rule.MerchantID,
+		Blockchain:      strings.ToLower(rule.Blockchain),
+		Asset:           rule.Asset,
+		Issuer:          rule.Issuer,
+		Action:          rule.Action,
+		Scope:           rule.Scope,
+		MaxPerTx:        rule.MaxPerTx,
+		MaxPerDay:       rule.MaxPerDay,
+		MaxPerMonth:     rule.MaxPerMonth,
+		MaxCountPerHour: rule.MaxCountPerHour,
+	}, nil
+}
+
+// GetHeldTransactions returns transactions on hold waiting for a decision of an admin,
+// merchantID filter is not applied if it is empty
+func (s ProcessingService) GetHeldTransactions(merchantID string) ([]HeldTransaction, error) {
+	if s.limitStore == nil {
+		return nil, errors.New("limits are not available")
+	}
+	holds, err := s.limitStore.GetPendingHolds(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]HeldTransaction, 0, len(holds))
+	for _, hold := range holds {
+		transaction, err := s.transactionStore.GetTransactionByGuid(hold.MerchantID, hold.TransactionGUID)
+		if err != nil {
+			return nil, fmt.Errorf("can't get transaction: %v on hold, err: %w", hold.TransactionGUID, err)
+		}
+		// a withdraw on hold can be deleted by the merchant, it doesn't wait for a decision anymore
+		if transaction.Status != storage.OnHoldTransaction {
+			continue
+		}
+		res = append(res, HeldTransaction{TransactionHold: hold, Transaction: *transaction})
+	}
+	return res, nil
+}
+
+// ResolveHeldTransaction releases the transaction on hold to its status before the hold and makes a job
+// to process it, or rejects it, a rejected withdraw releases its reserved funds and funds of a rejected deposit
+// are returned to the sender by a refund. The identity is the Ory identity of the admin deciding in UI,
+// it is empty for the admin token
+func (s ProcessingService) ResolveHeldTransaction(guid, identity string,
+	resolution HoldResolution) (*storage.TransactionHold, error) {
+	if s.limitStore == nil {
+		return nil, errors.New("limits are not available")
+	}
+	hold, err := s.limitStore.GetHold(guid)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Decision != "" {
+		return nil, fmt.Errorf("%w: hold of transaction %v is already decided", storage.ErrInvalidTransition, guid)
+	}
+	// the transaction is moved first, it is moved once even if admins decide at the same time
+	switch resolution.Decision {
+	case storage.HoldReleased:
+		err = s.transactionStore.ReleaseTransaction(hold.MerchantID, guid, hold.HeldStatus)
+	case storage.HoldRejected:
+		err = s.transactionStore.RejectHeldTransaction(hold.MerchantID, guid)
+	default:
+		return nil, fmt.Errorf("unknown decision on transaction on hold: %v", resolution.Decision)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = s.limitStore.Decide(guid, resolution.Decision, identity); err != nil {
+		log.Println(fmt.Sprintf("can't record decision on transaction: %v on hold, err: %v", guid, err))
+	}
+	switch resolution.Decision {
+	case storage.HoldReleased:
+		s.enqueueTransaction(hold.MerchantID, guid)
+	case storage.HoldRejected:
+		s.refundRejectedDeposit(hold.MerchantID, guid)
+	}
+	log.Println(fmt.Sprintf("admin: %q decided to %v transaction: %v of merchant: %v on hold",
+		identity, resolution.Decision, guid, hold.MerchantID))
+	hold.Decision, hold.DecidedBy = resolution.Decision, identity
+	return hold, nil
+}
+
+// refundRejectedDeposit returns funds of the rejected deposit to the sender, funds of a deposit received
+// to the receiving wallet of the merchant are returned from it
+func (s ProcessingService) refundRejectedDeposit(merchantID, guid string) {
+	tr, err := s.transactionStore.GetTransactionByGuid(merchantID, guid)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't get rejected transaction: %v to refund, err: %v", guid, err))
+		return
+	}
+	if tr.Action != storage.DepositTransaction {
+		return
+	}
+	externalId := tr.ExternalId
+	if isReceivedDeposit(*tr) {
+		_, wallet, err := s.getMerchantWallet(merchantID, tr.Blockchain)
+		if err != nil {
+			log.Println(fmt.Sprintf("can't get wallet to refund rejected deposit: %v, err: %v", guid, err))
+			return
+		}
+		externalId = wallet.ReceivingID
+	}
+	err = s.makeRefund(tr.Blockchain, merchantID, externalId, tr.ExtWallet, tr.Hash1, tr.Asset, tr.Issuer, tr.Amount)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't refund rejected deposit: %v, err: %v", guid, err))
+	}
+}
@@ -101,6 +101,9 @@ func (service *Merchants) UpdateMerchantCommission(id, blockchain string,
 	if _, ok := dataOld.Wallets[blockchain]; ok {
 		newData.ReceivingID = dataOld.Wallets[blockchain].ReceivingID
 		newData.SendingID = dataOld.Wallets[blockchain].SendingID
+		newData.DepositMemo = dataOld.Wallets[blockchain].DepositMemo
+		newData.WithdrawApproval = dataOld.Wallets[blockchain].WithdrawApproval
+		newData.AddressBook = dataOld.Wallets[blockchain].AddressBook
 	}
 	dataOld.Wallets[blockchain] = newData
 	dataByte, err := json.Marshal(dataOld)
@@ -113,6 +116,115 @@ func (service *Merchants) UpdateMerchantCommission(id, blockchain string,
 	}
 	return newData, err
 }
+
+// UpdateMerchantDepositMemo switches memo deposits of the merchant in the blockchain
+func (service *Merchants) UpdateMerchantDepositMemo(id, blockchain string,
+	data NewMerchantDepositMemo) (Wallets, error) {
+	_, dataRaw, err := service.store.Get(id)
+	if err != nil {
+		return Wallets{}, err
+	}
+	dataOld := MerchantData{}
+	err = json.Unmarshal(dataRaw, &dataOld)
+	if err != nil {
+		return Wallets{}, err
+	}
+	wallets, ok := dataOld.Wallets[blockchain]
+	if !ok {
+		return Wallets{}, fmt.Errorf("%s blockchain not found for merchant: %s", blockchain, id)
+	}
+	wallets.DepositMemo = data.DepositMemo
+	dataOld.Wallets[blockchain] = wallets
+	dataByte, err := json.Marshal(dataOld)
+	if err != nil {
+		return Wallets{}, err
+	}
+	_, err = service.store.Put(id, dataByte, storage.DefaultTTL)
+	if err != nil {
+		return Wallets{}, err
+	}
+	return wallets, nil
+}
+
+// UpdateWithdrawApproval sets the policy of approvals of withdraws of the merchant in the blockchain
+func (service *Merchants) UpdateWithdrawApproval(id, blockchain string, policy ApprovalPolicy) (Wallets, error) {
+	_, dataRaw, err := service.store.Get(id)
+	if err != nil {
+		return Wallets{}, err
+	}
+	dataOld := MerchantData{}
+	err = json.Unmarshal(dataRaw, &dataOld)
+	if err != nil {
+		return Wallets{}, err
+	}
+	wallets, ok := dataOld.Wallets[blockchain]
+	if !ok {
+		return Wallets{}, fmt.Errorf("%s blockchain not found for merchant: %s", blockchain, id)
+	}
+	wallets.WithdrawApproval = policy
+	dataOld.Wallets[blockchain] = wallets
+	dataByte, err := json.Marshal(dataOld)
+	if err != nil {
+		return Wallets{}, err
+	}
+	_, err = service.store.Put(id, dataByte, storage.DefaultTTL)
+	if err != nil {
+		return Wallets{}, err
+	}
+	return wallets, nil
+}
+
+// UpdateAddressBookPolicy sets how the address book of the merchant restricts withdraws in the blockchain
+// and the pending policy which replaces it later, nil pending policy cancels the one set before
+func (service *Merchants) UpdateAddressBookPolicy(id, blockchain string, policy AddressBookPolicy,
+	pending *PendingAddressBookPolicy) (Wallets, error) {
+	_, dataRaw, err := service.store.Get(id)
+	if err != nil {
+		return Wallets{}, err
+	}
+	dataOld := MerchantData{}
+	err = json.Unmarshal(dataRaw, &dataOld)
+	if err != nil {
+		return Wallets{}, err
+	}
+	wallets, ok := dataOld.Wallets[blockchain]
+	if !ok {
+		return Wallets{}, fmt.Errorf("%s blockchain not found for merchant: %s", blockchain, id)
+	}
+	wallets.AddressBook = policy
+	wallets.AddressBookPending = pending
+	dataOld.Wallets[blockchain] = wallets
+	dataByte, err := json.Marshal(dataOld)
+	if err != nil {
+		return Wallets{}, err
+	}
+	_, err = service.store.Put(id, dataByte, storage.DefaultTTL)
+	if err != nil {
+		return Wallets{}, err
+	}
+	return wallets, nil
+}
+
+// UpdateWebhookEvents sets types of webhook events the merchant is subscribed to
+func (service *Merchants) UpdateWebhookEvents(id string, events []string) error {
+	_, dataRaw, err := service.store.Get(id)
+	if err != nil {
+		return err
+	}
+	dataOld := MerchantData{}
+	err = json.Unmarshal(dataRaw, &dataOld)
+	if err != nil {
+		return err
+	}
+	dataOld.WebhookEvents = events
+	dataByte, err := json.Marshal(dataOld)
+	if err != nil {
+		return err
+	}
+	_, err = service.store.Put(id, dataByte, storage.DefaultTTL)
+	return err
+}
+
 func NewMerchantService(store storage.Storage) *Merchants {
 	return &Merchants{
 		store: store,
@@ -2,183 +2,177 @@ package service
 
 import (
 	"context"
+	"coreum_processor/modules/amount"
 	"coreum_processor/modules/storage"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"strings"
+	"time"
 )
 
 const (
 	limitTrxToDepositProcess = 1000
 )
 
-func (s ProcessingService) processDeposit(ctx context.Context, bc string, processor CryptoProcessor,
-	merch MerchantData, wallet Wallets) {
-
-	// Process initiated trx
-	s.processDepositInitiated(ctx, bc, processor, merch)
+// assetKey identifies an asset of the blockchain by its code and issuer
+type assetKey struct {
+	asset  string
+	issuer string
+}
 
-	// Process processed trx
-	s.processDepositProcessed(ctx, bc, processor, merch, wallet)
+// processDepositInitiated transfers initiated deposit to receiving wallet and checks status of the transfer,
+// hash of the transfer is recorded before it is broadcast
+func (s ProcessingService) processDepositInitiated(ctx context.Context, processor CryptoProcessor,
+	tr storage.TransactionStore) (jobResult, error) {
+	res, err := s.broadcastStatus(ctx, processor, tr.Hash2, tr.TimeoutHeight)
+	if err != nil {
+		return jobWait, fmt.Errorf("error in process deposit to get status of: %v, err: %w", tr.Hash2, err)
+	}
+	switch res {
+	case SuccessfulTransaction:
+		err = s.transactionStore.PutProcessedTransaction(tr.MerchantId, tr.ExternalId,
+			tr.GUID.String(), tr.Hash2, amount.Zero())
+		if err != nil {
+			return jobWait, fmt.Errorf("error in process deposit to put processed status: %w", err)
+		}
+		return jobNext, nil
+	case PendingTransaction:
+		return jobWait, nil
+	}
 
-	// Process settled trx
-	s.processDepositSettled(ctx, bc, processor, merch)
+	// failed or expired transfer doesn't move funds, it is made again
+	_, err = processor.TransferToReceiving(s.withBroadcastIntent(ctx, tr, storage.InitTransaction, tr.Hash2),
+		TransferRequest{
+			Amount:     tr.Amount,
+			Blockchain: tr.Blockchain,
+			Asset:      tr.Asset,
+			Issuer:     tr.Issuer,
+		}, tr.MerchantId, tr.ExternalId)
+	if err != nil {
+		return jobWait, fmt.Errorf("error in process deposit in transfer to receiving: %w", err)
+	}
+	return jobWait, nil
+}
 
+// enqueueDepositSettlement makes a job to settle processed deposits of the merchant in the asset of the deposit,
+// the deposit waits until it is settled by the job
+func (s ProcessingService) enqueueDepositSettlement(tr storage.TransactionStore) (jobResult, error) {
+	job := settleDepositJob{
+		MerchantID: tr.MerchantId,
+		Blockchain: strings.ToLower(tr.Blockchain),
+		Asset:      tr.Asset,
+		Issuer:     tr.Issuer,
+	}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return jobWait, err
+	}
+	key := strings.Join([]string{job.MerchantID, job.Blockchain, job.Asset, job.Issuer}, ":")
+	if _, err = s.jobStore.Enqueue(jobSettleDeposit, key, payload, time.Now()); err != nil {
+		return jobWait, err
+	}
+	return jobWait, nil
 }
 
-func (s ProcessingService) processDepositInitiated(ctx context.Context, bc string, processor CryptoProcessor,
-	merch MerchantData) {
-	trx, err := s.transactionStore.GetMerchantTrxForProcessingInBlockChain(merch.ID.String(), bc,
-		storage.DepositTransaction, storage.InitTransaction, limitTrxToDepositProcess)
-	if err != nil && errors.Is(storage.ErrNotFound, err) {
-		// no deposit transaction to process
-		return
+// settleDeposits settles processed deposits of the merchant in the asset of the job
+func (s ProcessingService) settleDeposits(ctx context.Context, job settleDepositJob) error {
+	processor := s.processors[job.Blockchain]
+	if processor == nil {
+		return fmt.Errorf("processing can't find processor for blockchain: %v", job.Blockchain)
+	}
+	merch, wallet, err := s.getMerchantWallet(job.MerchantID, job.Blockchain)
+	if err != nil {
+		return err
+	}
+	trx, err := s.transactionStore.GetMerchantTrxForProcessingInBlockChain(job.MerchantID, job.Blockchain,
+		storage.DepositTransaction, storage.ProcessedTransaction, limitTrxToDepositProcess)
+	if errors.Is(err, storage.ErrNotFound) {
+		// deposits have been already settled
+		return nil
 	} else if err != nil {
-		log.Println(fmt.Errorf(
-			"can't get initiated transaction: %v, err: %v, blockchain: %v", merch.ID, err, bc))
-		return
+		return fmt.Errorf("can't get processed transactions for merchant: %v, err: %w", job.MerchantID, err)
 	}
+	var group []storage.TransactionStore
 	for _, tr := range trx {
-		if tr.Hash2 == "" {
-			// blockchain transaction is not created for processing
-			res, err := processor.TransferToReceiving(ctx, TransferRequest{
-				Amount:     tr.Amount,
-				Blockchain: tr.Blockchain,
-				Asset:      tr.Asset,
-				Issuer:     tr.Issuer,
-			}, tr.MerchantId, tr.ExternalId)
-			if err != nil {
-				log.Println(fmt.Sprintf("error in process deposit in transfer to receiving: %v", err))
-				continue
-			}
-			// TODO: update store if err?
-			err = s.transactionStore.PutInitiatedPendingTransaction(tr.MerchantId, tr.ExternalId,
-				tr.GUID.String(), res.TransferHash)
-			if err != nil {
-				log.Println(
-					fmt.Sprintf("error in process deposit to put inititated pending transaction for: %v, err: %v",
-						tr.GUID.String(), err))
-				continue
-			}
-		} else {
-			res, err := processor.GetTransactionStatus(ctx, tr.Hash2)
-			if res == SuccessfulTransaction {
-				err = s.transactionStore.PutProcessedTransaction(tr.MerchantId, tr.ExternalId,
-					tr.GUID.String(), tr.Hash2, 0)
-			} else if res == FailedTransaction {
-				// reset transaction hash to create new transaction for processing
-				err = s.transactionStore.PutInitiatedPendingTransaction(tr.MerchantId, tr.ExternalId,
-					tr.GUID.String(), "")
-			}
-			if err != nil {
-				log.Println(
-					fmt.Sprintf("error in process deposit to put processed status for transaction: %v, err: %v",
-						tr.GUID.String(), err))
-			}
-			continue
+		// deposits received to the merchant wallet aren't transferred from the receiving wallet
+		if tr.Asset == job.Asset && tr.Issuer == job.Issuer && !isReceivedDeposit(tr) {
+			group = append(group, tr)
 		}
 	}
+	return s.settleDepositAsset(ctx, job.Blockchain, processor, merch, wallet,
+		assetKey{asset: job.Asset, issuer: job.Issuer}, group)
 }
 
-func (s ProcessingService) processDepositProcessed(ctx context.Context, bc string, processor CryptoProcessor,
-	merch MerchantData, wallet Wallets) {
-
-	trx, err := s.transactionStore.GetMerchantTrxForProcessingInBlockChain(merch.ID.String(), bc,
-		storage.DepositTransaction, storage.ProcessedTransaction, limitTrxToDepositProcess)
-	if err != nil && errors.Is(storage.ErrNotFound, err) {
-		// no deposit transaction to settle
-		return
-	} else if err != nil {
-		log.Println(fmt.Errorf(
-			"can't get processed transactions for merchant: %v, err: %v, blockchain: %v", merch.ID, err, bc))
-		return
+// settleDepositAsset transfers the processed deposits of the same asset from receiving wallet to the merchant,
+// the deposits are claimed by the settlement before the transfer is made and its hash is recorded
+// in them before it is broadcast
+func (s ProcessingService) settleDepositAsset(ctx context.Context, bc string, processor CryptoProcessor,
+	merch MerchantData, wallet Wallets, key assetKey, trx []storage.TransactionStore) error {
+	value := amount.Zero()
+	settle := make([]storage.TransactionStore, 0, len(trx))
+	for _, tr := range trx {
+		tr.Commission = wallet.CommissionReceiving.Calculate(tr.Amount)
+		value = value.Add(tr.Amount.Sub(tr.Commission))
+		settle = append(settle, tr)
 	}
-	if len(trx) == 0 {
-		return
+	if !value.IsPositive() {
+		return nil
 	}
-	amount := 0.
-	asset := ""
-	issuer := ""
-	for _, tr := range trx {
-		commission := wallet.CommissionReceiving.Fix + tr.Amount*wallet.CommissionReceiving.Percent/100
-		amount += tr.Amount - commission
-		s.transactionStore.PutProcessedTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(),
-			tr.Hash2, commission)
-		asset = tr.Asset
-		issuer = tr.Issuer
-		if amount > 0 {
-			//ToDo group transactions by assets
-			hash, err := processor.TransferFromReceiving(ctx, TransferRequest{
-				Amount:     amount,
-				Blockchain: bc,
-				Asset:      asset,
-				Issuer:     issuer,
-			}, merch.ID.String(), wallet.ReceivingID)
-			if err != nil {
-				log.Println(fmt.Errorf("can't settle transactions to merchant: %v, err: %v", merch.ID, err))
-				return
-			}
-			callBack, err := s.callBack.GetTransactionFn(tr.MerchantId)
-			if err != nil {
-				log.Println(fmt.Errorf(
-					"can't settle transactions to merchant: %v, due to issue with callback err: %v",
-					merch.ID, err))
-				return
-			}
-			for _, tr := range trx {
-				s.transactionStore.PutSettledTransaction(tr.MerchantId, tr.ExternalId,
-					tr.GUID.String(), hash.TransferHash)
-				if callBack != nil {
-					callBack(tr)
-				}
+	if err := s.transactionStore.ClaimSettledTransactions(settle); err != nil {
+		return fmt.Errorf("can't claim transactions of merchant: %v to settle, err: %w", merch.ID, err)
+	}
+	ctx = WithBroadcastIntent(ctx, func(hash string, timeoutHeight int64) error {
+		return s.transactionStore.PutSettlementBroadcast(settle, hash, timeoutHeight)
+	})
+	_, err := processor.TransferFromReceiving(ctx, TransferRequest{
+		Amount:     value,
+		Blockchain: bc,
+		Asset:      key.asset,
+		Issuer:     key.issuer,
+	}, merch.ID.String(), wallet.ReceivingID)
+	if err != nil {
+		err = fmt.Errorf("can't settle transactions to merchant: %v, asset: %v, issuer: %v, err: %w",
+			merch.ID, key.asset, key.issuer, err)
+		// deposits are released if the transfer hasn't been recorded, a recorded one is checked by them
+		for _, tr := range settle {
+			resetErr := s.transactionStore.ResetSettledTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), "")
+			if resetErr != nil && !errors.Is(resetErr, storage.ErrInvalidTransition) {
+				return fmt.Errorf("%v, can't release transaction: %v, err: %w", err, tr.GUID, resetErr)
 			}
 		}
+		return err
 	}
-
+	return nil
 }
 
-func (s ProcessingService) processDepositSettled(ctx context.Context, bc string, processor CryptoProcessor,
-	merch MerchantData) {
-	trx, err := s.transactionStore.GetMerchantTrxForProcessingInBlockChain(merch.ID.String(), bc,
-		storage.DepositTransaction, storage.SettledTransaction, limitTrxToDepositProcess)
-	if err != nil && errors.Is(storage.ErrNotFound, err) {
-		// no deposit transaction to settle
-		return
-	} else if err != nil {
-		log.Println(fmt.Errorf(
-			"can't get settled transaction for merchant: %v, err: %v, blockchain: %v", merch.ID, err, bc))
-		return
+// processDepositSettled completes the deposit when its settlement is confirmed in the blockchain,
+// the deposit is settled again if its settlement has failed, is expired or hasn't been broadcast
+func (s ProcessingService) processDepositSettled(ctx context.Context, processor CryptoProcessor,
+	tr storage.TransactionStore) (jobResult, error) {
+	if tr.Hash3 == "" && time.Since(tr.UpdatedAt) < jobLease {
+		// the settlement which has claimed the deposit may be still running
+		return jobWait, nil
 	}
-	for _, tr := range trx {
-		if tr.Hash3 != "" {
-			res, err := processor.GetTransactionStatus(ctx, tr.Hash3)
-			if res == SuccessfulTransaction {
-				// TODO: gas should be returned to sending wallet
-				s.transactionStore.PutDoneTransaction(tr.MerchantId, tr.ExternalId,
-					tr.GUID.String(), "")
-				callBack, err := s.callBack.GetTransactionFn(tr.MerchantId)
-				if err != nil {
-					log.Println(fmt.Errorf(
-						"error in process deposit for merchant: %v, due to issue with callback err: %v",
-						merch.ID, err))
-					continue
-				} else if callBack != nil {
-					callBack(tr)
-				}
-			} else if res == FailedTransaction {
-				// reset transaction for settlement
-				s.transactionStore.PutSettledTransaction(tr.MerchantId, tr.ExternalId,
-					tr.GUID.String(), "")
-			}
-			if err != nil {
-				log.Println(
-					fmt.Sprintf("error in process deposit to put processed status for transaction: %v, err: %v",
-						tr.GUID.String(), err))
-			}
-			continue
-		} else {
-			log.Println(fmt.Errorf(
-				"can't get merchant: %v, transactions to settle, err: %v, blockchain: %v", merch.ID, err, bc))
+	res, err := s.broadcastStatus(ctx, processor, tr.Hash3, tr.TimeoutHeight)
+	if err != nil {
+		return jobWait, fmt.Errorf("error in process deposit to get status of: %v, err: %w", tr.Hash3, err)
+	}
+	switch res {
+	case SuccessfulTransaction:
+		// gas left in the user wallet is returned to sending wallet by the processor sweep
+		err = s.transactionStore.PutDoneTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), "")
+		if err != nil {
+			return jobWait, fmt.Errorf("can't put transaction: %v to done status, err: %w", tr.GUID, err)
 		}
+		return jobDone, nil
+	case PendingTransaction:
+		return jobWait, nil
+	}
+	// the deposit returns to processed to be settled again
+	err = s.transactionStore.ResetSettledTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), tr.Hash3)
+	if err != nil {
+		return jobWait, fmt.Errorf("error in process deposit to reset settlement: %w", err)
 	}
+	return jobNext, nil
 }
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	jobTransaction     = "transaction"
+	jobSettleDeposit   = "settle-deposit"
+	limitJobsToProcess = 100
+	limitTrxToEnqueue  = 10000
+	jobLease           = 5 * time.Minute
+	jobMaxBackoff      = time.Hour
+)
+
+// jobResult tells what should be done with a job after its successful run
+type jobResult int
+
+const (
+	// jobDone means the job is finished and deleted
+	jobDone jobResult = iota
+	// jobNext means the step is done and the next step is run as soon as possible
+	jobNext
+	// jobWait means the step waits for the blockchain and is run again after the interval
+	jobWait
+)
+
+// transactionJob runs the step of a transaction according to its status
+type transactionJob struct {
+	MerchantID string `json:"merchant_id"`
+	GUID       string `json:"guid"`
+}
+
+// settleDepositJob settles processed deposits of the merchant in one asset
+type settleDepositJob struct {
+	MerchantID string `json:"merchant_id"`
+	Blockchain string `json:"blockchain"`
+	Asset      string `json:"asset"`
+	Issuer     string `json:"issuer"`
+}
+
+// enqueueTransaction makes a job to process the transaction
+func (s ProcessingService) enqueueTransaction(merchantID, guid string) {
+	payload, err := json.Marshal(transactionJob{MerchantID: merchantID, GUID: guid})
+	if err == nil {
+		_, err = s.jobStore.Enqueue(jobTransaction, guid, payload, time.Now())
+	}
+	if err != nil {
+		log.Println(fmt.Sprintf("can't enqueue job to process transaction: %v, err: %v", guid, err))
+	}
+}
+
+// enqueuePendingTransactions makes jobs for pending transactions which don't have them,
+// like transactions created before the jobs were introduced, existing jobs are kept
+func (s ProcessingService) enqueuePendingTransactions() {
+	pending := map[storage.ActionTx][]storage.StatusTx{
+		storage.DepositTransaction:  storage.PendingStatuses,
+		storage.RefundTransaction:   storage.PendingStatuses,
+		storage.WithdrawTransaction: {storage.ProcessedTransaction, storage.SettledTransaction},
+	}
+	for action, statuses := range pending {
+		trx, err := s.transactionStore.GetTransactionsByAction("", action, statuses, limitTrxToEnqueue)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		} else if err != nil {
+			log.Println(fmt.Sprintf("can't get pending %v transactions to enqueue, err: %v", action, err))
+			continue
+		}
+		for _, tr := range trx {
+			s.enqueueTransaction(tr.MerchantId, tr.GUID.String())
+		}
+	}
+}
+
+// processJobs runs jobs which are due, a failed job is retried with exponential backoff
+func (s ProcessingService) processJobs(ctx context.Context, interval time.Duration) {
+	jobs, err := s.jobStore.Take(limitJobsToProcess, jobLease)
+	if err != nil {
+		log.Println(fmt.Sprintf("processing can't take jobs, err: %v", err))
+		return
+	}
+	for _, job := range jobs {
+		res, err := s.runJob(ctx, job)
+		switch {
+		case err != nil:
+			log.Println(fmt.Sprintf("job: %v, key: %v, attempt: %v failed, err: %v",
+				job.Kind, job.Key, job.Attempts+1, err))
+			err = s.jobStore.Fail(job.ID, err.Error(), time.Now().Add(jobBackoff(interval, job.Attempts)))
+		case res == jobDone:
+			err = s.jobStore.Complete(job.ID)
+		case res == jobNext:
+			err = s.jobStore.Reschedule(job.ID, time.Now())
+		default:
+			err = s.jobStore.Reschedule(job.ID, time.Now().Add(interval))
+		}
+		if err != nil {
+			log.Println(fmt.Sprintf("can't update job: %v, key: %v, err: %v", job.Kind, job.Key, err))
+		}
+	}
+}
+
+func (s ProcessingService) runJob(ctx context.Context, job storage.Job) (jobResult, error) {
+	switch job.Kind {
+	case jobTransaction:
+		payload := transactionJob{}
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return jobWait, fmt.Errorf("can't unmarshal job payload: %w", err)
+		}
+		return s.processTransaction(ctx, payload)
+	case jobSettleDeposit:
+		payload := settleDepositJob{}
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return jobWait, fmt.Errorf("can't unmarshal job payload: %w", err)
+		}
+		return jobDone, s.settleDeposits(ctx, payload)
+	}
+	return jobWait, fmt.Errorf("unknown job: %v", job.Kind)
+}
+
+// processTransaction runs the next step of the transaction according to its action and status
+func (s ProcessingService) processTransaction(ctx context.Context, job transactionJob) (jobResult, error) {
+	tr, err := s.transactionStore.GetTransactionByGuid(job.MerchantID, job.GUID)
+	if err != nil {
+		return jobWait, fmt.Errorf("can't get transaction: %v, err: %w", job.GUID, err)
+	}
+	// a transaction on hold gets a job again when it is released by an admin
+	if tr.Status == storage.DoneTransaction || tr.Status == storage.RejectedTransaction ||
+		tr.Status == storage.OnHoldTransaction {
+		return jobDone, nil
+	}
+	bc := strings.ToLower(tr.Blockchain)
+	processor := s.processors[bc]
+	if processor == nil {
+		return jobWait, fmt.Errorf("processing can't find processor for blockchain: %v", bc)
+	}
+
+	switch tr.Action {
+	case storage.DepositTransaction:
+		switch tr.Status {
+		case storage.InitTransaction:
+			return s.processDepositInitiated(ctx, processor, *tr)
+		case storage.ProcessedTransaction:
+			if isReceivedDeposit(*tr) {
+				return s.processReceivedDeposit(*tr)
+			}
+			return s.enqueueDepositSettlement(*tr)
+		case storage.SettledTransaction:
+			return s.processDepositSettled(ctx, processor, *tr)
+		}
+	case storage.WithdrawTransaction:
+		switch tr.Status {
+		case storage.InitTransaction:
+			// withdraw is waiting for the merchant, the job is made again when it is confirmed
+			return jobDone, nil
+		case storage.ProcessedTransaction:
+			return s.processWithdrawProcessed(ctx, processor, *tr)
+		case storage.SettledTransaction:
+			return s.processWithdrawSettled(ctx, processor, *tr)
+		}
+	case storage.RefundTransaction:
+		switch tr.Status {
+		case storage.InitTransaction:
+			return s.processRefundInitiated(ctx, processor, *tr)
+		case storage.ProcessedTransaction:
+			return s.processRefundProcessed(*tr)
+		case storage.SettledTransaction:
+			return s.processRefundSettled(*tr)
+		}
+	case storage.SweepTransaction:
+		switch tr.Status {
+		case storage.ProcessedTransaction:
+			return s.processSweepProcessed(ctx, processor, *tr)
+		case storage.SettledTransaction:
+			return s.processSweepSettled(*tr)
+		}
+	}
+	return jobDone, nil
+}
+
+// getMerchantWallet returns merchant and its wallet in the blockchain
+func (s ProcessingService) getMerchantWallet(merchantID, bc string) (MerchantData, Wallets, error) {
+	merch, err := s.merchants.GetMerchantData(merchantID)
+	if err != nil {
+		return merch, Wallets{}, fmt.Errorf("can't get merchant: %v, err: %w", merchantID, err)
+	}
+	for name, wallet := range merch.Wallets {
+		if strings.EqualFold(name, bc) {
+			return merch, wallet, nil
+		}
+	}
+	return merch, Wallets{}, fmt.Errorf("cannot find blockchain: '%v' for merchant: %v", bc, merchantID)
+}
+
+// jobBackoff returns delay before the next attempt of the job which failed the given number of times
+func jobBackoff(interval time.Duration, attempts int) time.Duration {
+	backoff := interval
+	for i := 0; i < attempts && backoff < jobMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > jobMaxBackoff {
+		return jobMaxBackoff
+	}
+	return backoff
+}
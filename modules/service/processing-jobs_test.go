@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/storage"
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// fakeProcessor reports statuses of blockchain transactions and records broadcast
+// of the transfer from the sending wallet with the hash
+type fakeProcessor struct {
+	CryptoProcessor
+	statuses map[string]CryptoTransactionStatus
+	hash     string
+	sent     int
+}
+
+// fakeTimeoutHeight is the timeout height of transactions broadcast by the fake processor
+const fakeTimeoutHeight = 150
+
+func (f *fakeProcessor) GetTransactionStatus(_ context.Context, hash string) (CryptoTransactionStatus, error) {
+	if res, ok := f.statuses[hash]; ok {
+		return res, nil
+	}
+	return NoTransaction, nil
+}
+
+func (f *fakeProcessor) GetBroadcastStatus(ctx context.Context, hash string,
+	_ int64) (CryptoTransactionStatus, error) {
+	return f.GetTransactionStatus(ctx, hash)
+}
+
+func (f *fakeProcessor) TransferFromSending(ctx context.Context, _ TransferRequest,
+	_, _ string) (*TransferResponse, error) {
+	return f.broadcast(ctx)
+}
+
+func (f *fakeProcessor) TransferFromReceiving(ctx context.Context, _ TransferRequest,
+	_, _ string) (*TransferResponse, error) {
+	return f.broadcast(ctx)
+}
+
+func (f *fakeProcessor) TransferToReceiving(ctx context.Context, _ TransferRequest,
+	_, _ string) (*TransferResponse, error) {
+	return f.broadcast(ctx)
+}
+
+func (f *fakeProcessor) broadcast(ctx context.Context) (*TransferResponse, error) {
+	if err := RecordBroadcastIntent(ctx, f.hash, fakeTimeoutHeight); err != nil {
+		return nil, err
+	}
+	f.sent++
+	return &TransferResponse{TransferHash: f.hash}, nil
+}
+
+func newServiceMock(t *testing.T) (ProcessingService, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT 1 FROM "transactions"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT 1 FROM "processing_jobs"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	transactionStore, err := storage.NewTransactionStorage("transactions", db, nil, nil)
+	require.NoError(t, err)
+	jobStore, err := storage.NewJobStorage("processing_jobs", db)
+	require.NoError(t, err)
+	return ProcessingService{transactionStore: transactionStore, jobStore: jobStore}, mock
+}
+
+func settledWithdraw(hash string) storage.TransactionStore {
+	return storage.TransactionStore{
+		GUID:       uuid.New(),
+		MerchantId: "merchant",
+		ExternalId: "user",
+		Blockchain: "coreum",
+		Action:     storage.WithdrawTransaction,
+		ExtWallet:  "core1user",
+		Status:     storage.SettledTransaction,
+		Hash5:      hash,
+	}
+}
+
+var (
+	doneQuery      = regexp.QuoteMeta("set status = $1, updated_at = $2, hash5 = $3")
+	broadcastQuery = regexp.QuoteMeta("hash5 = $2, timeout_height = $3 where")
+)
+
+func TestWithdrawSettledIsBroadcastOnce(t *testing.T) {
+	s, mock := newServiceMock(t)
+	tr := settledWithdraw("")
+	processor := &fakeProcessor{hash: "SENT"}
+	mock.ExpectExec(broadcastQuery).
+		WithArgs(sqlmock.AnyArg(), "SENT", int64(fakeTimeoutHeight), tr.GUID.String(), "merchant", "user",
+			storage.SettledTransaction, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(doneQuery).
+		WithArgs(storage.DoneTransaction, sqlmock.AnyArg(), "SENT", tr.GUID.String(), "merchant", "user",
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	res, err := s.processWithdrawSettled(context.Background(), processor, tr)
+	require.NoError(t, err)
+	require.Equal(t, jobDone, res)
+	require.Equal(t, 1, processor.sent)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawSettledRetryChecksBlockchain(t *testing.T) {
+	tests := []struct {
+		name   string
+		status CryptoTransactionStatus
+		result jobResult
+	}{
+		{name: "confirmed", status: SuccessfulTransaction, result: jobDone},
+		{name: "pending", status: PendingTransaction, result: jobWait},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newServiceMock(t)
+			tr := settledWithdraw("SENT")
+			processor := &fakeProcessor{statuses: map[string]CryptoTransactionStatus{"SENT": tt.status}}
+			if tt.status == SuccessfulTransaction {
+				mock.ExpectExec(doneQuery).
+					WithArgs(storage.DoneTransaction, sqlmock.AnyArg(), "SENT", tr.GUID.String(), "merchant", "user",
+						sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			res, err := s.processWithdrawSettled(context.Background(), processor, tr)
+			require.NoError(t, err)
+			require.Equal(t, tt.result, res)
+			require.Zero(t, processor.sent)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWithdrawSettledResendsFailedBroadcast(t *testing.T) {
+	s, mock := newServiceMock(t)
+	tr := settledWithdraw("FAILED")
+	processor := &fakeProcessor{
+		statuses: map[string]CryptoTransactionStatus{"FAILED": FailedTransaction},
+		hash:     "RESENT",
+	}
+	mock.ExpectExec(broadcastQuery).
+		WithArgs(sqlmock.AnyArg(), "RESENT", int64(fakeTimeoutHeight), tr.GUID.String(), "merchant", "user",
+			storage.SettledTransaction, "FAILED").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(doneQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	res, err := s.processWithdrawSettled(context.Background(), processor, tr)
+	require.NoError(t, err)
+	require.Equal(t, jobDone, res)
+	require.Equal(t, 1, processor.sent)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawSettledIsNotBroadcastWithoutIntent(t *testing.T) {
+	s, mock := newServiceMock(t)
+	tr := settledWithdraw("")
+	processor := &fakeProcessor{hash: "SENT"}
+	// another run has recorded its transaction since the withdraw was read
+	mock.ExpectExec(broadcastQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	res, err := s.processWithdrawSettled(context.Background(), processor, tr)
+	require.ErrorIs(t, err, storage.ErrInvalidTransition)
+	require.Equal(t, jobWait, res)
+	require.Zero(t, processor.sent)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositInitiatedIsRecordedBeforeBroadcast(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "not sent", hash: ""},
+		{name: "expired", hash: "EXPIRED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newServiceMock(t)
+			tr := storage.TransactionStore{GUID: uuid.New(), MerchantId: "merchant", ExternalId: "user",
+				Action: storage.DepositTransaction, Status: storage.InitTransaction, Hash2: tt.hash}
+			processor := &fakeProcessor{hash: "SENT"}
+			mock.ExpectExec(regexp.QuoteMeta("hash2 = $2, timeout_height = $3 where")).
+				WithArgs(sqlmock.AnyArg(), "SENT", int64(fakeTimeoutHeight), tr.GUID.String(), "merchant", "user",
+					storage.InitTransaction, tt.hash).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			res, err := s.processDepositInitiated(context.Background(), processor, tr)
+			require.NoError(t, err)
+			require.Equal(t, jobWait, res)
+			require.Equal(t, 1, processor.sent)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDepositInitiatedWaitsForPendingTransfer(t *testing.T) {
+	s, mock := newServiceMock(t)
+	tr := storage.TransactionStore{GUID: uuid.New(), MerchantId: "merchant", ExternalId: "user",
+		Action: storage.DepositTransaction, Status: storage.InitTransaction, Hash2: "SENT"}
+	processor := &fakeProcessor{statuses: map[string]CryptoTransactionStatus{"SENT": PendingTransaction}}
+
+	res, err := s.processDepositInitiated(context.Background(), processor, tr)
+	require.NoError(t, err)
+	require.Equal(t, jobWait, res)
+	require.Zero(t, processor.sent)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func processedDeposits() []storage.TransactionStore {
+	var trx []storage.TransactionStore
+	for _, user := range []string{"alice", "bob"} {
+		trx = append(trx, storage.TransactionStore{GUID: uuid.New(), MerchantId: "merchant", ExternalId: user,
+			Blockchain: "coreum", Action: storage.DepositTransaction, Status: storage.ProcessedTransaction,
+			Asset: "token", Issuer: "core1issuer", Amount: amount.NewFromInt64(100)})
+	}
+	return trx
+}
+
+var (
+	claimQuery  = regexp.QuoteMeta("hash3 = '', commission = $3")
+	settleQuery = regexp.QuoteMeta("hash3 = $2, timeout_height = $3 where")
+)
+
+func TestDepositsAreClaimedBeforeSettlement(t *testing.T) {
+	s, mock := newServiceMock(t)
+	trx := processedDeposits()
+	wallet := Wallets{CommissionReceiving: Commission{Fix: amount.NewFromInt64(2), Percent: amount.Zero()}}
+	processor := &fakeProcessor{hash: "SETTLE"}
+	mock.ExpectBegin()
+	for _, tr := range trx {
+		mock.ExpectQuery(claimQuery).
+			WithArgs(storage.SettledTransaction, sqlmock.AnyArg(), amount.NewFromInt64(2), tr.GUID.String(),
+				"merchant", tr.ExternalId, storage.DepositTransaction, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "guid", "created_at", "updated_at", "deleted_at",
+				"merchant_id", "external_id", "blockchain", "action", "ext_wallet", "status", "asset", "issuer",
+				"amount", "commission", "hash1", "hash2", "hash3", "hash4", "hash5", "callback",
+				"timeout_height"}).
+				AddRow(1, tr.GUID, time.Now(), time.Now(), nil, "merchant", tr.ExternalId, "coreum", tr.Action, "",
+					storage.SettledTransaction, "token", "core1issuer", "100", "2", "", "", "", "", "", "", 0))
+	}
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	for _, tr := range trx {
+		mock.ExpectExec(settleQuery).
+			WithArgs(sqlmock.AnyArg(), "SETTLE", int64(fakeTimeoutHeight), tr.GUID.String(), "merchant",
+				tr.ExternalId, storage.SettledTransaction, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	err := s.settleDepositAsset(context.Background(), "coreum", processor, MerchantData{}, wallet,
+		assetKey{asset: "token", issuer: "core1issuer"}, trx)
+	require.NoError(t, err)
+	require.Equal(t, 1, processor.sent)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositsClaimedByAnotherSettlementAreNotTransferred(t *testing.T) {
+	s, mock := newServiceMock(t)
+	trx := processedDeposits()
+	wallet := Wallets{CommissionReceiving: Commission{Fix: amount.Zero(), Percent: amount.Zero()}}
+	processor := &fakeProcessor{hash: "SETTLE"}
+	mock.ExpectBegin()
+	mock.ExpectQuery(claimQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	err := s.settleDepositAsset(context.Background(), "coreum", processor, MerchantData{}, wallet,
+		assetKey{asset: "token", issuer: "core1issuer"}, trx)
+	require.ErrorIs(t, err, storage.ErrInvalidTransition)
+	require.Zero(t, processor.sent)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessJobsRetriesWithBackoff(t *testing.T) {
+	s, mock := newServiceMock(t)
+	interval := time.Minute
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE SKIP LOCKED")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), limitJobsToProcess).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "key", "payload", "attempts", "next_run_at",
+			"last_error", "created_at", "updated_at"}).
+			AddRow(1, "unknown", "key", []byte(`{}`), 2, now.Add(jobLease), "", now, now))
+	mock.ExpectExec(regexp.QuoteMeta("attempts = attempts + 1")).
+		WithArgs(runAfter{delay: 4 * interval}, sqlmock.AnyArg(), "unknown job: unknown", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s.processJobs(context.Background(), interval)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Minute},
+		{attempts: 1, want: 2 * time.Minute},
+		{attempts: 3, want: 8 * time.Minute},
+		{attempts: 10, want: jobMaxBackoff},
+		{attempts: 100, want: jobMaxBackoff},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, jobBackoff(time.Minute, tt.attempts), "attempts: %v", tt.attempts)
+	}
+}
+
+// runAfter matches time of the next run which is the delay after now
+type runAfter struct {
+	delay time.Duration
+}
+
+func (a runAfter) Match(v driver.Value) bool {
+	at, ok := v.(time.Time)
+	return ok && time.Until(at) <= a.delay && time.Until(at) > a.delay-time.Minute
+}
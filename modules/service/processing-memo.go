@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/storage"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// getMemoWallet returns wallets of the merchant in the blockchain if its users deposit with memo
+func (s ProcessingService) getMemoWallet(merchantID, blockchain string) (Wallets, bool) {
+	if s.memoStore == nil {
+		return Wallets{}, false
+	}
+	merch, err := s.merchants.GetMerchantData(merchantID)
+	if err != nil {
+		return Wallets{}, false
+	}
+	wallet, ok := merch.Wallets[blockchain]
+	return wallet, ok && wallet.DepositMemo
+}
+
+// depositWithMemo returns the receiving wallet of the merchant and memo of the user to deposit with
+func (s ProcessingService) depositWithMemo(ctx context.Context, processor CryptoProcessor,
+	deposit CredentialDeposit, merchantID, externalId string, wallet Wallets) (*DepositResponse, error) {
+	response, err := processor.Deposit(ctx, deposit, merchantID, wallet.ReceivingID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get receiving wallet for deposit: %s", err)
+	}
+	response.Memo, err = s.memoStore.GetOrCreate(merchantID, externalId, deposit.Blockchain)
+	if err != nil {
+		return nil, fmt.Errorf("could not get memo for deposit: %s", err)
+	}
+	return response, nil
+}
+
+// makeMemoDeposit creates a deposit of the user the memo belongs to,
+// the deposit is quarantined if the memo is unknown
+func (s ProcessingService) makeMemoDeposit(blockChain, merchantID, externalWallet, hash, memo, asset, issuer string,
+	value amount.Amount) error {
+	memo = strings.TrimSpace(memo)
+	if memo != "" && s.memoStore != nil {
+		user, err := s.memoStore.Get(blockChain, memo)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("error in deposit callback to get memo of transaction: %v, err: %w", hash, err)
+		}
+		if err == nil && user.MerchantID == merchantID {
+			known, err := s.isKnownDeposit(merchantID, user.ExternalID, blockChain, hash, externalWallet,
+				storage.DepositTransaction, asset, issuer)
+			if err != nil {
+				return fmt.Errorf("error in deposit callback to check transaction: %v, err: %w", hash, err)
+			}
+			if known {
+				return nil
+			}
+			err = s.createMemoDeposit(merchantID, user.ExternalID, blockChain, externalWallet, hash,
+				asset, issuer, value)
+			if err != nil {
+				return fmt.Errorf("error in storage to create transaction: %w", err)
+			}
+			return nil
+		}
+	}
+	if s.quarantineStore == nil {
+		log.Println(fmt.Sprintf("deposit: %v with unknown memo: %q to merchant: %v is not quarantined",
+			hash, memo, merchantID))
+		return nil
+	}
+	id, created, err := s.quarantineStore.Put(merchantID, blockChain, hash, memo, externalWallet, asset, issuer,
+		value)
+	if err != nil {
+		return fmt.Errorf("error in deposit callback to quarantine transaction: %v, err: %w", hash, err)
+	}
+	if !created {
+		return nil
+	}
+	log.Println(fmt.Sprintf("deposit: %v with unknown memo: %q to merchant: %v is quarantined",
+		hash, memo, merchantID))
+	s.putWebhookEvent(merchantID, storage.EventDepositQuarantined, storage.QuarantineEventData{
+		ID:         id,
+		MerchantID: merchantID,
+		Blockchain: blockChain,
+		Hash:       hash,
+		Memo:       memo,
+		Sender:     externalWallet,
+		Asset:      asset,
+		Issuer:     issuer,
+		Amount:     value,
+	})
+	return nil
+}
+
+// createMemoDeposit creates a deposit which is already received to the receiving wallet of the merchant,
+// so it is processed by its own hash unless it breaches limits of the merchant and is put on hold
+func (s ProcessingService) createMemoDeposit(merchantID, externalId, blockChain, externalWallet, hash, asset,
+	issuer string, value amount.Amount) error {
+	breach := s.checkDepositLimits(merchantID, externalId, blockChain, asset, issuer, value)
+	guid, err := s.transactionStore.CreateProcessedTransaction(merchantID, externalId, blockChain,
+		storage.DepositTransaction, externalWallet, hash, asset, issuer, value, amount.Zero())
+	if err != nil {
+		return err
+	}
+	s.processDeposit(merchantID, guid, storage.ProcessedTransaction, breach)
+	return nil
+}
+
+// isReceivedDeposit checks if the deposit is received to the receiving wallet of the merchant by its own
+// transaction, such deposit keeps the original hash as the processing one and is never transferred by the processing
+func isReceivedDeposit(tr storage.TransactionStore) bool {
+	return tr.Action == storage.DepositTransaction && tr.Hash1 != "" && tr.Hash2 == tr.Hash1
+}
+
+// processReceivedDeposit settles the deposit received to the receiving wallet of the merchant by its own
+// transaction, funds are already in the merchant wallet, so only the commission is accounted for it
+func (s ProcessingService) processReceivedDeposit(tr storage.TransactionStore) (jobResult, error) {
+	_, wallet, err := s.getMerchantWallet(tr.MerchantId, tr.Blockchain)
+	if err != nil {
+		return jobWait, err
+	}
+	commission := wallet.CommissionReceiving.Calculate(tr.Amount)
+	err = s.transactionStore.UpdateProcessedTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(),
+		tr.Hash2, tr.Hash2, commission)
+	if err != nil {
+		return jobWait, fmt.Errorf("can't put commission for transaction: %v, err: %w", tr.GUID, err)
+	}
+	err = s.transactionStore.PutSettledTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), tr.Hash1)
+	if err != nil {
+		return jobWait, fmt.Errorf("can't put transaction: %v to settled status, err: %w", tr.GUID, err)
+	}
+	return jobNext, nil
+}
+
+// UpdateMerchantDepositMemo switches deposits of the merchant in the blockchain to the receiving wallet with memo
+func (s ProcessingService) UpdateMerchantDepositMemo(merchantID, blockchain string,
+	data NewMerchantDepositMemo) (Wallets, error) {
+	return s.merchants.UpdateMerchantDepositMemo(merchantID, blockchain, data)
+}
+
+// GetQuarantinedDeposits returns deposits of the merchant which wait for manual resolution
+func (s ProcessingService) GetQuarantinedDeposits(merchantID, blockchain string) ([]storage.QuarantinedDeposit, error) {
+	if s.quarantineStore == nil {
+		return nil, errors.New("quarantine of deposits is not available")
+	}
+	return s.quarantineStore.GetByMerchant(merchantID, blockchain, storage.QuarantineHeld)
+}
+
+// ResolveQuarantinedDeposit credits the quarantined deposit to the user of the resolution,
+// without the user the deposit is dismissed and kept by the merchant
+func (s ProcessingService) ResolveQuarantinedDeposit(merchantID string, id int64,
+	resolution QuarantineResolution) (*storage.QuarantinedDeposit, error) {
+	if s.quarantineStore == nil {
+		return nil, errors.New("quarantine of deposits is not available")
+	}
+	deposit, err := s.quarantineStore.Get(merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if resolution.ExternalID == "" {
+		if err = s.quarantineStore.Resolve(merchantID, id, storage.QuarantineDismissed, ""); err != nil {
+			return nil, err
+		}
+		deposit.Status = storage.QuarantineDismissed
+		return deposit, nil
+	}
+	if err = s.quarantineStore.Resolve(merchantID, id, storage.QuarantineAssigned, resolution.ExternalID); err != nil {
+		return nil, err
+	}
+	err = s.createMemoDeposit(merchantID, resolution.ExternalID, deposit.Blockchain, deposit.Sender, deposit.Hash,
+		deposit.Asset, deposit.Issuer, deposit.Amount)
+	if err != nil {
+		if holdErr := s.quarantineStore.Hold(merchantID, id); holdErr != nil {
+			log.Println(fmt.Sprintf("can't return deposit: %v to quarantine, err: %v", id, holdErr))
+		}
+		return nil, fmt.Errorf("could not create deposit for user: %v, err: %w", resolution.ExternalID, err)
+	}
+	deposit.Status = storage.QuarantineAssigned
+	deposit.ExternalID = resolution.ExternalID
+	return deposit, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/storage"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// fakeMerchantStore returns the merchant data stored by its id
+type fakeMerchantStore struct {
+	storage.Storage
+	merchants map[string][]byte
+}
+
+func (f fakeMerchantStore) Get(key string) (int64, []byte, error) {
+	data, ok := f.merchants[key]
+	if !ok {
+		return 0, nil, storage.ErrNotFound
+	}
+	return 1, data, nil
+}
+
+func TestReceivedDepositIsSettledWithoutTransfer(t *testing.T) {
+	s, mock := newServiceMock(t)
+	s.merchants = &Merchants{store: fakeMerchantStore{merchants: map[string][]byte{
+		"merchant": []byte(`{"wallets": {"coreum": {"commission_receiving": {"fix": "2", "percent": "10"}}}}`),
+	}}}
+	s.processors = map[string]CryptoProcessor{"coreum": &fakeProcessor{}}
+	tr := storage.TransactionStore{
+		GUID:       uuid.New(),
+		MerchantId: "merchant",
+		ExternalId: "user",
+		Blockchain: "coreum",
+		Action:     storage.DepositTransaction,
+		Status:     storage.ProcessedTransaction,
+		Amount:     amount.NewFromInt64(100),
+		Hash1:      "DEPOSIT",
+		Hash2:      "DEPOSIT",
+	}
+	require.True(t, isReceivedDeposit(tr))
+	mock.ExpectQuery(regexp.QuoteMeta("guid = $1 AND merchant_id = $2")).
+		WithArgs(tr.GUID.String(), "merchant").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "guid", "created_at", "updated_at", "deleted_at",
+			"merchant_id", "external_id", "blockchain", "action", "ext_wallet", "status", "asset", "issuer",
+			"amount", "commission", "hash1", "hash2", "hash3", "hash4", "hash5", "callback",
+			"timeout_height"}).
+			AddRow(1, tr.GUID, time.Now(), time.Now(), nil, "merchant", "user", "coreum", tr.Action, "core1sender", tr.Status,
+				"token", "core1issuer", "100", "0", "DEPOSIT", "DEPOSIT", "", "", "", "", 0))
+	mock.ExpectExec(regexp.QuoteMeta("hash2 = $2, commission = $3")).
+		WithArgs(sqlmock.AnyArg(), "DEPOSIT", amount.NewFromInt64(12), tr.GUID.String(), "merchant", "user",
+			storage.ProcessedTransaction, "DEPOSIT").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("set status = $1, updated_at = $2, hash3 = $3")).
+		WithArgs(storage.SettledTransaction, sqlmock.AnyArg(), "DEPOSIT", tr.GUID.String(), "merchant", "user",
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// the settlement job isn't made and nothing is transferred by the processor
+	res, err := s.processTransaction(context.Background(), transactionJob{MerchantID: "merchant",
+		GUID: tr.GUID.String()})
+	require.NoError(t, err)
+	require.Equal(t, jobNext, res)
+	require.Zero(t, s.processors["coreum"].(*fakeProcessor).sent)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositCallbackFailsIfDepositIsNotRecorded(t *testing.T) {
+	s, mock := newServiceMock(t)
+	s.merchants = &Merchants{store: fakeMerchantStore{merchants: map[string][]byte{
+		"merchant": []byte(`{"wallets": {"coreum": {}}}`),
+	}}}
+	s.processors = map[string]CryptoProcessor{"coreum": &fakeProcessor{}}
+	mock.ExpectQuery(regexp.QuoteMeta("hash1 = $4")).WithArgs("merchant", "user", "coreum", "DEPOSIT").
+		WillReturnError(errors.New("connection refused"))
+
+	// the scanner doesn't move past the block of the deposit which isn't recorded
+	err := s.makeDepositCallback()("coreum", "merchant", "user", "core1sender", "DEPOSIT", "", "token",
+		"core1issuer", amount.NewFromInt64(100))
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
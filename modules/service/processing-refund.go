@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/storage"
+	"fmt"
+)
+
+const (
+	limitTrxToRefundProcess = 1000
+)
+
+// processRefundInitiated sends funds of the initiated refund back and checks status of the sent refund,
+// hash of the refund is recorded before it is broadcast
+func (s ProcessingService) processRefundInitiated(ctx context.Context, processor CryptoProcessor,
+	tr storage.TransactionStore) (jobResult, error) {
+	status, err := s.broadcastStatus(ctx, processor, tr.Hash2, tr.TimeoutHeight)
+	if err != nil {
+		return jobWait, fmt.Errorf("error in process refund to get status of: %v, err: %w",
+			tr.GUID.String(), err)
+	}
+	switch status {
+	case SuccessfulTransaction:
+		err = s.transactionStore.PutProcessedTransaction(tr.MerchantId, tr.ExternalId,
+			tr.GUID.String(), tr.Hash2, tr.Commission)
+		if err != nil {
+			return jobWait, fmt.Errorf("error in process refund to put processed status for: %v, err: %w",
+				tr.GUID.String(), err)
+		}
+		return jobNext, nil
+	case PendingTransaction:
+		return jobWait, nil
+	}
+
+	// failed or expired refund doesn't move funds, it is sent again
+	res, err := processor.Refund(s.withBroadcastIntent(ctx, tr, storage.InitTransaction, tr.Hash2),
+		TransferRequest{
+			Amount:     tr.Amount,
+			Blockchain: tr.Blockchain,
+			Asset:      tr.Asset,
+			Issuer:     tr.Issuer,
+			Address:    tr.ExtWallet,
+		}, tr.MerchantId, tr.ExternalId)
+	if err != nil {
+		return jobWait, fmt.Errorf("error in process refund: %v, err: %w", tr.GUID.String(), err)
+	}
+	// the hash is already recorded, the address and the network fee of the refund are added to it
+	err = s.transactionStore.PutRefundTransaction(tr.MerchantId, tr.ExternalId,
+		tr.GUID.String(), res.Address, res.TransferHash, res.TransferHash, res.Commission)
+	if err != nil {
+		return jobWait, fmt.Errorf("error in process refund to put refund hash for: %v, err: %w",
+			tr.GUID.String(), err)
+	}
+	return jobWait, nil
+}
+
+// processRefundProcessed settles the refund confirmed in the blockchain
+func (s ProcessingService) processRefundProcessed(tr storage.TransactionStore) (jobResult, error) {
+	err := s.transactionStore.PutSettledTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), tr.Hash2)
+	if err != nil {
+		return jobWait, fmt.Errorf("can't put refund: %v to settled status, err: %w", tr.GUID, err)
+	}
+	return jobNext, nil
+}
+
+// processRefundSettled completes the refund, merchant is informed about it by webhook
+func (s ProcessingService) processRefundSettled(tr storage.TransactionStore) (jobResult, error) {
+	err := s.transactionStore.PutDoneTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), "")
+	if err != nil {
+		return jobWait, fmt.Errorf("can't put refund: %v to done status, err: %w", tr.GUID, err)
+	}
+	return jobDone, nil
+}
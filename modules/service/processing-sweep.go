@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/storage"
+	"fmt"
+)
+
+// processSweepProcessed settles the gas sweep confirmed in the blockchain, the failed sweep is rejected
+// as the gas is left in the user wallet and is swept again
+func (s ProcessingService) processSweepProcessed(ctx context.Context, processor CryptoProcessor,
+	tr storage.TransactionStore) (jobResult, error) {
+	res, err := processor.GetTransactionStatus(ctx, tr.Hash2)
+	if err != nil {
+		return jobWait, fmt.Errorf("error in process sweep to get status of: %v, err: %w", tr.Hash2, err)
+	}
+	switch res {
+	case SuccessfulTransaction:
+		err = s.transactionStore.PutSettledTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), tr.Hash2)
+		if err != nil {
+			return jobWait, fmt.Errorf("can't put sweep: %v to settled status, err: %w", tr.GUID, err)
+		}
+		return jobNext, nil
+	case FailedTransaction:
+		err = s.transactionStore.RejectTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String())
+		if err != nil {
+			return jobWait, fmt.Errorf("can't reject failed sweep: %v, err: %w", tr.GUID, err)
+		}
+		return jobDone, nil
+	}
+	return jobWait, nil
+}
+
+// processSweepSettled completes the confirmed gas sweep
+func (s ProcessingService) processSweepSettled(tr storage.TransactionStore) (jobResult, error) {
+	err := s.transactionStore.PutDoneTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), "")
+	if err != nil {
+		return jobWait, fmt.Errorf("can't put sweep: %v to done status, err: %w", tr.GUID, err)
+	}
+	return jobDone, nil
+}
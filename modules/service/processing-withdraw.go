@@ -3,111 +3,110 @@ package service
 import (
 	"context"
 	"coreum_processor/modules/storage"
-	"errors"
 	"fmt"
-	"log"
 )
 
-const (
-	limitTrxToWithdrawProcess = 1000
-)
-
-func (s ProcessingService) processWithdraw(ctx context.Context, bc string, processor CryptoProcessor,
-	merch MerchantData, wallet Wallets) {
-
-	// Process processed transactions
-	s.processWithdrawProcessed(ctx, bc, processor, merch, wallet)
-
-	// Process settled transaction
-	s.processWithdrawSettled(ctx, bc, processor, merch)
+// processWithdrawProcessed sends the withdraw confirmed by the merchant from the merchant wallet to the processing
+func (s ProcessingService) processWithdrawProcessed(ctx context.Context, processor CryptoProcessor,
+	tr storage.TransactionStore) (jobResult, error) {
+	res, err := s.broadcastStatus(ctx, processor, tr.Hash3, tr.TimeoutHeight)
+	if err != nil {
+		return jobWait, err
+	}
+	switch res {
+	case SuccessfulTransaction:
+		err = s.transactionStore.PutSettledTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), tr.Hash3)
+		if err != nil {
+			return jobWait, fmt.Errorf("can't put transaction: %v to settled status, err: %w", tr.GUID, err)
+		}
+		return jobNext, nil
+	case PendingTransaction:
+		return jobWait, nil
+	}
 
+	merch, wallet, err := s.getMerchantWallet(tr.MerchantId, tr.Blockchain)
+	if err != nil {
+		return jobWait, err
+	}
+	hash, err := processor.Withdraw(s.withBroadcastIntent(ctx, tr, storage.ProcessedTransaction, tr.Hash3),
+		CredentialWithdraw{
+			Amount:        tr.Amount,
+			Blockchain:    tr.Blockchain,
+			WalletAddress: tr.ExtWallet,
+			Asset:         tr.Asset,
+			Issuer:        tr.Issuer,
+			Memo:          "",
+		}, merch.ID.String(), tr.ExternalId, tr.GUID.String(), wallet)
+	if err != nil {
+		return jobWait, fmt.Errorf("can't process transactions: %v to settle, err: %w", tr.GUID, err)
+	}
+	err = s.transactionStore.PutSettledTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(),
+		hash.TransactionHash)
+	if err != nil {
+		return jobWait, fmt.Errorf("can't put transaction: %v to settled status, err: %w", tr.GUID, err)
+	}
+	return jobNext, nil
 }
 
-func (s ProcessingService) processWithdrawProcessed(ctx context.Context, bc string, processor CryptoProcessor,
-	merch MerchantData, wallet Wallets) {
-	trx, err := s.transactionStore.GetMerchantTrxForProcessingInBlockChain(merch.ID.String(), bc,
-		storage.WithdrawTransaction, storage.ProcessedTransaction, 1000)
-	if err != nil && !errors.Is(storage.ErrNotFound, err) {
-		log.Println(fmt.Errorf("can't get merchant transactions to settle, err: %v", err))
-	} else if err == nil {
-
-		for _, tr := range trx {
+// processWithdrawSettled sends the settled withdraw from the sending wallet to the user
+func (s ProcessingService) processWithdrawSettled(ctx context.Context, processor CryptoProcessor,
+	tr storage.TransactionStore) (jobResult, error) {
+	res, err := s.broadcastStatus(ctx, processor, tr.Hash5, tr.TimeoutHeight)
+	if err != nil {
+		return jobWait, err
+	}
+	switch res {
+	case SuccessfulTransaction:
+		err = s.transactionStore.PutDoneTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), tr.Hash5)
+		if err != nil {
+			return jobWait, fmt.Errorf("can't put transaction to done status, err: %w", err)
+		}
+		return jobDone, nil
+	case PendingTransaction:
+		return jobWait, nil
+	}
 
-			commission := wallet.CommissionSending.Fix + tr.Amount*wallet.CommissionSending.Percent/100
+	hash, err := processor.TransferFromSending(s.withBroadcastIntent(ctx, tr, storage.SettledTransaction, tr.Hash5),
+		TransferRequest{
+			Amount:     tr.Amount,
+			Blockchain: tr.Blockchain,
+			Asset:      tr.Asset,
+			Issuer:     tr.Issuer,
+		}, tr.MerchantId, tr.ExtWallet)
+	if err != nil {
+		return jobWait, fmt.Errorf("can't process transactions: %v to settle, err: %w", tr.GUID, err)
+	}
 
-			s.transactionStore.PutProcessedTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(),
-				tr.Hash1, commission)
-			hash, err := processor.Withdraw(ctx, CredentialWithdraw{
-				Amount:        tr.Amount,
-				Blockchain:    tr.Blockchain,
-				WalletAddress: tr.ExtWallet,
-				Asset:         tr.Asset,
-				Issuer:        tr.Issuer,
-				Memo:          "",
-			}, merch.ID.String(), tr.ExternalId, tr.GUID.String(), wallet)
-			if err != nil {
-				log.Println(fmt.Errorf("can't process transactions: %v to settle, err: %v", tr.GUID, err))
-				continue
-			}
-			s.transactionStore.PutSettledTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(),
-				hash.TransactionHash)
-			callBackTrx, err := s.callBack.GetTransactionFn(tr.MerchantId)
-			if err != nil {
-				log.Println(fmt.Errorf(
-					"error in process withdraw processing for merchant: %v, due to issue with callback err: %v",
-					merch.ID, err))
-				continue
-			} else if callBackTrx != nil {
-				err = callBackTrx(tr)
-				if err != nil {
-					log.Println(fmt.Errorf(
-						"error in process withdraw processing for merchant: %v, callback err: %v",
-						merch.ID, err))
-				}
-			}
-		}
+	err = s.transactionStore.PutDoneTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(),
+		hash.TransferHash)
+	if err != nil {
+		return jobWait, fmt.Errorf("can't put transaction to done status, err: %w", err)
 	}
+	return jobDone, nil
 }
-func (s ProcessingService) processWithdrawSettled(ctx context.Context, bc string, processor CryptoProcessor,
-	merch MerchantData) {
-	trx, err := s.transactionStore.GetMerchantTrxForProcessingInBlockChain(merch.ID.String(), bc,
-		storage.WithdrawTransaction, storage.SettledTransaction, 1000)
-	if err != nil && !errors.Is(storage.ErrNotFound, err) {
-		log.Println(fmt.Errorf("can't get merchant transactions to done, err: %v", err))
-	} else if err == nil {
-		for _, tr := range trx {
-			hash, err := processor.TransferFromSending(ctx, TransferRequest{
-				Amount:     tr.Amount,
-				Blockchain: tr.Blockchain,
-				Asset:      tr.Asset,
-				Issuer:     tr.Issuer,
-			}, merch.ID.String(), tr.ExtWallet)
-			if err != nil {
-				log.Println(fmt.Errorf("can't process transactions: %v to settle, err: %v", tr.GUID, err))
-				continue
-			}
 
-			err = s.transactionStore.PutDoneTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(),
-				hash.TransferHash)
-			if err != nil {
-				log.Println(fmt.Errorf("can't put transaction to done status, err: %v", err))
-				return
-			}
-
-			callBackTrx, err := s.callBack.GetTransactionFn(tr.MerchantId)
-			if err != nil {
-				log.Println(fmt.Errorf(
-					"error in process withdraw settlement for merchant: %v, due to issue with callback err: %v",
-					merch.ID, err))
-				continue
-			} else if callBackTrx != nil {
-				err = callBackTrx(tr)
-				if err != nil {
-					log.Println(fmt.Errorf(
-						"error in process withdraw processing for merchant: %v, callback err: %v",
-						merch.ID, err))
-				}
-			}
-		}
+// broadcastStatus returns status of the blockchain transaction recorded before its broadcast,
+// NoTransaction is returned if nothing has been broadcast yet or the transaction is expired at its timeout height.
+// A failed or expired blockchain transaction doesn't move funds and is made again, a transaction which
+// isn't found before it is expired is pending, so only one of them can be included in a block
+func (s ProcessingService) broadcastStatus(ctx context.Context, processor CryptoProcessor,
+	hash string, timeoutHeight int64) (CryptoTransactionStatus, error) {
+	if hash == "" {
+		return NoTransaction, nil
+	}
+	res, err := processor.GetBroadcastStatus(ctx, hash, timeoutHeight)
+	if err != nil {
+		return NoTransaction, fmt.Errorf("can't get status of broadcast transaction: %v, err: %w", hash, err)
 	}
+	return res, nil
+}
+
+// withBroadcastIntent returns context which records hash of the blockchain transaction made by the processor
+// in the status of the transaction before it is broadcast, the hash is replaced only if it is still prevHash
+func (s ProcessingService) withBroadcastIntent(ctx context.Context, tr storage.TransactionStore,
+	status storage.StatusTx, prevHash string) context.Context {
+	return WithBroadcastIntent(ctx, func(hash string, timeoutHeight int64) error {
+		return s.transactionStore.PutBroadcastTransaction(tr.MerchantId, tr.ExternalId, tr.GUID.String(), status,
+			prevHash, hash, timeoutHeight)
+	})
 }
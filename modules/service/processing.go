@@ -1,102 +1,182 @@
 package service
 
 import (
-	"context"
+	"coreum_processor/modules/amount"
 	"coreum_processor/modules/storage"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"strings"
 )
 
-// makeDepositCallback function to create record in the transaction store to process deposit transaction
+// makeDepositCallback function to create record in the transaction store to process deposit transaction,
+// an error is returned if the deposit can't be recorded, so it is informed again
 func (s ProcessingService) makeDepositCallback() FuncDepositCallback {
-	return func(blockChain, merchantID, externalId, externalWallet, hash, asset, issuer string, amount float64) {
+	return func(blockChain, merchantID, externalId, externalWallet, hash, memo, asset, issuer string,
+		value amount.Amount) error {
 
 		// validate processor
 		_, ok := s.processors[blockChain]
 		if !ok {
-			log.Println(fmt.Sprintf("error in deposit callback to define processor for a blockchain: %v",
-				blockChain))
-			return
+			return fmt.Errorf("error in deposit callback to define processor for a blockchain: %v", blockChain)
 		}
 		// find merchant
 		merch, err := s.merchants.GetMerchantData(merchantID)
-		if err != nil {
-			log.Println(fmt.Sprintf("error in deposit callback to get merch data: %v", err))
-			return
+		if errors.Is(err, storage.ErrNotFound) {
+			// merchant is unknown, funds are returned to the sender
+			return s.makeRefund(blockChain, merchantID, externalId, externalWallet, hash, asset, issuer, value)
+		} else if err != nil {
+			return fmt.Errorf("error in deposit callback to get merch data: %w", err)
 		}
 
 		// validate merchant wallet
-		if wallet, ok := merch.Wallets[blockChain]; ok {
-			if wallet.SendingID == externalId || wallet.ReceivingID == externalId {
-				return
+		wallet, ok := merch.Wallets[blockChain]
+		if !ok {
+			// merchant is not configured for the blockchain, funds are returned to the sender
+			return s.makeRefund(blockChain, merchantID, externalId, externalWallet, hash, asset, issuer, value)
+		}
+		if wallet.ReceivingID == externalId && wallet.DepositMemo && hash != "" {
+			// deposit to the shared receiving wallet is attributed to the user by memo
+			return s.makeMemoDeposit(blockChain, merchantID, externalWallet, hash, memo, asset, issuer, value)
+		}
+		if wallet.SendingID == externalId || wallet.ReceivingID == externalId {
+			return nil
+		}
+
+		action := storage.DepositTransaction
+		if hash != "" {
+			// deposit is found by its transaction, it is recorded once per transaction and sender
+			known, err := s.isKnownDeposit(merchantID, externalId, blockChain, hash, externalWallet, action,
+				asset, issuer)
+			if err != nil {
+				return fmt.Errorf("error in deposit callback to check transaction: %v, err: %w", hash, err)
 			}
-		} else {
-			// TODO: refund transaction
+			if known {
+				return nil
+			}
+			return s.createDeposit(merchantID, externalId, blockChain, externalWallet, hash, asset, issuer, value)
 		}
 
 		// find all initiated transaction by merchant for user and check if it covers the amount
 		// if not create new transaction for reminding amount
-		action := storage.DepositTransaction
 		trx, err := s.transactionStore.GetInitTransactions(merchantID, externalId, blockChain, action)
 		if err != nil && !errors.Is(err, storage.ErrNotFound) {
-			log.Println(
-				fmt.Sprintf(
-					"error in deposit callback to get merch: %v inititated transactions for user: %v in blockchain: %v, err: %v",
-					merchantID, externalId, blockChain, err))
-			return
+			return fmt.Errorf(
+				"error in deposit callback to get merch: %v inititated transactions for user: %v in blockchain: %v, err: %w",
+				merchantID, externalId, blockChain, err)
 		}
-		processingAmount := 0.
 		for _, tx := range trx {
-			processingAmount += tx.Amount
+			value = value.Sub(tx.Amount)
 		}
-		amount -= processingAmount
-		if amount <= 0.0 {
-			return
+		// funds of rejected deposits are in the wallet until they are returned by refunds
+		refunds, err := s.transactionStore.GetPendingTransactions(merchantID, externalId, blockChain,
+			storage.RefundTransaction)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf(
+				"error in deposit callback to get merch: %v pending refunds for user: %v in blockchain: %v, err: %w",
+				merchantID, externalId, blockChain, err)
+		}
+		for _, tx := range refunds {
+			if tx.Asset == asset && tx.Issuer == issuer {
+				value = value.Sub(tx.Amount)
+			}
+		}
+		if !value.IsPositive() {
+			return nil
 		}
 
 		// initiated transaction doesn't cover amount, create a new
-		_, err = s.transactionStore.CreateTransaction(merchantID, externalId, blockChain,
-			action, externalWallet, hash, asset, issuer, amount, 0)
-		if err != nil {
-			log.Println(fmt.Sprintf("error in storage to create transaction: %v", err))
-		}
-		return
+		return s.createDeposit(merchantID, externalId, blockChain, externalWallet, hash, asset, issuer, value)
 	}
 }
 
-func (s ProcessingService) processTransaction(ctx context.Context) {
-	// get deposit to settle
-	merchants, err := s.merchants.GetMerchants()
+// createDeposit creates a deposit transaction and makes a job to process it,
+// the deposit breaching limits of the merchant is put on hold instead
+func (s ProcessingService) createDeposit(merchantID, externalId, blockChain, externalWallet, hash, asset,
+	issuer string, value amount.Amount) error {
+	breach := s.checkDepositLimits(merchantID, externalId, blockChain, asset, issuer, value)
+	guid, err := s.transactionStore.CreateTransaction(merchantID, externalId, blockChain,
+		storage.DepositTransaction, externalWallet, hash, asset, issuer, value, amount.Zero())
 	if err != nil {
-		log.Println("processing can't get merchants to settle, err", err)
-		return
+		return fmt.Errorf("error in storage to create transaction: %w", err)
 	}
-	for _, merchData := range merchants {
-		merch := MerchantData{}
-		err = json.Unmarshal(merchData.Data, &merch)
-		if err != nil {
-			log.Println(fmt.Errorf("can't unmarshal merchant data: %v to settle, err: %v", merchData.Data, err))
-			continue
-		}
-		for bc, wallet := range merch.Wallets {
-			bc = strings.ToLower(bc)
-			processor, ok := s.processors[bc]
-			if !ok {
-				//log.Println(fmt.Errorf("processing can't find processor for blockchain: %v", bc))
-				continue
-			}
-			if processor == nil {
-				continue
-			}
+	s.processDeposit(merchantID, guid, storage.InitTransaction, breach)
+	return nil
+}
 
-			// deposit processing
-			s.processDeposit(ctx, bc, processor, merch, wallet)
+// isKnownDeposit checks if the transaction of the action is already recorded for the deposit with the hash
+// from the sender
+func (s ProcessingService) isKnownDeposit(merchantID, externalId, blockChain, hash, sender string,
+	action storage.ActionTx, asset, issuer string) (bool, error) {
+	trx, err := s.transactionStore.GetUserTransactionsByHash(merchantID, externalId, blockChain, hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	for _, tx := range trx {
+		if tx.Action == action && tx.Asset == asset && tx.Issuer == issuer && tx.ExtWallet == sender {
+			return true, nil
+		}
+	}
+	return false, nil
+}
 
-			// withdraw processing
-			s.processWithdraw(ctx, bc, processor, merch, wallet)
+// makeSweepCallback function to record gas returned from user wallet to the processing as internal transaction,
+// the sweep is pending until it is confirmed in the blockchain
+func (s ProcessingService) makeSweepCallback() FuncSweepCallback {
+	return func(blockChain, merchantID, externalId, externalWallet, hash, asset string, value amount.Amount) {
+		guid, err := s.transactionStore.CreateProcessedTransaction(merchantID, externalId, blockChain,
+			storage.SweepTransaction, externalWallet, hash, asset, "", value, amount.Zero())
+		if err != nil {
+			log.Println(fmt.Sprintf("error in storage to create gas sweep transaction: %v, hash: %v", err, hash))
+			return
 		}
+		s.enqueueTransaction(merchantID, guid)
+	}
+}
+
+// makeRefund creates a refund transaction for the deposit to return it to the sender, a deposit found
+// by balance is refunded for the part which is not covered by pending refunds
+func (s ProcessingService) makeRefund(blockChain, merchantID, externalId, externalWallet, hash, asset,
+	issuer string, value amount.Amount) error {
+	if hash != "" {
+		known, err := s.isKnownDeposit(merchantID, externalId, blockChain, hash, externalWallet,
+			storage.RefundTransaction, asset, issuer)
+		if err != nil {
+			return fmt.Errorf("error in deposit callback to check transaction: %v, err: %w", hash, err)
+		}
+		if known {
+			return nil
+		}
+		return s.createRefund(merchantID, externalId, blockChain, externalWallet, hash, asset, issuer, value)
+	}
+	trx, err := s.transactionStore.GetPendingTransactions(merchantID, externalId, blockChain,
+		storage.RefundTransaction)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf(
+			"error in deposit callback to get merch: %v pending refunds for user: %v in blockchain: %v, err: %w",
+			merchantID, externalId, blockChain, err)
+	}
+	for _, tx := range trx {
+		if tx.Asset == asset && tx.Issuer == issuer {
+			value = value.Sub(tx.Amount)
+		}
+	}
+	if !value.IsPositive() {
+		return nil
+	}
+	return s.createRefund(merchantID, externalId, blockChain, externalWallet, hash, asset, issuer, value)
+}
+
+// createRefund creates a refund transaction and makes a job to process it, the sender is found
+// by the processor if it is not known
+func (s ProcessingService) createRefund(merchantID, externalId, blockChain, externalWallet, hash, asset,
+	issuer string, value amount.Amount) error {
+	guid, err := s.transactionStore.CreateTransaction(merchantID, externalId, blockChain,
+		storage.RefundTransaction, externalWallet, hash, asset, issuer, value, amount.Zero())
+	if err != nil {
+		return fmt.Errorf("error in storage to create refund transaction: %w", err)
 	}
+	s.enqueueTransaction(merchantID, guid)
+	return nil
 }
@@ -1,11 +1,11 @@
 package processor_coreum
 
 import (
+	"bytes"
 	"context"
 	"coreum_processor/modules/service"
 	"fmt"
 	"github.com/CoreumFoundation/coreum/v2/pkg/client"
-	"github.com/cosmos/cosmos-sdk/crypto/hd"
 	amomultisig "github.com/cosmos/cosmos-sdk/crypto/keys/multisig"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	"github.com/cosmos/cosmos-sdk/crypto/types/multisig"
@@ -14,6 +14,7 @@ import (
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	xauthsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
 	"github.com/dvsekhvalnov/jose2go/base64url"
+	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 func (s CoreumProcessing) broadcastTrx(ctx context.Context, merchantID, externalID, trxID, fromAddr string,
@@ -35,24 +36,7 @@ func (s CoreumProcessing) broadcastTrx(ctx context.Context, merchantID, external
 	}
 	if !ok {
 		// not multisign account
-		senderInfo, err := s.clientCtx.Keyring().NewAccount(
-			sendingWallet.WalletAddress,
-			sendingWallet.WalletSeed,
-			"",
-			sdk.GetConfig().GetFullBIP44Path(),
-			hd.Secp256k1,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("can't add account: %v to keyring for broadcast, error: %w",
-				sendingWallet.WalletAddress, err)
-		}
-		defer func() { _ = s.clientCtx.Keyring().DeleteByAddress(senderInfo.GetAddress()) }()
-		bech32, err := sdk.AccAddressFromBech32(sendingWallet.WalletAddress)
-		if err != nil {
-			return nil, err
-		}
-
-		return client.BroadcastTx(ctx, s.clientCtx.WithFromAddress(bech32), s.factory, msg)
+		return s.signAndBroadcast(ctx, sendingWallet, msg)
 	}
 
 	// multisign account process
@@ -82,8 +66,14 @@ func (s CoreumProcessing) broadcastTrx(ctx context.Context, merchantID, external
 			return nil, fmt.Errorf("can't define gas price for multisign transaction, error: %w", err)
 		}
 
+		timeoutHeight, err := s.broadcastTimeoutHeight(ctx)
+		if err != nil {
+			return nil, err
+		}
+
 		// TODO: gas -???
-		unsignedTx, err := s.factory.WithGas(uint64(124000)).WithGasPrices(gasPrice.String()).BuildUnsignedTx(msg)
+		unsignedTx, err := s.factory.WithGas(uint64(124000)).WithGasPrices(gasPrice.String()).
+			WithTimeoutHeight(uint64(timeoutHeight)).BuildUnsignedTx(msg)
 		if err != nil {
 			return nil, fmt.Errorf("can't buiild multisign transaction, error: %w", err)
 		}
@@ -94,14 +84,13 @@ func (s CoreumProcessing) broadcastTrx(ctx context.Context, merchantID, external
 		}
 		ms := multisig.NewMultisig(int(pubKey.Threshold))
 		// set sign from internal wallet
-		derivedPriv, err := hd.Secp256k1.Derive()(sendingWallet.WalletSeed, "",
-			sdk.GetConfig().GetFullBIP44Path())
+		signer := s.signerFor(sendingWallet)
+		signerPubKey, err := signer.PubKey(ctx, sendingWallet)
 		if err != nil {
-			return nil, fmt.Errorf("can't make multisign signature private key for wallet: %v, error: %w",
+			return nil, fmt.Errorf("can't get multisign signature public key for wallet: %v, error: %w",
 				fromAddr, err)
 		}
-		privKey := hd.Secp256k1.Generate()(derivedPriv)
-		sign, err := privKey.Sign(trxData)
+		sign, err := signer.Sign(ctx, sendingWallet, trxData)
 		if err != nil {
 			return nil, fmt.Errorf("can't sing transaction data by processing, error: %w", err)
 		}
@@ -110,7 +99,7 @@ func (s CoreumProcessing) broadcastTrx(ctx context.Context, merchantID, external
 			Signature: sign,
 		}
 		sigV2 := signing.SignatureV2{
-			PubKey:   privKey.PubKey(),
+			PubKey:   signerPubKey,
 			Data:     &sigData1,
 			Sequence: sequence,
 		}
@@ -170,6 +159,9 @@ func (s CoreumProcessing) broadcastTrx(ctx context.Context, merchantID, external
 		if err != nil {
 			return nil, fmt.Errorf("can't get transaction bytes for broadcast, error: %w", err)
 		}
+		if err = service.RecordBroadcastIntent(ctx, txHashOf(txBytes), timeoutHeight); err != nil {
+			return nil, err
+		}
 		txHash, err := client.BroadcastRawTx(ctx, s.clientCtx, txBytes)
 		if err != nil {
 			return nil, fmt.Errorf("can't broadcast transaction, error: %w", err)
@@ -178,3 +170,99 @@ func (s CoreumProcessing) broadcastTrx(ctx context.Context, merchantID, external
 	}
 	return nil, fmt.Errorf("multisign callback is not defined for merhcant: %v", merchantID)
 }
+
+// signAndBroadcast builds transaction with the messages, signs it by the signer of the wallet and broadcasts it
+func (s CoreumProcessing) signAndBroadcast(ctx context.Context, wallet service.Wallet,
+	msgs ...sdk.Msg) (*sdk.TxResponse, error) {
+	accAddress, err := sdk.AccAddressFromBech32(wallet.WalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("can't get address for account: %v, error: %w", wallet.WalletAddress, err)
+	}
+	info, err := client.GetAccountInfo(ctx, s.clientCtx, accAddress)
+	if err != nil {
+		return nil, fmt.Errorf("can't get info for account: %v, error: %w", wallet.WalletAddress, err)
+	}
+	signer := s.signerFor(wallet)
+	pubKey, err := signer.PubKey(ctx, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("can't get public key for account: %v, error: %w", wallet.WalletAddress, err)
+	}
+	if !bytes.Equal(pubKey.Address(), accAddress) {
+		return nil, fmt.Errorf("public key of signer doesn't match account: %v", wallet.WalletAddress)
+	}
+
+	gasPrice, err := client.GetGasPrice(ctx, s.clientCtx)
+	if err != nil {
+		return nil, fmt.Errorf("can't define gas price for transaction, error: %w", err)
+	}
+	gasPrice.Amount = gasPrice.Amount.Mul(s.clientCtx.GasPriceAdjustment())
+	gas, err := s.fee.simulate(ctx, msgs)
+	if err != nil {
+		return nil, fmt.Errorf("can't simulate transaction, error: %w", err)
+	}
+	timeoutHeight, err := s.broadcastTimeoutHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txf := s.factory.
+		WithAccountNumber(info.GetAccountNumber()).
+		WithSequence(info.GetSequence()).
+		WithGas(uint64(s.clientCtx.GasAdjustment() * float64(gas))).
+		WithGasPrices(gasPrice.String()).
+		WithTimeoutHeight(uint64(timeoutHeight))
+	unsignedTx, err := txf.BuildUnsignedTx(msgs...)
+	if err != nil {
+		return nil, fmt.Errorf("can't build transaction, error: %w", err)
+	}
+
+	// signer info should be set before sign bytes are made
+	sigData := signing.SingleSignatureData{SignMode: txf.SignMode()}
+	sig := signing.SignatureV2{
+		PubKey:   pubKey,
+		Data:     &sigData,
+		Sequence: info.GetSequence(),
+	}
+	if err = unsignedTx.SetSignatures(sig); err != nil {
+		return nil, fmt.Errorf("can't set signer info for wallet: %s, error: %w", wallet.WalletAddress, err)
+	}
+	signerData := xauthsigning.SignerData{
+		ChainID:       txf.ChainID(),
+		AccountNumber: info.GetAccountNumber(),
+		Sequence:      info.GetSequence(),
+	}
+	signBytes, err := s.clientCtx.TxConfig().SignModeHandler().GetSignBytes(txf.SignMode(),
+		signerData, unsignedTx.GetTx())
+	if err != nil {
+		return nil, fmt.Errorf("can't make transaction data for signature, error: %w", err)
+	}
+	if sigData.Signature, err = signer.Sign(ctx, wallet, signBytes); err != nil {
+		return nil, fmt.Errorf("can't sign transaction by wallet: %s, error: %w", wallet.WalletAddress, err)
+	}
+	if err = unsignedTx.SetSignatures(sig); err != nil {
+		return nil, fmt.Errorf("can't set signature for wallet: %s, error: %w", wallet.WalletAddress, err)
+	}
+
+	txBytes, err := s.clientCtx.TxConfig().TxEncoder()(unsignedTx.GetTx())
+	if err != nil {
+		return nil, fmt.Errorf("can't get transaction bytes for broadcast, error: %w", err)
+	}
+	if err = service.RecordBroadcastIntent(ctx, txHashOf(txBytes), timeoutHeight); err != nil {
+		return nil, err
+	}
+	return client.BroadcastRawTx(ctx, s.clientCtx, txBytes)
+}
+
+// txHashOf returns hash of the signed transaction as it is reported by the blockchain
+func txHashOf(txBytes []byte) string {
+	return fmt.Sprintf("%X", tmtypes.Tx(txBytes).Hash())
+}
+
+// signerFor returns signer of the wallet, hot wallets of the processing are signed by the configured signer
+// and wallets created by the processing are signed with their mnemonics from the store
+func (s CoreumProcessing) signerFor(wallet service.Wallet) Signer {
+	if wallet.WalletAddress == s.sendingWallet.WalletAddress ||
+		wallet.WalletAddress == s.receivingWallet.WalletAddress {
+		return s.signer
+	}
+	return s.walletSigner
+}
@@ -49,7 +49,7 @@ func (s CoreumProcessing) burnCoreumFT(ctx context.Context,
 		Coin:   sdk.Coin{Denom: strings.ToLower(subunit) + "-" + issuerAddress, Amount: sdk.NewInt(amount)}}
 
 	// update gas
-	_, err := s.updateGas(ctx, issuerAddress, coreumFeeBurnFT)
+	_, err := s.updateGas(ctx, issuerAddress, coreumFeeBurnFT, msgBurn)
 	if err != nil {
 		return "", fmt.Errorf("can't update gas to burn: %v, error: %w", msgBurn.Coin.Denom, err)
 	}
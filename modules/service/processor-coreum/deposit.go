@@ -6,11 +6,13 @@ import (
 	"coreum_processor/modules/storage"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
-	"strings"
 	"time"
 )
 
+const depositWalletsBatch = 100
+
 func (s CoreumProcessing) Deposit(ctx context.Context, request service.CredentialDeposit,
 	merchantID, externalId string) (*service.DepositResponse, error) {
 	depositData := service.DepositResponse{}
@@ -50,8 +52,21 @@ func (s CoreumProcessing) Deposit(ctx context.Context, request service.Credentia
 
 }
 
+// StreamDeposit scans new blocks for deposits if the scanner cursor store is set, otherwise it polls
+// user wallets for deposits, wallets are split into shards by their id
+// and a replica polls only shards it has been elected for
 func (s CoreumProcessing) StreamDeposit(ctx context.Context, callback service.FuncDepositCallback,
 	interval time.Duration) {
+	if s.cursors != nil {
+		go s.elector.Run(ctx, s.scannerLease())
+		go s.streamBlocks(ctx, callback, interval)
+		return
+	}
+	leases := make([]string, 0, s.depositShards)
+	for shard := int64(0); shard < s.depositShards; shard++ {
+		leases = append(leases, s.depositLease(shard))
+	}
+	go s.elector.Run(ctx, leases...)
 	go s.streamDeposit(ctx, callback, interval)
 	return
 }
@@ -66,32 +81,48 @@ func (s CoreumProcessing) streamDeposit(ctx context.Context, callback service.Fu
 			log.Println("exit from coreum processor deposit stream")
 			return
 		case <-ticker.C:
-			records, err := s.store.GetNext(next, 1)
+			records, err := s.store.GetNext(next, depositWalletsBatch)
 			if err != nil || len(records) == 0 {
 				next = 0
 				if err != nil {
 					log.Println("Error while getting DB records:", err)
 				}
-			} else if strings.Contains(records[0].ExternalID, records[0].MerchantID) {
-				next = records[0].ID
 				continue
-			} else {
-				record := records[len(records)-1]
-				balance, err := s.GetAssetsBalance(ctx,
-					service.BalanceRequest{Blockchain: s.blockchain, Asset: ""}, record.MerchantID, record.ExternalID)
-				if balance != nil && err == nil {
-					for i := 0; i < len(balance); i++ {
-						if balance[i].Amount > 0 && balance[i].Asset != s.denom {
-							callback(balance[i].Blockchain, record.MerchantID, record.ExternalID, record.Key, "",
-								balance[i].Asset, balance[i].Issuer, balance[i].Amount)
+			}
+			// one wallet is polled per tick, merchant wallets and wallets of other shards are skipped
+			var record *storage.KeyRecord
+			for i := range records {
+				next = records[i].ID
+				if s.isMerchantWallet(records[i]) ||
+					!s.elector.IsLeader(s.depositLease(records[i].ID%s.depositShards)) {
+					continue
+				}
+				record = &records[i]
+				break
+			}
+			if record == nil {
+				continue
+			}
+			balance, err := s.GetAssetsBalance(ctx,
+				service.BalanceRequest{Blockchain: s.blockchain, Asset: ""}, record.MerchantID, record.ExternalID)
+			if balance != nil && err == nil {
+				for i := 0; i < len(balance); i++ {
+					if balance[i].Amount.IsPositive() && balance[i].Asset != s.denom {
+						if err := callback(balance[i].Blockchain, record.MerchantID, record.ExternalID, record.Key,
+							"", "", balance[i].Asset, balance[i].Issuer, balance[i].Amount); err != nil {
+							log.Println(err)
 						}
 					}
 				}
-				if err != nil {
-					log.Println(err)
-				}
-				next = record.ID
+			}
+			if err != nil {
+				log.Println(err)
 			}
 		}
 	}
 }
+
+// depositLease returns name of the lease to poll deposits of the shard
+func (s CoreumProcessing) depositLease(shard int64) string {
+	return fmt.Sprintf("%s-deposit-%d", s.blockchain, shard)
+}
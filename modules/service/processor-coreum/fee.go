@@ -0,0 +1,182 @@
+package processor_coreum
+
+import (
+	"context"
+	"coreum_processor/modules/amount"
+	"errors"
+	"fmt"
+	"github.com/CoreumFoundation/coreum/v2/pkg/client"
+	assetfttypes "github.com/CoreumFoundation/coreum/v2/x/asset/ft/types"
+	assetnfttypes "github.com/CoreumFoundation/coreum/v2/x/asset/nft/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const feeCacheTimeToLive = time.Hour
+
+// FeeEstimator estimates fee of a transaction by simulation of its messages against the node,
+// simulated gas is cached per message type and multiplied by the safety multiplier
+type FeeEstimator struct {
+	clientCtx  client.Context
+	txService  txtypes.ServiceClient
+	mode       signing.SignMode
+	multiplier amount.Amount
+	mu         sync.RWMutex
+	gas        map[string]estimatedGas
+}
+
+type estimatedGas struct {
+	gas       uint64
+	expiresAt time.Time
+}
+
+// NewFeeEstimator creates fee estimator which simulates messages with the tx service
+func NewFeeEstimator(clientCtx client.Context, txService txtypes.ServiceClient, mode signing.SignMode,
+	multiplier amount.Amount) *FeeEstimator {
+	return &FeeEstimator{
+		clientCtx:  clientCtx,
+		txService:  txService,
+		mode:       mode,
+		multiplier: multiplier,
+		gas:        map[string]estimatedGas{},
+	}
+}
+
+// Estimate returns fee in subunits of the native coin to execute the messages in one transaction,
+// fallback is returned if there is no message or simulation fails
+func (e *FeeEstimator) Estimate(ctx context.Context, fallback int64, msgs ...sdk.Msg) int64 {
+	if len(msgs) == 0 {
+		return fallback
+	}
+	fee, err := e.Simulate(ctx, msgs...)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't estimate fee, fallback fee: %v is used, err: %v", fallback, err))
+		return fallback
+	}
+	return fee
+}
+
+// Simulate returns fee in subunits of the native coin to execute the messages in one transaction
+// estimated by simulation of the messages, the signer account must exist to simulate them
+func (e *FeeEstimator) Simulate(ctx context.Context, msgs ...sdk.Msg) (int64, error) {
+	if len(msgs) == 0 {
+		return 0, errors.New("no message to simulate")
+	}
+	gas, err := e.getGas(ctx, msgs)
+	if err != nil {
+		return 0, fmt.Errorf("can't simulate messages: %w", err)
+	}
+	price, err := client.GetGasPrice(ctx, e.clientCtx)
+	if err != nil {
+		return 0, fmt.Errorf("can't get gas price: %w", err)
+	}
+	gasPrice, err := amount.Parse(price.Amount.Mul(e.clientCtx.GasPriceAdjustment()).String())
+	if err != nil {
+		return 0, fmt.Errorf("can't parse gas price: %w", err)
+	}
+	fee := amount.NewFromInt64(int64(gas)).Mul(e.multiplier).Mul(gasPrice).Ceil()
+	msgFee, err := e.messagesFee(ctx, msgs)
+	if err != nil {
+		return 0, fmt.Errorf("can't get fee of messages: %w", err)
+	}
+	total, err := fee.Add(msgFee).Int()
+	if err != nil {
+		return 0, fmt.Errorf("can't get fee in subunits: %w", err)
+	}
+	return total.Int64(), nil
+}
+
+// getGas returns cached gas of the message types or simulates the messages
+func (e *FeeEstimator) getGas(ctx context.Context, msgs []sdk.Msg) (uint64, error) {
+	types := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		types = append(types, sdk.MsgTypeURL(msg))
+	}
+	key := strings.Join(types, ",")
+
+	e.mu.RLock()
+	cached, ok := e.gas[key]
+	e.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.gas, nil
+	}
+
+	gas, err := e.simulate(ctx, msgs)
+	if err != nil {
+		return 0, err
+	}
+	e.mu.Lock()
+	e.gas[key] = estimatedGas{gas: gas, expiresAt: time.Now().Add(feeCacheTimeToLive)}
+	e.mu.Unlock()
+	return gas, nil
+}
+
+// simulate executes the messages on the node without signature of the signer and returns used gas
+func (e *FeeEstimator) simulate(ctx context.Context, msgs []sdk.Msg) (uint64, error) {
+	signers := msgs[0].GetSigners()
+	if len(signers) == 0 {
+		return 0, fmt.Errorf("message: %v doesn't have signer", sdk.MsgTypeURL(msgs[0]))
+	}
+	account, err := client.GetAccountInfo(ctx, e.clientCtx, signers[0])
+	if err != nil {
+		return 0, fmt.Errorf("can't get info for account: %v, error: %w", signers[0], err)
+	}
+	// node doesn't verify public key of the signer in simulation
+	pubKey := account.GetPubKey()
+	if pubKey == nil {
+		pubKey = &secp256k1.PubKey{Key: make([]byte, secp256k1.PubKeySize)}
+	}
+
+	builder := e.clientCtx.TxConfig().NewTxBuilder()
+	if err = builder.SetMsgs(msgs...); err != nil {
+		return 0, err
+	}
+	err = builder.SetSignatures(signing.SignatureV2{
+		PubKey:   pubKey,
+		Data:     &signing.SingleSignatureData{SignMode: e.mode},
+		Sequence: account.GetSequence(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	txBytes, err := e.clientCtx.TxConfig().TxEncoder()(builder.GetTx())
+	if err != nil {
+		return 0, err
+	}
+	res, err := e.txService.Simulate(ctx, &txtypes.SimulateRequest{TxBytes: txBytes})
+	if err != nil {
+		return 0, err
+	}
+	if res.GasInfo == nil {
+		return 0, fmt.Errorf("empty gas info in simulation")
+	}
+	return res.GasInfo.GasUsed, nil
+}
+
+// messagesFee returns fee charged by the messages in addition to gas, like fee to issue FT or mint NFT
+func (e *FeeEstimator) messagesFee(ctx context.Context, msgs []sdk.Msg) (amount.Amount, error) {
+	fee := amount.Zero()
+	for _, msg := range msgs {
+		switch msg.(type) {
+		case *assetfttypes.MsgIssue:
+			res, err := assetfttypes.NewQueryClient(e.clientCtx).Params(ctx, &assetfttypes.QueryParamsRequest{})
+			if err != nil {
+				return fee, err
+			}
+			fee = fee.Add(amount.NewFromInt(res.Params.IssueFee.Amount))
+		case *assetnfttypes.MsgMint:
+			res, err := assetnfttypes.NewQueryClient(e.clientCtx).Params(ctx, &assetnfttypes.QueryParamsRequest{})
+			if err != nil {
+				return fee, err
+			}
+			fee = fee.Add(amount.NewFromInt(res.Params.MintFee.Amount))
+		}
+	}
+	return fee, nil
+}
@@ -7,11 +7,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/CoreumFoundation/coreum/v2/pkg/client"
 	assetfttypes "github.com/CoreumFoundation/coreum/v2/x/asset/ft/types"
 	assetnfttypes "github.com/CoreumFoundation/coreum/v2/x/asset/nft/types"
-	"github.com/cosmos/cosmos-sdk/crypto/hd"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	"strings"
 )
 
@@ -50,12 +49,13 @@ func (s CoreumProcessing) IssueNFTClass(ctx context.Context, request service.New
 			return nil, nil, fmt.Errorf("empty wallet address")
 		}
 	}
+	// the issuer is funded to mint NFT of the class as well
 	_, err = s.updateGas(ctx, wallet.WalletAddress, coreumFeeMintNFT+coreumFeeIssueNFT)
 	if err != nil {
 		return nil, nil, err
 	}
 	token, features, err := s.createCoreumNFTClass(ctx,
-		request.Symbol, request.Code, request.Issuer, request.Description, wallet.WalletSeed)
+		request.Symbol, request.Code, request.Description, wallet)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -99,10 +99,6 @@ func (s CoreumProcessing) IssueFT(ctx context.Context, request service.NewTokenR
 			return nil, nil, fmt.Errorf("empty wallet address")
 		}
 	}
-	_, err = s.updateGas(ctx, key, coreumFeeIssueFT+coreumFeeSendFT)
-	if err != nil {
-		return nil, nil, err
-	}
 	token, features, err := s.createCoreumFT(ctx, merchantID, externalId,
 		request.Symbol, request.Code, key, request.Description,
 		wallet, request.InitialAmount)
@@ -120,8 +116,17 @@ func (s CoreumProcessing) IssueFT(ctx context.Context, request service.NewTokenR
 			externalID, denom, err)
 	}
 
+	msgSend := &banktypes.MsgSend{
+		FromAddress: request.Issuer,
+		ToAddress:   addr,
+		Amount:      sdk.NewCoins(sdk.NewCoin(denom, sdk.NewInt(request.InitialAmount))),
+	}
+	_, err = s.updateGas(ctx, request.Issuer, coreumFeeSendFT, msgSend)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't update gas to transfer issued FT: %v, error: %w", denom, err)
+	}
 	token, err = s.transferCoreumFT(ctx, merchantID, externalID, "issue-send-"+denom, request.Issuer, addr,
-		denom, wallet, request.InitialAmount)
+		denom, wallet, sdk.NewInt(request.InitialAmount))
 	if err != nil {
 		return &service.NewTokenResponse{TxHash: token}, features, fmt.Errorf(
 			"can't transfer issued FT: %v, error: %w", denom, err)
@@ -146,6 +151,11 @@ func (s CoreumProcessing) createCoreumFT(ctx context.Context, merchantID, extern
 		Features:      features,
 	}
 
+	_, err := s.updateGas(ctx, issuerAddress, coreumFeeIssueFT, msgIssue)
+	if err != nil {
+		return "", nil, fmt.Errorf("can't update gas to create FT, error: %w", err)
+	}
+
 	trx, err := s.broadcastTrx(ctx, merchantID, externalID, "issue-"+symbol+"-"+subunit, issuerAddress,
 		sendingWallet, msgIssue)
 	if err != nil {
@@ -161,37 +171,24 @@ func (s CoreumProcessing) createCoreumFT(ctx context.Context, merchantID, extern
 }
 
 func (s CoreumProcessing) createCoreumNFTClass(ctx context.Context,
-	symbol, name, issuerAddress, description, mnemonic string) (string, []byte, error) {
-
-	senderInfo, err := s.clientCtx.Keyring().NewAccount(
-		issuerAddress,
-		mnemonic,
-		"",
-		sdk.GetConfig().GetFullBIP44Path(),
-		hd.Secp256k1,
-	)
-
-	defer func() { _ = s.clientCtx.Keyring().DeleteByAddress(senderInfo.GetAddress()) }()
-
-	if err != nil {
-		return "", nil, err
-	}
+	symbol, name, description string, issuerWallet service.Wallet) (string, []byte, error) {
 
 	features := []assetnfttypes.ClassFeature{assetnfttypes.ClassFeature_burning}
 
 	msgIssue := &assetnfttypes.MsgIssueClass{
-		Issuer:      senderInfo.GetAddress().String(),
+		Issuer:      issuerWallet.WalletAddress,
 		Symbol:      symbol,
 		Name:        name,
 		Description: description,
 		Features:    features,
 	}
-	trx, err := client.BroadcastTx(
-		ctx,
-		s.clientCtx.WithFromAddress(senderInfo.GetAddress()),
-		s.factory,
-		msgIssue,
-	)
+
+	_, err := s.updateGas(ctx, msgIssue.Issuer, coreumFeeIssueNFT, msgIssue)
+	if err != nil {
+		return "", nil, err
+	}
+
+	trx, err := s.signAndBroadcast(ctx, issuerWallet, msgIssue)
 	if err != nil {
 		return "", nil, err
 	}
@@ -5,10 +5,8 @@ import (
 	"coreum_processor/modules/service"
 	"encoding/json"
 	"fmt"
-	"github.com/CoreumFoundation/coreum/v2/pkg/client"
 	assetfttypes "github.com/CoreumFoundation/coreum/v2/x/asset/ft/types"
 	assetnfttypes "github.com/CoreumFoundation/coreum/v2/x/asset/nft/types"
-	"github.com/cosmos/cosmos-sdk/crypto/hd"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"strconv"
 )
@@ -50,7 +48,7 @@ func (s CoreumProcessing) MintFT(ctx context.Context,
 	}
 
 	token, err = s.transferCoreumFT(ctx, merchantID, externalID, "mint-send-"+denom, request.Issuer, addr,
-		denom, wallet, int64(amount))
+		denom, wallet, sdk.NewInt(int64(amount)))
 
 	if err != nil {
 		return nil, fmt.Errorf("can't transfer minted asset: %v, error: %w", denom, err)
@@ -76,19 +74,7 @@ func (s CoreumProcessing) MintNFT(ctx context.Context,
 		return nil, fmt.Errorf("empty or incorrect issuer wallet address")
 	}
 
-	senderInfo, err := s.clientCtx.Keyring().NewAccount(
-		wallet.WalletAddress,
-		wallet.WalletSeed,
-		"",
-		sdk.GetConfig().GetFullBIP44Path(),
-		hd.Secp256k1,
-	)
-	defer func() { _ = s.clientCtx.Keyring().DeleteByAddress(senderInfo.GetAddress()) }()
-	if err != nil {
-		return nil, err
-	}
-
-	token, err := s.mintCoreumNFT(ctx, request.ClassID, request.Issuer, request.NftId)
+	token, err := s.mintCoreumNFT(ctx, request.ClassID, request.NftId, wallet)
 	if err != nil {
 		return nil, err
 	}
@@ -105,7 +91,7 @@ func (s CoreumProcessing) mintCoreumFT(ctx context.Context,
 	}
 
 	// update gas
-	_, err := s.updateGas(ctx, issuerAddress, coerumFeeMintFT)
+	_, err := s.updateGas(ctx, issuerAddress, coerumFeeMintFT, msgMint)
 	if err != nil {
 		return "", err
 	}
@@ -120,34 +106,28 @@ func (s CoreumProcessing) mintCoreumFT(ctx context.Context,
 	return trx.TxHash, err
 }
 
-func (s CoreumProcessing) mintCoreumNFT(ctx context.Context, classId, issuerAddress, nftId string) (string, error) {
-	address, err := sdk.AccAddressFromBech32(issuerAddress)
-	if err != nil {
-		return "", err
-	}
-	senderInfo, err := s.clientCtx.Keyring().KeyByAddress(address)
-	if err != nil {
-		return "", err
-	}
-
+func (s CoreumProcessing) mintCoreumNFT(ctx context.Context, classId, nftId string,
+	issuerWallet service.Wallet) (string, error) {
+	address, err := sdk.AccAddressFromBech32(issuerWallet.WalletAddress)
 	if err != nil {
 		return "", err
 	}
 
-	classID := assetnfttypes.BuildClassID(classId, senderInfo.GetAddress())
+	classID := assetnfttypes.BuildClassID(classId, address)
 
 	msgMint := &assetnfttypes.MsgMint{
-		Sender:  senderInfo.GetAddress().String(),
+		Sender:  address.String(),
 		ClassID: classID,
 		ID:      nftId,
 	}
 
-	trx, err := client.BroadcastTx(
-		ctx,
-		s.clientCtx.WithFromAddress(senderInfo.GetAddress()),
-		s.factory,
-		msgMint,
-	)
+	// update gas
+	_, err = s.updateGas(ctx, msgMint.Sender, coreumFeeMintNFT, msgMint)
+	if err != nil {
+		return "", err
+	}
+
+	trx, err := s.signAndBroadcast(ctx, issuerWallet, msgMint)
 	if err != nil {
 		return "", err
 	}
@@ -2,29 +2,37 @@ package processor_coreum
 
 import (
 	"context"
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/leader"
 	"coreum_processor/modules/service"
 	"coreum_processor/modules/storage"
-	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/CoreumFoundation/coreum/v2/pkg/client"
 	"github.com/CoreumFoundation/coreum/v2/pkg/config/constant"
 	"github.com/CoreumFoundation/coreum/v2/x/asset/ft"
 	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
 	"github.com/cosmos/cosmos-sdk/client/tx"
-	"github.com/cosmos/cosmos-sdk/crypto/hd"
 	"github.com/cosmos/cosmos-sdk/crypto/keyring"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/module"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	"github.com/cosmos/cosmos-sdk/x/auth"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"log"
 	"strings"
+	"time"
 )
 
+// fees of messages used as fallback if fee can't be estimated by simulation
 const (
 	coreumFeeMessage  = 50000
 	coreumFeeIssueBug = 10000000
@@ -35,11 +43,27 @@ const (
 	coreumFeeIssueNFT = 16000
 	coreumFeeMintNFT  = 39000
 	coreumDecimals    = 1000000
+	mempoolTxsLimit   = 100
+	// broadcastTimeoutBlocks is the number of blocks a transaction recorded before broadcast can be included in
+	broadcastTimeoutBlocks = 100
 )
 
 type CoreumProcessing struct {
 	blockchain      string
 	client          *grpc.ClientConn
+	txService       txtypes.ServiceClient
+	tmService       tmservice.ServiceClient
+	mempool         rpcclient.MempoolClient
+	minConfirmation int64
+	elector         *leader.Elector
+	depositShards   int64
+	cursors         *storage.CursorsPSQL
+	scannerStart    int64
+	sweepInterval   time.Duration
+	sweepDust       amount.Amount
+	fee             *FeeEstimator
+	signer          Signer
+	walletSigner    Signer
 	config          *sdk.Config
 	factory         tx.Factory
 	clientCtx       client.Context
@@ -49,16 +73,24 @@ type CoreumProcessing struct {
 	store           *storage.KeysPSQL
 	callBack        *service.CallBacks
 	apiURL          string
-	minimumValue    float64
+	minimumValue    amount.Amount
 	senderMnemonic  string
 	denom           string
 	addressPrefix   string
 }
 
+// NewCoreumCryptoProcessor creates Coreum processing which uses the connection to the node for transactions,
+// the tx and Tendermint services are used to get their statuses and to scan blocks, and the optional mempool
+// to find transactions which are not included in a block yet. Blocks are scanned from the start height
+// until the cursor of the scanner is stored
 func NewCoreumCryptoProcessor(sendingWallet, receivingWallet service.Wallet,
-	blockchain string, store *storage.KeysPSQL, minValue float64,
-	chainID constant.ChainID, nodeAddress, addressPrefix, denom string, mode signing.SignMode,
-	callBack *service.CallBacks) service.CryptoProcessor {
+	blockchain string, store *storage.KeysPSQL, elector *leader.Elector, depositShards int64,
+	cursors *storage.CursorsPSQL, scannerStart int64,
+	minValue amount.Amount, minConfirmation int64,
+	sweepInterval time.Duration, sweepDust amount.Amount, feeMultiplier amount.Amount,
+	chainID constant.ChainID, grpcClient *grpc.ClientConn, txService txtypes.ServiceClient,
+	tmService tmservice.ServiceClient, mempool rpcclient.MempoolClient, addressPrefix, denom string,
+	mode signing.SignMode, signer Signer, callBack *service.CallBacks) service.CryptoProcessor {
 
 	// Configure Cosmos SDK
 	config := sdk.GetConfig()
@@ -73,12 +105,6 @@ func NewCoreumCryptoProcessor(sendingWallet, receivingWallet service.Wallet,
 	)
 
 	// Configure client context and tx factory
-	// If you don't use TLS then replace `grpc.WithTransportCredentials()` with `grpc.WithInsecure()`
-	grpcClient, err := grpc.Dial(nodeAddress, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
-	if err != nil {
-		panic(err)
-	}
-
 	clientCtx := client.NewContext(client.DefaultContextConfig(), modules).
 		WithChainID(string(chainID)).
 		WithGRPCClient(grpcClient).
@@ -92,9 +118,27 @@ func NewCoreumCryptoProcessor(sendingWallet, receivingWallet service.Wallet,
 		WithSignMode(mode).
 		WithSimulateAndExecute(true)
 
+	// wallets are polled for deposits at least in one shard
+	if depositShards < 1 {
+		depositShards = 1
+	}
+
 	return &CoreumProcessing{
 		blockchain:      blockchain,
 		client:          grpcClient,
+		txService:       txService,
+		tmService:       tmService,
+		mempool:         mempool,
+		minConfirmation: minConfirmation,
+		elector:         elector,
+		depositShards:   depositShards,
+		cursors:         cursors,
+		scannerStart:    scannerStart,
+		sweepInterval:   sweepInterval,
+		sweepDust:       sweepDust,
+		fee:             NewFeeEstimator(clientCtx, txService, mode, feeMultiplier),
+		signer:          signer,
+		walletSigner:    NewInProcessSigner(),
 		clientCtx:       clientCtx,
 		factory:         txFactory,
 		config:          config,
@@ -108,12 +152,111 @@ func NewCoreumCryptoProcessor(sendingWallet, receivingWallet service.Wallet,
 	}
 }
 
-// GetTransactionStatus returns transaction status from the blockchain
-func (s CoreumProcessing) GetTransactionStatus(_ context.Context, hash string) (service.CryptoTransactionStatus, error) {
-	//todo
+// GetTransactionStatus returns transaction status from the blockchain, a transaction included in a block
+// is pending until it gets the minimum number of confirmations
+func (s CoreumProcessing) GetTransactionStatus(ctx context.Context, hash string) (service.CryptoTransactionStatus, error) {
+	res, err := s.txService.GetTx(ctx, &txtypes.GetTxRequest{Hash: hash})
+	if status.Code(err) == codes.NotFound {
+		return s.getMempoolStatus(ctx, hash)
+	} else if err != nil {
+		return service.NoTransaction, fmt.Errorf("can't get transaction: %s, err: %w", hash, err)
+	}
+	if res.TxResponse == nil {
+		return service.NoTransaction, fmt.Errorf("can't get transaction: %s, empty response", hash)
+	}
+	if res.TxResponse.Code != 0 {
+		return service.FailedTransaction, nil
+	}
+	if s.minConfirmation <= 1 {
+		return service.SuccessfulTransaction, nil
+	}
+	latest, err := s.latestHeight(ctx)
+	if err != nil {
+		return service.NoTransaction, err
+	}
+	if latest-res.TxResponse.Height+1 < s.minConfirmation {
+		return service.PendingTransaction, nil
+	}
 	return service.SuccessfulTransaction, nil
 }
 
+// GetBroadcastStatus returns status of the transaction recorded before broadcast, the transaction which isn't found
+// is pending until the latest block is after its timeout height, it can't be included in a block after it.
+// The transaction without timeout height is never known to be lost
+func (s CoreumProcessing) GetBroadcastStatus(ctx context.Context, hash string,
+	timeoutHeight int64) (service.CryptoTransactionStatus, error) {
+	// the height is taken before the transaction is looked for, so the transaction included
+	// up to the timeout height is found
+	latest, err := s.latestHeight(ctx)
+	if err != nil {
+		return service.NoTransaction, err
+	}
+	res, err := s.GetTransactionStatus(ctx, hash)
+	if err != nil || res != service.NoTransaction {
+		return res, err
+	}
+	if timeoutHeight <= 0 || latest <= timeoutHeight {
+		return service.PendingTransaction, nil
+	}
+	return service.NoTransaction, nil
+}
+
+// broadcastTimeoutHeight returns the height the transaction broadcast with the context can be included in a block
+// up to, zero is returned if the transaction isn't recorded before broadcast. The recorded transaction
+// can't be found before it is included in a block without the mempool, so it isn't made without one
+func (s CoreumProcessing) broadcastTimeoutHeight(ctx context.Context) (int64, error) {
+	if !service.HasBroadcastIntent(ctx) {
+		return 0, nil
+	}
+	if s.mempool == nil {
+		return 0, errors.New("can't record transaction before broadcast without Tendermint RPC")
+	}
+	latest, err := s.latestHeight(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return latest + broadcastTimeoutBlocks, nil
+}
+
+// latestHeight returns height of the latest block of the chain
+func (s CoreumProcessing) latestHeight(ctx context.Context) (int64, error) {
+	block, err := s.tmService.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("can't get latest block, err: %w", err)
+	}
+	if block.Block == nil {
+		return 0, errors.New("can't get latest block, empty response")
+	}
+	return block.Block.Header.Height, nil
+}
+
+// getMempoolStatus returns pending status if transaction is waiting in the mempool to be included in a block
+func (s CoreumProcessing) getMempoolStatus(ctx context.Context, hash string) (service.CryptoTransactionStatus, error) {
+	if s.mempool == nil {
+		return service.NoTransaction, nil
+	}
+	limit := mempoolTxsLimit
+	res, err := s.mempool.UnconfirmedTxs(ctx, &limit)
+	if err != nil {
+		return service.NoTransaction, fmt.Errorf("can't get unconfirmed transactions, err: %w", err)
+	}
+	for _, tx := range res.Txs {
+		if strings.EqualFold(hex.EncodeToString(tx.Hash()), hash) {
+			return service.PendingTransaction, nil
+		}
+	}
+	return service.NoTransaction, nil
+}
+
+// newCoins returns coins of the denom for the amount, an error is returned if the amount isn't whole subunits
+func newCoins(denom string, value amount.Amount) (sdk.Coins, error) {
+	i, err := value.Int()
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewCoins(sdk.NewCoin(denom, i)), nil
+}
+
 func (s CoreumProcessing) TransferToReceiving(ctx context.Context, request service.TransferRequest,
 	merchantID, externalId string) (*service.TransferResponse, error) {
 	_, key, userWallet, err := s.store.GetByUser(merchantID, externalId)
@@ -124,18 +267,23 @@ func (s CoreumProcessing) TransferToReceiving(ctx context.Context, request servi
 
 	sendingWallet := service.Wallet{}
 	err = json.Unmarshal(userWallet, &sendingWallet)
-	//check gas
-	_, err = s.updateGas(ctx, sendingWallet.WalletAddress, coreumFeeSendFT)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
-
+	coins, err := newCoins(fmt.Sprintf("%s-%s", request.Asset, request.Issuer), request.Amount)
+	if err != nil {
+		return nil, err
+	}
 	msg := &banktypes.MsgSend{
 		FromAddress: key,
 		ToAddress:   s.receivingWallet.WalletAddress,
-		Amount: sdk.NewCoins(sdk.NewInt64Coin(fmt.Sprintf("%s-%s", request.Asset, request.Issuer),
-			int64(request.Amount))),
+		Amount:      coins,
+	}
+	//check gas
+	_, err = s.updateGas(ctx, sendingWallet.WalletAddress, coreumFeeSendFT, msg)
+	if err != nil {
+		log.Println(err)
+		return nil, err
 	}
 
 	result, err := s.broadcastTrx(ctx, merchantID, externalId, "deposit-transfer-receiving",
@@ -149,7 +297,7 @@ func (s CoreumProcessing) TransferToReceiving(ctx context.Context, request servi
 
 func (s CoreumProcessing) TransferFromReceiving(ctx context.Context, request service.TransferRequest,
 	merchantID, externalId string) (*service.TransferResponse, error) {
-	if request.Amount < s.minimumValue {
+	if request.Amount.LT(s.minimumValue) {
 		return nil, fmt.Errorf("transaction amount is to small to be received")
 	}
 
@@ -162,38 +310,21 @@ func (s CoreumProcessing) TransferFromReceiving(ctx context.Context, request ser
 	if err != nil {
 		return nil, err
 	}
-	senderInfo, err := s.clientCtx.Keyring().NewAccount(
-		s.receivingWallet.WalletAddress,
-		string(s.receivingWallet.WalletSeed),
-		"",
-		sdk.GetConfig().GetFullBIP44Path(),
-		hd.Secp256k1,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = s.clientCtx.Keyring().DeleteByAddress(senderInfo.GetAddress()) }()
-	//check gas
-	_, err = s.updateGas(ctx, s.receivingWallet.WalletAddress, coreumFeeSendFT)
+	coins, err := newCoins(fmt.Sprintf("%s-%s", request.Asset, request.Issuer), request.Amount)
 	if err != nil {
 		return nil, err
 	}
 	msg := &banktypes.MsgSend{
 		FromAddress: s.receivingWallet.WalletAddress,
 		ToAddress:   clientWallet.WalletAddress,
-		Amount: sdk.NewCoins(sdk.NewInt64Coin(fmt.Sprintf("%s-%s", request.Asset, request.Issuer),
-			int64(request.Amount))),
+		Amount:      coins,
 	}
-	bech32, err := sdk.AccAddressFromBech32(s.receivingWallet.WalletAddress)
+	//check gas
+	_, err = s.updateGas(ctx, s.receivingWallet.WalletAddress, coreumFeeSendFT, msg)
 	if err != nil {
 		return nil, err
 	}
-	result, err := client.BroadcastTx(
-		ctx,
-		s.clientCtx.WithFromAddress(bech32),
-		s.factory,
-		msg,
-	)
+	result, err := s.signAndBroadcast(ctx, s.receivingWallet, msg)
 	if err != nil {
 		return nil, err
 	}
@@ -222,17 +353,20 @@ func (s CoreumProcessing) TransferBetweenMerchantWallets(ctx context.Context,
 		return nil, err
 	}
 
-	//check gas
-	_, err = s.updateGas(ctx, receivingWallet.WalletAddress, coreumFeeSendFT)
+	coins, err := newCoins(fmt.Sprintf("%s-%s", request.Asset, request.Issuer), request.Amount)
 	if err != nil {
 		return nil, err
 	}
-
 	msg := &banktypes.MsgSend{
 		FromAddress: keyR,
 		ToAddress:   keyS,
-		Amount: sdk.NewCoins(sdk.NewInt64Coin(fmt.Sprintf("%s-%s", request.Asset, request.Issuer),
-			int64(request.Amount))),
+		Amount:      coins,
+	}
+
+	//check gas
+	_, err = s.updateGas(ctx, receivingWallet.WalletAddress, coreumFeeSendFT, msg)
+	if err != nil {
+		return nil, err
 	}
 
 	result, err := s.broadcastTrx(ctx, merchantID, merchantID+"-R", "transfer-R-to-S", keyR, receivingWallet, msg)
@@ -246,39 +380,22 @@ func (s CoreumProcessing) TransferBetweenMerchantWallets(ctx context.Context,
 
 func (s CoreumProcessing) TransferFromSending(ctx context.Context, request service.TransferRequest,
 	merchantID, receivingWallet string) (*service.TransferResponse, error) {
-	senderInfo, err := s.clientCtx.Keyring().NewAccount(
-		s.sendingWallet.WalletAddress,
-		string(s.sendingWallet.WalletSeed),
-		"",
-		sdk.GetConfig().GetFullBIP44Path(),
-		hd.Secp256k1,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = s.clientCtx.Keyring().DeleteByAddress(senderInfo.GetAddress()) }()
 	denom := s.denom
 	if request.Asset == "" {
 		request.Asset = s.denom
 	} else {
 		denom = request.Asset + "-" + request.Issuer
 	}
+	coins, err := newCoins(denom, request.Amount)
+	if err != nil {
+		return nil, err
+	}
 	msg := &banktypes.MsgSend{
 		FromAddress: s.sendingWallet.WalletAddress,
 		ToAddress:   receivingWallet,
-		Amount: sdk.NewCoins(sdk.NewInt64Coin(denom,
-			int64(request.Amount))),
-	}
-	bech32, err := sdk.AccAddressFromBech32(s.sendingWallet.WalletAddress)
-	if err != nil {
-		return nil, err
+		Amount:      coins,
 	}
-	result, err := client.BroadcastTx(
-		ctx,
-		s.clientCtx.WithFromAddress(bech32),
-		s.factory,
-		msg,
-	)
+	result, err := s.signAndBroadcast(ctx, s.sendingWallet, msg)
 	if err != nil {
 		return nil, err
 	}
@@ -303,7 +420,7 @@ func (s CoreumProcessing) GetTokenSupply(ctx context.Context, request service.Ba
 func (s CoreumProcessing) GetBalance(ctx context.Context, merchantID, externalID string) (service.Balance, error) {
 	_, key, byteAddress, err := s.store.GetByUser(merchantID, externalID)
 	balance := service.Balance{
-		Amount:     0,
+		Amount:     amount.Zero(),
 		Blockchain: "coreum",
 		Asset:      constant.DenomTest,
 		Issuer:     "",
@@ -318,8 +435,8 @@ func (s CoreumProcessing) GetBalance(ctx context.Context, merchantID, externalID
 		return balance, err
 	}
 
-	amount, _, err := s.balanceCoreum(ctx, key, constant.DenomTest)
-	balance.Amount = float64(amount) / coreumDecimals
+	value, _, err := s.balanceCoreum(ctx, key, constant.DenomTest)
+	balance.Amount = amount.NewFromInt64(int64(value)).Quo(amount.NewFromInt64(coreumDecimals))
 
 	if err != nil {
 		return balance, err
@@ -361,12 +478,14 @@ func (s CoreumProcessing) GetAssetsBalance(ctx context.Context,
 			if balanceDenom == s.denom {
 				asset = s.denom
 			} else {
-				assetInfo := strings.Split(balanceDenom, "-")
-				asset = assetInfo[0]
-				issuer = assetInfo[1]
+				var ok bool
+				// only the native coin is without issuer, other denoms without it aren't assets of the processing
+				if asset, issuer, ok = strings.Cut(balanceDenom, "-"); !ok {
+					continue
+				}
 			}
 			balances = append(balances, service.Balance{Blockchain: request.Blockchain,
-				Amount: float64(resp.Balances[i].Amount.Int64()),
+				Amount: amount.NewFromInt(resp.Balances[i].Amount),
 				Asset:  asset, Issuer: issuer})
 		}
 		return balances, nil
@@ -381,7 +500,7 @@ func (s CoreumProcessing) GetAssetsBalance(ctx context.Context,
 		return nil, fmt.Errorf("can't get balance for denom: %v, error: %w", denom, err)
 	}
 	balances = append(balances, service.Balance{Blockchain: request.Blockchain,
-		Amount: float64(resp.Balance.Amount.Int64()),
+		Amount: amount.NewFromInt(resp.Balance.Amount),
 		Asset:  request.Asset, Issuer: request.Issuer})
 
 	return balances, nil
@@ -398,18 +517,66 @@ func (s CoreumProcessing) GetWalletById(merchantID, externalId string) (string,
 	return address, nil
 }
 
-func (s CoreumProcessing) updateGas(ctx context.Context, address string, txGasPrice int64) (string, error) {
+// ValidateAddress checks that the address is a bech32 account address with the prefix of the chain
+// and returns it in lower case, bech32 addresses are the same in upper and lower case
+func (s CoreumProcessing) ValidateAddress(address string) (string, error) {
+	bz, err := sdk.GetFromBech32(address, s.addressPrefix)
+	if err != nil {
+		return "", fmt.Errorf("invalid address: %v, err: %w", address, err)
+	}
+	if err = sdk.VerifyAddressFormat(bz); err != nil {
+		return "", fmt.Errorf("invalid address: %v, err: %w", address, err)
+	}
+	return sdk.Bech32ifyAddressBytes(s.addressPrefix, bz)
+}
+
+// MinimumValue returns the least amount in subunits the processor transfers
+func (s CoreumProcessing) MinimumValue() amount.Amount {
+	return s.minimumValue
+}
+
+// updateGas tops up the address with native coin to pay fee of a transaction with the messages,
+// the fee is estimated by simulation of the messages with fallback to the given value.
+// The account of a new wallet doesn't exist until it gets coins and can't be simulated,
+// so it is topped up by the fallback value first and the messages are simulated after that
+func (s CoreumProcessing) updateGas(ctx context.Context, address string, fallback int64,
+	msgs ...sdk.Msg) (string, error) {
 	core, _, err := s.balanceCoreum(ctx, address, s.denom)
 	if err != nil {
 		return "", err
 	}
-	if int64(core) >= txGasPrice {
-		return "", nil
+	if len(msgs) == 0 {
+		return s.topUpGas(ctx, address, int64(core), fallback)
+	}
+	txGasPrice, err := s.fee.Simulate(ctx, msgs...)
+	if err == nil {
+		return s.topUpGas(ctx, address, int64(core), txGasPrice)
+	}
+	if core > 0 {
+		log.Println(fmt.Sprintf("can't estimate fee, fallback fee: %v is used, err: %v", fallback, err))
+		return s.topUpGas(ctx, address, int64(core), fallback)
 	}
-	trx, err := s.transferCoreumFT(ctx, "", "", "",
-		s.sendingWallet.WalletAddress, address, s.denom, s.sendingWallet, txGasPrice-int64(core))
+	trx, err := s.topUpGas(ctx, address, 0, fallback)
+	if err != nil {
+		return "", err
+	}
+	txGasPrice = s.fee.Estimate(ctx, fallback, msgs...)
+	if txGasPrice <= fallback {
+		return trx, nil
+	}
+	return s.topUpGas(ctx, address, fallback, txGasPrice)
+}
 
-	return trx, err
+// topUpGas transfers native coin from the sending wallet to the address to have the fee, the address has
+// the balance of native coin
+func (s CoreumProcessing) topUpGas(ctx context.Context, address string, balance, fee int64) (string, error) {
+	if balance >= fee {
+		return "", nil
+	}
+	// gas is not the transaction the caller records before broadcast
+	ctx = service.WithBroadcastIntent(ctx, nil)
+	return s.transferCoreumFT(ctx, "", "", "",
+		s.sendingWallet.WalletAddress, address, s.denom, s.sendingWallet, sdk.NewInt(fee-balance))
 }
 
 func (s CoreumProcessing) balanceCoreum(ctx context.Context, userAddress, denom string) (int, string, error) {
@@ -437,18 +604,12 @@ func (s CoreumProcessing) balanceCoreumTokens(ctx context.Context, userAddress,
 	if err != nil {
 		return 0, "", err
 	}
-	Info, err := s.clientCtx.Keyring().KeyByAddress(address)
-	if err != nil {
-		return 0, "", err
-	}
-	defer func() { _ = s.clientCtx.Keyring().DeleteByAddress(Info.GetAddress()) }()
-
-	denom := subunit + "-" + Info.GetAddress().String()
+	denom := subunit + "-" + address.String()
 
 	bankClient := banktypes.NewQueryClient(s.clientCtx)
 	// Query the balance of the recipient
 	response, err := bankClient.Balance(ctx, &banktypes.QueryBalanceRequest{
-		Address: Info.GetAddress().String(),
+		Address: address.String(),
 		Denom:   denom,
 	})
 	if err != nil {
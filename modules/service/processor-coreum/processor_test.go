@@ -0,0 +1,225 @@
+package processor_coreum
+
+import (
+	"context"
+	"coreum_processor/modules/service"
+	"encoding/hex"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+// fakeTxService returns transactions of the chain by their hashes
+type fakeTxService struct {
+	txtypes.ServiceClient
+	txs map[string]*sdk.TxResponse
+}
+
+func (f fakeTxService) GetTx(_ context.Context, in *txtypes.GetTxRequest,
+	_ ...grpc.CallOption) (*txtypes.GetTxResponse, error) {
+	tx, ok := f.txs[in.Hash]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "tx not found: %s", in.Hash)
+	}
+	return &txtypes.GetTxResponse{TxResponse: tx}, nil
+}
+
+// fakeTmService returns the latest block of the chain at the height
+type fakeTmService struct {
+	tmservice.ServiceClient
+	height int64
+}
+
+func (f fakeTmService) GetLatestBlock(context.Context, *tmservice.GetLatestBlockRequest,
+	...grpc.CallOption) (*tmservice.GetLatestBlockResponse, error) {
+	return &tmservice.GetLatestBlockResponse{Block: &tmproto.Block{Header: tmproto.Header{Height: f.height}}}, nil
+}
+
+// fakeMempool returns unconfirmed transactions of the chain
+type fakeMempool struct {
+	rpcclient.MempoolClient
+	txs []tmtypes.Tx
+}
+
+func (f fakeMempool) UnconfirmedTxs(context.Context, *int) (*ctypes.ResultUnconfirmedTxs, error) {
+	return &ctypes.ResultUnconfirmedTxs{Count: len(f.txs), Txs: f.txs}, nil
+}
+
+func TestGetTransactionStatus(t *testing.T) {
+	pending := tmtypes.Tx("pending transaction")
+	pendingHash := hex.EncodeToString(pending.Hash())
+	s := CoreumProcessing{
+		txService: fakeTxService{txs: map[string]*sdk.TxResponse{
+			"FAILED": {Height: 5, Code: 5},
+			"DEEP":   {Height: 5},
+			"RECENT": {Height: 9},
+		}},
+		tmService:       fakeTmService{height: 10},
+		mempool:         fakeMempool{txs: []tmtypes.Tx{pending}},
+		minConfirmation: 3,
+	}
+	tests := []struct {
+		hash string
+		want service.CryptoTransactionStatus
+	}{
+		{hash: "FAILED", want: service.FailedTransaction},
+		{hash: "DEEP", want: service.SuccessfulTransaction},
+		{hash: "RECENT", want: service.PendingTransaction},
+		{hash: pendingHash, want: service.PendingTransaction},
+		{hash: "UNKNOWN", want: service.NoTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			res, err := s.GetTransactionStatus(context.Background(), tt.hash)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestGetTransactionStatusWithoutConfirmations(t *testing.T) {
+	s := CoreumProcessing{
+		txService:       fakeTxService{txs: map[string]*sdk.TxResponse{"INCLUDED": {Height: 10}}},
+		minConfirmation: 1,
+	}
+	res, err := s.GetTransactionStatus(context.Background(), "INCLUDED")
+	require.NoError(t, err)
+	require.Equal(t, service.SuccessfulTransaction, res)
+
+	// without mempool a transaction which isn't in a block is unknown
+	res, err = s.GetTransactionStatus(context.Background(), "UNKNOWN")
+	require.NoError(t, err)
+	require.Equal(t, service.NoTransaction, res)
+}
+
+func TestGetBroadcastStatus(t *testing.T) {
+	s := CoreumProcessing{
+		txService:       fakeTxService{txs: map[string]*sdk.TxResponse{"INCLUDED": {Height: 10}}},
+		tmService:       fakeTmService{height: 20},
+		mempool:         fakeMempool{},
+		minConfirmation: 1,
+	}
+	tests := []struct {
+		name          string
+		hash          string
+		timeoutHeight int64
+		want          service.CryptoTransactionStatus
+	}{
+		{name: "included", hash: "INCLUDED", timeoutHeight: 15, want: service.SuccessfulTransaction},
+		{name: "not expired", hash: "UNKNOWN", timeoutHeight: 20, want: service.PendingTransaction},
+		{name: "expired", hash: "UNKNOWN", timeoutHeight: 19, want: service.NoTransaction},
+		{name: "without timeout", hash: "UNKNOWN", want: service.PendingTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetBroadcastStatus(context.Background(), tt.hash, tt.timeoutHeight)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestBroadcastTimeoutHeight(t *testing.T) {
+	s := CoreumProcessing{tmService: fakeTmService{height: 20}}
+	height, err := s.broadcastTimeoutHeight(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, height)
+
+	// transaction recorded before broadcast is made only with the mempool
+	ctx := service.WithBroadcastIntent(context.Background(), func(string, int64) error { return nil })
+	_, err = s.broadcastTimeoutHeight(ctx)
+	require.Error(t, err)
+
+	s.mempool = fakeMempool{}
+	height, err = s.broadcastTimeoutHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(20+broadcastTimeoutBlocks), height)
+}
+
+func TestCollectDepositsBySender(t *testing.T) {
+	s := CoreumProcessing{denom: "ucore"}
+	token := "token-core1issuer"
+	deposits := s.collectDeposits([]transfer{
+		{recipient: "user", sender: "alice", coins: sdk.NewCoins(sdk.NewInt64Coin(token, 10))},
+		{recipient: "user", sender: "bob", coins: sdk.NewCoins(sdk.NewInt64Coin(token, 20))},
+		{recipient: "user", sender: "alice", coins: sdk.NewCoins(sdk.NewInt64Coin(token, 5))},
+		{recipient: "user", sender: "processing", coins: sdk.NewCoins(sdk.NewInt64Coin("ucore", 100))},
+	})
+	require.Equal(t, []deposit{
+		{recipient: "user", sender: "alice", denom: token, value: sdk.NewInt(15)},
+		{recipient: "user", sender: "bob", denom: token, value: sdk.NewInt(20)},
+	}, deposits)
+}
+
+func TestParseTransfersOfMergedEvents(t *testing.T) {
+	token := "token-core1issuer"
+	tx := &sdk.TxResponse{Logs: sdk.ABCIMessageLogs{
+		{
+			// transfers of two MsgSend in one message are merged to one event
+			Events: sdk.StringEvents{
+				{Type: sdk.EventTypeMessage, Attributes: []sdk.Attribute{
+					{Key: sdk.AttributeKeySender, Value: "alice"},
+					{Key: sdk.AttributeKeySender, Value: "bob"},
+				}},
+				{Type: banktypes.EventTypeTransfer, Attributes: []sdk.Attribute{
+					{Key: banktypes.AttributeKeyRecipient, Value: "user1"},
+					{Key: banktypes.AttributeKeySender, Value: "alice"},
+					{Key: sdk.AttributeKeyAmount, Value: "10" + token},
+					{Key: banktypes.AttributeKeyRecipient, Value: "user2"},
+					{Key: banktypes.AttributeKeySender, Value: "bob"},
+					{Key: sdk.AttributeKeyAmount, Value: "20" + token},
+				}},
+			},
+		},
+		{
+			// outputs of MsgMultiSend don't have the sender, it is the sender of the message
+			Events: sdk.StringEvents{
+				{Type: sdk.EventTypeMessage, Attributes: []sdk.Attribute{
+					{Key: sdk.AttributeKeyAction, Value: "/cosmos.bank.v1beta1.MsgMultiSend"},
+					{Key: sdk.AttributeKeySender, Value: "carol"},
+					{Key: sdk.AttributeKeyModule, Value: "bank"},
+					{Key: sdk.AttributeKeySender, Value: "carol"},
+				}},
+				{Type: banktypes.EventTypeTransfer, Attributes: []sdk.Attribute{
+					{Key: banktypes.AttributeKeyRecipient, Value: "user1"},
+					{Key: sdk.AttributeKeyAmount, Value: "5" + token},
+					{Key: banktypes.AttributeKeyRecipient, Value: "user3"},
+					{Key: sdk.AttributeKeyAmount, Value: "7" + token},
+				}},
+			},
+		},
+	}}
+	coins := func(value int64) sdk.Coins { return sdk.NewCoins(sdk.NewInt64Coin(token, value)) }
+	require.Equal(t, []transfer{
+		{recipient: "user1", sender: "alice", coins: coins(10)},
+		{recipient: "user2", sender: "bob", coins: coins(20)},
+		{recipient: "user1", sender: "carol", coins: coins(5)},
+		{recipient: "user3", sender: "carol", coins: coins(7)},
+	}, parseTransfers(tx))
+}
+
+func TestParseTransfersOfMultiSendWithSeveralInputs(t *testing.T) {
+	tx := &sdk.TxResponse{Logs: sdk.ABCIMessageLogs{{Events: sdk.StringEvents{
+		{Type: sdk.EventTypeMessage, Attributes: []sdk.Attribute{
+			{Key: sdk.AttributeKeySender, Value: "alice"},
+			{Key: sdk.AttributeKeySender, Value: "bob"},
+		}},
+		{Type: banktypes.EventTypeTransfer, Attributes: []sdk.Attribute{
+			{Key: banktypes.AttributeKeyRecipient, Value: "user1"},
+			{Key: sdk.AttributeKeyAmount, Value: "5ucore"},
+		}},
+	}}}}
+	// the sender of the output can't be known, it is found for a refund by the processor
+	require.Equal(t, []transfer{{recipient: "user1", coins: sdk.NewCoins(sdk.NewInt64Coin("ucore", 5))}},
+		parseTransfers(tx))
+}
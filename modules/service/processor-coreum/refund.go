@@ -0,0 +1,98 @@
+package processor_coreum
+
+import (
+	"context"
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/service"
+	"encoding/json"
+	"fmt"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+const depositSenderTxsLimit = 100
+
+// Refund returns funds from the user wallet to the address they were deposited from,
+// network fee is deducted from a refund of the native coin, for tokens the fee is paid by the processing
+func (s CoreumProcessing) Refund(ctx context.Context, request service.TransferRequest,
+	merchantID, externalId string) (*service.RefundResponse, error) {
+	_, key, userWallet, err := s.store.GetByUser(merchantID, externalId)
+	if err != nil {
+		return nil, fmt.Errorf("can't get user: %v coreum wallet from store, err: %w", externalId, err)
+	}
+	wallet := service.Wallet{}
+	err = json.Unmarshal(userWallet, &wallet)
+	if err != nil {
+		return nil, fmt.Errorf("can't unmarshal user wallet: %v, err: %w", key, err)
+	}
+
+	denom := s.denom
+	if request.Asset != "" && request.Asset != s.denom {
+		denom = fmt.Sprintf("%s-%s", request.Asset, request.Issuer)
+	}
+	address := request.Address
+	if address == "" {
+		if address, err = s.getDepositSender(ctx, key, denom); err != nil {
+			return nil, err
+		}
+	}
+
+	value := request.Amount
+	commission := amount.Zero()
+	coins, err := newCoins(denom, value)
+	if err != nil {
+		return nil, err
+	}
+	msg := &banktypes.MsgSend{
+		FromAddress: key,
+		ToAddress:   address,
+		Amount:      coins,
+	}
+	if denom == s.denom {
+		commission = amount.NewFromInt64(s.fee.Estimate(ctx, coreumFeeSendFT, msg))
+		value = value.Sub(commission)
+		if !value.IsPositive() {
+			return nil, fmt.Errorf("refund amount: %v doesn't cover network fee: %v", request.Amount, commission)
+		}
+		if msg.Amount, err = newCoins(denom, value); err != nil {
+			return nil, err
+		}
+	} else {
+		//check gas
+		_, err = s.updateGas(ctx, key, coreumFeeSendFT, msg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result, err := s.broadcastTrx(ctx, merchantID, externalId, "refund", key, wallet, msg)
+	if err != nil {
+		return nil, fmt.Errorf("can't broadcast refund to: %v, err: %w", address, err)
+	}
+	return &service.RefundResponse{TransferHash: result.TxHash, Address: address, Commission: commission}, nil
+}
+
+// getDepositSender returns an address of the latest transfer of the denom to the address
+func (s CoreumProcessing) getDepositSender(ctx context.Context, address, denom string) (string, error) {
+	event := fmt.Sprintf("%s.%s='%s'", banktypes.EventTypeTransfer, banktypes.AttributeKeyRecipient, address)
+	res, err := s.txService.GetTxsEvent(ctx, &txtypes.GetTxsEventRequest{
+		Events:     []string{event},
+		Pagination: &query.PageRequest{Limit: depositSenderTxsLimit},
+		OrderBy:    txtypes.OrderBy_ORDER_BY_DESC,
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't get transfers to: %v, err: %w", address, err)
+	}
+	for _, tx := range res.TxResponses {
+		if tx.Code != 0 {
+			continue
+		}
+		for _, d := range sumTransfers(parseTransfers(tx)) {
+			if d.recipient == address && d.sender != s.sendingWallet.WalletAddress && d.denom == denom &&
+				d.value.IsPositive() {
+				return d.sender, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("can't find sender of: %v to: %v", denom, address)
+}
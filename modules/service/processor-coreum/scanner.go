@@ -0,0 +1,261 @@
+package processor_coreum
+
+import (
+	"context"
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"errors"
+	"fmt"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	scannerBlocksBatch = 50
+	scannerTxsLimit    = 100
+)
+
+// transfer is a transfer of coins decoded from events of a transaction
+type transfer struct {
+	recipient string
+	sender    string
+	coins     sdk.Coins
+}
+
+// deposit is a sum of transfers of the denom from the sender to the recipient in one transaction
+type deposit struct {
+	recipient string
+	sender    string
+	denom     string
+	value     sdk.Int
+}
+
+// streamBlocks follows new blocks and informs about transfers to user wallets found in them,
+// height of the last processed block is kept in the cursor so blocks are not missed after restart
+func (s CoreumProcessing) streamBlocks(ctx context.Context, callback service.FuncDepositCallback,
+	interval time.Duration) {
+	ticker := time.NewTicker(time.Second * interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("exit from coreum processor block scanner")
+			return
+		case <-ticker.C:
+			if !s.elector.IsLeader(s.scannerLease()) {
+				continue
+			}
+			if err := s.scanBlocks(ctx, callback); err != nil {
+				log.Println(fmt.Sprintf("can't scan blocks for deposits, err: %v", err))
+			}
+		}
+	}
+}
+
+// scanBlocks processes blocks after the cursor up to the latest block, the scan starts from the configured
+// height if there is no cursor yet
+func (s CoreumProcessing) scanBlocks(ctx context.Context, callback service.FuncDepositCallback) error {
+	latest, err := s.latestHeight(ctx)
+	if err != nil {
+		return err
+	}
+	cursor, err := s.cursors.Get(s.scannerLease())
+	if errors.Is(err, storage.ErrNotFound) {
+		if s.scannerStart <= 0 {
+			return fmt.Errorf("block scanner: %v doesn't have cursor and start height is not set", s.scannerLease())
+		}
+		log.Println(fmt.Sprintf("block scanner: %v starts from the block: %v", s.scannerLease(), s.scannerStart))
+		cursor = s.scannerStart - 1
+	} else if err != nil {
+		return err
+	}
+	for height := cursor + 1; height <= latest && height <= cursor+scannerBlocksBatch; height++ {
+		if ctx.Err() != nil || !s.elector.IsLeader(s.scannerLease()) {
+			return nil
+		}
+		if err = s.scanBlock(ctx, height, callback); err != nil {
+			return fmt.Errorf("can't scan block: %v, err: %w", height, err)
+		}
+		if err = s.cursors.Set(s.scannerLease(), height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanBlock informs about deposits to user wallets made by successful transactions of the block
+func (s CoreumProcessing) scanBlock(ctx context.Context, height int64, callback service.FuncDepositCallback) error {
+	for offset := uint64(0); ; offset += scannerTxsLimit {
+		res, err := s.txService.GetTxsEvent(ctx, &txtypes.GetTxsEventRequest{
+			Events:     []string{fmt.Sprintf("tx.height=%d", height)},
+			Pagination: &query.PageRequest{Offset: offset, Limit: scannerTxsLimit},
+			OrderBy:    txtypes.OrderBy_ORDER_BY_ASC,
+		})
+		if err != nil {
+			return fmt.Errorf("can't get transactions, err: %w", err)
+		}
+		for i, tx := range res.TxResponses {
+			if tx.Code != 0 {
+				continue
+			}
+			memo := ""
+			if i < len(res.Txs) && res.Txs[i].Body != nil {
+				memo = res.Txs[i].Body.Memo
+			}
+			for _, d := range s.collectDeposits(parseTransfers(tx)) {
+				if err = s.informDeposit(d, tx.TxHash, memo, callback); err != nil {
+					return err
+				}
+			}
+		}
+		if len(res.TxResponses) < scannerTxsLimit {
+			return nil
+		}
+	}
+}
+
+// collectDeposits sums transfers of tokens by recipient, sender and denom, native coin is sent to user wallets
+// by the processing as gas, so it isn't a deposit
+func (s CoreumProcessing) collectDeposits(transfers []transfer) []deposit {
+	var deposits []deposit
+	for _, d := range sumTransfers(transfers) {
+		if d.denom != s.denom {
+			deposits = append(deposits, d)
+		}
+	}
+	return deposits
+}
+
+// sumTransfers sums transfers by recipient, sender and denom, so every sender of a transaction
+// with several senders gets its own deposit
+func sumTransfers(transfers []transfer) []deposit {
+	var deposits []deposit
+	for _, t := range transfers {
+		for _, coin := range t.coins {
+			found := false
+			for i := range deposits {
+				if deposits[i].recipient == t.recipient && deposits[i].sender == t.sender &&
+					deposits[i].denom == coin.Denom {
+					deposits[i].value = deposits[i].value.Add(coin.Amount)
+					found = true
+					break
+				}
+			}
+			if !found {
+				deposits = append(deposits, deposit{recipient: t.recipient, sender: t.sender,
+					denom: coin.Denom, value: coin.Amount})
+			}
+		}
+	}
+	return deposits
+}
+
+// informDeposit calls the callback if the deposit is made to a user wallet, an error is returned
+// if the deposit can't be checked or recorded
+func (s CoreumProcessing) informDeposit(d deposit, hash, memo string, callback service.FuncDepositCallback) error {
+	record, err := s.store.GetRecordByKey(d.recipient)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't get wallet: %v to inform about deposit: %v, err: %w", d.recipient, hash, err)
+	}
+	if s.isMerchantWallet(*record) {
+		// transfers between wallets of the processing aren't deposits,
+		// other transfers to merchant wallets can be deposits with memo
+		managed, err := s.isManagedWallet(d.sender)
+		if err != nil || managed {
+			return err
+		}
+	}
+	asset, issuer, _ := strings.Cut(d.denom, "-")
+	err = callback(s.blockchain, record.MerchantID, record.ExternalID, d.sender, hash, memo, asset, issuer,
+		amount.NewFromInt(d.value))
+	if err != nil {
+		return fmt.Errorf("can't inform about deposit: %v to wallet: %v, err: %w", hash, d.recipient, err)
+	}
+	return nil
+}
+
+// isManagedWallet checks if the address is a wallet of the processing
+func (s CoreumProcessing) isManagedWallet(address string) (bool, error) {
+	if address == s.sendingWallet.WalletAddress || address == s.receivingWallet.WalletAddress {
+		return true, nil
+	}
+	_, err := s.store.GetRecordByKey(address)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("can't get wallet: %v to check sender of deposit, err: %w", address, err)
+	}
+	return true, nil
+}
+
+// scannerLease returns name of the lease to scan blocks, it is also name of the scanner cursor
+func (s CoreumProcessing) scannerLease() string {
+	return s.blockchain + "-block-scanner"
+}
+
+// parseTransfers returns transfers made by the transaction from its transfer events. Events of the same type
+// are merged in the log of a message, so attributes of every transfer go one after another: recipient, sender
+// and amount. Transfers to outputs of MsgMultiSend don't have the sender, it is taken from the message event
+func parseTransfers(tx *sdk.TxResponse) []transfer {
+	var transfers []transfer
+	for _, txLog := range tx.Logs {
+		msgSender := messageSender(txLog)
+		for _, event := range txLog.Events {
+			if event.Type != banktypes.EventTypeTransfer {
+				continue
+			}
+			var recipient, sender string
+			for _, attr := range event.Attributes {
+				switch attr.Key {
+				case banktypes.AttributeKeyRecipient:
+					recipient = attr.Value
+				case banktypes.AttributeKeySender:
+					sender = attr.Value
+				case sdk.AttributeKeyAmount:
+					if sender == "" {
+						sender = msgSender
+					}
+					coins, err := sdk.ParseCoinsNormalized(attr.Value)
+					if err != nil {
+						log.Println(fmt.Sprintf("can't parse amount: %v of transaction: %v, err: %v",
+							attr.Value, tx.TxHash, err))
+					} else {
+						transfers = append(transfers, transfer{recipient: recipient, sender: sender, coins: coins})
+					}
+					// the next transfer of the merged event has its own recipient and sender
+					recipient, sender = "", ""
+				}
+			}
+		}
+	}
+	return transfers
+}
+
+// messageSender returns sender of the message from its message event, empty sender is returned
+// if the message has several senders like MsgMultiSend with several inputs
+func messageSender(txLog sdk.ABCIMessageLog) string {
+	sender := ""
+	for _, event := range txLog.Events {
+		if event.Type != sdk.EventTypeMessage {
+			continue
+		}
+		for _, attr := range event.Attributes {
+			if attr.Key != sdk.AttributeKeySender || attr.Value == sender {
+				continue
+			}
+			if sender != "" {
+				return ""
+			}
+			sender = attr.Value
+		}
+	}
+	return sender
+}
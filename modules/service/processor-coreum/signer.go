@@ -0,0 +1,191 @@
+package processor_coreum
+
+import (
+	"bytes"
+	"context"
+	"coreum_processor/modules/service"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const remoteSignerTimeout = 30 * time.Second
+
+// Signer signs transactions of processing accounts, so key material of the accounts
+// can be kept outside of the processing service
+type Signer interface {
+	// PubKey returns public key of the account
+	PubKey(ctx context.Context, account service.Wallet) (cryptotypes.PubKey, error)
+	// Sign returns signature of the sign bytes of an unsigned transaction made by the account
+	Sign(ctx context.Context, account service.Wallet, signBytes []byte) ([]byte, error)
+}
+
+// InProcessSigner derives private key of the account from its mnemonic for every signature
+type InProcessSigner struct{}
+
+// NewInProcessSigner creates signer which uses mnemonics of the wallets
+func NewInProcessSigner() *InProcessSigner {
+	return &InProcessSigner{}
+}
+
+func (s *InProcessSigner) PubKey(_ context.Context, account service.Wallet) (cryptotypes.PubKey, error) {
+	privKey, err := s.privKey(account)
+	if err != nil {
+		return nil, err
+	}
+	return privKey.PubKey(), nil
+}
+
+func (s *InProcessSigner) Sign(_ context.Context, account service.Wallet, signBytes []byte) ([]byte, error) {
+	privKey, err := s.privKey(account)
+	if err != nil {
+		return nil, err
+	}
+	return privKey.Sign(signBytes)
+}
+
+func (s *InProcessSigner) privKey(account service.Wallet) (cryptotypes.PrivKey, error) {
+	if account.WalletSeed == "" {
+		return nil, fmt.Errorf("mnemonic of wallet: %v is not available", account.WalletAddress)
+	}
+	derivedPriv, err := hd.Secp256k1.Derive()(account.WalletSeed, "", sdk.GetConfig().GetFullBIP44Path())
+	if err != nil {
+		return nil, fmt.Errorf("can't derive private key for wallet: %v, error: %w", account.WalletAddress, err)
+	}
+	return hd.Secp256k1.Generate()(derivedPriv), nil
+}
+
+// KeyringSigner signs with keys of the cosmos keyring stored in files protected by the passphrase
+type KeyringSigner struct {
+	keyring keyring.Keyring
+}
+
+// NewKeyringSigner opens the file keyring in the directory
+func NewKeyringSigner(appName, dir, passphrase string) (*KeyringSigner, error) {
+	// passphrase is asked twice if the keyring is created
+	input := strings.NewReader(passphrase + "\n" + passphrase + "\n")
+	kr, err := keyring.New(appName, keyring.BackendFile, dir, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't open keyring in: %v, error: %w", dir, err)
+	}
+	return &KeyringSigner{keyring: kr}, nil
+}
+
+func (s *KeyringSigner) PubKey(_ context.Context, account service.Wallet) (cryptotypes.PubKey, error) {
+	address, err := sdk.AccAddressFromBech32(account.WalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("can't get address for account: %v, error: %w", account.WalletAddress, err)
+	}
+	info, err := s.keyring.KeyByAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("can't find key for account: %v in keyring, error: %w", account.WalletAddress, err)
+	}
+	return info.GetPubKey(), nil
+}
+
+func (s *KeyringSigner) Sign(_ context.Context, account service.Wallet, signBytes []byte) ([]byte, error) {
+	address, err := sdk.AccAddressFromBech32(account.WalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("can't get address for account: %v, error: %w", account.WalletAddress, err)
+	}
+	signature, _, err := s.keyring.SignByAddress(address, signBytes)
+	if err != nil {
+		return nil, fmt.Errorf("can't sign by account: %v in keyring, error: %w", account.WalletAddress, err)
+	}
+	return signature, nil
+}
+
+// RemoteSigner asks the signing service over HTTP to sign transactions,
+// the service keeps keys of the accounts and is authorized by the bearer token
+type RemoteSigner struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// RemoteSignRequest is sent to the signing service, sign bytes are omitted to get public key of the account
+type RemoteSignRequest struct {
+	Address   string `json:"address"`
+	SignBytes string `json:"sign_bytes,omitempty"`
+}
+
+// RemoteSignResponse is returned by the signing service, values are base64 encoded
+type RemoteSignResponse struct {
+	PubKey    string `json:"pub_key"`
+	Signature string `json:"signature"`
+}
+
+// NewRemoteSigner creates signer for the signing service at the url
+func NewRemoteSigner(url, token string) *RemoteSigner {
+	return &RemoteSigner{
+		url:    strings.TrimRight(url, "/"),
+		token:  token,
+		client: &http.Client{Timeout: remoteSignerTimeout},
+	}
+}
+
+func (s *RemoteSigner) PubKey(ctx context.Context, account service.Wallet) (cryptotypes.PubKey, error) {
+	res, err := s.call(ctx, "/pubkey", RemoteSignRequest{Address: account.WalletAddress})
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(res.PubKey)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode public key of account: %v, error: %w", account.WalletAddress, err)
+	}
+	if len(key) != secp256k1.PubKeySize {
+		return nil, fmt.Errorf("incorrect public key of account: %v", account.WalletAddress)
+	}
+	return &secp256k1.PubKey{Key: key}, nil
+}
+
+func (s *RemoteSigner) Sign(ctx context.Context, account service.Wallet, signBytes []byte) ([]byte, error) {
+	res, err := s.call(ctx, "/sign", RemoteSignRequest{
+		Address:   account.WalletAddress,
+		SignBytes: base64.StdEncoding.EncodeToString(signBytes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode signature of account: %v, error: %w", account.WalletAddress, err)
+	}
+	return signature, nil
+}
+
+func (s *RemoteSigner) call(ctx context.Context, path string, request RemoteSignRequest) (*RemoteSignResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+path, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("can't call signer for account: %v, error: %w", request.Address, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signer returned status: %v for account: %v", resp.StatusCode, request.Address)
+	}
+	res := RemoteSignResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("can't decode signer response for account: %v, error: %w", request.Address, err)
+	}
+	return &res, nil
+}
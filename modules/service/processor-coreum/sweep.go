@@ -0,0 +1,143 @@
+package processor_coreum
+
+import (
+	"context"
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/service"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"fmt"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"log"
+	"time"
+)
+
+const sweepWalletsBatch = 100
+
+// StreamSweep periodically returns gas left in user wallets to the sending wallet of the processing,
+// sweep is disabled if the interval is not set
+func (s CoreumProcessing) StreamSweep(ctx context.Context, callback service.FuncSweepCallback) {
+	if s.sweepInterval <= 0 {
+		return
+	}
+	go s.elector.Run(ctx, s.sweepLease())
+	go s.streamSweep(ctx, callback)
+}
+
+func (s CoreumProcessing) streamSweep(ctx context.Context, callback service.FuncSweepCallback) {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("exit from coreum processor gas sweep")
+			return
+		case <-ticker.C:
+			if !s.elector.IsLeader(s.sweepLease()) {
+				continue
+			}
+			s.sweep(ctx, callback)
+		}
+	}
+}
+
+// sweepLease returns name of the lease to sweep gas, only one replica sweeps wallets
+func (s CoreumProcessing) sweepLease() string {
+	return s.blockchain + "-sweep"
+}
+
+// sweep goes through all user wallets and returns their gas above the dust threshold
+func (s CoreumProcessing) sweep(ctx context.Context, callback service.FuncSweepCallback) {
+	next := int64(0)
+	for ctx.Err() == nil {
+		records, err := s.store.GetNext(next, sweepWalletsBatch)
+		if err != nil {
+			log.Println("error while getting wallets to sweep gas:", err)
+			return
+		}
+		if len(records) == 0 {
+			return
+		}
+		for _, record := range records {
+			next = record.ID
+			if s.isMerchantWallet(record) {
+				// merchant wallets keep their gas
+				continue
+			}
+			hash, value, err := s.sweepWallet(ctx, record)
+			if err != nil {
+				log.Println(fmt.Sprintf("can't sweep gas from wallet: %v, err: %v", record.Key, err))
+				continue
+			}
+			if hash != "" {
+				callback(s.blockchain, record.MerchantID, record.ExternalID, s.sendingWallet.WalletAddress,
+					hash, s.denom, value)
+			}
+		}
+	}
+}
+
+// isMerchantWallet checks if the wallet of the record is the sending or receiving wallet of its merchant,
+// the wallet is treated as a merchant one if it can't be checked
+func (s CoreumProcessing) isMerchantWallet(record storage.KeyRecord) bool {
+	if s.callBack == nil {
+		return true
+	}
+	merchant, err := s.callBack.IsMerchantWallet(s.blockchain, record.MerchantID, record.ExternalID)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't check if wallet: %v is of merchant: %v, err: %v",
+			record.Key, record.MerchantID, err))
+		return true
+	}
+	return merchant
+}
+
+// sweepWallet transfers gas of the wallet to the sending wallet keeping the fee of the transfer,
+// wallets holding tokens are skipped as their gas is required to transfer the tokens to receiving wallet
+func (s CoreumProcessing) sweepWallet(ctx context.Context,
+	record storage.KeyRecord) (string, amount.Amount, error) {
+	balances, err := s.GetAssetsBalance(ctx, service.BalanceRequest{Blockchain: s.blockchain},
+		record.MerchantID, record.ExternalID)
+	if err != nil {
+		return "", amount.Zero(), err
+	}
+	gas := amount.Zero()
+	for _, balance := range balances {
+		if balance.Asset != s.denom && balance.Amount.IsPositive() {
+			return "", amount.Zero(), nil
+		}
+		if balance.Asset == s.denom {
+			gas = balance.Amount
+		}
+	}
+	coins, err := newCoins(s.denom, gas)
+	if err != nil {
+		return "", amount.Zero(), err
+	}
+	// fee of the sweep is paid from the swept gas
+	fee := s.fee.Estimate(ctx, coreumFeeSendFT, &banktypes.MsgSend{
+		FromAddress: record.Key,
+		ToAddress:   s.sendingWallet.WalletAddress,
+		Amount:      coins,
+	})
+	value := gas.Sub(amount.NewFromInt64(fee))
+	if !value.GT(s.sweepDust) {
+		return "", amount.Zero(), nil
+	}
+
+	wallet := service.Wallet{}
+	err = json.Unmarshal(record.Data, &wallet)
+	if err != nil {
+		return "", amount.Zero(), err
+	}
+	swept, err := value.Int()
+	if err != nil {
+		return "", amount.Zero(), err
+	}
+	hash, err := s.transferCoreumFT(ctx, record.MerchantID, record.ExternalID, "gas-sweep",
+		record.Key, s.sendingWallet.WalletAddress, s.denom, wallet, swept)
+	if err != nil {
+		return "", amount.Zero(), err
+	}
+	return hash, value, nil
+}
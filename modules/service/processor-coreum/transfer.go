@@ -5,9 +5,7 @@ import (
 	"coreum_processor/modules/service"
 	"encoding/json"
 	"fmt"
-	"github.com/CoreumFoundation/coreum/v2/pkg/client"
 	"github.com/CoreumFoundation/coreum/v2/x/nft"
-	"github.com/cosmos/cosmos-sdk/crypto/hd"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 )
@@ -41,15 +39,24 @@ func (s CoreumProcessing) TransferFT(ctx context.Context,
 		return "", err
 	}
 
-	_, err = s.updateGas(ctx, sendingWallet.WalletAddress, coreumFeeSendFT)
+	denom := fmt.Sprintf("%s-%s", request.Subunit, request.Issuer)
+	value, err := request.Amount.Int()
+	if err != nil {
+		return "", err
+	}
+	msgSend := &banktypes.MsgSend{
+		FromAddress: key,
+		ToAddress:   receivingWallet.WalletAddress,
+		Amount:      sdk.NewCoins(sdk.NewCoin(denom, value)),
+	}
+	_, err = s.updateGas(ctx, sendingWallet.WalletAddress, coreumFeeSendFT, msgSend)
 	if err != nil {
 		return "", nil
 	}
 
-	denom := fmt.Sprintf("%s-%s", request.Subunit, request.Issuer)
 	//@ToDo multiply request.amount by the amount of decimals
 	res, err := s.transferCoreumFT(ctx, merchantID, SendingExternalId, "transfer-"+denom, key,
-		receivingWallet.WalletAddress, denom, sendingWallet, int64(request.Amount))
+		receivingWallet.WalletAddress, denom, sendingWallet, value)
 
 	if err != nil {
 		return "", err
@@ -86,26 +93,9 @@ func (s CoreumProcessing) TransferNFT(ctx context.Context,
 		return "", err
 	}
 
-	senderInfo, err := s.clientCtx.Keyring().NewAccount(
-		sendingWallet.WalletAddress,
-		sendingWallet.WalletSeed,
-		"",
-		sdk.GetConfig().GetFullBIP44Path(),
-		hd.Secp256k1,
-	)
-	defer func() { _ = s.clientCtx.Keyring().DeleteByAddress(senderInfo.GetAddress()) }()
-	if err != nil {
-		return "", nil
-	}
-
-	_, err = s.updateGas(ctx, sendingWallet.WalletAddress, coreumFeeSendFT)
-	if err != nil {
-		return "", nil
-	}
-
 	//@ToDo how to use nft id and class id
-	res, err := s.transferCoreumNFT(ctx,
-		sendingWallet.WalletAddress, receivingWallet.WalletAddress, request.NftId, request.NftClassId)
+	res, err := s.transferCoreumNFT(ctx, sendingWallet, receivingWallet.WalletAddress, request.NftId,
+		request.NftClassId)
 
 	if err != nil {
 		return "", err
@@ -113,30 +103,21 @@ func (s CoreumProcessing) TransferNFT(ctx context.Context,
 	return res, nil
 }
 
-func (s CoreumProcessing) transferCoreumNFT(ctx context.Context,
-	senderAddress, recipientAddress, nftId, classId string) (string, error) {
-
-	address, err := sdk.AccAddressFromBech32(senderAddress)
-	if err != nil {
-		return "", err
-	}
-	senderInfo, err := s.clientCtx.Keyring().KeyByAddress(address)
-	if err != nil {
-		return "", err
-	}
+func (s CoreumProcessing) transferCoreumNFT(ctx context.Context, sendingWallet service.Wallet,
+	recipientAddress, nftId, classId string) (string, error) {
 
+	senderAddress := sendingWallet.WalletAddress
 	msgSend := &nft.MsgSend{
 		Sender:   senderAddress,
 		Receiver: recipientAddress,
 		Id:       nftId,
 		ClassId:  classId,
 	}
-	response, err := client.BroadcastTx(
-		ctx,
-		s.clientCtx.WithFromAddress(senderInfo.GetAddress()),
-		s.factory,
-		msgSend,
-	)
+	_, err := s.updateGas(ctx, senderAddress, coreumFeeSendFT, msgSend)
+	if err != nil {
+		return "", err
+	}
+	response, err := s.signAndBroadcast(ctx, sendingWallet, msgSend)
 	if err != nil {
 		fmt.Println(err)
 		return "", err
@@ -146,12 +127,12 @@ func (s CoreumProcessing) transferCoreumNFT(ctx context.Context,
 
 func (s CoreumProcessing) transferCoreumFT(ctx context.Context, merchantID, externalID, trxID,
 	senderAddress, recipientAddress, denom string, sendingWallet service.Wallet,
-	amount int64) (string, error) {
+	amount sdk.Int) (string, error) {
 
 	msgSend := &banktypes.MsgSend{
 		FromAddress: senderAddress,
 		ToAddress:   recipientAddress,
-		Amount:      sdk.NewCoins(sdk.NewInt64Coin(denom, amount)),
+		Amount:      sdk.NewCoins(sdk.NewCoin(denom, amount)),
 	}
 	trx, err := s.broadcastTrx(ctx, merchantID, externalID, trxID, senderAddress, sendingWallet, msgSend)
 
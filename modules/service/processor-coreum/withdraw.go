@@ -2,20 +2,20 @@ package processor_coreum
 
 import (
 	"context"
+	"coreum_processor/modules/amount"
 	"coreum_processor/modules/service"
 	"encoding/json"
 	"fmt"
-	sdk "github.com/cosmos/cosmos-sdk/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 )
 
 func (s CoreumProcessing) Withdraw(ctx context.Context,
 	request service.CredentialWithdraw, merchantID, externalId, trxID string,
 	merchantWallets service.Wallets) (*service.WithdrawResponse, error) {
-	commission := 0.0
+	commission := amount.Zero()
 	if externalId != merchantWallets.ReceivingID || externalId != merchantWallets.SendingID {
 		commission = merchantWallets.CommissionSending.Fix
-		commission += merchantWallets.CommissionSending.Percent / 100. * (request.Amount - commission)
+		commission = commission.Add(request.Amount.Sub(commission).Percent(merchantWallets.CommissionSending.Percent))
 	}
 	denom := s.denom
 	if request.Asset == "" {
@@ -30,7 +30,7 @@ func (s CoreumProcessing) Withdraw(ctx context.Context,
 		return nil, fmt.Errorf("can't get merchant: %v, sending wallet: %v, err: %w",
 			merchantID, merchantWallets.SendingID, err)
 	}
-	if balance[0].Amount < request.Amount+commission {
+	if balance[0].Amount.LT(request.Amount.Add(commission)) {
 		return nil, fmt.Errorf("merchant: %s, doesn't have enough balance to pay: %v %v, with commission: %v",
 			merchantID, request.Amount, request.Asset, commission)
 	}
@@ -45,16 +45,19 @@ func (s CoreumProcessing) Withdraw(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
-	//check gas
-	_, err = s.updateGas(ctx, key, coreumFeeSendFT)
+	coins, err := newCoins(denom, request.Amount)
 	if err != nil {
 		return nil, err
 	}
-
 	msg := &banktypes.MsgSend{
 		FromAddress: key,
 		ToAddress:   s.sendingWallet.WalletAddress,
-		Amount:      sdk.NewCoins(sdk.NewInt64Coin(denom, int64(request.Amount))),
+		Amount:      coins,
+	}
+	//check gas
+	_, err = s.updateGas(ctx, key, coreumFeeSendFT, msg)
+	if err != nil {
+		return nil, err
 	}
 	result, err := s.broadcastTrx(ctx, merchantID, externalId, trxID, msg.FromAddress, sendingWallet, msg)
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	"embed"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	encoder "github.com/golang-jwt/jwt"
 	"github.com/lestrrat-go/jwx/jwa"
@@ -36,13 +37,32 @@ type ProcessingService struct {
 	merchants        *Merchants
 	callBack         *CallBacks
 	transactionStore *storage.TransactionPSQL
+	idempotencyStore *storage.IdempotencyPSQL
+	jobStore         *storage.JobsPSQL
+	memoStore        *storage.MemosPSQL
+	quarantineStore  *storage.QuarantinePSQL
+	webhookStore     *storage.WebhooksPSQL
+	endpointStore    *storage.EndpointsPSQL
+	assetStore       *storage.AssetPSQL
+	ledgerStore      *storage.LedgerPSQL
+	approvalStore    *storage.ApprovalsPSQL
+	addressStore     *storage.AddressesPSQL
+	limitStore       *storage.LimitsPSQL
 	userStorage      *storage.UserStore
+	// limitsFailOpen makes deposits processed without holds if limits can't be checked or deposits can't be held
+	limitsFailOpen bool
 }
 
 // NewProcessingService create a service to process transaction by provided crypto processor
 func NewProcessingService(publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey,
 	tokenTimeToLive int, processors map[string]CryptoProcessor,
-	merchants *Merchants, callBack *CallBacks, transactionStore *storage.TransactionPSQL) *ProcessingService {
+	merchants *Merchants, callBack *CallBacks, transactionStore *storage.TransactionPSQL,
+	idempotencyStore *storage.IdempotencyPSQL, jobStore *storage.JobsPSQL,
+	memoStore *storage.MemosPSQL, quarantineStore *storage.QuarantinePSQL,
+	webhookStore *storage.WebhooksPSQL, endpointStore *storage.EndpointsPSQL,
+	assetStore *storage.AssetPSQL, ledgerStore *storage.LedgerPSQL,
+	approvalStore *storage.ApprovalsPSQL, addressStore *storage.AddressesPSQL,
+	limitStore *storage.LimitsPSQL, limitsFailOpen bool) *ProcessingService {
 	return &ProcessingService{
 		publicKey:        publicKey,
 		privateKey:       privateKey,
@@ -51,6 +71,18 @@ func NewProcessingService(publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey,
 		merchants:        merchants,
 		callBack:         callBack,
 		transactionStore: transactionStore,
+		idempotencyStore: idempotencyStore,
+		jobStore:         jobStore,
+		memoStore:        memoStore,
+		quarantineStore:  quarantineStore,
+		webhookStore:     webhookStore,
+		endpointStore:    endpointStore,
+		assetStore:       assetStore,
+		ledgerStore:      ledgerStore,
+		approvalStore:    approvalStore,
+		addressStore:     addressStore,
+		limitStore:       limitStore,
+		limitsFailOpen:   limitsFailOpen,
 	}
 }
 
@@ -60,6 +92,12 @@ func (s ProcessingService) ListenAndServe(ctx context.Context, interval time.Dur
 			continue
 		}
 		processor.StreamDeposit(ctx, s.makeDepositCallback(), interval)
+		processor.StreamSweep(ctx, s.makeSweepCallback())
+	}
+	s.enqueuePendingTransactions()
+	go s.streamWebhooks(ctx, interval)
+	if s.approvalStore != nil {
+		go s.streamApprovalExpiry(ctx, interval)
 	}
 	ticker := time.NewTicker(time.Second * interval)
 	for {
@@ -68,7 +106,7 @@ func (s ProcessingService) ListenAndServe(ctx context.Context, interval time.Dur
 			log.Println("exit from crypto processing")
 			return nil
 		case <-ticker.C:
-			s.processTransaction(ctx)
+			s.processJobs(ctx, time.Second*interval)
 		}
 	}
 }
@@ -205,10 +243,20 @@ func (s ProcessingService) GetMerchants() ([]MerchantData, error) {
 }
 
 func (s ProcessingService) CreateMerchants(guid string, merchant MerchantData) (int64, error) {
+	if merchant.CallBackURL != "" {
+		if err := ValidateCallBackURL(merchant.CallBackURL); err != nil {
+			return 0, err
+		}
+	}
 	return s.merchants.CreateMerchantData(guid, merchant)
 }
 
 func (s ProcessingService) UpdateMerchant(guid string, merchant NewMerchant) (string, error) {
+	if merchant.Callback != "" {
+		if err := ValidateCallBackURL(merchant.Callback); err != nil {
+			return "", err
+		}
+	}
 	return s.merchants.UpdateMerchantData(guid, merchant)
 }
 
@@ -234,12 +282,12 @@ func (s ProcessingService) UpdateMerchantCommission(ctx context.Context, guid, b
 		return Wallets{}, fmt.Errorf("%s blockchain not found", blockchain)
 	}
 
-	_, err = processor.Deposit(ctx, CredentialDeposit{Blockchain: blockchain, Amount: 0}, guid, wallets.SendingID)
+	_, err = processor.Deposit(ctx, CredentialDeposit{Blockchain: blockchain}, guid, wallets.SendingID)
 	if err != nil {
 		return Wallets{}, err
 	}
 	//wallets.SendingID = res.WalletAddress
-	_, err = processor.Deposit(ctx, CredentialDeposit{Blockchain: blockchain, Amount: 0}, guid, wallets.ReceivingID)
+	_, err = processor.Deposit(ctx, CredentialDeposit{Blockchain: blockchain}, guid, wallets.ReceivingID)
 	if err != nil {
 		return Wallets{}, err
 	}
@@ -251,7 +299,7 @@ func (s ProcessingService) CreateWallet(ctx context.Context,
 	blockchain, merchantID, externalID string) (*Wallet, error) {
 	processor, ok := s.processors[blockchain]
 	if !ok {
-		return nil, fmt.Errorf("%s blockchain not found")
+		return nil, fmt.Errorf("%s blockchain not found", blockchain)
 	}
 	response, err := processor.CreateWallet(ctx, merchantID, externalID)
 	if err != nil {
@@ -268,6 +316,10 @@ func (s ProcessingService) Deposit(ctx context.Context,
 		return nil, fmt.Errorf("%s blockchain not found", deposit.Blockchain)
 	}
 
+	if wallet, ok := s.getMemoWallet(merchantID, deposit.Blockchain); ok {
+		return s.depositWithMemo(ctx, processor, deposit, merchantID, externalId, wallet)
+	}
+
 	response, err := processor.Deposit(ctx, deposit, merchantID, externalId)
 	if err != nil {
 		return nil, fmt.Errorf("could not perform deposit: %s", err)
@@ -291,9 +343,11 @@ func (s ProcessingService) TransferMerchantWallets(ctx context.Context,
 	return response, nil
 }
 
-func (s ProcessingService) InitWithdraw(withdraw CredentialWithdraw,
+// InitWithdraw creates withdraw for the user after pre-flight checks of the request,
+// violations of the checks are returned as WithdrawValidationError
+func (s ProcessingService) InitWithdraw(ctx context.Context, withdraw CredentialWithdraw,
 	merchantID, externalId string) (*WithdrawResponse, error) {
-	_, ok := s.processors[withdraw.Blockchain]
+	processor, ok := s.processors[withdraw.Blockchain]
 	if !ok {
 		return nil, fmt.Errorf("%s blockchain not found", withdraw.Blockchain)
 	}
@@ -301,39 +355,80 @@ func (s ProcessingService) InitWithdraw(withdraw CredentialWithdraw,
 	if err != nil {
 		return nil, err
 	}
-	_, ok = merchData.Wallets[withdraw.Blockchain]
+	wallet, ok := merchData.Wallets[withdraw.Blockchain]
 	if !ok {
 		return nil, fmt.Errorf("%s blockchain not found for mercchant: %s", withdraw.Blockchain, merchantID)
 	}
-	guid, err := s.transactionStore.CreateTransaction(merchantID, externalId, withdraw.Blockchain,
-		storage.WithdrawTransaction,
-		withdraw.WalletAddress, "", withdraw.Asset, withdraw.Issuer, withdraw.Amount, 0)
-	return &WithdrawResponse{TransactionHash: guid}, err
-}
-
-func (s ProcessingService) UpdateWithdraw(transactionID, merchantID, externalId, hash string) error {
-	merchant, err := s.merchants.GetMerchantData(merchantID)
+	// assets are registered in lower case, the same asset is used for checks, the ledger and the withdraw
+	withdraw.Asset = strings.ToLower(withdraw.Asset)
+	balance, err := s.checkWithdraw(ctx, processor, withdraw, merchantID, wallet)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	transaction, err := s.transactionStore.GetTransactionByGuid(merchantID, transactionID)
-	if err != nil {
-		return err
+	// amount with commission is reserved in the ledger until the withdraw is sent from the sending wallet,
+	// limits are checked under the same lock, so concurrent withdraws are counted
+	var breach *limitBreach
+	commission := wallet.CommissionSending.Calculate(withdraw.Amount)
+	guid, err := s.transactionStore.CreateWithdrawTransaction(merchantID, externalId, withdraw.Blockchain,
+		withdraw.WalletAddress, withdraw.Asset, withdraw.Issuer, withdraw.Amount, commission, balance,
+		func(volume storage.VolumeFunc) error {
+			breach, err = s.checkLimits(volume, merchantID, externalId, withdraw.Blockchain, withdraw.Asset,
+				withdraw.Issuer, storage.WithdrawTransaction, withdraw.Amount)
+			return err
+		})
+	if errors.Is(err, storage.ErrInsufficientFunds) {
+		log.Println(err)
+		return nil, insufficientBalance("balance %v is reserved by other withdraws", balance)
+	} else if err != nil {
+		return nil, err
 	}
-
-	wallet, ok := merchant.Wallets[transaction.Blockchain]
-	if !ok {
-		return fmt.Errorf("cannot find blockchain: '%v' for merchant", transaction.Blockchain)
+	if breach == nil {
+		return &WithdrawResponse{TransactionHash: guid}, nil
 	}
+	// the withdraw on hold keeps its funds reserved until an admin releases or rejects it
+	if err = s.holdTransaction(merchantID, guid, storage.InitTransaction, *breach); err != nil {
+		if rejectErr := s.transactionStore.RejectTransaction(merchantID, externalId, guid); rejectErr != nil {
+			log.Println(fmt.Sprintf("can't reject withdraw: %v which is not held, err: %v", guid, rejectErr))
+		}
+		return nil, err
+	}
+	return &WithdrawResponse{TransactionHash: guid, Status: storage.OnHoldTransaction}, nil
+}
 
-	commission := transaction.Amount*wallet.CommissionSending.Percent/100.0 + wallet.CommissionSending.Fix
-
-	err = s.transactionStore.PutProcessedTransaction(merchantID, externalId, transactionID, hash, commission)
+// GetMerchantBalances returns funds of the merchant assets by the ledger, available funds are counted
+// the same way as for withdraws from the balance of the sending wallet
+func (s ProcessingService) GetMerchantBalances(ctx context.Context,
+	merchantID, blockchain string) ([]storage.LedgerBalance, error) {
+	if s.ledgerStore == nil {
+		return nil, errors.New("ledger is not available")
+	}
+	balances, err := s.ledgerStore.GetBalances(merchantID, blockchain)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	merchData, err := s.merchants.GetMerchantData(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	for i, balance := range balances {
+		processor, ok := s.processors[balance.Blockchain]
+		if !ok {
+			continue
+		}
+		wallet, ok := merchData.Wallets[balance.Blockchain]
+		if !ok {
+			continue
+		}
+		_, balances[i].Available, err = s.availableBalance(ctx, processor, CredentialWithdraw{
+			Blockchain: balance.Blockchain,
+			Asset:      balance.Asset,
+			Issuer:     balance.Issuer,
+		}, merchantID, wallet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return balances, nil
 }
 
 func (s ProcessingService) DeleteWithdraw(transaction, merchantID, externalId string) error {
@@ -368,6 +463,13 @@ func (s ProcessingService) IssueFT(ctx context.Context, request NewTokenRequest,
 	if err != nil {
 		return nil, nil, err
 	}
+	s.putWebhookEvent(merchantID, storage.EventAssetIssued, storage.AssetEventData{
+		MerchantID: merchantID,
+		Blockchain: request.Blockchain,
+		Code:       request.Code,
+		Issuer:     response.Issuer,
+		Hash:       response.TxHash,
+	})
 	return response, features, nil
 }
 
@@ -488,6 +590,16 @@ func (s ProcessingService) GetTransactions(request TransactionRequest, merchantI
 	return transactions, nil
 }
 
+// GetPendingRefunds returns refunds of all merchants which are not completed yet
+func (s ProcessingService) GetPendingRefunds() ([]storage.TransactionStore, error) {
+	transactions, err := s.transactionStore.GetTransactionsByAction("", storage.RefundTransaction,
+		storage.PendingStatuses, limitTrxToRefundProcess)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return transactions, nil
+}
+
 /*
 	func (service ProcessingService) MakeFormDeposit(w http.ResponseWriter, r *http.Request, blockchain string, merchantID, externalId string) {
 		processor, ok := service.processors[blockchain]
@@ -519,6 +631,6 @@ func (s ProcessingService) SetHeaders(w http.ResponseWriter) http.ResponseWriter
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 	w.Header().Set("Access-Control-Allow-Headers",
-		"Content-Type, Authorization, X-Authorization, origin, x-requested-with, content-type")
+		"Content-Type, Authorization, X-Authorization, Idempotency-Key, origin, x-requested-with, content-type")
 	return w
 }
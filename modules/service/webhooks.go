@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	limitWebhooksToDeliver = 100
+	limitWebhooksToList    = 100
+	webhookLease           = 5 * time.Minute
+	maxLengthCallBackURL   = 2048
+)
+
+// streamWebhooks delivers webhooks from the outbox until the context is done
+func (s ProcessingService) streamWebhooks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(time.Second * interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("exit from webhook dispatcher")
+			return
+		case <-ticker.C:
+			s.dispatchWebhooks()
+		}
+	}
+}
+
+// dispatchWebhooks delivers webhooks which are due, a failed webhook is retried with exponential backoff
+// and goes to dead letters when it has no attempts left. A webhook of the merchant without callback url
+// fails as well, so it can be replayed when the url is set
+func (s ProcessingService) dispatchWebhooks() {
+	events, err := s.webhookStore.Take(limitWebhooksToDeliver, webhookLease)
+	if err != nil {
+		log.Println(fmt.Sprintf("processing can't take webhooks, err: %v", err))
+		return
+	}
+	for _, event := range events {
+		fanned, err := s.fanoutWebhook(event)
+		if fanned {
+			continue
+		}
+		if err == nil {
+			err = s.callBack.DeliverWebhook(event)
+		}
+		if err == nil {
+			err = s.webhookStore.Delivered(event.ID)
+		} else if errors.Is(err, ErrNotSubscribed) || errors.Is(err, ErrEndpointDisabled) {
+			err = s.webhookStore.Skip(event.ID)
+		} else {
+			backoff, dead := s.callBack.NextWebhookAttempt(event.Attempts)
+			log.Println(fmt.Sprintf("webhook: %v for merchant: %v, attempt: %v failed, err: %v",
+				event.ID, event.MerchantID, event.Attempts+1, err))
+			err = s.webhookStore.Fail(event.ID, err.Error(), time.Now().Add(backoff), dead)
+		}
+		if err != nil {
+			log.Println(fmt.Sprintf("can't update webhook: %v, err: %v", event.ID, err))
+		}
+	}
+}
+
+// fanoutWebhook puts a copy of the event for each enabled notification endpoint of the merchant subscribed to it
+// and returns true, the event is delivered to callback url of the merchant if it doesn't have such endpoints
+func (s ProcessingService) fanoutWebhook(event storage.WebhookEvent) (bool, error) {
+	if event.EndpointID != 0 || s.endpointStore == nil {
+		return false, nil
+	}
+	endpoints, err := s.endpointStore.GetEnabled(event.MerchantID, storage.EndpointNotifications)
+	if err != nil || len(endpoints) == 0 {
+		return false, err
+	}
+	var endpointIDs []int64
+	for _, endpoint := range endpoints {
+		if endpoint.IsSubscribed(event.Event) {
+			endpointIDs = append(endpointIDs, endpoint.ID)
+		}
+	}
+	if len(endpointIDs) == 0 {
+		return false, ErrNotSubscribed
+	}
+	if err = s.webhookStore.Fanout(event, endpointIDs); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// putWebhookEvent puts the event with the data to the outbox, events about transactions are put
+// by the transaction store together with the change of the transaction
+func (s ProcessingService) putWebhookEvent(merchantID, eventType string, data interface{}) {
+	if s.webhookStore == nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err == nil {
+		err = s.webhookStore.Put(merchantID, eventType, payload)
+	}
+	if err != nil {
+		log.Println(fmt.Sprintf("can't put webhook: %v for merchant: %v, err: %v", eventType, merchantID, err))
+	}
+}
+
+// UpdateWebhookSubscription subscribes the merchant to the types of webhook events, all events are delivered
+// if the list is empty
+func (s ProcessingService) UpdateWebhookSubscription(merchantID string,
+	subscription WebhookSubscription) error {
+	if err := validateWebhookEvents(subscription.Events); err != nil {
+		return err
+	}
+	return s.merchants.UpdateWebhookEvents(merchantID, subscription.Events)
+}
+
+// GetWebhookEndpoints returns all endpoints of the merchant
+func (s ProcessingService) GetWebhookEndpoints(merchantID string) ([]storage.WebhookEndpoint, error) {
+	if s.endpointStore == nil {
+		return nil, errors.New("webhook endpoints are not available")
+	}
+	return s.endpointStore.GetByMerchant(merchantID)
+}
+
+// CreateWebhookEndpoint validates and registers the endpoint of the merchant with a new secret,
+// the secret is returned only here
+func (s ProcessingService) CreateWebhookEndpoint(merchantID string,
+	endpoint NewWebhookEndpoint) (CreatedWebhookEndpoint, error) {
+	if s.endpointStore == nil {
+		return CreatedWebhookEndpoint{}, errors.New("webhook endpoints are not available")
+	}
+	if !storage.IsEndpointPurpose(endpoint.Purpose) {
+		return CreatedWebhookEndpoint{}, fmt.Errorf("unknown purpose of endpoint: %v", endpoint.Purpose)
+	}
+	if err := validateWebhookEndpoint(endpoint.URL, endpoint.Purpose, endpoint.Events); err != nil {
+		return CreatedWebhookEndpoint{}, err
+	}
+	res, err := s.endpointStore.Create(merchantID, endpoint.Name, endpoint.URL, endpoint.Purpose, true,
+		endpoint.Events)
+	if err != nil {
+		return CreatedWebhookEndpoint{}, err
+	}
+	return CreatedWebhookEndpoint{WebhookEndpoint: res, Secret: res.Secret}, nil
+}
+
+// UpdateWebhookEndpoint validates and changes the endpoint of the merchant
+func (s ProcessingService) UpdateWebhookEndpoint(merchantID string, id int64,
+	endpoint UpdateWebhookEndpoint) error {
+	if s.endpointStore == nil {
+		return errors.New("webhook endpoints are not available")
+	}
+	old, err := s.endpointStore.Get(merchantID, id)
+	if err != nil {
+		return err
+	}
+	if err = validateWebhookEndpoint(endpoint.URL, old.Purpose, endpoint.Events); err != nil {
+		return err
+	}
+	return s.endpointStore.Update(merchantID, id, endpoint.Name, endpoint.URL, endpoint.Enabled, endpoint.Events)
+}
+
+// DeleteWebhookEndpoint removes the endpoint of the merchant
+func (s ProcessingService) DeleteWebhookEndpoint(merchantID string, id int64) error {
+	if s.endpointStore == nil {
+		return errors.New("webhook endpoints are not available")
+	}
+	return s.endpointStore.Delete(merchantID, id)
+}
+
+// SendTestWebhook delivers test event to the notification endpoint of the merchant at once
+func (s ProcessingService) SendTestWebhook(merchantID string, id int64) error {
+	if s.endpointStore == nil {
+		return errors.New("webhook endpoints are not available")
+	}
+	endpoint, err := s.endpointStore.Get(merchantID, id)
+	if err != nil {
+		return err
+	}
+	if endpoint.Purpose != storage.EndpointNotifications {
+		return fmt.Errorf("test event can't be sent to %v endpoint", endpoint.Purpose)
+	}
+	return s.callBack.DeliverTestWebhook(endpoint)
+}
+
+// ValidateCallBackURL checks that the url is absolute https url without fragment which host resolves only
+// to public addresses, the processing adds paths of entry points to callback url, so it can't have query
+func ValidateCallBackURL(rawURL string) error {
+	if len(rawURL) > maxLengthCallBackURL {
+		return fmt.Errorf("url is longer than %v", maxLengthCallBackURL)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("url must start with https://")
+	}
+	if u.Host == "" || u.User != nil || u.Fragment != "" || u.RawQuery != "" {
+		return fmt.Errorf("url must have host and no credentials, query or fragment")
+	}
+	return checkPublicHost(u.Hostname())
+}
+
+// checkPublicHost resolves the host and checks that all its addresses are public
+func checkPublicHost(host string) error {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return fmt.Errorf("can't resolve host: %v, err: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("%w: %v", ErrPrivateAddress, host)
+		}
+	}
+	return nil
+}
+
+// isPublicIP checks that the address is not loopback, private, link-local, multicast or unspecified one
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// publicAddressControl is called by the dialer of callbacks for every address the host is resolved to,
+// so the address is checked on delivery again in case the host is resolved to another address since it is validated
+func publicAddressControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %v", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// validateWebhookEndpoint checks the url of endpoint and its filter, the events are posted to url
+// of notification endpoint as is, so it can have query
+func validateWebhookEndpoint(rawURL string, purpose storage.EndpointPurpose, events []string) error {
+	if purpose == storage.EndpointNotifications {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("invalid url: %w", err)
+		}
+		u.RawQuery = ""
+		rawURL = u.String()
+	}
+	if err := ValidateCallBackURL(rawURL); err != nil {
+		return err
+	}
+	return validateWebhookEvents(events)
+}
+
+// validateWebhookEvents checks that the events are known types of webhook events or their wildcards
+func validateWebhookEvents(events []string) error {
+	for _, pattern := range events {
+		if !storage.IsWebhookEventType(pattern) {
+			return fmt.Errorf("unknown webhook event: %v", pattern)
+		}
+	}
+	return nil
+}
+
+// GetDeadWebhooks returns the latest webhooks of the merchant which exhausted their delivery attempts
+func (s ProcessingService) GetDeadWebhooks(merchantID string) ([]storage.WebhookEvent, error) {
+	if s.webhookStore == nil {
+		return nil, errors.New("webhooks are not available")
+	}
+	return s.webhookStore.GetByMerchant(merchantID, storage.WebhookDead, limitWebhooksToList)
+}
+
+// ReplayWebhook returns the dead webhook of the merchant to delivery
+func (s ProcessingService) ReplayWebhook(merchantID string, id int64) error {
+	if s.webhookStore == nil {
+		return errors.New("webhooks are not available")
+	}
+	return s.webhookStore.Replay(merchantID, id)
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/storage"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// defaultApprovalExpiry is time a withdraw waits for approvals if the policy doesn't set it
+const defaultApprovalExpiry = 24 * time.Hour
+
+const (
+	// WithdrawConfirmed is status of the confirmed withdraw which is sent for processing
+	WithdrawConfirmed = "success"
+	// WithdrawPendingApproval is status of the confirmed withdraw which waits for approvals of merchant users
+	WithdrawPendingApproval = "pending_approval"
+)
+
+// UpdateWithdraw confirms the initiated withdraw with the hash and returns its status, the withdraw is sent
+// for processing unless the approval policy of the merchant requires approvals for its amount.
+// The identity is the Ory identity of the user confirming the withdraw in UI, it is empty for the merchant token
+func (s ProcessingService) UpdateWithdraw(transactionID, merchantID, externalId, hash, identity string) (string, error) {
+	merchant, err := s.merchants.GetMerchantData(merchantID)
+	if err != nil {
+		return "", err
+	}
+
+	transaction, err := s.transactionStore.GetTransactionByGuid(merchantID, transactionID)
+	if err != nil {
+		return "", err
+	}
+
+	wallet, ok := merchant.Wallets[transaction.Blockchain]
+	if !ok {
+		return "", fmt.Errorf("cannot find blockchain: '%v' for merchant", transaction.Blockchain)
+	}
+
+	// a withdraw which is already processed is confirmed again only to update its hash
+	required := wallet.WithdrawApproval.RequiredApprovals(transaction.Asset, transaction.Issuer, transaction.Amount)
+	if required == 0 || s.approvalStore == nil || transaction.Status != storage.InitTransaction {
+		return WithdrawConfirmed, s.confirmWithdraw(*transaction, externalId, hash, wallet)
+	}
+	if transaction.Action != storage.WithdrawTransaction || transaction.ExternalId != externalId {
+		return "", fmt.Errorf("%w: transaction %v is not a withdraw of user %v", storage.ErrNotFound,
+			transactionID, externalId)
+	}
+
+	expiry := defaultApprovalExpiry
+	if wallet.WithdrawApproval.ExpirySeconds > 0 {
+		expiry = time.Duration(wallet.WithdrawApproval.ExpirySeconds) * time.Second
+	}
+	created, err := s.approvalStore.Create(merchantID, transactionID, identity, hash, required, time.Now().Add(expiry))
+	if err != nil {
+		return "", err
+	}
+	if !created {
+		// the withdraw is approved already, but it could fail to be sent for processing
+		request, err := s.approvalStore.Get(merchantID, transactionID)
+		if err != nil {
+			return "", err
+		}
+		if request.Status == storage.ApprovalApproved {
+			return WithdrawConfirmed, s.confirmWithdraw(*transaction, externalId, request.Hash, wallet)
+		}
+	}
+	return WithdrawPendingApproval, nil
+}
+
+// confirmWithdraw moves the withdraw to processed status with commission of the merchant and sends it for processing,
+// the hash and commission of the already processed withdraw are updated
+func (s ProcessingService) confirmWithdraw(transaction storage.TransactionStore, externalId, hash string,
+	wallet Wallets) error {
+	commission := wallet.CommissionSending.Calculate(transaction.Amount)
+	var err error
+	if transaction.Status == storage.ProcessedTransaction {
+		err = s.transactionStore.UpdateProcessedTransaction(transaction.MerchantId, externalId,
+			transaction.GUID.String(), transaction.Hash2, hash, commission)
+	} else {
+		err = s.transactionStore.PutProcessedTransaction(transaction.MerchantId, externalId,
+			transaction.GUID.String(), hash, commission)
+	}
+	if err != nil {
+		return err
+	}
+	s.enqueueTransaction(transaction.MerchantId, transaction.GUID.String())
+	return nil
+}
+
+// UpdateWithdrawApproval sets the policy of approvals of withdraws of the merchant in the blockchain
+func (s ProcessingService) UpdateWithdrawApproval(merchantID, blockchain string,
+	policy ApprovalPolicy) (Wallets, error) {
+	for i, thresholds := range policy.Thresholds {
+		if thresholds.AutoApproveBelow.IsNegative() ||
+			thresholds.TwoApprovalsFrom != nil && thresholds.TwoApprovalsFrom.IsNegative() {
+			return Wallets{}, errors.New("amounts of approval policy can't be negative")
+		}
+		// assets are registered in lower case
+		policy.Thresholds[i].Asset = strings.ToLower(thresholds.Asset)
+	}
+	if policy.ExpirySeconds < 0 {
+		return Wallets{}, errors.New("expiry of approvals can't be negative")
+	}
+	return s.merchants.UpdateWithdrawApproval(merchantID, blockchain, policy)
+}
+
+// GetWithdrawApprovals returns withdraws of the merchant waiting for approvals
+func (s ProcessingService) GetWithdrawApprovals(merchantID string) (WithdrawApprovalsResponse, error) {
+	response := WithdrawApprovalsResponse{Approvals: []WithdrawApprovalItem{}}
+	if s.approvalStore == nil {
+		return response, nil
+	}
+	requests, err := s.approvalStore.GetByMerchant(merchantID, storage.ApprovalPending)
+	if err != nil {
+		return response, err
+	}
+	for _, request := range requests {
+		transaction, err := s.transactionStore.GetTransactionByGuid(merchantID, request.TransactionGUID)
+		if err != nil {
+			return response, fmt.Errorf("can't get withdraw: %v, err: %w", request.TransactionGUID, err)
+		}
+		decisions, err := s.approvalStore.GetDecisions(merchantID, request.TransactionGUID)
+		if err != nil {
+			return response, err
+		}
+		response.Approvals = append(response.Approvals, WithdrawApprovalItem{
+			ApprovalRequest: request,
+			Transaction:     *transaction,
+			Decisions:       decisions,
+		})
+	}
+	return response, nil
+}
+
+// DecideWithdraw records the decision of the merchant user with the Ory identity on the withdraw waiting
+// for approvals, the withdraw is rejected by a rejection and sent for processing when it gets enough approvals
+func (s ProcessingService) DecideWithdraw(merchantID, transactionID, identity string,
+	decision storage.ApprovalDecision) (*storage.ApprovalRequest, error) {
+	if s.approvalStore == nil {
+		return nil, errors.New("approvals of withdraws are not available")
+	}
+	if identity == "" {
+		return nil, errors.New("identity of the user is required to decide on withdraw")
+	}
+	request, err := s.approvalStore.Decide(merchantID, transactionID, identity, decision)
+	if err != nil {
+		return nil, err
+	}
+	log.Println(fmt.Sprintf("user: %v decided to %v withdraw: %v of merchant: %v, approvals: %v of %v",
+		identity, decision, transactionID, merchantID, request.Approvals, request.Required))
+	switch request.Status {
+	case storage.ApprovalRejected:
+		err = s.rejectWithdraw(merchantID, transactionID)
+	case storage.ApprovalApproved:
+		err = s.approveWithdraw(merchantID, transactionID, request.Hash)
+	}
+	return request, err
+}
+
+// approveWithdraw sends the withdraw approved by merchant users for processing
+func (s ProcessingService) approveWithdraw(merchantID, transactionID, hash string) error {
+	transaction, err := s.transactionStore.GetTransactionByGuid(merchantID, transactionID)
+	if err != nil {
+		return err
+	}
+	_, wallet, err := s.getMerchantWallet(merchantID, transaction.Blockchain)
+	if err != nil {
+		return err
+	}
+	return s.confirmWithdraw(*transaction, transaction.ExternalId, hash, wallet)
+}
+
+// rejectWithdraw rejects the withdraw which is not approved, its reserved funds are released
+func (s ProcessingService) rejectWithdraw(merchantID, transactionID string) error {
+	transaction, err := s.transactionStore.GetTransactionByGuid(merchantID, transactionID)
+	if err != nil {
+		return err
+	}
+	return s.transactionStore.RejectTransaction(merchantID, transaction.ExternalId, transactionID)
+}
+
+// streamApprovalExpiry rejects withdraws which didn't get approvals in time until the context is done
+func (s ProcessingService) streamApprovalExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(time.Second * interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("exit from withdraw approvals expiry")
+			return
+		case <-ticker.C:
+			s.expireWithdrawApprovals()
+		}
+	}
+}
+
+// expireWithdrawApprovals rejects expired withdraws waiting for approvals, a withdraw already rejected
+// by the merchant is only marked as expired
+func (s ProcessingService) expireWithdrawApprovals() {
+	requests, err := s.approvalStore.GetExpired(time.Now())
+	if err != nil {
+		log.Println(fmt.Sprintf("processing can't get expired withdraw approvals, err: %v", err))
+		return
+	}
+	for _, request := range requests {
+		err = s.rejectWithdraw(request.MerchantID, request.TransactionGUID)
+		if err != nil && !errors.Is(err, storage.ErrInvalidTransition) {
+			log.Println(fmt.Sprintf("can't reject expired withdraw: %v, err: %v", request.TransactionGUID, err))
+			continue
+		}
+		if err = s.approvalStore.Expire(request.TransactionGUID); err != nil {
+			log.Println(fmt.Sprintf("can't expire approvals of withdraw: %v, err: %v", request.TransactionGUID, err))
+		}
+	}
+}
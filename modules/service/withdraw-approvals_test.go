@@ -0,0 +1,25 @@
+package service
+
+import (
+	"coreum_processor/modules/amount"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRequiredApprovalsByAsset(t *testing.T) {
+	two := amount.NewFromInt64(1000)
+	policy := ApprovalPolicy{Enabled: true, Thresholds: []ApprovalThresholds{
+		{Asset: "usd", Issuer: "issuer", AutoApproveBelow: amount.NewFromInt64(100), TwoApprovalsFrom: &two},
+		{AutoApproveBelow: amount.NewFromInt64(1000000)},
+	}}
+
+	require.Equal(t, 0, policy.RequiredApprovals("usd", "issuer", amount.NewFromInt64(99)))
+	require.Equal(t, 1, policy.RequiredApprovals("USD", "issuer", amount.NewFromInt64(100)))
+	require.Equal(t, 2, policy.RequiredApprovals("usd", "issuer", amount.NewFromInt64(1000)))
+	require.Equal(t, 0, policy.RequiredApprovals("", "", amount.NewFromInt64(1000)))
+	require.Equal(t, 1, policy.RequiredApprovals("usd", "other", amount.NewFromInt64(1)))
+	require.Equal(t, 1, policy.RequiredApprovals("eur", "issuer", amount.NewFromInt64(1)))
+
+	policy.Enabled = false
+	require.Equal(t, 0, policy.RequiredApprovals("eur", "issuer", amount.NewFromInt64(1)))
+}
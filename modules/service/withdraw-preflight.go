@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"coreum_processor/modules/amount"
+	"coreum_processor/modules/storage"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ViolationCode is a code of violation found by pre-flight checks of a withdraw request
+type ViolationCode string
+
+const (
+	ViolationInvalidAmount       ViolationCode = "invalid_amount"
+	ViolationInvalidAddress      ViolationCode = "invalid_address"
+	ViolationAssetNotFound       ViolationCode = "asset_not_found"
+	ViolationAssetInactive       ViolationCode = "asset_inactive"
+	ViolationAmountTooSmall      ViolationCode = "amount_too_small"
+	ViolationAmountTooLarge      ViolationCode = "amount_too_large"
+	ViolationInsufficientBalance ViolationCode = "insufficient_balance"
+	ViolationAddressNotAllowed   ViolationCode = "address_not_allowed"
+	ViolationAddressCoolingOff   ViolationCode = "address_cooling_off"
+)
+
+// WithdrawViolation describes a reason the withdraw request is refused and the field of the request it concerns
+type WithdrawViolation struct {
+	Code    ViolationCode `json:"code"`
+	Field   string        `json:"field"`
+	Message string        `json:"message"`
+}
+
+// WithdrawValidationError is returned when the withdraw request doesn't pass pre-flight checks
+type WithdrawValidationError struct {
+	Message    string              `json:"message"`
+	Violations []WithdrawViolation `json:"violations"`
+}
+
+func (e *WithdrawValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(messages, "; "))
+}
+
+func (e *WithdrawValidationError) add(code ViolationCode, field, format string, args ...interface{}) {
+	e.Violations = append(e.Violations, WithdrawViolation{Code: code, Field: field,
+		Message: fmt.Sprintf(format, args...)})
+}
+
+// checkWithdraw validates the withdraw request of the merchant with asset in lower case before it is created:
+// the address must belong to the blockchain and to the address book if the merchant allows only it, the asset
+// must be active, the amount must be within limits of the processor and the asset, and the sending wallet must
+// hold it with commission besides funds reserved by pending withdraws.
+// It returns balance of the asset in the sending wallet
+func (s ProcessingService) checkWithdraw(ctx context.Context, processor CryptoProcessor,
+	withdraw CredentialWithdraw, merchantID string, wallet Wallets) (amount.Amount, error) {
+	validation := &WithdrawValidationError{Message: "withdraw request is refused"}
+	if !withdraw.Amount.IsPositive() {
+		validation.add(ViolationInvalidAmount, "amount", "amount must be positive")
+	} else if _, err := withdraw.Amount.Int(); err != nil {
+		validation.add(ViolationInvalidAmount, "amount", "amount must be whole subunits of the asset")
+	}
+	if address, err := processor.ValidateAddress(withdraw.WalletAddress); err != nil {
+		validation.add(ViolationInvalidAddress, "wallet_address", "%v is not a valid %v address",
+			withdraw.WalletAddress, withdraw.Blockchain)
+	} else if err = s.checkDestination(validation, withdraw, address, merchantID, wallet); err != nil {
+		return amount.Zero(), err
+	}
+
+	minimum := processor.MinimumValue()
+	var maximum *amount.Amount
+	// native coin of the blockchain is requested with empty asset and isn't registered as an asset
+	if withdraw.Asset != "" {
+		status, limits, err := s.assetStore.GetWithdrawLimits(withdraw.Blockchain, withdraw.Asset, withdraw.Issuer)
+		if errors.Is(err, storage.ErrNotFound) {
+			validation.add(ViolationAssetNotFound, "asset", "asset %v of issuer %v is not found in %v",
+				withdraw.Asset, withdraw.Issuer, withdraw.Blockchain)
+		} else if err != nil {
+			return amount.Zero(), fmt.Errorf("can't get asset: %v, issuer: %v, err: %w",
+				withdraw.Asset, withdraw.Issuer, err)
+		} else if status != storage.AssetActive {
+			validation.add(ViolationAssetInactive, "asset", "asset %v is %v", withdraw.Asset, status)
+		}
+		if limits.Min != nil && limits.Min.GT(minimum) {
+			minimum = *limits.Min
+		}
+		maximum = limits.Max
+	}
+	if withdraw.Amount.IsPositive() && withdraw.Amount.LT(minimum) {
+		validation.add(ViolationAmountTooSmall, "amount", "amount %v is less than minimum %v",
+			withdraw.Amount, minimum)
+	}
+	if maximum != nil && withdraw.Amount.GT(*maximum) {
+		validation.add(ViolationAmountTooLarge, "amount", "amount %v is greater than maximum %v",
+			withdraw.Amount, *maximum)
+	}
+	if len(validation.Violations) > 0 {
+		return amount.Zero(), validation
+	}
+
+	balance, available, err := s.availableBalance(ctx, processor, withdraw, merchantID, wallet)
+	if err != nil {
+		return amount.Zero(), err
+	}
+	required := withdraw.Amount.Add(wallet.CommissionSending.Calculate(withdraw.Amount))
+	if available.LT(required) {
+		return amount.Zero(), insufficientBalance("available balance %v is less than amount with commission %v",
+			available, required)
+	}
+	return balance, nil
+}
+
+// insufficientBalance returns validation error of the withdraw which is not covered by available balance
+func insufficientBalance(format string, args ...interface{}) error {
+	validation := &WithdrawValidationError{Message: "withdraw request is refused"}
+	validation.add(ViolationInsufficientBalance, "amount", format, args...)
+	return validation
+}
+
+// availableBalance returns balance of the asset in the sending wallet and funds of it available for withdraws,
+// which are the balance less funds reserved by pending withdraws and withdraws on hold
+func (s ProcessingService) availableBalance(ctx context.Context, processor CryptoProcessor,
+	withdraw CredentialWithdraw, merchantID string, wallet Wallets) (amount.Amount, amount.Amount, error) {
+	balance, err := s.sendingBalance(ctx, processor, withdraw, merchantID, wallet)
+	if err != nil {
+		return amount.Zero(), amount.Zero(), err
+	}
+	reserved, err := s.ledgerStore.GetReserved(merchantID, withdraw.Blockchain, withdraw.Asset, withdraw.Issuer)
+	if err != nil {
+		return amount.Zero(), amount.Zero(), fmt.Errorf("can't get reserved funds of merchant: %v, err: %w",
+			merchantID, err)
+	}
+	return balance, balance.Sub(reserved), nil
+}
+
+// sendingBalance returns balance of the asset in the merchant sending wallet
+func (s ProcessingService) sendingBalance(ctx context.Context, processor CryptoProcessor,
+	withdraw CredentialWithdraw, merchantID string, wallet Wallets) (amount.Amount, error) {
+	balances, err := processor.GetAssetsBalance(ctx, BalanceRequest{
+		Blockchain: withdraw.Blockchain,
+		Asset:      withdraw.Asset,
+		Issuer:     withdraw.Issuer,
+	}, merchantID, wallet.SendingID)
+	if err != nil {
+		return amount.Zero(), fmt.Errorf("can't get balance of merchant: %v, sending wallet: %v, err: %w",
+			merchantID, wallet.SendingID, err)
+	}
+	for _, balance := range balances {
+		// all balances are returned for the native coin, it is the only one without issuer
+		if balance.Issuer == withdraw.Issuer && (withdraw.Asset == "" || balance.Asset == withdraw.Asset) {
+			return balance.Amount, nil
+		}
+	}
+	return amount.Zero(), nil
+}
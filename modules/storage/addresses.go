@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAddressExists returned in case of the address is already in the address book of the merchant
+var ErrAddressExists = errors.New("address is already in address book")
+
+// AddressBookEntry is an address the merchant allows to withdraw to, it can be used from ActiveFrom
+type AddressBookEntry struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ActiveFrom time.Time `json:"active_from"`
+	MerchantID string    `json:"merchant_id"`
+	Blockchain string    `json:"blockchain"`
+	Address    string    `json:"address"`
+	Label      string    `json:"label"`
+}
+
+// IsActive checks if the cooling-off period of the address is over at the time
+func (e AddressBookEntry) IsActive(at time.Time) bool {
+	return !e.ActiveFrom.After(at)
+}
+
+// addressColumns lists columns of the address book table in order expected by rowsToAddressBookEntries
+const addressColumns = "id, created_at, updated_at, active_from, merchant_id, blockchain, address, label"
+
+type AddressesPSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// NewAddressStorage creates new storage for address books of merchants
+func NewAddressStorage(namespace string, db *sql.DB) (*AddressesPSQL, error) {
+	s := AddressesPSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to address book storage: %v", err)
+	}
+	return &s, nil
+}
+
+// Create adds the address to the address book of the merchant, it can be used from activeFrom,
+// ErrAddressExists is returned if the address is already in the book
+func (s *AddressesPSQL) Create(merchantID, blockchain, address, label string,
+	activeFrom time.Time) (AddressBookEntry, error) {
+	query := fmt.Sprintf("INSERT INTO %s (created_at, updated_at, active_from, merchant_id, blockchain, address, "+
+		"label) VALUES ($1, $1, $2, $3, $4, $5, $6) ON CONFLICT (merchant_id, blockchain, address) DO NOTHING "+
+		"RETURNING %s", s.namespace, addressColumns)
+	rows, err := s.db.Query(query, time.Now().UTC(), activeFrom.UTC(), merchantID, blockchain, address, label)
+	if err != nil {
+		return AddressBookEntry{}, fmt.Errorf("could not add address for merchant: %v, err: %w", merchantID, err)
+	}
+	defer func() { _ = rows.Close() }()
+	entries, err := rowsToAddressBookEntries(rows)
+	if err != nil {
+		return AddressBookEntry{}, err
+	}
+	if len(entries) == 0 {
+		return AddressBookEntry{}, fmt.Errorf("%w: %v", ErrAddressExists, address)
+	}
+	return entries[0], nil
+}
+
+// Find returns the address from the address book of the merchant,
+// ErrNotFound is returned if the merchant doesn't have it
+func (s *AddressesPSQL) Find(merchantID, blockchain, address string) (AddressBookEntry, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 and blockchain = $2 and address = $3",
+		addressColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, blockchain, address)
+	if err != nil {
+		return AddressBookEntry{}, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	entries, err := rowsToAddressBookEntries(rows)
+	if err != nil {
+		return AddressBookEntry{}, err
+	}
+	if len(entries) == 0 {
+		return AddressBookEntry{}, ErrNotFound
+	}
+	return entries[0], nil
+}
+
+// GetByMerchant returns the address book of the merchant, blockchain filter is not applied if it is empty
+func (s *AddressesPSQL) GetByMerchant(merchantID, blockchain string) ([]AddressBookEntry, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 and ($2 = '' or blockchain = $2) "+
+		"ORDER BY blockchain, id", addressColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, blockchain)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	entries, err := rowsToAddressBookEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []AddressBookEntry{}
+	}
+	return entries, nil
+}
+
+// UpdateLabel changes label of the address of the merchant, ErrNotFound is returned if the merchant doesn't have it
+func (s *AddressesPSQL) UpdateLabel(merchantID string, id int64, label string) error {
+	query := fmt.Sprintf("UPDATE %s SET updated_at = $1, label = $2 WHERE merchant_id = $3 and id = $4",
+		s.namespace)
+	res, err := s.db.Exec(query, time.Now().UTC(), label, merchantID, id)
+	return checkAddressAffected(res, err, id)
+}
+
+// Delete removes the address from the address book of the merchant,
+// ErrNotFound is returned if the merchant doesn't have it
+func (s *AddressesPSQL) Delete(merchantID string, id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE merchant_id = $1 and id = $2", s.namespace)
+	res, err := s.db.Exec(query, merchantID, id)
+	return checkAddressAffected(res, err, id)
+}
+
+func checkAddressAffected(res sql.Result, err error, id int64) error {
+	if err != nil {
+		return fmt.Errorf("could not change address: %v, err: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not change address: %v, err: %w", id, err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func rowsToAddressBookEntries(rows *sql.Rows) ([]AddressBookEntry, error) {
+	var entries []AddressBookEntry
+	for rows.Next() {
+		e := AddressBookEntry{}
+		err := rows.Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt, &e.ActiveFrom, &e.MerchantID, &e.Blockchain,
+			&e.Address, &e.Label)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan address: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read addresses: %w", err)
+	}
+	return entries, nil
+}
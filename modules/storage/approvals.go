@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrSelfApproval returned in case of a user decides on the withdraw the user confirmed
+	ErrSelfApproval = errors.New("withdraw can't be approved by the user who requested it")
+	// ErrAlreadyDecided returned in case of a user decides on the withdraw more than once
+	ErrAlreadyDecided = errors.New("user has already decided on the withdraw")
+)
+
+type ApprovalStatus string
+
+const (
+	// ApprovalPending is a withdraw waiting for approvals of merchant users
+	ApprovalPending ApprovalStatus = "pending"
+	// ApprovalApproved is a withdraw approved by the required number of distinct users
+	ApprovalApproved ApprovalStatus = "approved"
+	// ApprovalRejected is a withdraw rejected by a merchant user
+	ApprovalRejected ApprovalStatus = "rejected"
+	// ApprovalExpired is a withdraw which didn't get approvals in time
+	ApprovalExpired ApprovalStatus = "expired"
+)
+
+type ApprovalDecision string
+
+const (
+	ApprovalDecisionApprove ApprovalDecision = "approve"
+	ApprovalDecisionReject  ApprovalDecision = "reject"
+)
+
+// ApprovalRequest is a confirmed withdraw of the merchant which waits for approvals of merchant users,
+// RequestedBy is empty if the withdraw is confirmed by the merchant token
+type ApprovalRequest struct {
+	TransactionGUID string         `json:"transaction_guid"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	ExpiresAt       time.Time      `json:"expires_at"`
+	MerchantID      string         `json:"merchant_id"`
+	RequestedBy     string         `json:"requested_by"`
+	Hash            string         `json:"-"`
+	Required        int            `json:"required"`
+	Approvals       int            `json:"approvals"`
+	Status          ApprovalStatus `json:"status"`
+}
+
+// ApprovalRecord is a decision of a merchant user on the withdraw
+type ApprovalRecord struct {
+	CreatedAt       time.Time        `json:"created_at"`
+	TransactionGUID string           `json:"transaction_guid"`
+	Identity        string           `json:"identity"`
+	Decision        ApprovalDecision `json:"decision"`
+}
+
+type ApprovalsPSQL struct {
+	db                 *sql.DB
+	namespace          string
+	decisionsNamespace string
+}
+
+// NewApprovalStorage creates new storage for withdraws waiting for approvals and decisions of users on them
+func NewApprovalStorage(namespace, decisionsNamespace string, db *sql.DB) (*ApprovalsPSQL, error) {
+	s := ApprovalsPSQL{
+		db:                 db,
+		namespace:          namespace,
+		decisionsNamespace: decisionsNamespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to approvals storage: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", decisionsNamespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to approval decisions storage: %v", err)
+	}
+	return &s, nil
+}
+
+// approvalColumns returns columns of the approvals table in order expected by scanApprovalRequest
+// with number of approvals made for the request, the approve decision is passed as the parameter of the number
+func (s *ApprovalsPSQL) approvalColumns(decisionParam int) string {
+	return fmt.Sprintf("a.transaction_guid, a.created_at, a.updated_at, a.expires_at, a.merchant_id, "+
+		"a.requested_by, a.hash, a.required, a.status, (SELECT count(*) FROM %s d "+
+		"WHERE d.transaction_guid = a.transaction_guid and d.decision = $%d)",
+		s.decisionsNamespace, decisionParam)
+}
+
+// Create makes the withdraw wait for the required number of approvals until it expires,
+// a withdraw already waiting for approvals is kept as is and false is returned
+func (s *ApprovalsPSQL) Create(merchantID, guid, requestedBy, hash string, required int,
+	expiresAt time.Time) (bool, error) {
+	query := fmt.Sprintf("INSERT INTO %s (transaction_guid, created_at, updated_at, expires_at, merchant_id, "+
+		"requested_by, hash, required, status) VALUES ($1, $2, $2, $3, $4, $5, $6, $7, $8) "+
+		"ON CONFLICT (transaction_guid) DO NOTHING", s.namespace)
+	res, err := s.db.Exec(query, guid, time.Now().UTC(), expiresAt.UTC(), merchantID, requestedBy, hash, required,
+		ApprovalPending)
+	if err != nil {
+		return false, fmt.Errorf("could not create approval request for withdraw: %v, err: %w", guid, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get created approval requests: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Get returns the approval request of the merchant withdraw
+func (s *ApprovalsPSQL) Get(merchantID, guid string) (*ApprovalRequest, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s a WHERE a.merchant_id = $1 and a.transaction_guid = $2",
+		s.approvalColumns(3), s.namespace)
+	request, err := scanApprovalRequest(s.db.QueryRow(query, merchantID, guid, ApprovalDecisionApprove))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get approval request for withdraw: %v, err: %w", guid, err)
+	}
+	return request, nil
+}
+
+// GetByMerchant returns approval requests of the merchant in the status ordered by creation
+func (s *ApprovalsPSQL) GetByMerchant(merchantID string, status ApprovalStatus) ([]ApprovalRequest, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s a WHERE a.merchant_id = $1 and a.status = $2 "+
+		"ORDER BY a.created_at", s.approvalColumns(3), s.namespace)
+	return s.query(query, merchantID, status, ApprovalDecisionApprove)
+}
+
+// GetExpired returns pending approval requests of all merchants which expired before the time
+func (s *ApprovalsPSQL) GetExpired(before time.Time) ([]ApprovalRequest, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s a WHERE a.status = $1 and a.expires_at < $2 "+
+		"ORDER BY a.expires_at", s.approvalColumns(3), s.namespace)
+	return s.query(query, ApprovalPending, before.UTC(), ApprovalDecisionApprove)
+}
+
+// GetDecisions returns decisions of users on the merchant withdraw in order they are made
+func (s *ApprovalsPSQL) GetDecisions(merchantID, guid string) ([]ApprovalRecord, error) {
+	query := fmt.Sprintf("SELECT d.created_at, d.transaction_guid, d.identity, d.decision FROM %s d "+
+		"JOIN %s a ON a.transaction_guid = d.transaction_guid WHERE a.merchant_id = $1 and a.transaction_guid = $2 "+
+		"ORDER BY d.id", s.decisionsNamespace, s.namespace)
+	rows, err := s.db.Query(query, merchantID, guid)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	records := []ApprovalRecord{}
+	for rows.Next() {
+		r := ApprovalRecord{}
+		if err = rows.Scan(&r.CreatedAt, &r.TransactionGUID, &r.Identity, &r.Decision); err != nil {
+			return nil, fmt.Errorf("could not get approval decision from query: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Decide records the decision of the user on the pending withdraw and returns the request with its new status:
+// a rejection rejects the request and the request is approved when it gets the required number of approvals
+// of distinct users. The user who requested the withdraw can't decide on it, ErrInvalidTransition is returned
+// if the request is not pending or expired
+func (s *ApprovalsPSQL) Decide(merchantID, guid, identity string, decision ApprovalDecision) (*ApprovalRequest, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := fmt.Sprintf("SELECT %s FROM %s a WHERE a.merchant_id = $1 and a.transaction_guid = $2 "+
+		"FOR UPDATE OF a", s.approvalColumns(3), s.namespace)
+	request, err := scanApprovalRequest(tx.QueryRow(query, merchantID, guid, ApprovalDecisionApprove))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get approval request for withdraw: %v, err: %w", guid, err)
+	}
+	now := time.Now().UTC()
+	if request.Status != ApprovalPending || request.ExpiresAt.Before(now) {
+		return nil, fmt.Errorf("%w: approval request for withdraw %v is %v, expires at %v", ErrInvalidTransition,
+			guid, request.Status, request.ExpiresAt)
+	}
+	if identity == request.RequestedBy {
+		return nil, ErrSelfApproval
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (created_at, transaction_guid, identity, decision) "+
+		"VALUES ($1, $2, $3, $4) ON CONFLICT (transaction_guid, identity) DO NOTHING", s.decisionsNamespace)
+	res, err := tx.Exec(query, now, guid, identity, decision)
+	if err != nil {
+		return nil, fmt.Errorf("could not record decision on withdraw: %v, err: %w", guid, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("could not get recorded decisions: %w", err)
+	} else if n == 0 {
+		return nil, ErrAlreadyDecided
+	}
+
+	switch decision {
+	case ApprovalDecisionReject:
+		request.Status = ApprovalRejected
+	case ApprovalDecisionApprove:
+		request.Approvals++
+		if request.Approvals >= request.Required {
+			request.Status = ApprovalApproved
+		}
+	default:
+		return nil, fmt.Errorf("unknown decision: %v", decision)
+	}
+	if request.Status != ApprovalPending {
+		query = fmt.Sprintf("UPDATE %s SET status = $1, updated_at = $2 WHERE transaction_guid = $3", s.namespace)
+		if _, err = tx.Exec(query, request.Status, now, guid); err != nil {
+			return nil, fmt.Errorf("could not update approval request for withdraw: %v, err: %w", guid, err)
+		}
+		request.UpdatedAt = now
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return request, nil
+}
+
+// Expire moves the pending approval request to expired status,
+// ErrInvalidTransition is returned if the request is not pending
+func (s *ApprovalsPSQL) Expire(guid string) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $1, updated_at = $2 WHERE transaction_guid = $3 and status = $4",
+		s.namespace)
+	res, err := s.db.Exec(query, ApprovalExpired, time.Now().UTC(), guid, ApprovalPending)
+	if err != nil {
+		return fmt.Errorf("could not update approval request for withdraw: %v, err: %w", guid, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get updated approval requests: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: approval request for withdraw %v to %v from status other than %v",
+			ErrInvalidTransition, guid, ApprovalExpired, ApprovalPending)
+	}
+	return nil
+}
+
+func (s *ApprovalsPSQL) query(query string, args ...interface{}) ([]ApprovalRequest, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	requests := []ApprovalRequest{}
+	for rows.Next() {
+		request, err := scanApprovalRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not get approval request from query: %w", err)
+		}
+		requests = append(requests, *request)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not get approval requests from query: %w", err)
+	}
+	return requests, nil
+}
+
+// rowScanner is implemented by both row and rows of a query
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanApprovalRequest(row rowScanner) (*ApprovalRequest, error) {
+	r := ApprovalRequest{}
+	if err := row.Scan(&r.TransactionGUID, &r.CreatedAt, &r.UpdatedAt, &r.ExpiresAt, &r.MerchantID,
+		&r.RequestedBy, &r.Hash, &r.Required, &r.Status, &r.Approvals); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
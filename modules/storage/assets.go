@@ -1,10 +1,12 @@
 package storage
 
 import (
+	"coreum_processor/modules/amount"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"time"
 )
 
@@ -36,6 +38,13 @@ type AssetStore struct {
 	Features      json.RawMessage `json:"features"`
 }
 
+// WithdrawLimits are the least and the largest amounts of the asset in subunits allowed to withdraw at once,
+// nil means there is no limit
+type WithdrawLimits struct {
+	Min *amount.Amount
+	Max *amount.Amount
+}
+
 type AssetPSQL struct {
 	db                      *sql.DB
 	assetsNamespace         string
@@ -54,14 +63,14 @@ func (s *AssetPSQL) GetBlockChainAssetByCodeAndIssuer(blockchain, code, issuer s
 	query += fmt.Sprintf(" join %s ma on %s.id = ma.asset_id ",
 		s.merchantAssetsNamespace, an)
 	query += fmt.Sprintf("join %s ml on ma.merchant_list_id = ml.id ", s.merchantListNamespace)
+	args := []interface{}{blockchain, code}
 	if issuer == "" {
-		query += fmt.Sprintf(" WHERE blockchain = '%s' and code = '%s' and issuer IS NULL and %s.deleted_at IS NULL",
-			blockchain, code, an)
+		query += fmt.Sprintf(" WHERE blockchain = $1 and code = $2 and issuer IS NULL and %s.deleted_at IS NULL", an)
 	} else {
-		query += fmt.Sprintf(" WHERE blockchain = '%s' and code = '%s' and issuer = '%s' and %s.deleted_at IS NULL",
-			blockchain, code, issuer, an)
+		args = append(args, issuer)
+		query += fmt.Sprintf(" WHERE blockchain = $1 and code = $2 and issuer = $3 and %s.deleted_at IS NULL", an)
 	}
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, args...)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	} else if err != nil {
@@ -91,41 +100,33 @@ func (s *AssetPSQL) GetAssetList(merchID string, blockChain, code []string, code
 		s.merchantAssetsNamespace, an)
 	query += fmt.Sprintf("join %s ml on ma.merchant_list_id = ml.id ", s.merchantListNamespace)
 
+	var args []interface{}
 	if merchID != "" {
-		query += fmt.Sprintf("where merchant_id = '%s' and ", merchID)
+		args = append(args, merchID)
+		query += "where merchant_id = $1 and "
 	} else {
 		query += "where "
 	}
-	query += fmt.Sprintf("%s.deleted_at IS NULL and %s.created_at > '%v' and %s.created_at < '%v' ",
-		an, an, from.Format(time.RFC3339), an, to.Format(time.RFC3339))
+	args = append(args, from, to)
+	query += fmt.Sprintf("%s.deleted_at IS NULL and %s.created_at > $%d and %s.created_at < $%d ",
+		an, an, len(args)-1, an, len(args))
 
-	if blockChain != nil && len(blockChain) > 0 && blockChain[0] != "" {
-		query += fmt.Sprintf(" and %s.blockchain in (", an)
-		for i, val := range blockChain {
-			if i > 0 {
-				query += ", "
-			}
-			query += fmt.Sprintf("%v", val)
-		}
-		query += ") "
+	if len(blockChain) > 0 && blockChain[0] != "" {
+		args = append(args, pq.Array(blockChain))
+		query += fmt.Sprintf(" and %s.blockchain = ANY($%d) ", an, len(args))
 	}
 
-	if code != nil && len(code) > 0 && code[0] != "" {
-		query += fmt.Sprintf(" and %s.code in (", an)
-		for i, val := range blockChain {
-			if i > 0 {
-				query += ", "
-			}
-			query += fmt.Sprintf("%v", val)
-		}
-		query += ") "
+	if len(code) > 0 && code[0] != "" {
+		args = append(args, pq.Array(code))
+		query += fmt.Sprintf(" and %s.code = ANY($%d) ", an, len(args))
 	}
 
 	if status != "" {
-		query += fmt.Sprintf("and %s.status = '%s' ", an, status)
+		args = append(args, status)
+		query += fmt.Sprintf("and %s.status = $%d ", an, len(args))
 	}
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, args...)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	} else if err != nil {
@@ -145,29 +146,30 @@ func (s *AssetPSQL) CreateAsset(blockchain, code, smartContractAddress, name, de
 	features json.RawMessage) error {
 	var err error
 	if smartContractAddress != "" {
-		query := fmt.Sprintf("WITH merchantID AS (SELECT id FROM %s WHERE merchant_id = '%s'), "+
+		query := fmt.Sprintf("WITH merchantID AS (SELECT id FROM %s WHERE merchant_id = $8), "+
 			"assetID AS (INSERT INTO %s(created_at, updated_at, blockchain, code, issuer, name, description, status) "+
 			"values ($1, $1, $2, $3, $4, $5, $6, $7) "+
 			"on conflict (blockchain, code, issuer)  do update set blockchain = $2 RETURNING id) "+
 			"INSERT INTO %s (created_at, updated_at, asset_id, merchant_list_id) VALUES "+
-			"($1, $1, (SELECT id FROM assetID), (SELECT id FROM merchantID)) RETURNING id",
-			s.merchantListNamespace, merchantOwnerID, s.assetsNamespace, s.merchantAssetsNamespace)
-		_, err = s.db.Query(query,
-			time.Now().UTC(), blockchain, code, smartContractAddress, name, description, AssetPending)
+			"($1, $1, (SELECT id FROM assetID), (SELECT id FROM merchantID))",
+			s.merchantListNamespace, s.assetsNamespace, s.merchantAssetsNamespace)
+		_, err = s.db.Exec(query,
+			time.Now().UTC(), blockchain, code, smartContractAddress, name, description, AssetPending, merchantOwnerID)
 	} else {
-		query := fmt.Sprintf("WITH merchantID AS (SELECT id FROM %s WHERE merchant_id = '%s'), "+
+		query := fmt.Sprintf("WITH merchantID AS (SELECT id FROM %s WHERE merchant_id = $10), "+
 			"assetID AS (INSERT INTO %s "+
 			"(created_at, updated_at, blockchain, code, issuer, name, description, status, type, features, merchant_owner) "+
 			"VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, $9, (SELECT id FROM merchantID)) RETURNING id) "+
 			"INSERT INTO %s (created_at, updated_at, asset_id, merchant_list_id) VALUES "+
 			"($1, $1, (SELECT id FROM assetID), (SELECT id FROM merchantID)) RETURNING id",
-			s.merchantListNamespace, merchantOwnerID, s.assetsNamespace, s.merchantAssetsNamespace)
+			s.merchantListNamespace, s.assetsNamespace, s.merchantAssetsNamespace)
 		if features == nil {
 			features = json.RawMessage{}
 			_ = features.UnmarshalJSON([]byte("{}"))
 		}
 		_, err = s.db.Exec(query,
-			time.Now().UTC(), blockchain, code, nil, name, description, AssetPending, assetType, features)
+			time.Now().UTC(), blockchain, code, nil, name, description, AssetPending, assetType, features,
+			merchantOwnerID)
 	}
 
 	if err != nil {
@@ -180,10 +182,10 @@ func (s *AssetPSQL) CreateAsset(blockchain, code, smartContractAddress, name, de
 func (s *AssetPSQL) ActivateAsset(blockchain, code, issuer, merchantID string) error {
 	query := fmt.Sprintf(
 		"UPDATE %s SET updated_at = $4, status = $5, issuer  = $3 WHERE blockchain = $1 AND code  = $2 AND "+
-			"merchant_owner = (select id from %s where merchant_id = '%s') ",
-		s.assetsNamespace, s.merchantListNamespace, merchantID)
+			"merchant_owner = (select id from %s where merchant_id = $6) ",
+		s.assetsNamespace, s.merchantListNamespace)
 	_, err := s.db.Exec(query,
-		blockchain, code, issuer, time.Now().UTC(), AssetActive)
+		blockchain, code, issuer, time.Now().UTC(), AssetActive, merchantID)
 	if err != nil {
 		return err
 	}
@@ -206,11 +208,11 @@ func (s *AssetPSQL) SetAssetStatus(asset AssetStore, status AssetStatus) error {
 // DeleteAssetRequest deletes the request for asset from "assets" database
 func (s *AssetPSQL) DeleteAssetRequest(asset AssetStore, merchantID string) error {
 	query := fmt.Sprintf(
-		"DELETE FROM %s WHERE merchant_list_id = (SELECT id FROM %s WHERE merchant_id = '%s') AND "+
+		"DELETE FROM %s WHERE merchant_list_id = (SELECT id FROM %s WHERE merchant_id = $4) AND "+
 			"asset_id = (SELECT id FROM %s WHERE blockchain = $1 AND code  = $2 AND issuer  = $3)",
-		s.merchantAssetsNamespace, s.merchantListNamespace, merchantID, s.assetsNamespace)
-	_, err := s.db.Query(query,
-		asset.BlockChain, asset.Code, asset.Issuer)
+		s.merchantAssetsNamespace, s.merchantListNamespace, s.assetsNamespace)
+	_, err := s.db.Exec(query,
+		asset.BlockChain, asset.Code, asset.Issuer, merchantID)
 	if err != nil {
 		return err
 	}
@@ -228,7 +230,7 @@ func (s *AssetPSQL) DeleteAssetRequest(asset AssetStore, merchantID string) erro
 // UpdateDescription updates the description in store by blockchain, code and issuer from AssetStore structure
 func (s *AssetPSQL) UpdateDescription(asset AssetStore, description string) error {
 	query := fmt.Sprintf(
-		"UPDATE %s SET updated_at = $4, description = $5 WHERE blockchain = $1, code  = $2, issuer  = $3",
+		"UPDATE %s SET updated_at = $4, description = $5 WHERE blockchain = $1 AND code  = $2 AND issuer  = $3",
 		s.assetsNamespace)
 	_, err := s.db.Exec(query,
 		asset.BlockChain, asset.Code, asset.Issuer, time.Now().UTC(), description)
@@ -238,14 +240,49 @@ func (s *AssetPSQL) UpdateDescription(asset AssetStore, description string) erro
 	return nil
 }
 
+// GetWithdrawLimits returns status and withdraw limits of the blockchain asset by code and issuer
+func (s *AssetPSQL) GetWithdrawLimits(blockchain, code, issuer string) (AssetStatus, WithdrawLimits, error) {
+	limits := WithdrawLimits{}
+	query := fmt.Sprintf("SELECT status, min_withdraw, max_withdraw FROM %s "+
+		"WHERE blockchain = $1 and code = $2 and coalesce(issuer, '') = $3 and deleted_at IS NULL",
+		s.assetsNamespace)
+	var status AssetStatus
+	err := s.db.QueryRow(query, blockchain, code, issuer).Scan(&status, &limits.Min, &limits.Max)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", limits, ErrNotFound
+	} else if err != nil {
+		return "", limits, fmt.Errorf("could not execute query: %w", err)
+	}
+	return status, limits, nil
+}
+
+// SetWithdrawLimits sets withdraw limits of the blockchain asset by code and issuer
+func (s *AssetPSQL) SetWithdrawLimits(blockchain, code, issuer string, limits WithdrawLimits) error {
+	query := fmt.Sprintf("UPDATE %s SET updated_at = $4, min_withdraw = $5, max_withdraw = $6 "+
+		"WHERE blockchain = $1 and code = $2 and coalesce(issuer, '') = $3 and deleted_at IS NULL",
+		s.assetsNamespace)
+	res, err := s.db.Exec(query, blockchain, code, issuer, time.Now().UTC(), limits.Min, limits.Max)
+	if err != nil {
+		return fmt.Errorf("could not execute query: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // LinkAssetToMerchant link the asset to a merchant store by unique user identity and merchant id
 func (s *UserPSQL) LinkAssetToMerchant(identity, merchantID string, merchantAccess MerchantAccess) error {
 	query := fmt.Sprintf(
 		"INSERT INTO %s (created_at, updated_at, deleted_at, user_id, merchant_list_id)"+
-			"values (now(), now(), null, (select id from %s where identity = '%s'), "+
-			"(select id from %s where merchant_id = '%s'))",
-		s.merchantUsersNamespace, s.userNamespace, identity, s.merchantListNamespace, merchantID)
-	_, err := s.db.Exec(query)
+			"values (now(), now(), null, (select id from %s where identity = $1), "+
+			"(select id from %s where merchant_id = $2))",
+		s.merchantUsersNamespace, s.userNamespace, s.merchantListNamespace)
+	_, err := s.db.Exec(query, identity, merchantID)
 	if err != nil {
 		return err
 	}
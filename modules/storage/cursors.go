@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type CursorsPSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// NewCursorStorage creates new storage for heights of the last processed blocks by blockchain scanners
+func NewCursorStorage(namespace string, db *sql.DB) (*CursorsPSQL, error) {
+	s := CursorsPSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to cursor storage: %v", err)
+	}
+	return &s, nil
+}
+
+// Get returns height of the last processed block by the scanner
+func (s *CursorsPSQL) Get(name string) (int64, error) {
+	query := fmt.Sprintf("SELECT height FROM %s WHERE name = $1", s.namespace)
+	var height int64
+	err := s.db.QueryRow(query, name).Scan(&height)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, fmt.Errorf("could not get cursor: %v, err: %w", name, err)
+	}
+	return height, nil
+}
+
+// Set stores height of the last processed block by the scanner
+func (s *CursorsPSQL) Set(name string, height int64) error {
+	query := fmt.Sprintf("INSERT INTO %s (name, height, updated_at) VALUES ($1, $2, $3) "+
+		"ON CONFLICT (name) DO UPDATE SET height = EXCLUDED.height, updated_at = EXCLUDED.updated_at",
+		s.namespace)
+	if _, err := s.db.Exec(query, name, height, time.Now().UTC()); err != nil {
+		return fmt.Errorf("could not set cursor: %v, err: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"coreum_processor/modules/encryption"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type EndpointPurpose string
+
+const (
+	// EndpointNotifications is an endpoint which receives webhook events
+	EndpointNotifications EndpointPurpose = "notifications"
+	// EndpointMultiSign is an endpoint which provides addresses and signatures for multisignature wallets
+	EndpointMultiSign EndpointPurpose = "multisign"
+
+	webhookSecretPrefix = "whsec_"
+	webhookSecretLength = 32
+)
+
+// WebhookEndpoint is an url registered by the merchant with its own secret and filter of events,
+// the secret is stored encrypted and is not returned with the endpoint
+type WebhookEndpoint struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	MerchantID string          `json:"merchant_id"`
+	Name       string          `json:"name"`
+	URL        string          `json:"url"`
+	Purpose    EndpointPurpose `json:"purpose"`
+	Secret     string          `json:"-"`
+	Enabled    bool            `json:"enabled"`
+	// Events lists types of webhook events sent to the endpoint, empty list means all
+	Events []string `json:"events"`
+}
+
+// IsSubscribed checks if the endpoint receives the type of webhook event
+func (e WebhookEndpoint) IsSubscribed(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, pattern := range e.Events {
+		if MatchEventType(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEndpointPurpose checks if the purpose of endpoint is known
+func IsEndpointPurpose(purpose EndpointPurpose) bool {
+	return purpose == EndpointNotifications || purpose == EndpointMultiSign
+}
+
+// endpointColumns lists columns of the endpoints table in order expected by rowsToWebhookEndpoints
+const endpointColumns = "id, created_at, updated_at, merchant_id, name, url, purpose, secret, enabled, events"
+
+type EndpointsPSQL struct {
+	db        *sql.DB
+	namespace string
+	provider  encryption.KeyProvider
+}
+
+// NewEndpointStorage creates new storage for webhook endpoints of merchants, secrets of the endpoints
+// are encrypted with keys of the provider bound to the merchant and id of the endpoint, the provider is required
+func NewEndpointStorage(namespace string, db *sql.DB, provider encryption.KeyProvider) (*EndpointsPSQL, error) {
+	if provider == nil {
+		return nil, errors.New("encryption key provider is not set")
+	}
+	s := EndpointsPSQL{
+		db:        db,
+		namespace: namespace,
+		provider:  provider,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to endpoint storage: %v", err)
+	}
+	return &s, nil
+}
+
+// Create registers the endpoint of the merchant with a new random secret
+func (s *EndpointsPSQL) Create(merchantID, name, url string, purpose EndpointPurpose, enabled bool,
+	events []string) (WebhookEndpoint, error) {
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+	// id is taken before insert, so the secret is sealed bound to it
+	var id int64
+	err = s.db.QueryRow("SELECT nextval(pg_get_serial_sequence($1, 'id'))", s.namespace).Scan(&id)
+	if err != nil {
+		return WebhookEndpoint{}, fmt.Errorf("could not get id of endpoint for merchant: %v, err: %w", merchantID, err)
+	}
+	sealed, err := encryption.Seal(s.provider, []byte(secret), endpointSecretAAD(merchantID, id))
+	if err != nil {
+		return WebhookEndpoint{}, fmt.Errorf("could not encrypt secret of endpoint: %v, err: %w", id, err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (id, created_at, updated_at, merchant_id, name, url, purpose, secret, "+
+		"enabled, events) VALUES ($1, $2, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING %s",
+		s.namespace, endpointColumns)
+	rows, err := s.db.Query(query, id, time.Now().UTC(), merchantID, name, url, purpose, string(sealed), enabled,
+		pq.Array(events))
+	if err != nil {
+		return WebhookEndpoint{}, fmt.Errorf("could not create endpoint for merchant: %v, err: %w", merchantID, err)
+	}
+	defer func() { _ = rows.Close() }()
+	endpoints, err := s.rowsToWebhookEndpoints(rows)
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+	if len(endpoints) == 0 {
+		return WebhookEndpoint{}, fmt.Errorf("could not create endpoint for merchant: %v", merchantID)
+	}
+	return endpoints[0], nil
+}
+
+// Get returns the endpoint of the merchant, ErrNotFound is returned if the merchant doesn't have it
+func (s *EndpointsPSQL) Get(merchantID string, id int64) (WebhookEndpoint, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 and id = $2", endpointColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, id)
+	if err != nil {
+		return WebhookEndpoint{}, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	endpoints, err := s.rowsToWebhookEndpoints(rows)
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+	if len(endpoints) == 0 {
+		return WebhookEndpoint{}, ErrNotFound
+	}
+	return endpoints[0], nil
+}
+
+// GetByMerchant returns all endpoints of the merchant
+func (s *EndpointsPSQL) GetByMerchant(merchantID string) ([]WebhookEndpoint, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 ORDER BY id", endpointColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return s.rowsToWebhookEndpoints(rows)
+}
+
+// GetEnabled returns enabled endpoints of the merchant with the purpose
+func (s *EndpointsPSQL) GetEnabled(merchantID string, purpose EndpointPurpose) ([]WebhookEndpoint, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 and purpose = $2 and enabled ORDER BY id",
+		endpointColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, purpose)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return s.rowsToWebhookEndpoints(rows)
+}
+
+// Update changes the endpoint of the merchant, the secret is kept,
+// ErrNotFound is returned if the merchant doesn't have the endpoint
+func (s *EndpointsPSQL) Update(merchantID string, id int64, name, url string, enabled bool,
+	events []string) error {
+	query := fmt.Sprintf("UPDATE %s SET updated_at = $1, name = $2, url = $3, enabled = $4, events = $5 "+
+		"WHERE merchant_id = $6 and id = $7", s.namespace)
+	res, err := s.db.Exec(query, time.Now().UTC(), name, url, enabled, pq.Array(events), merchantID, id)
+	return checkEndpointAffected(res, err, id)
+}
+
+// Delete removes the endpoint of the merchant, events fanned out to it are skipped,
+// ErrNotFound is returned if the merchant doesn't have the endpoint
+func (s *EndpointsPSQL) Delete(merchantID string, id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE merchant_id = $1 and id = $2", s.namespace)
+	res, err := s.db.Exec(query, merchantID, id)
+	return checkEndpointAffected(res, err, id)
+}
+
+func checkEndpointAffected(res sql.Result, err error, id int64) error {
+	if err != nil {
+		return fmt.Errorf("could not change endpoint: %v, err: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not change endpoint: %v, err: %w", id, err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// generateWebhookSecret returns random secret to sign webhooks sent to an endpoint
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("could not generate webhook secret: %w", err)
+	}
+	return webhookSecretPrefix + hex.EncodeToString(secret), nil
+}
+
+// endpointSecretAAD returns additional data which binds the sealed secret to the endpoint of the merchant
+func endpointSecretAAD(merchantID string, id int64) []byte {
+	return []byte(fmt.Sprintf("%s/%d", merchantID, id))
+}
+
+// rowsToWebhookEndpoints reads endpoints from the rows and decrypts their secrets
+func (s *EndpointsPSQL) rowsToWebhookEndpoints(rows *sql.Rows) ([]WebhookEndpoint, error) {
+	var endpoints []WebhookEndpoint
+	for rows.Next() {
+		e := WebhookEndpoint{}
+		var sealed string
+		err := rows.Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt, &e.MerchantID, &e.Name, &e.URL, &e.Purpose,
+			&sealed, &e.Enabled, pq.Array(&e.Events))
+		if err != nil {
+			return nil, fmt.Errorf("could not scan endpoint: %w", err)
+		}
+		secret, err := encryption.Open(s.provider, []byte(sealed), endpointSecretAAD(e.MerchantID, e.ID))
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt secret of endpoint: %v, err: %w", e.ID, err)
+		}
+		e.Secret = string(secret)
+		endpoints = append(endpoints, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read endpoints: %w", err)
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"coreum_processor/modules/amount"
+	"strings"
+	"time"
+)
+
+// WebhookEventVersion is version of the envelope and data of webhook events,
+// it is changed when a field of them is changed or removed
+const WebhookEventVersion = 1
+
+const (
+	EventDepositDetected    = "deposit.detected"
+	EventDepositQuarantined = "deposit.quarantined"
+	EventDepositSettled     = "deposit.settled"
+	EventDepositDone        = "deposit.done"
+	EventDepositRejected    = "deposit.rejected"
+	EventDepositHeld        = "deposit.held"
+	EventWithdrawRequested  = "withdraw.requested"
+	EventWithdrawBroadcast  = "withdraw.broadcast"
+	EventWithdrawDone       = "withdraw.done"
+	EventWithdrawRejected   = "withdraw.rejected"
+	EventWithdrawHeld       = "withdraw.held"
+	EventRefundCreated      = "refund.created"
+	EventRefundBroadcast    = "refund.broadcast"
+	EventRefundDone         = "refund.done"
+	EventRefundRejected     = "refund.rejected"
+	EventAssetIssued        = "asset.issued"
+	// EventWebhookTest is sent only on request of the merchant to check its endpoint, it can't be subscribed to
+	EventWebhookTest = "webhook.test"
+)
+
+// WebhookEventTypes lists types of events merchants can subscribe to
+var WebhookEventTypes = []string{
+	EventDepositDetected, EventDepositQuarantined, EventDepositSettled, EventDepositDone, EventDepositRejected,
+	EventDepositHeld,
+	EventWithdrawRequested, EventWithdrawBroadcast, EventWithdrawDone, EventWithdrawRejected, EventWithdrawHeld,
+	EventRefundCreated, EventRefundBroadcast, EventRefundDone, EventRefundRejected,
+	EventAssetIssued,
+}
+
+// createdEventTypes lists types of events about a new transaction by its action
+var createdEventTypes = map[ActionTx]string{
+	DepositTransaction:  EventDepositDetected,
+	WithdrawTransaction: EventWithdrawRequested,
+	RefundTransaction:   EventRefundCreated,
+}
+
+// transitEventTypes lists types of events about a transaction moved to the status by its action
+var transitEventTypes = map[StatusTx]map[ActionTx]string{
+	SettledTransaction: {
+		DepositTransaction: EventDepositSettled,
+		RefundTransaction:  EventRefundBroadcast,
+	},
+	DoneTransaction: {
+		DepositTransaction:  EventDepositDone,
+		WithdrawTransaction: EventWithdrawDone,
+		RefundTransaction:   EventRefundDone,
+	},
+	RejectedTransaction: {
+		DepositTransaction:  EventDepositRejected,
+		WithdrawTransaction: EventWithdrawRejected,
+		RefundTransaction:   EventRefundRejected,
+	},
+	OnHoldTransaction: {
+		DepositTransaction:  EventDepositHeld,
+		WithdrawTransaction: EventWithdrawHeld,
+	},
+}
+
+// precedingEventTypes lists types of events which happen together with the event and are put before it,
+// the payout of a withdraw to the user is broadcast by the same step which moves the withdraw to done status
+var precedingEventTypes = map[string][]string{
+	EventWithdrawDone: {EventWithdrawBroadcast},
+}
+
+// MatchEventType checks if the event type is matched by the pattern, which is either the type
+// or a prefix followed by wildcard like deposit.*
+func MatchEventType(pattern, eventType string) bool {
+	if strings.HasSuffix(pattern, ".*") {
+		return strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == eventType
+}
+
+// IsWebhookEventType checks if the pattern matches any type of the catalogue
+func IsWebhookEventType(pattern string) bool {
+	for _, eventType := range WebhookEventTypes {
+		if MatchEventType(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// TransactionEventData is data of events about transactions, it includes hashes of blockchain transactions
+// made for the transaction in order of the processing steps
+type TransactionEventData struct {
+	GUID           string        `json:"guid"`
+	MerchantID     string        `json:"merchant_id"`
+	ExternalID     string        `json:"external_id"`
+	Blockchain     string        `json:"blockchain"`
+	Action         ActionTx      `json:"action"`
+	Status         StatusTx      `json:"status"`
+	ExtWallet      string        `json:"ext_wallet"`
+	Asset          string        `json:"asset"`
+	Issuer         string        `json:"issuer"`
+	Amount         amount.Amount `json:"amount"`
+	Commission     amount.Amount `json:"commission"`
+	Hash           string        `json:"hash,omitempty"`
+	ProcessingHash string        `json:"processing_hash,omitempty"`
+	SettlementHash string        `json:"settlement_hash,omitempty"`
+	CompletionHash string        `json:"completion_hash,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// NewTransactionEventData returns data of an event about the transaction
+func NewTransactionEventData(tr TransactionStore) TransactionEventData {
+	return TransactionEventData{
+		GUID:           tr.GUID.String(),
+		MerchantID:     tr.MerchantId,
+		ExternalID:     tr.ExternalId,
+		Blockchain:     tr.Blockchain,
+		Action:         tr.Action,
+		Status:         tr.Status,
+		ExtWallet:      tr.ExtWallet,
+		Asset:          tr.Asset,
+		Issuer:         tr.Issuer,
+		Amount:         tr.Amount,
+		Commission:     tr.Commission,
+		Hash:           tr.Hash1,
+		ProcessingHash: tr.Hash2,
+		SettlementHash: tr.Hash3,
+		CompletionHash: tr.Hash5,
+		CreatedAt:      tr.CreatedAt,
+		UpdatedAt:      tr.UpdatedAt,
+	}
+}
+
+// QuarantineEventData is data of events about quarantined deposits
+type QuarantineEventData struct {
+	ID         int64         `json:"id"`
+	MerchantID string        `json:"merchant_id"`
+	Blockchain string        `json:"blockchain"`
+	Hash       string        `json:"hash"`
+	Memo       string        `json:"memo"`
+	Sender     string        `json:"sender"`
+	Asset      string        `json:"asset"`
+	Issuer     string        `json:"issuer"`
+	Amount     amount.Amount `json:"amount"`
+}
+
+// AssetEventData is data of events about assets of the merchant
+type AssetEventData struct {
+	MerchantID string `json:"merchant_id"`
+	Blockchain string `json:"blockchain"`
+	Code       string `json:"code"`
+	Issuer     string `json:"issuer"`
+	Hash       string `json:"hash"`
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyRecord represents a request made by a merchant with an idempotency key,
+// StatusCode is zero while the request is in progress
+type IdempotencyRecord struct {
+	MerchantID  string
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Response    []byte
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type IdempotencyPSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// NewIdempotencyStorage creates new storage for idempotency keys of merchant requests
+func NewIdempotencyStorage(namespace string, db *sql.DB) (*IdempotencyPSQL, error) {
+	s := IdempotencyPSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to idempotency storage: %v", err)
+	}
+	return &s, nil
+}
+
+// reserveAttempts is how many times the key is reserved again if it is released while it is read
+const reserveAttempts = 3
+
+// Reserve makes a new record for the merchant key in progress state and returns nil, the key in progress
+// longer than the lease is taken over as the request holding it is considered lost.
+// If the key has already been used the existing record is returned
+func (s *IdempotencyPSQL) Reserve(merchantID, key, requestHash string, lease time.Duration) (*IdempotencyRecord, error) {
+	query := fmt.Sprintf("INSERT INTO %[1]s AS k (created_at, updated_at, merchant_id, idempotency_key, request_hash) "+
+		"VALUES ($1, $1, $2, $3, $4) ON CONFLICT (merchant_id, idempotency_key) DO UPDATE "+
+		"SET created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at, request_hash = EXCLUDED.request_hash "+
+		"WHERE k.status_code = 0 AND k.updated_at < $5", s.namespace)
+	for attempt := 0; ; attempt++ {
+		now := time.Now().UTC()
+		res, err := s.db.Exec(query, now, merchantID, key, requestHash, now.Add(-lease))
+		if err != nil {
+			return nil, fmt.Errorf("could not reserve idempotency key: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("could not reserve idempotency key: %w", err)
+		}
+		if n == 1 {
+			return nil, nil
+		}
+		record, err := s.Get(merchantID, key)
+		// the key is released by the failed request after the insert, it can be reserved now
+		if errors.Is(err, ErrNotFound) && attempt+1 < reserveAttempts {
+			continue
+		}
+		return record, err
+	}
+}
+
+// Get returns a record for the merchant key
+func (s *IdempotencyPSQL) Get(merchantID, key string) (*IdempotencyRecord, error) {
+	query := fmt.Sprintf("SELECT merchant_id, idempotency_key, request_hash, status_code, response, "+
+		"created_at, updated_at FROM %s WHERE merchant_id = $1 AND idempotency_key = $2", s.namespace)
+	record := IdempotencyRecord{}
+	err := s.db.QueryRow(query, merchantID, key).Scan(&record.MerchantID, &record.Key, &record.RequestHash,
+		&record.StatusCode, &record.Response, &record.CreatedAt, &record.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get idempotency key: %w", err)
+	}
+	return &record, nil
+}
+
+// Complete stores the response for the reserved merchant key which is still in progress
+func (s *IdempotencyPSQL) Complete(merchantID, key string, statusCode int, response []byte) error {
+	query := fmt.Sprintf("UPDATE %s SET updated_at = $1, status_code = $2, response = $3 "+
+		"WHERE merchant_id = $4 AND idempotency_key = $5 AND status_code = 0", s.namespace)
+	_, err := s.db.Exec(query, time.Now().UTC(), statusCode, response, merchantID, key)
+	if err != nil {
+		return fmt.Errorf("could not complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release deletes the merchant key which is still in progress, so the request can be retried with the same key
+func (s *IdempotencyPSQL) Release(merchantID, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE merchant_id = $1 AND idempotency_key = $2 AND status_code = 0",
+		s.namespace)
+	_, err := s.db.Exec(query, merchantID, key)
+	if err != nil {
+		return fmt.Errorf("could not release idempotency key: %w", err)
+	}
+	return nil
+}
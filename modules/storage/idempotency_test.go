@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func newIdempotencyMock(t *testing.T) (*IdempotencyPSQL, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return &IdempotencyPSQL{db: db, namespace: "idempotency_keys"}, mock
+}
+
+var (
+	reserveQuery = regexp.QuoteMeta("INSERT INTO idempotency_keys AS k")
+	getKeyQuery  = regexp.QuoteMeta("SELECT merchant_id, idempotency_key, request_hash")
+	keyColumns   = []string{"merchant_id", "idempotency_key", "request_hash", "status_code", "response",
+		"created_at", "updated_at"}
+)
+
+func TestReserveNewKey(t *testing.T) {
+	s, mock := newIdempotencyMock(t)
+	mock.ExpectExec(reserveQuery).
+		WithArgs(sqlmock.AnyArg(), "merchant", "key", "hash", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	record, err := s.Reserve("merchant", "key", "hash", time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, record)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserveUsedKey(t *testing.T) {
+	s, mock := newIdempotencyMock(t)
+	now := time.Now()
+	mock.ExpectExec(reserveQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(getKeyQuery).WithArgs("merchant", "key").WillReturnRows(sqlmock.NewRows(keyColumns).
+		AddRow("merchant", "key", "hash", 200, []byte(`{}`), now, now))
+
+	record, err := s.Reserve("merchant", "key", "hash", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 200, record.StatusCode)
+	require.Equal(t, []byte(`{}`), record.Response)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserveTakesOverExpiredLease(t *testing.T) {
+	s, mock := newIdempotencyMock(t)
+	lease := time.Minute
+	mock.ExpectExec(reserveQuery).
+		WithArgs(sqlmock.AnyArg(), "merchant", "key", "hash", leaseBefore{lease: lease}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	record, err := s.Reserve("merchant", "key", "hash", lease)
+	require.NoError(t, err)
+	require.Nil(t, record)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserveRetriesReleasedKey(t *testing.T) {
+	s, mock := newIdempotencyMock(t)
+	mock.ExpectExec(reserveQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(getKeyQuery).WillReturnRows(sqlmock.NewRows(keyColumns))
+	mock.ExpectExec(reserveQuery).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	record, err := s.Reserve("merchant", "key", "hash", time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, record)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserveGivesUpOnReleasedKey(t *testing.T) {
+	s, mock := newIdempotencyMock(t)
+	for i := 0; i < reserveAttempts; i++ {
+		mock.ExpectExec(reserveQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(getKeyQuery).WillReturnRows(sqlmock.NewRows(keyColumns))
+	}
+
+	_, err := s.Reserve("merchant", "key", "hash", time.Minute)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// leaseBefore matches the time the key in progress must be updated before to be taken over
+type leaseBefore struct {
+	lease time.Duration
+}
+
+func (l leaseBefore) Match(v driver.Value) bool {
+	expired, ok := v.(time.Time)
+	return ok && time.Since(expired) >= l.lease && time.Since(expired) < l.lease+time.Minute
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// injectionPayloads are values breaking out of quoted SQL literals if they are interpolated into a query
+var injectionPayloads = []string{
+	"x' OR '1'='1",
+	"'; DROP TABLE transactions; --",
+	`o'reilly\'`,
+}
+
+// boundPayload matches an argument bound to a placeholder which carries the payload,
+// payloads of array arguments are bound inside the array literal
+type boundPayload string
+
+func (p boundPayload) Match(v driver.Value) bool {
+	switch value := v.(type) {
+	case string:
+		return strings.Contains(value, string(p)) || strings.Contains(value, strings.ReplaceAll(string(p), `\`, `\\`))
+	case []byte:
+		return p.Match(string(value))
+	}
+	return false
+}
+
+// newInjectionMock returns database mock which fails any query containing the payload
+func newInjectionMock(t *testing.T, payload string) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(
+		func(expectedSQL, actualSQL string) error {
+			if strings.Contains(actualSQL, payload) {
+				return fmt.Errorf("payload is interpolated into query: %s", actualSQL)
+			}
+			if !strings.Contains(actualSQL, expectedSQL) {
+				return fmt.Errorf("query: %s doesn't contain: %s", actualSQL, expectedSQL)
+			}
+			return nil
+		})))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db, mock
+}
+
+func TestQueriesBindPayloads(t *testing.T) {
+	from, to := time.Now().Add(-time.Hour), time.Now()
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock, p boundPayload)
+		call   func(db *sql.DB, p string) error
+	}{
+		{
+			name: "user by identity",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectQuery("WHERE identity = $1").WithArgs(p).WillReturnRows(sqlmock.NewRows(nil))
+			},
+			call: func(db *sql.DB, p string) error {
+				_, err := (&UserPSQL{db: db, userNamespace: "users"}).GetUserByIdentity(p)
+				return err
+			},
+		},
+		{
+			name: "user list",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectQuery("merchant_id = $1").
+					WithArgs(p, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(nil))
+			},
+			call: func(db *sql.DB, p string) error {
+				_, err := (&UserPSQL{db: db, userNamespace: "users"}).GetUserList(p, []int{1}, from, to)
+				return err
+			},
+		},
+		{
+			name: "user merchants",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectQuery("where identity = $1").WithArgs(p).WillReturnRows(sqlmock.NewRows(nil))
+			},
+			call: func(db *sql.DB, p string) error {
+				_, err := (&UserPSQL{db: db, userNamespace: "users"}).GetUserMerchants(p)
+				return err
+			},
+		},
+		{
+			name: "link user to merchant",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectExec("merchant_id = $2").WithArgs(p, p).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			call: func(db *sql.DB, p string) error {
+				return (&UserPSQL{db: db, userNamespace: "users"}).LinkUserToMerchant(p, p, nil)
+			},
+		},
+		{
+			name: "merchant request",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectExec("values (now(), now(), null, $1, $2)").WithArgs(p, p, p).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			call: func(db *sql.DB, p string) error {
+				return (&UserPSQL{db: db, userNamespace: "users"}).RequestMerchantForUser(p, p, p)
+			},
+		},
+		{
+			name: "asset by code and issuer",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectQuery("issuer = $3").WithArgs(p, p, p).WillReturnRows(sqlmock.NewRows(nil))
+			},
+			call: func(db *sql.DB, p string) error {
+				_, err := (&AssetPSQL{db: db, assetsNamespace: "assets"}).GetBlockChainAssetByCodeAndIssuer(p, p, p)
+				return err
+			},
+		},
+		{
+			name: "asset list",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectQuery("blockchain = ANY($4)").
+					WithArgs(p, sqlmock.AnyArg(), sqlmock.AnyArg(), p, p, p).
+					WillReturnRows(sqlmock.NewRows(nil))
+			},
+			call: func(db *sql.DB, p string) error {
+				_, err := (&AssetPSQL{db: db, assetsNamespace: "assets"}).GetAssetList(p, []string{p}, []string{p},
+					"", p, from, to)
+				return err
+			},
+		},
+		{
+			name: "asset activation",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectExec("merchant_id = $6").
+					WithArgs(p, p, p, sqlmock.AnyArg(), AssetActive, p).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(db *sql.DB, p string) error {
+				return (&AssetPSQL{db: db, assetsNamespace: "assets"}).ActivateAsset(p, p, p, p)
+			},
+		},
+		{
+			name: "transaction by guid",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectQuery("guid = $1 AND merchant_id = $2").WithArgs(p, p).
+					WillReturnRows(sqlmock.NewRows(nil))
+			},
+			call: func(db *sql.DB, p string) error {
+				_, err := (&TransactionPSQL{db: db, namespace: "transactions"}).GetTransactionByGuid(p, p)
+				return err
+			},
+		},
+		{
+			name: "transactions of merchant",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectQuery("status = ANY($6)").
+					WithArgs(p, sqlmock.AnyArg(), sqlmock.AnyArg(), p, p, p).
+					WillReturnRows(sqlmock.NewRows(nil))
+			},
+			call: func(db *sql.DB, p string) error {
+				_, err := (&TransactionPSQL{db: db, namespace: "transactions"}).GetTransactionsByMerchant(p, p,
+					[]string{p}, []string{p}, from, to)
+				return err
+			},
+		},
+		{
+			name: "assets of blockchain",
+			expect: func(mock sqlmock.Sqlmock, p boundPayload) {
+				mock.ExpectQuery("WHERE blockchain = $1").WithArgs(p).
+					WillReturnRows(sqlmock.NewRows([]string{"asset", "smart_contract"}))
+			},
+			call: func(db *sql.DB, p string) error {
+				_, err := (&TokenPSQL{db: db, namespace: "tokens"}).GetAllAssetsForBlockchain(p)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, payload := range injectionPayloads {
+			t.Run(tt.name+"/"+payload, func(t *testing.T) {
+				db, mock := newInjectionMock(t, payload)
+				tt.expect(mock, boundPayload(payload))
+				// errors of scanning the empty result are not in the scope, only the query sent is checked
+				_ = tt.call(db, payload)
+				require.NoError(t, mock.ExpectationsWereMet())
+			})
+		}
+	}
+}
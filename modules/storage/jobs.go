@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Job represents a step of the processing which is run by one of the processing replicas,
+// a taken job is leased by moving its next run, so it is run again if the replica fails
+type Job struct {
+	ID        int64
+	Kind      string
+	Key       string
+	Payload   []byte
+	Attempts  int
+	NextRunAt time.Time
+	LastError string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// jobColumns lists columns of the job table in order expected by rowsToJobs
+const jobColumns = "id, kind, key, payload, attempts, next_run_at, last_error, created_at, updated_at"
+
+type JobsPSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// NewJobStorage creates new storage for jobs of the processing
+func NewJobStorage(namespace string, db *sql.DB) (*JobsPSQL, error) {
+	s := JobsPSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to job storage: %v", err)
+	}
+	return &s, nil
+}
+
+// Enqueue makes a new job of the kind with the key to be run at the given time and returns true,
+// if the job with the same kind and key already exists it is kept as is and false is returned
+func (s *JobsPSQL) Enqueue(kind, key string, payload []byte, runAt time.Time) (bool, error) {
+	query := fmt.Sprintf("INSERT INTO %s (created_at, updated_at, kind, key, payload, next_run_at) "+
+		"VALUES ($1, $1, $2, $3, $4, $5) ON CONFLICT (kind, key) DO NOTHING", s.namespace)
+	res, err := s.db.Exec(query, time.Now().UTC(), kind, key, payload, runAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("could not enqueue job: %v, key: %v, err: %w", kind, key, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not enqueue job: %v, key: %v, err: %w", kind, key, err)
+	}
+	return n == 1, nil
+}
+
+// Take returns up to limit jobs which are due to run and leases them for the given duration,
+// jobs taken by another replica are skipped
+func (s *JobsPSQL) Take(limit int, lease time.Duration) ([]Job, error) {
+	now := time.Now().UTC()
+	query := fmt.Sprintf("UPDATE %[1]s SET next_run_at = $1, updated_at = $2 WHERE id IN ("+
+		"SELECT id FROM %[1]s WHERE next_run_at <= $2 ORDER BY next_run_at LIMIT $3 FOR UPDATE SKIP LOCKED) "+
+		"RETURNING %[2]s", s.namespace, jobColumns)
+	rows, err := s.db.Query(query, now.Add(lease), now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not take jobs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return rowsToJobs(rows)
+}
+
+// Complete deletes the finished job
+func (s *JobsPSQL) Complete(id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.namespace)
+	if _, err := s.db.Exec(query, id); err != nil {
+		return fmt.Errorf("could not complete job: %v, err: %w", id, err)
+	}
+	return nil
+}
+
+// Reschedule sets the next run of the job which has been run successfully and resets its attempts
+func (s *JobsPSQL) Reschedule(id int64, runAt time.Time) error {
+	query := fmt.Sprintf("UPDATE %s SET next_run_at = $1, updated_at = $2, attempts = 0, last_error = '' "+
+		"WHERE id = $3", s.namespace)
+	if _, err := s.db.Exec(query, runAt.UTC(), time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("could not reschedule job: %v, err: %w", id, err)
+	}
+	return nil
+}
+
+// Fail records the error of the job run and sets the next attempt to run it
+func (s *JobsPSQL) Fail(id int64, lastError string, runAt time.Time) error {
+	query := fmt.Sprintf("UPDATE %s SET next_run_at = $1, updated_at = $2, attempts = attempts + 1, "+
+		"last_error = $3 WHERE id = $4", s.namespace)
+	if _, err := s.db.Exec(query, runAt.UTC(), time.Now().UTC(), lastError, id); err != nil {
+		return fmt.Errorf("could not fail job: %v, err: %w", id, err)
+	}
+	return nil
+}
+
+func rowsToJobs(rows *sql.Rows) ([]Job, error) {
+	var jobs []Job
+	for rows.Next() {
+		job := Job{}
+		err := rows.Scan(&job.ID, &job.Kind, &job.Key, &job.Payload, &job.Attempts, &job.NextRunAt,
+			&job.LastError, &job.CreatedAt, &job.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read jobs: %w", err)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func newJobsMock(t *testing.T) (*JobsPSQL, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return &JobsPSQL{db: db, namespace: "processing_jobs"}, mock
+}
+
+var jobRowColumns = []string{"id", "kind", "key", "payload", "attempts", "next_run_at", "last_error",
+	"created_at", "updated_at"}
+
+func TestEnqueueKeepsExistingJob(t *testing.T) {
+	s, mock := newJobsMock(t)
+	query := regexp.QuoteMeta("ON CONFLICT (kind, key) DO NOTHING")
+	mock.ExpectExec(query).WithArgs(sqlmock.AnyArg(), "transaction", "guid", []byte(`{}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query).WithArgs(sqlmock.AnyArg(), "transaction", "guid", []byte(`{}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	created, err := s.Enqueue("transaction", "guid", []byte(`{}`), time.Now())
+	require.NoError(t, err)
+	require.True(t, created)
+	created, err = s.Enqueue("transaction", "guid", []byte(`{}`), time.Now())
+	require.NoError(t, err)
+	require.False(t, created)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTakeLeasesJobs(t *testing.T) {
+	s, mock := newJobsMock(t)
+	lease := 5 * time.Minute
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE SKIP LOCKED")).
+		WithArgs(timeAfter{delay: lease}, timeAfter{}, 10).
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(1, "transaction", "guid", []byte(`{}`), 2, now.Add(lease), "failed", now, now))
+
+	jobs, err := s.Take(10, lease)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "guid", jobs[0].Key)
+	require.Equal(t, 2, jobs[0].Attempts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFailSchedulesRetry(t *testing.T) {
+	s, mock := newJobsMock(t)
+	backoff := 4 * time.Minute
+	mock.ExpectExec(regexp.QuoteMeta("attempts = attempts + 1")).
+		WithArgs(timeAfter{delay: backoff}, sqlmock.AnyArg(), "node is down", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, s.Fail(1, "node is down", time.Now().Add(backoff)))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRescheduleResetsAttempts(t *testing.T) {
+	s, mock := newJobsMock(t)
+	mock.ExpectExec(regexp.QuoteMeta("attempts = 0, last_error = ''")).
+		WithArgs(timeAfter{}, sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, s.Reschedule(1, time.Now()))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// timeAfter matches time which is the delay after now
+type timeAfter struct {
+	delay time.Duration
+}
+
+func (a timeAfter) Match(v driver.Value) bool {
+	at, ok := v.(time.Time)
+	return ok && time.Until(at) <= a.delay && time.Until(at) > a.delay-time.Minute
+}
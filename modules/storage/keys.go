@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"coreum_processor/modules/encryption"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -8,6 +9,9 @@ import (
 	"time"
 )
 
+// keysUpdateBatch is number of records read at once to rewrap or encrypt them
+const keysUpdateBatch = 100
+
 // KeyRecord represents a record in the storage
 type KeyRecord struct {
 	ID         int64
@@ -20,13 +24,19 @@ type KeyRecord struct {
 type KeysPSQL struct {
 	db        *sql.DB
 	namespace string
+	provider  encryption.KeyProvider
 }
 
-// NewKeys creates new storage for blockchain wallets
-func NewKeys(namespace string, db *sql.DB) (*KeysPSQL, error) {
+// NewKeys creates new storage for blockchain wallets, data of the records is encrypted
+// with keys of the provider bound to the key of the record, the provider is required
+func NewKeys(namespace string, db *sql.DB, provider encryption.KeyProvider) (*KeysPSQL, error) {
+	if provider == nil {
+		return nil, errors.New("encryption key provider is not set")
+	}
 	s := KeysPSQL{
 		db:        db,
 		namespace: namespace,
+		provider:  provider,
 	}
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("could not connect to DB: %v", err)
@@ -40,6 +50,10 @@ func NewKeys(namespace string, db *sql.DB) (*KeysPSQL, error) {
 // Put creates or updates a record in the storage with the key and data and returns
 // numberic ID of the created record
 func (s *KeysPSQL) Put(merchantID, externalID, key string, data []byte) (int64, error) {
+	data, err := s.seal(key, data)
+	if err != nil {
+		return 0, err
+	}
 	row := s.db.QueryRow(
 		fmt.Sprintf(`INSERT INTO "%s" (created_at, updated_at, merchant_id, external_id, key, value)
 VALUES
@@ -59,13 +73,17 @@ RETURNING id;`, s.namespace), time.Now().UTC(), time.Now().UTC(), merchantID, ex
 // numberic ID, true of the created record. If key has already exists,
 // then (0, false, nil) returned.
 func (s *KeysPSQL) Set(merchantID, externalID, key string, data []byte) (int64, bool, error) {
+	data, err := s.seal(key, data)
+	if err != nil {
+		return 0, false, err
+	}
 	row := s.db.QueryRow(fmt.Sprintf(`INSERT INTO %s(merchant_id, external_id, key, value)
 VALUES ($1, $2, $3, $4)
 ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value
-RETURNING id`, s.namespace, s.namespace, s.namespace), merchantID, externalID, key, data)
+RETURNING id`, s.namespace), merchantID, externalID, key, data)
 
 	var id int64
-	err := row.Scan(&id)
+	err = row.Scan(&id)
 
 	if err == nil {
 		return id, true, nil
@@ -147,6 +165,9 @@ func (s *KeysPSQL) getByKey(key string, query string) (*KeyRecord, error) {
 	)
 	err := row.Scan(&id, &merchantID, &externalID, &value, &updatedAt)
 	if err == nil {
+		if value, err = s.open(key, value); err != nil {
+			return nil, err
+		}
 		return &KeyRecord{
 				ID:         id,
 				MerchantID: merchantID,
@@ -173,6 +194,9 @@ func (s *KeysPSQL) getByUser(merchantID, externalID, query string) (*KeyRecord,
 	)
 	err := row.Scan(&id, &key, &value, &updatedAt)
 	if err == nil {
+		if value, err = s.open(key, value); err != nil {
+			return nil, err
+		}
 		return &KeyRecord{
 				ID:         id,
 				MerchantID: merchantID,
@@ -210,6 +234,9 @@ func (s *KeysPSQL) GetNext(id, limit int64) ([]KeyRecord, error) {
 		)
 		err := rows.Scan(&id, &merchantID, &externalID, &key, &value, &updatedAt)
 		if err == nil {
+			if value, err = s.open(key, value); err != nil {
+				return nil, err
+			}
 			record := &KeyRecord{
 				ID:         id,
 				MerchantID: merchantID,
@@ -225,3 +252,89 @@ func (s *KeysPSQL) GetNext(id, limit int64) ([]KeyRecord, error) {
 	}
 	return records, nil
 }
+
+// Rewrap wraps data keys of encrypted records with the current master key of the provider
+// and returns number of updated records, it is used after rotation of the master key
+func (s *KeysPSQL) Rewrap() (int64, error) {
+	return s.update(func(_ string, value []byte) ([]byte, bool, error) {
+		if !encryption.IsSealed(value) {
+			return nil, false, nil
+		}
+		return encryption.Rewrap(s.provider, value)
+	})
+}
+
+// EncryptLegacy encrypts records stored as plaintext and returns number of updated records
+func (s *KeysPSQL) EncryptLegacy() (int64, error) {
+	return s.update(func(key string, value []byte) ([]byte, bool, error) {
+		if encryption.IsSealed(value) {
+			return nil, false, nil
+		}
+		res, err := encryption.Seal(s.provider, value, []byte(key))
+		return res, err == nil, err
+	})
+}
+
+// update goes through all records and replaces raw data of the records with the result of the function,
+// the record is skipped if it has been changed concurrently
+func (s *KeysPSQL) update(fn func(key string, value []byte) ([]byte, bool, error)) (int64, error) {
+	query := fmt.Sprintf(`SELECT id, key, value FROM %s WHERE id > $1 ORDER BY id LIMIT $2`, s.namespace)
+	update := fmt.Sprintf(`UPDATE %s SET value = $1, updated_at = $2 WHERE id = $3 AND value = $4`, s.namespace)
+	var (
+		next, count int64
+	)
+	for {
+		rows, err := s.db.Query(query, next, keysUpdateBatch)
+		if err != nil {
+			return count, fmt.Errorf("could not select rows from storage: %w", err)
+		}
+		var records []KeyRecord
+		for rows.Next() {
+			record := KeyRecord{}
+			if err = rows.Scan(&record.ID, &record.Key, &record.Data); err != nil {
+				_ = rows.Close()
+				return count, fmt.Errorf("could not scan row from storage: %w", err)
+			}
+			records = append(records, record)
+		}
+		_ = rows.Close()
+		if len(records) == 0 {
+			return count, nil
+		}
+		for _, record := range records {
+			next = record.ID
+			value, ok, err := fn(record.Key, record.Data)
+			if err != nil {
+				return count, fmt.Errorf("could not update record: %v, err: %w", record.ID, err)
+			}
+			if !ok {
+				continue
+			}
+			res, err := s.db.Exec(update, value, time.Now().UTC(), record.ID, record.Data)
+			if err != nil {
+				return count, fmt.Errorf("could not update record: %v, err: %w", record.ID, err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return count, fmt.Errorf("could not update record: %v, err: %w", record.ID, err)
+			}
+			count += n
+		}
+	}
+}
+
+func (s *KeysPSQL) seal(key string, data []byte) ([]byte, error) {
+	res, err := encryption.Seal(s.provider, data, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt record: %w", err)
+	}
+	return res, nil
+}
+
+func (s *KeysPSQL) open(key string, data []byte) ([]byte, error) {
+	res, err := encryption.Open(s.provider, data, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt record: %w", err)
+	}
+	return res, nil
+}
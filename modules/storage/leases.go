@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type LeasesPSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// NewLeaseStorage creates new storage for leases of the leader election between processing replicas
+func NewLeaseStorage(namespace string, db *sql.DB) (*LeasesPSQL, error) {
+	s := LeasesPSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to lease storage: %v", err)
+	}
+	return &s, nil
+}
+
+// Acquire takes or renews the lease of the name for the holder and returns true,
+// false is returned if the lease is held by another holder and has not expired yet.
+// Expiration is counted by the database clock, so clocks of the replicas don't matter
+func (s *LeasesPSQL) Acquire(name, holder string, ttl time.Duration) (bool, error) {
+	query := fmt.Sprintf("INSERT INTO %[1]s (name, holder, expires_at, updated_at) "+
+		"VALUES ($1, $2, now() + $3 * interval '1 millisecond', now()) "+
+		"ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at, "+
+		"updated_at = EXCLUDED.updated_at WHERE %[1]s.holder = EXCLUDED.holder OR %[1]s.expires_at < now()",
+		s.namespace)
+	res, err := s.db.Exec(query, name, holder, ttl.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("could not acquire lease: %v, err: %w", name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not acquire lease: %v, err: %w", name, err)
+	}
+	return n == 1, nil
+}
+
+// Release gives up the lease of the name if it is held by the holder
+func (s *LeasesPSQL) Release(name, holder string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE name = $1 AND holder = $2", s.namespace)
+	if _, err := s.db.Exec(query, name, holder); err != nil {
+		return fmt.Errorf("could not release lease: %v, err: %w", name, err)
+	}
+	return nil
+}
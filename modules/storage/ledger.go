@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"coreum_processor/modules/amount"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type LedgerAccount string
+
+const (
+	// LedgerMerchant keeps funds the merchant got through the processing, done deposits come to it and withdraws
+	// go from it, spendable funds are held by the sending wallet, so they are not taken from this account
+	LedgerMerchant LedgerAccount = "merchant"
+	// LedgerReserved keeps funds of the merchant held for pending withdraws
+	LedgerReserved LedgerAccount = "reserved"
+	// LedgerHeld keeps reserved funds of withdraws on hold
+	LedgerHeld LedgerAccount = "held"
+	// LedgerIncoming keeps funds received to user wallets by deposits which are not done yet
+	LedgerIncoming LedgerAccount = "incoming"
+	// LedgerIncomingHeld keeps funds received by deposits on hold
+	LedgerIncomingHeld LedgerAccount = "incoming_held"
+	// LedgerRefunding keeps funds which are being returned to senders by refunds
+	LedgerRefunding LedgerAccount = "refunding"
+	// LedgerFees keeps commissions the merchant paid to the processing
+	LedgerFees LedgerAccount = "fees"
+	// LedgerExternal is the other side of funds coming to the merchant and going out
+	LedgerExternal LedgerAccount = "external"
+)
+
+// ledgerAccounts lists accounts in order lines of a posting are written
+var ledgerAccounts = []LedgerAccount{LedgerExternal, LedgerIncoming, LedgerIncomingHeld, LedgerRefunding,
+	LedgerMerchant, LedgerReserved, LedgerHeld, LedgerFees}
+
+// LedgerBalance is a balance of the merchant asset by accounts of the ledger, available funds are the balance
+// of the sending wallet less reserved and held ones, they are filled by the processing
+type LedgerBalance struct {
+	Blockchain string        `json:"blockchain"`
+	Asset      string        `json:"asset"`
+	Issuer     string        `json:"issuer"`
+	Available  amount.Amount `json:"available"`
+	Reserved   amount.Amount `json:"reserved"`
+	Held       amount.Amount `json:"held"`
+	Incoming   amount.Amount `json:"incoming"`
+	Fees       amount.Amount `json:"fees"`
+}
+
+// ledgerLine is a line of a posting, lines of a posting sum up to zero
+type ledgerLine struct {
+	account LedgerAccount
+	value   amount.Amount
+}
+
+// rowQuerier is implemented by both database and database transaction
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// LedgerPSQL is a double-entry ledger of merchant assets, entries are posted for changes of transaction statuses
+type LedgerPSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// GetBalances returns balances of assets of the merchant without available funds,
+// blockchain filter is not applied if it is empty
+func (s *LedgerPSQL) GetBalances(merchantID, blockchain string) ([]LedgerBalance, error) {
+	query := fmt.Sprintf("SELECT blockchain, asset, issuer, "+
+		"coalesce(sum(amount) filter (where account = $2), 0), "+
+		"coalesce(sum(amount) filter (where account = $3), 0), "+
+		"coalesce(sum(amount) filter (where account = any($4)), 0), "+
+		"coalesce(sum(amount) filter (where account = $5), 0) "+
+		"FROM %s WHERE merchant_id = $1 ", s.namespace)
+	args := []interface{}{merchantID, LedgerReserved, LedgerHeld,
+		pq.Array([]string{string(LedgerIncoming), string(LedgerIncomingHeld)}), LedgerFees}
+	if blockchain != "" {
+		args = append(args, blockchain)
+		query += fmt.Sprintf("and blockchain = $%d ", len(args))
+	}
+	query += "GROUP BY blockchain, asset, issuer ORDER BY blockchain, asset, issuer"
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	balances := []LedgerBalance{}
+	for rows.Next() {
+		balance := LedgerBalance{}
+		if err = rows.Scan(&balance.Blockchain, &balance.Asset, &balance.Issuer,
+			&balance.Reserved, &balance.Held, &balance.Incoming, &balance.Fees); err != nil {
+			return nil, fmt.Errorf("could not get balance from query: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+// GetReserved returns amount of the merchant asset reserved for pending withdraws including ones on hold
+func (s *LedgerPSQL) GetReserved(merchantID, blockchain, asset, issuer string) (amount.Amount, error) {
+	return s.reserved(s.db, merchantID, blockchain, asset, issuer)
+}
+
+// reserve locks the merchant asset until the end of the database transaction and returns ErrInsufficientFunds
+// if the spendable amount less already reserved one doesn't cover the required amount
+func (s *LedgerPSQL) reserve(tx *sql.Tx, merchantID, blockchain, asset, issuer string,
+	spendable, required amount.Amount) error {
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))",
+		fmt.Sprintf("ledger:%s:%s:%s:%s", merchantID, blockchain, asset, issuer))
+	if err != nil {
+		return fmt.Errorf("could not lock merchant: %v asset: %v, err: %w", merchantID, asset, err)
+	}
+	reserved, err := s.reserved(tx, merchantID, blockchain, asset, issuer)
+	if err != nil {
+		return err
+	}
+	if spendable.Sub(reserved).LT(required) {
+		return fmt.Errorf("%w: %v reserved of %v, required %v", ErrInsufficientFunds, reserved, spendable, required)
+	}
+	return nil
+}
+
+func (s *LedgerPSQL) reserved(q rowQuerier, merchantID, blockchain, asset, issuer string) (amount.Amount, error) {
+	query := fmt.Sprintf("SELECT coalesce(sum(amount), 0) FROM %s "+
+		"WHERE merchant_id = $1 and blockchain = $2 and asset = $3 and issuer = $4 and account = any($5)",
+		s.namespace)
+	reserved := amount.Zero()
+	accounts := pq.Array([]string{string(LedgerReserved), string(LedgerHeld)})
+	if err := q.QueryRow(query, merchantID, blockchain, asset, issuer, accounts).Scan(&reserved); err != nil {
+		return amount.Zero(), fmt.Errorf("could not get reserved amount: %w", err)
+	}
+	return reserved, nil
+}
+
+// record posts entries which move balances of the transaction to the ones of its current status, so every change
+// of the status is posted and repeated put of the same status doesn't change balances:
+// a deposit is incoming until it is done and comes to the merchant less commission, a withdraw reserves its amount
+// with commission until it is sent from the sending wallet, a refund is refunding until the funds are returned,
+// funds of a transaction on hold are held, and a rejected transaction has no funds
+func (s *LedgerPSQL) record(tx *sql.Tx, tr TransactionStore) error {
+	query := fmt.Sprintf("SELECT account, coalesce(sum(amount), 0) FROM %s WHERE transaction_guid = $1 "+
+		"GROUP BY account", s.namespace)
+	rows, err := tx.Query(query, tr.GUID)
+	if err != nil {
+		return fmt.Errorf("could not get ledger balances of transaction: %v, err: %w", tr.GUID, err)
+	}
+	current := map[LedgerAccount]amount.Amount{}
+	for rows.Next() {
+		var account LedgerAccount
+		value := amount.Zero()
+		if err = rows.Scan(&account, &value); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("could not scan ledger balance of transaction: %v, err: %w", tr.GUID, err)
+		}
+		current[account] = value
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("could not read ledger balances of transaction: %v, err: %w", tr.GUID, err)
+	}
+	target := ledgerBalances(tr)
+	var lines []ledgerLine
+	for _, account := range ledgerAccounts {
+		value, ok := target[account]
+		if !ok {
+			value = amount.Zero()
+		}
+		if prev, ok := current[account]; ok {
+			value = value.Sub(prev)
+		}
+		lines = append(lines, ledgerLine{account: account, value: value})
+	}
+	return s.post(tx, tr, lines)
+}
+
+// ledgerBalances returns balances of ledger accounts the transaction has in its status
+func ledgerBalances(tr TransactionStore) map[LedgerAccount]amount.Amount {
+	value := tr.Amount
+	switch tr.Action {
+	case DepositTransaction:
+		switch tr.Status {
+		case InitTransaction, ProcessedTransaction, SettledTransaction:
+			return map[LedgerAccount]amount.Amount{LedgerExternal: amount.Zero().Sub(value), LedgerIncoming: value}
+		case OnHoldTransaction:
+			return map[LedgerAccount]amount.Amount{LedgerExternal: amount.Zero().Sub(value), LedgerIncomingHeld: value}
+		case DoneTransaction:
+			return map[LedgerAccount]amount.Amount{
+				LedgerExternal: amount.Zero().Sub(value),
+				LedgerMerchant: value.Sub(tr.Commission),
+				LedgerFees:     tr.Commission,
+			}
+		}
+	case WithdrawTransaction:
+		withCommission := value.Add(tr.Commission)
+		switch tr.Status {
+		case InitTransaction, ProcessedTransaction:
+			return map[LedgerAccount]amount.Amount{
+				LedgerMerchant: amount.Zero().Sub(withCommission), LedgerReserved: withCommission}
+		case OnHoldTransaction:
+			return map[LedgerAccount]amount.Amount{
+				LedgerMerchant: amount.Zero().Sub(withCommission), LedgerHeld: withCommission}
+		case SettledTransaction, DoneTransaction:
+			return map[LedgerAccount]amount.Amount{
+				LedgerMerchant: amount.Zero().Sub(withCommission),
+				LedgerExternal: value,
+				LedgerFees:     tr.Commission,
+			}
+		}
+	case RefundTransaction:
+		switch tr.Status {
+		case InitTransaction, ProcessedTransaction, SettledTransaction:
+			return map[LedgerAccount]amount.Amount{LedgerExternal: amount.Zero().Sub(value), LedgerRefunding: value}
+		}
+	}
+	// funds of rejected and returned transactions are not kept, gas sweeps are not funds of the merchant
+	return nil
+}
+
+// post writes lines of the posting for the transaction status, lines with zero amount are skipped
+func (s *LedgerPSQL) post(tx *sql.Tx, tr TransactionStore, lines []ledgerLine) error {
+	total := amount.Zero()
+	for _, line := range lines {
+		total = total.Add(line.value)
+	}
+	if !total.IsZero() {
+		return fmt.Errorf("posting for transaction: %v, status: %v is not balanced by %v", tr.GUID, tr.Status, total)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (created_at, merchant_id, blockchain, asset, issuer, account, amount, "+
+		"transaction_guid, posting) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", s.namespace)
+	now := time.Now().UTC()
+	for _, line := range lines {
+		if line.value.IsZero() {
+			continue
+		}
+		_, err := tx.Exec(query, now, tr.MerchantId, tr.Blockchain, tr.Asset, tr.Issuer, line.account, line.value,
+			tr.GUID, tr.Status)
+		if err != nil {
+			return fmt.Errorf("could not post ledger entry for transaction: %v, err: %w", tr.GUID, err)
+		}
+	}
+	return nil
+}
+
+// NewLedgerStorage creates new storage for the ledger of merchant assets
+func NewLedgerStorage(namespace string, db *sql.DB) (*LedgerPSQL, error) {
+	s := LedgerPSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to ledger storage: %v", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"coreum_processor/modules/amount"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestLedgerRecordMovesBalancesToStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		current map[LedgerAccount]int64
+		status  StatusTx
+		posted  []ledgerLine
+	}{
+		{
+			name:   "withdraw created",
+			status: InitTransaction,
+			posted: []ledgerLine{
+				{account: LedgerMerchant, value: amount.NewFromInt64(-110)},
+				{account: LedgerReserved, value: amount.NewFromInt64(110)},
+			},
+		},
+		{
+			name:    "withdraw put on hold",
+			current: map[LedgerAccount]int64{LedgerMerchant: -110, LedgerReserved: 110},
+			status:  OnHoldTransaction,
+			posted: []ledgerLine{
+				{account: LedgerReserved, value: amount.NewFromInt64(-110)},
+				{account: LedgerHeld, value: amount.NewFromInt64(110)},
+			},
+		},
+		{
+			name:    "withdraw on hold rejected",
+			current: map[LedgerAccount]int64{LedgerMerchant: -110, LedgerHeld: 110},
+			status:  RejectedTransaction,
+			posted: []ledgerLine{
+				{account: LedgerMerchant, value: amount.NewFromInt64(110)},
+				{account: LedgerHeld, value: amount.NewFromInt64(-110)},
+			},
+		},
+		{
+			name:    "withdraw settled",
+			current: map[LedgerAccount]int64{LedgerMerchant: -110, LedgerReserved: 110},
+			status:  SettledTransaction,
+			posted: []ledgerLine{
+				{account: LedgerExternal, value: amount.NewFromInt64(100)},
+				{account: LedgerReserved, value: amount.NewFromInt64(-110)},
+				{account: LedgerFees, value: amount.NewFromInt64(10)},
+			},
+		},
+		{
+			name:    "withdraw done after settled",
+			current: map[LedgerAccount]int64{LedgerMerchant: -110, LedgerExternal: 100, LedgerFees: 10},
+			status:  DoneTransaction,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = db.Close() })
+			s := &LedgerPSQL{db: db, namespace: "ledger_entries"}
+			tr := TransactionStore{GUID: uuid.New(), MerchantId: "merchant", Blockchain: "coreum", Asset: "asset",
+				Issuer: "issuer", Action: WithdrawTransaction, Status: tt.status,
+				Amount: amount.NewFromInt64(100), Commission: amount.NewFromInt64(10)}
+
+			mock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{"account", "sum"})
+			for account, value := range tt.current {
+				rows.AddRow(string(account), value)
+			}
+			mock.ExpectQuery(regexp.QuoteMeta("GROUP BY account")).WithArgs(tr.GUID).WillReturnRows(rows)
+			for _, line := range tt.posted {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO ledger_entries")).
+					WithArgs(sqlmock.AnyArg(), "merchant", "coreum", "asset", "issuer", line.account,
+						line.value.String(), tr.GUID, tt.status).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			tx, err := db.Begin()
+			require.NoError(t, err)
+
+			require.NoError(t, s.record(tx, tr))
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"coreum_processor/modules/amount"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type LimitScope string
+
+const (
+	// LimitScopeUser is a rule counted for every user (external_id) of the merchant separately
+	LimitScopeUser LimitScope = "user"
+	// LimitScopeMerchant is a rule counted for all users of the merchant together
+	LimitScopeMerchant LimitScope = "merchant"
+)
+
+type HoldDecision string
+
+const (
+	// HoldReleased is a transaction returned by an admin to processing
+	HoldReleased HoldDecision = "release"
+	// HoldRejected is a transaction rejected by an admin
+	HoldRejected HoldDecision = "reject"
+)
+
+// LimitRule limits deposits or withdraws of the merchant asset, amounts are in subunits of the asset
+// and a limit which is not set is not checked
+type LimitRule struct {
+	ID              int64          `json:"id"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	MerchantID      string         `json:"merchant_id"`
+	Blockchain      string         `json:"blockchain"`
+	Asset           string         `json:"asset"`
+	Issuer          string         `json:"issuer"`
+	Action          ActionTx       `json:"action"`
+	Scope           LimitScope     `json:"scope"`
+	MaxPerTx        *amount.Amount `json:"max_per_tx,omitempty"`
+	MaxPerDay       *amount.Amount `json:"max_per_day,omitempty"`
+	MaxPerMonth     *amount.Amount `json:"max_per_month,omitempty"`
+	MaxCountPerHour *int64         `json:"max_count_per_hour,omitempty"`
+}
+
+// TransactionHold is a transaction put on hold for breaching the limit rule, it waits for a decision of an admin
+type TransactionHold struct {
+	TransactionGUID string       `json:"transaction_guid"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	MerchantID      string       `json:"merchant_id"`
+	RuleID          int64        `json:"rule_id"`
+	Reason          string       `json:"reason"`
+	HeldStatus      StatusTx     `json:"held_status"`
+	Decision        HoldDecision `json:"decision"`
+	DecidedBy       string       `json:"decided_by"`
+}
+
+// limitRuleColumns lists columns of the limit rules table in order expected by rowsToLimitRules
+const limitRuleColumns = "id, created_at, updated_at, merchant_id, blockchain, asset, issuer, action, scope, " +
+	"max_per_tx, max_per_day, max_per_month, max_count_per_hour"
+
+// holdColumns lists columns of the holds table in order expected by rowsToTransactionHolds
+const holdColumns = "transaction_guid, created_at, updated_at, merchant_id, rule_id, reason, held_status, " +
+	"decision, decided_by"
+
+type LimitsPSQL struct {
+	db             *sql.DB
+	namespace      string
+	holdsNamespace string
+}
+
+// NewLimitStorage creates new storage for limit rules of merchants and transactions put on hold by them
+func NewLimitStorage(namespace, holdsNamespace string, db *sql.DB) (*LimitsPSQL, error) {
+	s := LimitsPSQL{
+		db:             db,
+		namespace:      namespace,
+		holdsNamespace: holdsNamespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to limit rules storage: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", holdsNamespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to transaction holds storage: %v", err)
+	}
+	return &s, nil
+}
+
+// CreateRule adds the limit rule and returns it with its id
+func (s *LimitsPSQL) CreateRule(rule LimitRule) (LimitRule, error) {
+	query := fmt.Sprintf("INSERT INTO %s (created_at, updated_at, merchant_id, blockchain, asset, issuer, action, "+
+		"scope, max_per_tx, max_per_day, max_per_month, max_count_per_hour) "+
+		"VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING %s", s.namespace, limitRuleColumns)
+	rows, err := s.db.Query(query, time.Now().UTC(), rule.MerchantID, rule.Blockchain, rule.Asset, rule.Issuer,
+		rule.Action, rule.Scope, rule.MaxPerTx, rule.MaxPerDay, rule.MaxPerMonth, rule.MaxCountPerHour)
+	if err != nil {
+		return LimitRule{}, fmt.Errorf("could not add limit rule for merchant: %v, err: %w", rule.MerchantID, err)
+	}
+	defer func() { _ = rows.Close() }()
+	rules, err := rowsToLimitRules(rows)
+	if err != nil {
+		return LimitRule{}, err
+	}
+	if len(rules) == 0 {
+		return LimitRule{}, fmt.Errorf("limit rule for merchant: %v is not added", rule.MerchantID)
+	}
+	return rules[0], nil
+}
+
+// UpdateRule replaces the limit rule with the id, ErrNotFound is returned if there is no such rule
+func (s *LimitsPSQL) UpdateRule(id int64, rule LimitRule) error {
+	query := fmt.Sprintf("UPDATE %s SET updated_at = $1, merchant_id = $2, blockchain = $3, asset = $4, "+
+		"issuer = $5, action = $6, scope = $7, max_per_tx = $8, max_per_day = $9, max_per_month = $10, "+
+		"max_count_per_hour = $11 WHERE id = $12", s.namespace)
+	res, err := s.db.Exec(query, time.Now().UTC(), rule.MerchantID, rule.Blockchain, rule.Asset, rule.Issuer,
+		rule.Action, rule.Scope, rule.MaxPerTx, rule.MaxPerDay, rule.MaxPerMonth, rule.MaxCountPerHour, id)
+	return checkRuleAffected(res, err, id)
+}
+
+// DeleteRule removes the limit rule with the id, ErrNotFound is returned if there is no such rule
+func (s *LimitsPSQL) DeleteRule(id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.namespace)
+	res, err := s.db.Exec(query, id)
+	return checkRuleAffected(res, err, id)
+}
+
+// GetRules returns limit rules of the merchant, rules of all merchants are returned if merchantID is empty
+func (s *LimitsPSQL) GetRules(merchantID string) ([]LimitRule, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE ($1 = '' or merchant_id = $1) ORDER BY merchant_id, id",
+		limitRuleColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	rules, err := rowsToLimitRules(rows)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []LimitRule{}
+	}
+	return rules, nil
+}
+
+// GetMatchingRules returns limit rules of the merchant for the action in the asset
+func (s *LimitsPSQL) GetMatchingRules(merchantID, blockchain, asset, issuer string,
+	action ActionTx) ([]LimitRule, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 and blockchain = $2 and asset = $3 "+
+		"and issuer = $4 and action = $5 ORDER BY id", limitRuleColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, blockchain, asset, issuer, action)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return rowsToLimitRules(rows)
+}
+
+// CreateHold records that the transaction in the status is held for breaching the rule,
+// a transaction already held is kept as is
+func (s *LimitsPSQL) CreateHold(merchantID, transactionGUID string, ruleID int64, reason string,
+	heldStatus StatusTx) error {
+	query := fmt.Sprintf("INSERT INTO %s (transaction_guid, created_at, updated_at, merchant_id, rule_id, reason, "+
+		"held_status) VALUES ($1, $2, $2, $3, $4, $5, $6) ON CONFLICT (transaction_guid) DO NOTHING",
+		s.holdsNamespace)
+	_, err := s.db.Exec(query, transactionGUID, time.Now().UTC(), merchantID, ruleID, reason, heldStatus)
+	if err != nil {
+		return fmt.Errorf("could not hold transaction: %v, err: %w", transactionGUID, err)
+	}
+	return nil
+}
+
+// GetHold returns the hold of the transaction
+func (s *LimitsPSQL) GetHold(transactionGUID string) (*TransactionHold, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE transaction_guid = $1", holdColumns, s.holdsNamespace)
+	rows, err := s.db.Query(query, transactionGUID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	holds, err := rowsToTransactionHolds(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(holds) == 0 {
+		return nil, ErrNotFound
+	}
+	return &holds[0], nil
+}
+
+// GetPendingHolds returns holds without a decision of an admin, merchantID filter is not applied if it is empty
+func (s *LimitsPSQL) GetPendingHolds(merchantID string) ([]TransactionHold, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE decision = '' and ($1 = '' or merchant_id = $1) "+
+		"ORDER BY created_at", holdColumns, s.holdsNamespace)
+	rows, err := s.db.Query(query, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return rowsToTransactionHolds(rows)
+}
+
+// Decide records the decision of the admin on the hold,
+// ErrInvalidTransition is returned if the hold is already decided
+func (s *LimitsPSQL) Decide(transactionGUID string, decision HoldDecision, decidedBy string) error {
+	query := fmt.Sprintf("UPDATE %s SET updated_at = $1, decision = $2, decided_by = $3 "+
+		"WHERE transaction_guid = $4 and decision = ''", s.holdsNamespace)
+	res, err := s.db.Exec(query, time.Now().UTC(), decision, decidedBy, transactionGUID)
+	if err != nil {
+		return fmt.Errorf("could not decide on hold of transaction: %v, err: %w", transactionGUID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get decided holds: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: hold of transaction %v is already decided", ErrInvalidTransition, transactionGUID)
+	}
+	return nil
+}
+
+func checkRuleAffected(res sql.Result, err error, id int64) error {
+	if err != nil {
+		return fmt.Errorf("could not change limit rule: %v, err: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not change limit rule: %v, err: %w", id, err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func rowsToLimitRules(rows *sql.Rows) ([]LimitRule, error) {
+	var rules []LimitRule
+	for rows.Next() {
+		r := LimitRule{}
+		err := rows.Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt, &r.MerchantID, &r.Blockchain, &r.Asset, &r.Issuer,
+			&r.Action, &r.Scope, &r.MaxPerTx, &r.MaxPerDay, &r.MaxPerMonth, &r.MaxCountPerHour)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan limit rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read limit rules: %w", err)
+	}
+	return rules, nil
+}
+
+func rowsToTransactionHolds(rows *sql.Rows) ([]TransactionHold, error) {
+	var holds []TransactionHold
+	for rows.Next() {
+		h := TransactionHold{}
+		err := rows.Scan(&h.TransactionGUID, &h.CreatedAt, &h.UpdatedAt, &h.MerchantID, &h.RuleID, &h.Reason,
+			&h.HeldStatus, &h.Decision, &h.DecidedBy)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan transaction hold: %w", err)
+		}
+		holds = append(holds, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read transaction holds: %w", err)
+	}
+	return holds, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	// memoDigits is length of generated memo, memos are numeric to be accepted by any wallet
+	memoDigits   = 12
+	memoAttempts = 3
+)
+
+// DepositMemo attributes deposits to the shared receiving wallet of the merchant to the user
+type DepositMemo struct {
+	Blockchain string
+	Memo       string
+	MerchantID string
+	ExternalID string
+	CreatedAt  time.Time
+}
+
+type MemosPSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// NewMemoStorage creates new storage for memos of deposits
+func NewMemoStorage(namespace string, db *sql.DB) (*MemosPSQL, error) {
+	s := MemosPSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to memo storage: %v", err)
+	}
+	return &s, nil
+}
+
+// GetOrCreate returns memo of the user in the blockchain, a unique memo is generated for a new user
+func (s *MemosPSQL) GetOrCreate(merchantID, externalID, blockchain string) (string, error) {
+	query := fmt.Sprintf("INSERT INTO %s (blockchain, memo, created_at, merchant_id, external_id) "+
+		"VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING", s.namespace)
+	for i := 0; i < memoAttempts; i++ {
+		memo, err := generateMemo()
+		if err != nil {
+			return "", err
+		}
+		if _, err = s.db.Exec(query, blockchain, memo, time.Now().UTC(), merchantID, externalID); err != nil {
+			return "", fmt.Errorf("could not create memo for user: %v, err: %w", externalID, err)
+		}
+		// the user has a memo already or the generated one is used by another user
+		memo, err = s.getByUser(merchantID, externalID, blockchain)
+		if !errors.Is(err, ErrNotFound) {
+			return memo, err
+		}
+	}
+	return "", fmt.Errorf("could not generate unique memo for user: %v", externalID)
+}
+
+// Get returns the user the memo is generated for
+func (s *MemosPSQL) Get(blockchain, memo string) (*DepositMemo, error) {
+	query := fmt.Sprintf("SELECT blockchain, memo, merchant_id, external_id, created_at FROM %s "+
+		"WHERE blockchain = $1 and memo = $2", s.namespace)
+	m := DepositMemo{}
+	err := s.db.QueryRow(query, blockchain, memo).Scan(&m.Blockchain, &m.Memo, &m.MerchantID, &m.ExternalID,
+		&m.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get memo: %v, err: %w", memo, err)
+	}
+	return &m, nil
+}
+
+func (s *MemosPSQL) getByUser(merchantID, externalID, blockchain string) (string, error) {
+	query := fmt.Sprintf("SELECT memo FROM %s WHERE blockchain = $1 and merchant_id = $2 and external_id = $3",
+		s.namespace)
+	memo := ""
+	err := s.db.QueryRow(query, blockchain, merchantID, externalID).Scan(&memo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", fmt.Errorf("could not get memo of user: %v, err: %w", externalID, err)
+	}
+	return memo, nil
+}
+
+// generateMemo returns random numeric memo without leading zero
+func generateMemo() (string, error) {
+	min := new(big.Int).Exp(big.NewInt(10), big.NewInt(memoDigits-1), nil)
+	n, err := rand.Int(rand.Reader, new(big.Int).Mul(min, big.NewInt(9)))
+	if err != nil {
+		return "", fmt.Errorf("could not generate memo: %w", err)
+	}
+	return n.Add(n, min).String(), nil
+}
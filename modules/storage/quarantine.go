@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"coreum_processor/modules/amount"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type QuarantineStatus string
+
+const (
+	// QuarantineHeld is a deposit which can't be attributed to a user and waits for manual resolution
+	QuarantineHeld QuarantineStatus = "held"
+	// QuarantineAssigned is a deposit assigned to a user by the merchant
+	QuarantineAssigned QuarantineStatus = "assigned"
+	// QuarantineDismissed is a deposit kept by the merchant without crediting any user
+	QuarantineDismissed QuarantineStatus = "dismissed"
+)
+
+// QuarantinedDeposit is a deposit to the shared receiving wallet of the merchant with unknown memo
+type QuarantinedDeposit struct {
+	ID         int64            `json:"id"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	MerchantID string           `json:"merchant_id"`
+	Blockchain string           `json:"blockchain"`
+	Hash       string           `json:"hash"`
+	Memo       string           `json:"memo"`
+	Sender     string           `json:"sender"`
+	Asset      string           `json:"asset"`
+	Issuer     string           `json:"issuer"`
+	Amount     amount.Amount    `json:"amount"`
+	Status     QuarantineStatus `json:"status"`
+	ExternalID string           `json:"external_id"`
+}
+
+// quarantineColumns lists columns of the quarantine table in order expected by rowsToQuarantinedDeposits
+const quarantineColumns = "id, created_at, updated_at, merchant_id, blockchain, hash, memo, sender, " +
+	"asset, issuer, amount, status, external_id"
+
+type QuarantinePSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// NewQuarantineStorage creates new storage for deposits which can't be attributed to a user
+func NewQuarantineStorage(namespace string, db *sql.DB) (*QuarantinePSQL, error) {
+	s := QuarantinePSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to quarantine storage: %v", err)
+	}
+	return &s, nil
+}
+
+// Put holds the deposit for manual resolution and returns its id, a deposit already held is kept as is
+// and false is returned
+func (s *QuarantinePSQL) Put(merchantID, blockchain, hash, memo, sender, asset, issuer string,
+	value amount.Amount) (int64, bool, error) {
+	query := fmt.Sprintf("INSERT INTO %s (created_at, updated_at, merchant_id, blockchain, hash, memo, sender, "+
+		"asset, issuer, amount, status) VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10) "+
+		"ON CONFLICT (merchant_id, blockchain, hash, sender, asset, issuer) DO NOTHING RETURNING id", s.namespace)
+	id := int64(0)
+	err := s.db.QueryRow(query, time.Now().UTC(), merchantID, blockchain, hash, memo, sender, asset, issuer,
+		value, QuarantineHeld).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("could not quarantine deposit: %v, err: %w", hash, err)
+	}
+	return id, true, nil
+}
+
+// GetByMerchant returns deposits of the merchant in the status, blockchain is optional
+func (s *QuarantinePSQL) GetByMerchant(merchantID, blockchain string,
+	status QuarantineStatus) ([]QuarantinedDeposit, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 and status = $2 "+
+		"and ($3 = '' or blockchain = $3) order by id", quarantineColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, status, blockchain)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return rowsToQuarantinedDeposits(rows)
+}
+
+// Get returns the deposit of the merchant
+func (s *QuarantinePSQL) Get(merchantID string, id int64) (*QuarantinedDeposit, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 and id = $2", quarantineColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	deposits, err := rowsToQuarantinedDeposits(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(deposits) == 0 {
+		return nil, ErrNotFound
+	}
+	return &deposits[0], nil
+}
+
+// Resolve moves the held deposit to the status with the user it is assigned to,
+// ErrInvalidTransition is returned if the deposit is not held
+func (s *QuarantinePSQL) Resolve(merchantID string, id int64, status QuarantineStatus, externalID string) error {
+	return s.move(merchantID, id, QuarantineHeld, status, externalID)
+}
+
+// Hold returns the assigned deposit to quarantine, it is used if the deposit can't be credited to the user
+func (s *QuarantinePSQL) Hold(merchantID string, id int64) error {
+	return s.move(merchantID, id, QuarantineAssigned, QuarantineHeld, "")
+}
+
+func (s *QuarantinePSQL) move(merchantID string, id int64, from, to QuarantineStatus, externalID string) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $1, external_id = $2, updated_at = $3 "+
+		"WHERE merchant_id = $4 and id = $5 and status = $6", s.namespace)
+	res, err := s.db.Exec(query, to, externalID, time.Now().UTC(), merchantID, id, from)
+	if err != nil {
+		return fmt.Errorf("could not update quarantined deposit: %v, err: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get updated quarantined deposits: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: quarantined deposit %v to %v from status other than %v",
+			ErrInvalidTransition, id, to, from)
+	}
+	return nil
+}
+
+func rowsToQuarantinedDeposits(rows *sql.Rows) ([]QuarantinedDeposit, error) {
+	var deposits []QuarantinedDeposit
+	for rows.Next() {
+		d := QuarantinedDeposit{}
+		if err := rows.Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt, &d.MerchantID, &d.Blockchain, &d.Hash, &d.Memo,
+			&d.Sender, &d.Asset, &d.Issuer, &d.Amount, &d.Status, &d.ExternalID); err != nil {
+			return nil, fmt.Errorf("could not get quarantined deposit from query: %w", err)
+		}
+		deposits = append(deposits, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not get quarantined deposits from query: %w", err)
+	}
+	return deposits, nil
+}
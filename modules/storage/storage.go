@@ -9,6 +9,10 @@ var (
 	// ErrNotFound returned in case of key does not exist
 	ErrNotFound     = errors.New("not found")
 	ErrNotSupported = errors.New("unsupported")
+	// ErrInvalidTransition returned in case of a transaction can't be moved to the requested status
+	ErrInvalidTransition = errors.New("invalid transaction status transition")
+	// ErrInsufficientFunds returned in case of funds of the merchant don't cover the amount to reserve
+	ErrInsufficientFunds = errors.New("insufficient funds")
 )
 
 const DefaultTTL = time.Hour * 24 * 365 * 111 // more than 100 years
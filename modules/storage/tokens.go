@@ -39,14 +39,14 @@ func NewTokenStorage(namespace string, db *sql.DB) (*TokenPSQL, error) {
 }
 
 func (s *TokenPSQL) GetAllAssetsForBlockchain(blockchain string) (map[string]string, error) {
-	query := `SELECT asset, smart_contract FROM $1 WHERE blockchain = $2`
-	rows, err := s.db.Query(query, s.namespace, blockchain)
+	query := fmt.Sprintf(`SELECT asset, smart_contract FROM %s WHERE blockchain = $1`, s.namespace)
+	rows, err := s.db.Query(query, blockchain)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var smartContracts map[string]string
+	smartContracts := map[string]string{}
 
 	for rows.Next() {
 		var asset, smartContract string
@@ -86,7 +86,7 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 	now := time.Now()
 
 	// Execute SQL statement
-	_, err := s.db.Query(query, now, now, token.Blockchain, token.Asset, token.Issuer,
+	_, err := s.db.Exec(query, now, now, token.Blockchain, token.Asset, token.Issuer,
 		token.Contract.SmartContractAddress, token.Contract.FeeLimit, token.Format)
 	if err != nil {
 		return err
@@ -1,10 +1,13 @@
 package storage
 
 import (
+	"coreum_processor/modules/amount"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"time"
 )
 
@@ -17,50 +20,82 @@ const (
 	SettledTransaction   StatusTx = "settle"
 	DoneTransaction      StatusTx = "done"
 	RejectedTransaction  StatusTx = "rejected"
+	OnHoldTransaction    StatusTx = "on_hold"
 )
 
+// statusTransitions lists for every status of a transaction the statuses it can be reached from,
+// a transaction goes init -> processed -> settle -> done, or it is rejected before it has been settled.
+// A transaction breaching limits is put on hold before it has been settled and returns to its status
+// when it is released. A settled deposit returns to processed if its settlement fails in the blockchain,
+// see ResetSettledTransaction. Hash of a blockchain transaction and commission are updated without a change
+// of the status only if the hash is not changed since it was read, see update
+var statusTransitions = map[StatusTx][]StatusTx{
+	ProcessedTransaction: {InitTransaction, SettledTransaction},
+	SettledTransaction:   {ProcessedTransaction},
+	DoneTransaction:      {SettledTransaction},
+	RejectedTransaction:  {InitTransaction, ProcessedTransaction, OnHoldTransaction},
+	OnHoldTransaction:    {InitTransaction, ProcessedTransaction},
+}
+
 const (
 	DepositTransaction  ActionTx = "deposit"
 	WithdrawTransaction ActionTx = "withdraw"
+	RefundTransaction   ActionTx = "refund"
+	SweepTransaction    ActionTx = "sweep"
 )
 
+// PendingStatuses lists statuses of a transaction that is not completed yet
+var PendingStatuses = []StatusTx{InitTransaction, ProcessedTransaction, SettledTransaction}
+
 type TransactionStore struct {
-	Id         int        `json:"-"`
-	GUID       uuid.UUID  `json:"GUID"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
-	DeletedAt  *time.Time `json:"-"`
-	MerchantId string     `json:"merchant_id"`
-	ExternalId string     `json:"external_id"`
-	Blockchain string     `json:"blockchain"`
-	Action     ActionTx   `json:"action"`
-	ExtWallet  string     `json:"ext_wallet"`
-	Status     StatusTx   `json:"status"`
-	Asset      string     `json:"asset"`
-	Issuer     string     `json:"issuer"`
-	Amount     float64    `json:"amount"`
-	Commission float64    `json:"-"`
-	Hash1      string     `json:"-"`
-	Hash2      string     `json:"-"`
-	Hash3      string     `json:"-"`
-	Hash4      string     `json:"-"`
-	Hash5      string     `json:"-"`
-	Callback   string     `json:"-"`
+	Id         int           `json:"-"`
+	GUID       uuid.UUID     `json:"GUID"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	DeletedAt  *time.Time    `json:"-"`
+	MerchantId string        `json:"merchant_id"`
+	ExternalId string        `json:"external_id"`
+	Blockchain string        `json:"blockchain"`
+	Action     ActionTx      `json:"action"`
+	ExtWallet  string        `json:"ext_wallet"`
+	Status     StatusTx      `json:"status"`
+	Asset      string        `json:"asset"`
+	Issuer     string        `json:"issuer"`
+	Amount     amount.Amount `json:"amount"`
+	Commission amount.Amount `json:"-"`
+	Hash1      string        `json:"-"`
+	Hash2      string        `json:"-"`
+	Hash3      string        `json:"-"`
+	Hash4      string        `json:"-"`
+	Hash5      string        `json:"-"`
+	Callback   string        `json:"-"`
+	// TimeoutHeight is the block height the latest blockchain transaction recorded before broadcast
+	// can be included up to
+	TimeoutHeight int64 `json:"-"`
 }
 
+// transactionColumns lists columns of the transaction table in order expected by rowsToTransaction
+const transactionColumns = "id, guid, created_at, updated_at, deleted_at, merchant_id, external_id, blockchain, " +
+	"action, ext_wallet, status, asset, issuer, amount, commission, hash1, hash2, hash3, hash4, hash5, callback, " +
+	"timeout_height"
+
 type TransactionPSQL struct {
 	db        *sql.DB
 	namespace string
+	outbox    *WebhooksPSQL
+	ledger    *LedgerPSQL
 }
 
 func (s *TransactionPSQL) GetTransactionByGuid(merchID, guid string) (*TransactionStore, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE guid = '%s' AND merchant_id = '%s'", s.namespace, guid, merchID)
-	rows, err := s.db.Query(query)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE guid = $1 AND merchant_id = $2",
+		transactionColumns, s.namespace)
+	rows, err := s.db.Query(query, guid, merchID)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("could not execute query: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
 
 	transactionStore, err := rowsToTransaction(rows)
 	if err != nil {
@@ -76,71 +111,46 @@ func (s *TransactionPSQL) GetTransactionsByMerchant(merchID, blockchain string,
 	actionFilter []string, statusFilter []string,
 	from, to time.Time) ([]TransactionStore, error) {
 	query := fmt.Sprintf(
-		"SELECT * FROM %s WHERE deleted_at IS NULL and merchant_id = '%s' and created_at > '%v' and created_at < '%v' ",
-		s.namespace, merchID, from.Format(time.RFC3339), to.Format(time.RFC3339))
+		"SELECT %s FROM %s WHERE deleted_at IS NULL and merchant_id = $1 and created_at > $2 and created_at < $3 ",
+		transactionColumns, s.namespace)
+	args := []interface{}{merchID, from, to}
 	if blockchain != "" {
-		query += fmt.Sprintf(" and blockchain = '%s'", blockchain)
+		args = append(args, blockchain)
+		query += fmt.Sprintf(" and blockchain = $%d", len(args))
 	}
-	if actionFilter != nil && len(actionFilter) > 0 && actionFilter[0] != "" {
-		query += " and action in ("
-		for i, val := range actionFilter {
-			if i > 0 {
-				query += ", "
-			}
-			query += fmt.Sprintf(string("'" + val + "'"))
-		}
-		query += ") "
+	if len(actionFilter) > 0 && actionFilter[0] != "" {
+		args = append(args, pq.Array(actionFilter))
+		query += fmt.Sprintf(" and action = ANY($%d) ", len(args))
 	}
-	if statusFilter != nil && len(statusFilter) > 0 && statusFilter[0] != "" {
-		query += "and status in ("
-		for i, val := range statusFilter {
-			if i > 0 {
-				query += ", "
-			}
-			query += fmt.Sprintf(string("'" + val + "'"))
-		}
-		query += ") "
+	if len(statusFilter) > 0 && statusFilter[0] != "" {
+		args = append(args, pq.Array(statusFilter))
+		query += fmt.Sprintf(" and status = ANY($%d) ", len(args))
 	}
 	query += "order by created_at"
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("could not execute query: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
 
-	var transactions []TransactionStore
-
-	for rows.Next() {
-		transaction := TransactionStore{}
-		if err := rows.Scan(
-			&transaction.Id, &transaction.GUID,
-			&transaction.CreatedAt, &transaction.UpdatedAt, &transaction.DeletedAt,
-			&transaction.MerchantId, &transaction.ExternalId, &transaction.Blockchain,
-			&transaction.Action, &transaction.ExtWallet, &transaction.Status,
-			&transaction.Asset, &transaction.Issuer,
-			&transaction.Amount, &transaction.Commission,
-			&transaction.Hash1, &transaction.Hash2,
-			&transaction.Hash3, &transaction.Hash4,
-			&transaction.Hash5, &transaction.Callback,
-		); err != nil {
-			return []TransactionStore{}, err
-		}
-		transactions = append(transactions, transaction)
-	}
-	return transactions, nil
+	return rowsToTransaction(rows)
 }
 
 func (s *TransactionPSQL) GetMerchantTrxForProcessingInBlockChain(merchantID, blockchain string,
 	action ActionTx, status StatusTx, limit uint) ([]TransactionStore, error) {
 
 	query := fmt.Sprintf(
-		"SELECT * FROM %s WHERE deleted_at IS NULL and merchant_id = '%s' and blockchain = '%s' and action = '%s' and status = '%s' order by created_at limit %v",
-		s.namespace, merchantID, blockchain, action, status, limit)
-	rows, err := s.db.Query(query)
+		"SELECT %s FROM %s WHERE deleted_at IS NULL and merchant_id = $1 and blockchain = $2 "+
+			"and action = $3 and status = $4 order by created_at limit $5",
+		transactionColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, blockchain, action, status, limit)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("could not execute query: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
+
 	transactions, err := rowsToTransaction(rows)
 	if err != nil {
 		return nil, fmt.Errorf("could not get tensaction from query: %w", err)
@@ -152,29 +162,117 @@ func (s *TransactionPSQL) GetMerchantTrxForProcessingInBlockChain(merchantID, bl
 }
 
 // GetInitTransactions returns an array of transaction that was initiated by merchant for user
-// in specified blockchain and action ["deposit"/"withdrawal"]
+// in specified blockchain and action ["deposit"/"withdrawal"], initiated transactions put on hold are included
 func (s *TransactionPSQL) GetInitTransactions(merchantID, externalID,
 	blockchain string, action ActionTx) ([]TransactionStore, error) {
 
 	query := fmt.Sprintf(
-		"SELECT * FROM %s WHERE deleted_at IS NULL and merchant_id = '%s' and external_id = '%s' and blockchain = '%s' and action = '%s' and status = '%s' order by created_at",
-		s.namespace, merchantID, externalID, blockchain, action, InitTransaction)
-	rows, err := s.db.Query(query)
+		"SELECT %s FROM %s WHERE deleted_at IS NULL and merchant_id = $1 and external_id = $2 "+
+			"and blockchain = $3 and action = $4 and status = ANY($5) order by created_at",
+		transactionColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, externalID, blockchain, action,
+		pq.Array([]string{string(InitTransaction), string(OnHoldTransaction)}))
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("could not execute query: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
+
+	transactions, err := rowsToTransaction(rows)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction from query: %w", err)
+	}
+
+	if len(transactions) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return transactions, nil
+}
+
+// GetTransactionsByAction returns an array of transactions of all merchants with specified action and statuses,
+// blockchain filter is not applied if it is empty
+func (s *TransactionPSQL) GetTransactionsByAction(blockchain string, action ActionTx, statuses []StatusTx,
+	limit uint) ([]TransactionStore, error) {
+	statusFilter := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		statusFilter = append(statusFilter, string(status))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE deleted_at IS NULL and action = $1 and status = ANY($2) ",
+		transactionColumns, s.namespace)
+	args := []interface{}{action, pq.Array(statusFilter)}
+	if blockchain != "" {
+		args = append(args, blockchain)
+		query += fmt.Sprintf(" and blockchain = $%d ", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf("order by created_at limit $%d", len(args))
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
 
 	transactions, err := rowsToTransaction(rows)
 	if err != nil {
 		return nil, fmt.Errorf("could not get transaction from query: %w", err)
 	}
+	if len(transactions) == 0 {
+		return nil, ErrNotFound
+	}
+	return transactions, nil
+}
+
+// GetPendingTransactions returns an array of not completed transactions of the user
+// in specified blockchain and action
+func (s *TransactionPSQL) GetPendingTransactions(merchantID, externalID,
+	blockchain string, action ActionTx) ([]TransactionStore, error) {
+	statusFilter := make([]string, 0, len(PendingStatuses))
+	for _, status := range PendingStatuses {
+		statusFilter = append(statusFilter, string(status))
+	}
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE deleted_at IS NULL and merchant_id = $1 and external_id = $2 "+
+			"and blockchain = $3 and action = $4 and status = ANY($5) order by created_at",
+		transactionColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, externalID, blockchain, action, pq.Array(statusFilter))
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
 
+	transactions, err := rowsToTransaction(rows)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction from query: %w", err)
+	}
 	if len(transactions) == 0 {
 		return nil, ErrNotFound
 	}
+	return transactions, nil
+}
+
+// GetUserTransactionsByHash returns an array of transactions of the user created for the blockchain transaction,
+// it is used to not record the same deposit twice
+func (s *TransactionPSQL) GetUserTransactionsByHash(merchantID, externalID,
+	blockchain, hash string) ([]TransactionStore, error) {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE deleted_at IS NULL and merchant_id = $1 and external_id = $2 "+
+			"and blockchain = $3 and hash1 = $4 order by created_at",
+		transactionColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, externalID, blockchain, hash)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
 
+	transactions, err := rowsToTransaction(rows)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction from query: %w", err)
+	}
+	if len(transactions) == 0 {
+		return nil, ErrNotFound
+	}
 	return transactions, nil
 }
 
@@ -182,15 +280,110 @@ func (s *TransactionPSQL) GetInitTransactions(merchantID, externalID,
 // and return guid new created transaction
 func (s *TransactionPSQL) CreateTransaction(merchantID, externalID, blockchain string, action ActionTx,
 	externalWallet, hash, asset, issuer string,
-	amount, commission float64) (string, error) {
+	value, commission amount.Amount) (string, error) {
 	guid, err := uuid.NewUUID()
 	query := fmt.Sprintf("INSERT INTO %s (guid, created_at, updated_at, merchant_id, external_id, blockchain, action, ext_wallet, status, asset, issuer, amount, commission, hash1)",
 		s.namespace)
-	query += "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING guid"
-	_, err = s.db.Query(
+	query += "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)"
+	err = s.insert(
+		query, action,
+		guid, time.Now().UTC(), time.Now().UTC(), merchantID, externalID, blockchain, action, externalWallet,
+		InitTransaction, asset, issuer, value, commission, hash)
+	if err != nil {
+		return "", err
+	}
+	return guid.String(), nil
+}
+
+// CreateWithdrawTransaction makes a new withdraw record in the transaction store and reserves its amount
+// with commission in the ledger, ErrInsufficientFunds is returned if the spendable amount of the merchant asset
+// doesn't cover it together with already reserved funds. The check is called with volumes of withdraws
+// of the merchant asset under the same lock as the reservation, the withdraw is not created if it returns error.
+// It returns guid of new created transaction
+func (s *TransactionPSQL) CreateWithdrawTransaction(merchantID, externalID, blockchain string,
+	externalWallet, asset, issuer string,
+	value, commission, spendable amount.Amount, check func(volume VolumeFunc) error) (string, error) {
+	if s.ledger == nil {
+		if err := check(s.GetVolume); err != nil {
+			return "", err
+		}
+		return s.CreateTransaction(merchantID, externalID, blockchain, WithdrawTransaction,
+			externalWallet, "", asset, issuer, value, commission)
+	}
+	guid, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	err = s.ledger.reserve(tx, merchantID, blockchain, asset, issuer, spendable, value.Add(commission))
+	if err != nil {
+		return "", err
+	}
+	err = check(func(merchantID, externalID, blockchain, asset, issuer string, action ActionTx,
+		since time.Time) (amount.Amount, int64, error) {
+		return s.volume(tx, merchantID, externalID, blockchain, asset, issuer, action, since)
+	})
+	if err != nil {
+		return "", err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (guid, created_at, updated_at, merchant_id, external_id, blockchain, "+
+		"action, ext_wallet, status, asset, issuer, amount, commission, hash1) "+
+		"VALUES ($1, $2, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, '')", s.namespace)
+	_, err = s.writeWithEvents(tx, query, func(tr TransactionStore) string {
+		return createdEventTypes[tr.Action]
+	}, guid, time.Now().UTC(), merchantID, externalID, blockchain, WithdrawTransaction, externalWallet,
+		InitTransaction, asset, issuer, value, commission)
+	if err != nil {
+		return "", err
+	}
+	if err = tx.Commit(); err != nil {
+		return "", fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return guid.String(), nil
+}
+
+// CreateDoneTransaction makes a new record of internal transaction which is already completed in the blockchain
+// and return guid new created transaction
+func (s *TransactionPSQL) CreateDoneTransaction(merchantID, externalID, blockchain string, action ActionTx,
+	externalWallet, hash, asset, issuer string,
+	value, commission amount.Amount) (string, error) {
+	guid, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (guid, created_at, updated_at, merchant_id, external_id, blockchain, "+
+		"action, ext_wallet, status, asset, issuer, amount, commission, hash1) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)", s.namespace)
+	_, err = s.db.Exec(
 		query,
 		guid, time.Now().UTC(), time.Now().UTC(), merchantID, externalID, blockchain, action, externalWallet,
-		InitTransaction, asset, issuer, amount, commission, hash)
+		DoneTransaction, asset, issuer, value, commission, hash)
+	if err != nil {
+		return "", err
+	}
+	return guid.String(), nil
+}
+
+// CreateProcessedTransaction makes a new record of transaction which funds are already received
+// to the processing wallet, the hash is kept as both the original and processing one
+func (s *TransactionPSQL) CreateProcessedTransaction(merchantID, externalID, blockchain string, action ActionTx,
+	externalWallet, hash, asset, issuer string,
+	value, commission amount.Amount) (string, error) {
+	guid, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (guid, created_at, updated_at, merchant_id, external_id, blockchain, "+
+		"action, ext_wallet, status, asset, issuer, amount, commission, hash1, hash2) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)", s.namespace)
+	err = s.insert(
+		query, action,
+		guid, time.Now().UTC(), time.Now().UTC(), merchantID, externalID, blockchain, action, externalWallet,
+		ProcessedTransaction, asset, issuer, value, commission, hash)
 	if err != nil {
 		return "", err
 	}
@@ -199,67 +392,374 @@ func (s *TransactionPSQL) CreateTransaction(merchantID, externalID, blockchain s
 
 // RejectTransaction marks a specified transaction created by merchant for the user as rejected
 func (s *TransactionPSQL) RejectTransaction(merchantID, externalID, transaction string) error {
-	// TODO: check status transaction only "init" can be rejected
-	query := fmt.Sprintf("UPDATE %s set status = '%s' where guid = $1 and merchant_id = $2 and external_id = $3",
-		s.namespace, RejectedTransaction)
-	_, err := s.db.Query(query, transaction, merchantID, externalID)
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2 "+
+		"where guid = $3 and merchant_id = $4 and external_id = $5 and status = ANY($6)",
+		s.namespace)
+	return s.transit(query, RejectedTransaction, time.Now().UTC(), transaction, merchantID, externalID)
+}
+
+// PutInitiatedPendingTransaction replaces the hash of initiated transaction to trace status of a blockchain
+// transaction, status is not changed for the transaction. The hash is replaced only if it is still prevHash
+func (s *TransactionPSQL) PutInitiatedPendingTransaction(merchantID, externalID, transaction,
+	prevHash, hash string) error {
+	query := fmt.Sprintf("UPDATE %s set updated_at = $1, hash2 = $2 "+
+		"where guid = $3 and merchant_id = $4 and external_id = $5 and status = $6 and hash2 = $7",
+		s.namespace)
+	return s.update(query, time.Now().UTC(), hash, transaction, merchantID, externalID, InitTransaction, prevHash)
+}
+
+// PutRefundTransaction add the address funds are returned to, a hash and a network fee to initiated refund
+// transaction, status is not changed for the transaction. The hash is replaced only if it is still prevHash
+func (s *TransactionPSQL) PutRefundTransaction(merchantID, externalID, transaction, address, prevHash, hash string,
+	commission amount.Amount) error {
+	query := fmt.Sprintf("UPDATE %s set updated_at = $1, ext_wallet = $2, hash2 = $3, commission = $4 "+
+		"where guid = $5 and merchant_id = $6 and external_id = $7 and action = $8 and status = $9 and hash2 = $10",
+		s.namespace)
+	return s.update(query, time.Now().UTC(), address, hash, commission,
+		transaction, merchantID, externalID, RefundTransaction, InitTransaction, prevHash)
+}
+
+// PutProcessedTransaction moves an initiated transaction to processed status
+func (s *TransactionPSQL) PutProcessedTransaction(merchantID, externalID, transaction, hash string, commission amount.Amount) error {
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2, hash2 = $3, commission = $4 "+
+		"where guid = $5 and merchant_id = $6 and external_id = $7 and status = ANY($8)",
+		s.namespace)
+	return s.transitFrom(query, ProcessedTransaction, []StatusTx{InitTransaction}, time.Now().UTC(), hash,
+		commission, transaction, merchantID, externalID)
+}
 
+// ResetSettledTransaction returns a settled deposit to processed status to be settled again,
+// it is done only if the settlement hash is still the given one which failed in the blockchain
+func (s *TransactionPSQL) ResetSettledTransaction(merchantID, externalID, transaction, hash string) error {
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2, hash3 = '' "+
+		"where guid = $3 and merchant_id = $4 and external_id = $5 and action = $6 and hash3 = $7 "+
+		"and status = ANY($8)",
+		s.namespace)
+	return s.transitFrom(query, ProcessedTransaction, []StatusTx{SettledTransaction}, time.Now().UTC(),
+		transaction, merchantID, externalID, DepositTransaction, hash)
+}
+
+// ClaimSettledTransactions moves processed deposits to settled status with their commissions in one database
+// transaction before the blockchain transaction which settles them is made, nothing is changed and
+// ErrInvalidTransition is returned if any of them isn't processed any more, so a deposit is settled only once
+func (s *TransactionPSQL) ClaimSettledTransactions(trx []TransactionStore) error {
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2, hash3 = '', commission = $3 "+
+		"where guid = $4 and merchant_id = $5 and external_id = $6 and action = $7 and status = ANY($8)",
+		s.namespace)
+	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	prev := []string{string(ProcessedTransaction)}
+	for _, tr := range trx {
+		args := []interface{}{SettledTransaction, time.Now().UTC(), tr.Commission, tr.GUID.String(), tr.MerchantId,
+			tr.ExternalId, DepositTransaction, pq.Array(prev)}
+		n, err := s.writeWithEvents(tx, query, func(tr TransactionStore) string {
+			return transitEventTypes[SettledTransaction][tr.Action]
+		}, args...)
+		if err != nil {
+			return fmt.Errorf("could not update transaction status to %v: %w", SettledTransaction, err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: to %v from status other than %v", ErrInvalidTransition, SettledTransaction, prev)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
 	}
 	return nil
 }
 
-// PutInitiatedPendingTransaction add a hash to initiated transaction to trace status of a blockchain transaction
-// status is not changed for the transaction
-func (s *TransactionPSQL) PutInitiatedPendingTransaction(merchantID, externalID, transaction, hash string) error {
-	// TODO: check status transaction only "init" can be pending
-	query := fmt.Sprintf("UPDATE %s set status = '%s', hash2 = $1 where guid = $2 and merchant_id = $3 and external_id = $4",
-		s.namespace, InitTransaction)
-	_, err := s.db.Query(query, hash, transaction, merchantID, externalID)
+// broadcastHashColumns lists columns which keep hash of the blockchain transaction made in the status
+// to move the transaction to the next one
+var broadcastHashColumns = map[StatusTx]string{
+	InitTransaction:      "hash2",
+	ProcessedTransaction: "hash3",
+	SettledTransaction:   "hash5",
+}
+
+// PutBroadcastTransaction records hash of the blockchain transaction signed to move the transaction from
+// the status and the height it can be included up to before it is broadcast, status is not changed
+// for the transaction. The hash is replaced only if it is still prevHash, so the blockchain transaction
+// is broadcast only once
+func (s *TransactionPSQL) PutBroadcastTransaction(merchantID, externalID, transaction string, status StatusTx,
+	prevHash, hash string, timeoutHeight int64) error {
+	column, ok := broadcastHashColumns[status]
+	if !ok {
+		return fmt.Errorf("%w: no broadcast in status %v", ErrInvalidTransition, status)
+	}
+	return s.update(s.broadcastQuery(column), time.Now().UTC(), hash, timeoutHeight, transaction, merchantID, externalID, status,
+		prevHash)
+}
+
+// PutSettlementBroadcast records hash of the blockchain transaction which settles the claimed deposits and
+// the height it can be included up to before it is broadcast. The hash is recorded in one database transaction
+// only if none of the deposits has a settlement hash yet
+func (s *TransactionPSQL) PutSettlementBroadcast(trx []TransactionStore, hash string, timeoutHeight int64) error {
+	query := s.broadcastQuery("hash3")
+	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, tr := range trx {
+		res, err := tx.Exec(query, time.Now().UTC(), hash, timeoutHeight, tr.GUID.String(), tr.MerchantId,
+			tr.ExternalId, SettledTransaction, "")
+		if err != nil {
+			return fmt.Errorf("could not update transaction: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("could not get updated transactions: %w", err)
+		} else if n == 0 {
+			return fmt.Errorf("%w: deposit: %v is not claimed by the settlement", ErrInvalidTransition, tr.GUID)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
 	}
 	return nil
 }
 
-func (s *TransactionPSQL) PutProcessedTransaction(merchantID, externalID, transaction, hash string, commission float64) error {
-	// TODO: check status transaction only "init" can be processed
-	query := fmt.Sprintf("UPDATE %s set status = '%s', hash2 = $1, commission =$5 where guid = $2 and merchant_id = $3 and external_id = $4",
-		s.namespace, ProcessedTransaction)
-	_, err := s.db.Query(query, hash, transaction, merchantID, externalID, commission)
+// broadcastQuery returns query which records hash of the blockchain transaction in the column
+// if it still has the previous hash
+func (s *TransactionPSQL) broadcastQuery(column string) string {
+	return fmt.Sprintf("UPDATE %s set updated_at = $1, %s = $2, timeout_height = $3 "+
+		"where guid = $4 and merchant_id = $5 and external_id = $6 and status = $7 and %s = $8",
+		s.namespace, column, column)
+}
+
+// UpdateProcessedTransaction replaces hash and commission of the processed transaction,
+// status is not changed for the transaction. They are replaced only if the hash is still prevHash
+func (s *TransactionPSQL) UpdateProcessedTransaction(merchantID, externalID, transaction, prevHash, hash string,
+	commission amount.Amount) error {
+	query := fmt.Sprintf("UPDATE %s set updated_at = $1, hash2 = $2, commission = $3 "+
+		"where guid = $4 and merchant_id = $5 and external_id = $6 and status = $7 and hash2 = $8",
+		s.namespace)
+	return s.update(query, time.Now().UTC(), hash, commission, transaction, merchantID, externalID,
+		ProcessedTransaction, prevHash)
+}
+
+// PutSettledTransaction moves a processed transaction to settled status
+func (s *TransactionPSQL) PutSettledTransaction(merchantID, externalID, transaction, hash string) error {
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2, hash3 = $3 "+
+		"where guid = $4 and merchant_id = $5 and external_id = $6 and status = ANY($7)",
+		s.namespace)
+	return s.transit(query, SettledTransaction, time.Now().UTC(), hash, transaction, merchantID, externalID)
+}
+
+// PutDoneTransaction moves a settled transaction to done status
+func (s *TransactionPSQL) PutDoneTransaction(merchantID, externalID, transaction, hash string) error {
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2, hash5 = $3 "+
+		"where guid = $4 and merchant_id = $5 and external_id = $6 and status = ANY($7)",
+		s.namespace)
+	return s.transit(query, DoneTransaction, time.Now().UTC(), hash, transaction, merchantID, externalID)
+}
+
+// HoldTransaction puts the initiated or processed transaction of the merchant on hold,
+// it isn't processed until it is released
+func (s *TransactionPSQL) HoldTransaction(merchantID, transaction string) error {
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2 "+
+		"where guid = $3 and merchant_id = $4 and status = ANY($5)",
+		s.namespace)
+	return s.transit(query, OnHoldTransaction, time.Now().UTC(), transaction, merchantID)
+}
+
+// ReleaseTransaction returns the transaction of the merchant on hold to the status it had before the hold,
+// no event is put about the release, the merchant is informed about the transaction when it is created
+func (s *TransactionPSQL) ReleaseTransaction(merchantID, transaction string, status StatusTx) error {
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2 "+
+		"where guid = $3 and merchant_id = $4 and status = ANY($5)",
+		s.namespace)
+	return s.transitFrom(query, status, []StatusTx{OnHoldTransaction}, time.Now().UTC(), transaction, merchantID)
+}
+
+// RejectHeldTransaction marks the transaction of the merchant on hold as rejected
+func (s *TransactionPSQL) RejectHeldTransaction(merchantID, transaction string) error {
+	query := fmt.Sprintf("UPDATE %s set status = $1, updated_at = $2 "+
+		"where guid = $3 and merchant_id = $4 and status = ANY($5)",
+		s.namespace)
+	return s.transitFrom(query, RejectedTransaction, []StatusTx{OnHoldTransaction}, time.Now().UTC(),
+		transaction, merchantID)
+}
+
+// GetVolume returns sum of amounts and number of not rejected transactions of the action in the merchant asset
+// created since the time, transactions of all users of the merchant are counted if externalID is empty
+func (s *TransactionPSQL) GetVolume(merchantID, externalID, blockchain, asset, issuer string, action ActionTx,
+	since time.Time) (amount.Amount, int64, error) {
+	return s.volume(s.db, merchantID, externalID, blockchain, asset, issuer, action, since)
+}
+
+// VolumeFunc returns sum of amounts and number of transactions of the action like GetVolume
+type VolumeFunc func(merchantID, externalID, blockchain, asset, issuer string, action ActionTx,
+	since time.Time) (amount.Amount, int64, error)
+
+// volume is GetVolume made by the querier
+func (s *TransactionPSQL) volume(q rowQuerier, merchantID, externalID, blockchain, asset, issuer string,
+	action ActionTx, since time.Time) (amount.Amount, int64, error) {
+	query := fmt.Sprintf("SELECT coalesce(sum(amount), 0), count(*) FROM %s WHERE deleted_at IS NULL "+
+		"and merchant_id = $1 and ($2 = '' or external_id = $2) and blockchain = $3 and asset = $4 "+
+		"and issuer = $5 and action = $6 and status <> $7 and created_at >= $8", s.namespace)
+	volume, count := amount.Zero(), int64(0)
+	err := q.QueryRow(query, merchantID, externalID, blockchain, asset, issuer, action, RejectedTransaction,
+		since.UTC()).Scan(&volume, &count)
 	if err != nil {
-		return err
+		return volume, 0, fmt.Errorf("could not get volume of %v transactions of merchant: %v, err: %w",
+			action, merchantID, err)
+	}
+	return volume, count, nil
+}
+
+// update executes conditional update query which changes a transaction without a change of its status,
+// ErrInvalidTransition is returned if the transaction is changed since it was read or has another status
+func (s *TransactionPSQL) update(query string, args ...interface{}) error {
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("could not update transaction: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get updated transactions: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: transaction is changed or has another status", ErrInvalidTransition)
 	}
 	return nil
 }
 
-func (s *TransactionPSQL) PutSettledTransaction(merchantID, externalID, transaction, hash string) error {
-	// TODO: check status transaction only "processed" can be settled
-	query := fmt.Sprintf("UPDATE %s set status = '%s', hash3 = $1 where guid = $2 and merchant_id = $3 and external_id = $4",
-		s.namespace, SettledTransaction)
-	_, err := s.db.Query(query, hash, transaction, merchantID, externalID)
+// transit executes conditional update query which moves a transaction to the status,
+// the first argument of the query must be the new status and the last one the list of allowed previous statuses.
+// ErrInvalidTransition is returned if the transaction is not in any of the allowed statuses
+func (s *TransactionPSQL) transit(query string, to StatusTx, args ...interface{}) error {
+	from, ok := statusTransitions[to]
+	if !ok {
+		return fmt.Errorf("%w: unknown status %v", ErrInvalidTransition, to)
+	}
+	return s.transitFrom(query, to, from, args...)
+}
+
+// transitFrom is transit of a transaction to the status from the given statuses instead of statusTransitions
+func (s *TransactionPSQL) transitFrom(query string, to StatusTx, from []StatusTx, args ...interface{}) error {
+	prev := make([]string, 0, len(from))
+	for _, status := range from {
+		prev = append(prev, string(status))
+	}
+	args = append([]interface{}{to}, args...)
+	args = append(args, pq.Array(prev))
+	if s.outbox != nil && transitEventTypes[to] != nil || s.ledger != nil {
+		return s.transitWithEvent(query, to, prev, args...)
+	}
+	res, err := s.db.Exec(query, args...)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not update transaction status to %v: %w", to, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get updated transactions: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: to %v from status other than %v", ErrInvalidTransition, to, prev)
 	}
 	return nil
 }
 
-func (s *TransactionPSQL) PutDoneTransaction(merchantID, externalID, transaction, hash string) error {
-	// TODO: check status transaction only "settled" can be done
-	query := fmt.Sprintf("UPDATE %s set status = '%s', hash5 = $1 where guid = $2 and merchant_id = $3 and external_id = $4",
-		s.namespace, DoneTransaction)
-	_, err := s.db.Query(query, hash, transaction, merchantID, externalID)
+// transitWithEvent moves a transaction to the status and puts the event about it to the webhook outbox
+// and entries to the ledger in the same database transaction, so they are written only if the status is changed
+func (s *TransactionPSQL) transitWithEvent(query string, to StatusTx, prev []string, args ...interface{}) error {
+	n, err := s.execWithEvents(query, func(tr TransactionStore) string {
+		return transitEventTypes[to][tr.Action]
+	}, args...)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not update transaction status to %v: %w", to, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: to %v from status other than %v", ErrInvalidTransition, to, prev)
 	}
 	return nil
 }
 
-func NewTransactionStorage(namespace string, db *sql.DB) (*TransactionPSQL, error) {
+// insert executes the query which makes a new transaction of the action,
+// the event about it is put to the webhook outbox and entries to the ledger in the same database transaction
+func (s *TransactionPSQL) insert(query string, action ActionTx, args ...interface{}) error {
+	if (s.outbox == nil || createdEventTypes[action] == "") && s.ledger == nil {
+		_, err := s.db.Exec(query, args...)
+		return err
+	}
+	_, err := s.execWithEvents(query, func(tr TransactionStore) string {
+		return createdEventTypes[tr.Action]
+	}, args...)
+	return err
+}
+
+// execWithEvents executes the query in a database transaction together with putting events about
+// the affected transactions to the outbox and posting their entries to the ledger, event type is given
+// by eventType and no event is put for empty type. It returns number of affected transactions
+func (s *TransactionPSQL) execWithEvents(query string, eventType func(tr TransactionStore) string,
+	args ...interface{}) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	n, err := s.writeWithEvents(tx, query, eventType, args...)
+	if err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return n, nil
+}
+
+// writeWithEvents executes the query in the database transaction and writes events and ledger entries
+// of the affected transactions, it returns number of affected transactions
+func (s *TransactionPSQL) writeWithEvents(tx *sql.Tx, query string, eventType func(tr TransactionStore) string,
+	args ...interface{}) (int, error) {
+	rows, err := tx.Query(query+" RETURNING "+transactionColumns, args...)
+	if err != nil {
+		return 0, err
+	}
+	transactions, err := rowsToTransaction(rows)
+	_ = rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("could not get affected transactions: %w", err)
+	}
+	for _, tr := range transactions {
+		if s.ledger != nil {
+			if err = s.ledger.record(tx, tr); err != nil {
+				return 0, err
+			}
+		}
+		event := eventType(tr)
+		if s.outbox == nil || event == "" {
+			continue
+		}
+		payload, err := json.Marshal(NewTransactionEventData(tr))
+		if err != nil {
+			return 0, fmt.Errorf("could not marshal transaction: %v for webhook, err: %w", tr.GUID, err)
+		}
+		for _, preceding := range precedingEventTypes[event] {
+			if err = s.outbox.put(tx, tr.MerchantId, preceding, payload); err != nil {
+				return 0, err
+			}
+		}
+		if err = s.outbox.put(tx, tr.MerchantId, event, payload); err != nil {
+			return 0, err
+		}
+	}
+	return len(transactions), nil
+}
+
+// NewTransactionStorage creates new storage for transactions, if the outbox is set events about
+// created transactions and changes of their statuses are put to it, if the ledger is set entries
+// for changes of their statuses are posted to it
+func NewTransactionStorage(namespace string, db *sql.DB, outbox *WebhooksPSQL,
+	ledger *LedgerPSQL) (*TransactionPSQL, error) {
 	s := TransactionPSQL{
 		db:        db,
 		namespace: namespace,
+		outbox:    outbox,
+		ledger:    ledger,
 	}
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("could not connect to DB: %v", err)
@@ -288,6 +788,7 @@ func rowsToTransaction(rows *sql.Rows) ([]TransactionStore, error) {
 			&transaction.Hash1, &transaction.Hash2,
 			&transaction.Hash3, &transaction.Hash4,
 			&transaction.Hash5, &callBack,
+			&transaction.TimeoutHeight,
 		); err != nil {
 			return nil, err
 		}
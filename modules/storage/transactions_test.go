@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"coreum_processor/modules/amount"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTransactionsMock(t *testing.T) (*TransactionPSQL, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return &TransactionPSQL{db: db, namespace: "transactions"}, mock
+}
+
+func TestTransitToAllowedStatus(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions set status = $1")).
+		WithArgs(SettledTransaction, sqlmock.AnyArg(), "hash", "guid", "merchant", "user", `{"processed"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, s.PutSettledTransaction("merchant", "user", "guid", "hash"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransitFromNotAllowedStatus(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions set status = $1")).
+		WithArgs(DoneTransaction, sqlmock.AnyArg(), "hash", "guid", "merchant", "user", `{"settle"}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := s.PutDoneTransaction("merchant", "user", "guid", "hash")
+	require.ErrorIs(t, err, ErrInvalidTransition)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateProcessedTransactionChangedHash(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	mock.ExpectExec(regexp.QuoteMeta("hash2 = $8")).
+		WithArgs(sqlmock.AnyArg(), "new", sqlmock.AnyArg(), "guid", "merchant", "user", ProcessedTransaction,
+			"prev").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := s.UpdateProcessedTransaction("merchant", "user", "guid", "prev", "new", amount.Zero())
+	require.ErrorIs(t, err, ErrInvalidTransition)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPutBroadcastTransaction(t *testing.T) {
+	tests := []struct {
+		status StatusTx
+		column string
+	}{
+		{status: InitTransaction, column: "hash2"},
+		{status: ProcessedTransaction, column: "hash3"},
+		{status: SettledTransaction, column: "hash5"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			s, mock := newTransactionsMock(t)
+			mock.ExpectExec(regexp.QuoteMeta(tt.column+" = $2, timeout_height = $3 where")).
+				WithArgs(sqlmock.AnyArg(), "new", int64(150), "guid", "merchant", "user", tt.status, "prev").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			require.NoError(t, s.PutBroadcastTransaction("merchant", "user", "guid", tt.status, "prev", "new", 150))
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPutBroadcastTransactionRecordedByAnotherRun(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	mock.ExpectExec(regexp.QuoteMeta("hash5 = $2, timeout_height = $3 where")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := s.PutBroadcastTransaction("merchant", "user", "guid", SettledTransaction, "", "new", 150)
+	require.ErrorIs(t, err, ErrInvalidTransition)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPutBroadcastTransactionWithoutBroadcast(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+
+	err := s.PutBroadcastTransaction("merchant", "user", "guid", DoneTransaction, "", "new", 150)
+	require.ErrorIs(t, err, ErrInvalidTransition)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimSettledTransactionsIsAllOrNothing(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	trx := []TransactionStore{
+		{GUID: uuid.New(), MerchantId: "merchant", ExternalId: "alice", Commission: amount.NewFromInt64(1)},
+		{GUID: uuid.New(), MerchantId: "merchant", ExternalId: "bob", Commission: amount.NewFromInt64(2)},
+	}
+	now := time.Now()
+	claimQuery := regexp.QuoteMeta("hash3 = '', commission = $3")
+	mock.ExpectBegin()
+	mock.ExpectQuery(claimQuery).
+		WithArgs(SettledTransaction, sqlmock.AnyArg(), amount.NewFromInt64(1), trx[0].GUID.String(), "merchant",
+			"alice", DepositTransaction, `{"processed"}`).
+		WillReturnRows(sqlmock.NewRows(strings.Split(transactionColumns, ", ")).
+			AddRow(1, trx[0].GUID.String(), now, now, nil, "merchant", "alice", "coreum", DepositTransaction, "",
+				SettledTransaction, "ucore", "", "10", "1", "", "", "", "", "", "", 0))
+	// the second deposit has been settled by another run
+	mock.ExpectQuery(claimQuery).
+		WithArgs(SettledTransaction, sqlmock.AnyArg(), amount.NewFromInt64(2), trx[1].GUID.String(), "merchant",
+			"bob", DepositTransaction, `{"processed"}`).
+		WillReturnRows(sqlmock.NewRows(strings.Split(transactionColumns, ", ")))
+	mock.ExpectRollback()
+
+	err := s.ClaimSettledTransactions(trx)
+	require.ErrorIs(t, err, ErrInvalidTransition)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPutSettlementBroadcast(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	trx := []TransactionStore{
+		{GUID: uuid.New(), MerchantId: "merchant", ExternalId: "alice"},
+		{GUID: uuid.New(), MerchantId: "merchant", ExternalId: "bob"},
+	}
+	mock.ExpectBegin()
+	for _, tr := range trx {
+		mock.ExpectExec(regexp.QuoteMeta("hash3 = $2, timeout_height = $3 where")).
+			WithArgs(sqlmock.AnyArg(), "SETTLE", int64(150), tr.GUID.String(), "merchant", tr.ExternalId,
+				SettledTransaction, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	require.NoError(t, s.PutSettlementBroadcast(trx, "SETTLE", 150))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPutSettlementBroadcastRecordedByAnotherRun(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	trx := []TransactionStore{{GUID: uuid.New(), MerchantId: "merchant", ExternalId: "alice"}}
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("hash3 = $2, timeout_height = $3 where")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := s.PutSettlementBroadcast(trx, "SETTLE", 150)
+	require.ErrorIs(t, err, ErrInvalidTransition)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetSettledTransaction(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	mock.ExpectExec(regexp.QuoteMeta("hash3 = '' where")).
+		WithArgs(ProcessedTransaction, sqlmock.AnyArg(), "guid", "merchant", "user", DepositTransaction, "failed",
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, s.ResetSettledTransaction("merchant", "user", "guid", "failed"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoneWithdrawIsBroadcast(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	s.outbox = &WebhooksPSQL{db: s.db, namespace: "webhook_outbox"}
+	guid := uuid.New()
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("hash5 = $3")).
+		WillReturnRows(sqlmock.NewRows(strings.Split(transactionColumns, ", ")).
+			AddRow(1, guid.String(), now, now, nil, "merchant", "user", "coreum", WithdrawTransaction, "core1user",
+				DoneTransaction, "ucore", "", "10", "1", "", "", "", "", "SENT", "", 0))
+	putQuery := regexp.QuoteMeta("INSERT INTO webhook_outbox")
+	mock.ExpectExec(putQuery).WithArgs(sqlmock.AnyArg(), "merchant", EventWithdrawBroadcast, sqlmock.AnyArg(),
+		WebhookPending).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(putQuery).WithArgs(sqlmock.AnyArg(), "merchant", EventWithdrawDone, sqlmock.AnyArg(),
+		WebhookPending).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, s.PutDoneTransaction("merchant", "user", guid.String(), "SENT"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReleasedWithdrawHasNoEvent(t *testing.T) {
+	s, mock := newTransactionsMock(t)
+	s.outbox = &WebhooksPSQL{db: s.db, namespace: "webhook_outbox"}
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions set status = $1")).
+		WithArgs(InitTransaction, sqlmock.AnyArg(), "guid", "merchant", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, s.ReleaseTransaction("merchant", "guid", InitTransaction))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"time"
 )
 
@@ -54,8 +55,8 @@ type UserPSQL struct {
 
 // GetUserByIdentity find a user in the store by unique user identity
 func (s *UserPSQL) GetUserByIdentity(identity string) (*UserStore, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE identity = '%s'", s.userNamespace, identity)
-	rows, err := s.db.Query(query)
+	query := fmt.Sprintf("SELECT * FROM %s WHERE identity = $1", s.userNamespace)
+	rows, err := s.db.Query(query, identity)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	} else if err != nil {
@@ -83,25 +84,26 @@ func (s *UserPSQL) GetUserList(merchID string, accessFilter []int, from, to time
 	query += fmt.Sprintf(" join %s mu on %s.id = mu.user_id ",
 		s.merchantUsersNamespace, un)
 	query += fmt.Sprintf("join %s ml on mu.merchant_list_id = ml.id ", s.merchantListNamespace)
+	var args []interface{}
 	if merchID != "" {
-		query += fmt.Sprintf(" where merchant_id = '%s' and ", merchID)
+		args = append(args, merchID)
+		query += " where merchant_id = $1 and "
 	} else {
-		query += fmt.Sprintf(" where merchant_id IS NULL AND ")
+		query += " where merchant_id IS NULL AND "
 	}
 
-	query += fmt.Sprintf(" %s.deleted_at IS NULL and %s.created_at > '%v' and %s.created_at < '%v' ",
-		un, un, from.Format(time.RFC3339), un, to.Format(time.RFC3339))
-	if accessFilter != nil && len(accessFilter) > 0 {
-		query += fmt.Sprintf(" and %s.access in (", un)
-		for i, val := range accessFilter {
-			if i > 0 {
-				query += ", "
-			}
-			query += fmt.Sprintf("%v", val)
+	args = append(args, from, to)
+	query += fmt.Sprintf(" %s.deleted_at IS NULL and %s.created_at > $%d and %s.created_at < $%d ",
+		un, un, len(args)-1, un, len(args))
+	if len(accessFilter) > 0 {
+		access := make([]int64, 0, len(accessFilter))
+		for _, val := range accessFilter {
+			access = append(access, int64(val))
 		}
-		query += ") "
+		args = append(args, pq.Array(access))
+		query += fmt.Sprintf(" and %s.access = ANY($%d) ", un, len(args))
 	}
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, args...)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	} else if err != nil {
@@ -120,9 +122,9 @@ func (s *UserPSQL) GetUserList(merchID string, accessFilter []int, from, to time
 func (s *UserPSQL) GetUserMerchants(identity string) ([]UserMerchant, error) {
 	query := fmt.Sprintf("select ml.merchant_id, ml.created_at, ml.updated_at, ml.deleted_at, "+
 		"ml.company_name, ml.email, ml.is_blocked, mu.access, mu.meta_data, ml.meta_data "+
-		"from %s join %s mu on %s.id = mu.user_id join %s ml on ml.id = mu.merchant_list_id where identity = '%s'",
-		s.userNamespace, s.merchantUsersNamespace, s.userNamespace, s.merchantListNamespace, identity)
-	rows, err := s.db.Query(query)
+		"from %s join %s mu on %s.id = mu.user_id join %s ml on ml.id = mu.merchant_list_id where identity = $1",
+		s.userNamespace, s.merchantUsersNamespace, s.userNamespace, s.merchantListNamespace)
+	rows, err := s.db.Query(query, identity)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	} else if err != nil {
@@ -181,10 +183,10 @@ func (s *UserPSQL) UpdateUser(user UserStore) error {
 func (s *UserPSQL) LinkUserToMerchant(identity, merchantID string, merchantAccess MerchantAccess) error {
 	query := fmt.Sprintf(
 		"INSERT INTO %s (created_at, updated_at, deleted_at, user_id, merchant_list_id)"+
-			"values (now(), now(), null, (select id from %s where identity = '%s'), "+
-			"(select id from %s where merchant_id = '%s'))",
-		s.merchantUsersNamespace, s.userNamespace, identity, s.merchantListNamespace, merchantID)
-	_, err := s.db.Exec(query)
+			"values (now(), now(), null, (select id from %s where identity = $1), "+
+			"(select id from %s where merchant_id = $2))",
+		s.merchantUsersNamespace, s.userNamespace, s.merchantListNamespace)
+	_, err := s.db.Exec(query, identity, merchantID)
 	if err != nil {
 		return err
 	}
@@ -211,12 +213,12 @@ func (s *UserPSQL) ApproveUserMerchant(identity, merchantID string) error {
 func (s *UserPSQL) RequestMerchantForUser(identity, merchantName, merchantEmail string) error {
 	query := fmt.Sprintf(
 		"WITH merchantID AS (INSERT INTO %s (created_at, updated_at, deleted_at, email, company_name) "+
-			"values (now(), now(), null, '%s', '%s') returning id) "+
+			"values (now(), now(), null, $1, $2) returning id) "+
 			"INSERT INTO %s (created_at, updated_at, deleted_at, user_id, merchant_list_id) "+
-			"values (now(), now(), null, (select id from %s where identity = '%s'), "+
+			"values (now(), now(), null, (select id from %s where identity = $3), "+
 			"(select id from merchantID))",
-		s.merchantListNamespace, merchantEmail, merchantName, s.merchantUsersNamespace, s.userNamespace, identity)
-	_, err := s.db.Exec(query)
+		s.merchantListNamespace, s.merchantUsersNamespace, s.userNamespace)
+	_, err := s.db.Exec(query, merchantEmail, merchantName, identity)
 	if err != nil {
 		return err
 	}
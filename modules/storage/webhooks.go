@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type WebhookStatus string
+
+const (
+	// WebhookPending is an event waiting for delivery to the merchant
+	WebhookPending WebhookStatus = "pending"
+	// WebhookDelivered is an event accepted by the merchant
+	WebhookDelivered WebhookStatus = "delivered"
+	// WebhookDead is an event which exhausted its delivery attempts, it is delivered again only on replay
+	WebhookDead WebhookStatus = "dead"
+	// WebhookSkipped is an event the merchant isn't subscribed to
+	WebhookSkipped WebhookStatus = "skipped"
+	// WebhookDispatched is an event fanned out to copies for each endpoint of the merchant
+	WebhookDispatched WebhookStatus = "dispatched"
+)
+
+// WebhookEvent is an event for the merchant kept in the outbox until it is delivered
+type WebhookEvent struct {
+	ID            int64         `json:"id"`
+	MerchantID    string        `json:"merchant_id"`
+	EndpointID    int64         `json:"endpoint_id"`
+	EventID       int64         `json:"event_id"`
+	Event         string        `json:"event"`
+	Payload       []byte        `json:"payload"`
+	Status        WebhookStatus `json:"status"`
+	Attempts      int           `json:"attempts"`
+	NextAttemptAt time.Time     `json:"next_attempt_at"`
+	LastError     string        `json:"last_error"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// OriginID returns id of the event put for the merchant, copies fanned out to endpoints keep it
+func (e WebhookEvent) OriginID() int64 {
+	if e.EventID != 0 {
+		return e.EventID
+	}
+	return e.ID
+}
+
+// webhookColumns lists columns of the outbox table in order expected by rowsToWebhookEvents
+const webhookColumns = "id, merchant_id, endpoint_id, event_id, event, payload, status, attempts, next_attempt_at, last_error, " +
+	"created_at, updated_at"
+
+// execer executes a query either in the database or in its transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+type WebhooksPSQL struct {
+	db        *sql.DB
+	namespace string
+}
+
+// NewWebhookStorage creates new outbox for webhook events
+func NewWebhookStorage(namespace string, db *sql.DB) (*WebhooksPSQL, error) {
+	s := WebhooksPSQL{
+		db:        db,
+		namespace: namespace,
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SELECT 1 FROM %q LIMIT 1", namespace)); err != nil {
+		return nil, fmt.Errorf("could not connect to webhook storage: %v", err)
+	}
+	return &s, nil
+}
+
+// Put adds the event to the outbox to be delivered as soon as possible
+func (s *WebhooksPSQL) Put(merchantID, event string, payload []byte) error {
+	return s.put(s.db, merchantID, event, payload)
+}
+
+// put adds the event to the outbox by the executor, so the event can be written in the transaction
+// which has caused it
+func (s *WebhooksPSQL) put(db execer, merchantID, event string, payload []byte) error {
+	query := fmt.Sprintf("INSERT INTO %s (created_at, updated_at, merchant_id, event, payload, status, "+
+		"next_attempt_at) VALUES ($1, $1, $2, $3, $4, $5, $1)", s.namespace)
+	if _, err := db.Exec(query, time.Now().UTC(), merchantID, event, payload, WebhookPending); err != nil {
+		return fmt.Errorf("could not put webhook event: %v for merchant: %v, err: %w", event, merchantID, err)
+	}
+	return nil
+}
+
+// Take returns up to limit pending events which are due to be delivered and leases them for the given duration,
+// events taken by another replica are skipped
+func (s *WebhooksPSQL) Take(limit int, lease time.Duration) ([]WebhookEvent, error) {
+	now := time.Now().UTC()
+	query := fmt.Sprintf("UPDATE %[1]s SET next_attempt_at = $1, updated_at = $2 WHERE id IN ("+
+		"SELECT id FROM %[1]s WHERE status = $3 and next_attempt_at <= $2 ORDER BY next_attempt_at LIMIT $4 "+
+		"FOR UPDATE SKIP LOCKED) RETURNING %[2]s", s.namespace, webhookColumns)
+	rows, err := s.db.Query(query, now.Add(lease), now, WebhookPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not take webhook events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return rowsToWebhookEvents(rows)
+}
+
+// Fanout puts a copy of the event for each endpoint and marks the event dispatched in one transaction,
+// so the event is fanned out once
+func (s *WebhooksPSQL) Fanout(event WebhookEvent, endpointIDs []int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC()
+	query := fmt.Sprintf("INSERT INTO %s (created_at, updated_at, merchant_id, endpoint_id, event_id, event, "+
+		"payload, status, next_attempt_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $2)", s.namespace)
+	for _, endpointID := range endpointIDs {
+		_, err = tx.Exec(query, event.CreatedAt, now, event.MerchantID, endpointID, event.ID, event.Event,
+			event.Payload, WebhookPending)
+		if err != nil {
+			return fmt.Errorf("could not fan out webhook event: %v to endpoint: %v, err: %w",
+				event.ID, endpointID, err)
+		}
+	}
+	query = fmt.Sprintf("UPDATE %s SET status = $1, updated_at = $2 WHERE id = $3", s.namespace)
+	if _, err = tx.Exec(query, WebhookDispatched, now, event.ID); err != nil {
+		return fmt.Errorf("could not mark webhook event: %v dispatched, err: %w", event.ID, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit fan out of webhook event: %v, err: %w", event.ID, err)
+	}
+	return nil
+}
+
+// Delivered marks the event accepted by the merchant
+func (s *WebhooksPSQL) Delivered(id int64) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $1, updated_at = $2, attempts = attempts + 1, last_error = '' "+
+		"WHERE id = $3", s.namespace)
+	if _, err := s.db.Exec(query, WebhookDelivered, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("could not mark webhook event: %v delivered, err: %w", id, err)
+	}
+	return nil
+}
+
+// Skip marks the event which isn't delivered because the merchant or its endpoint isn't subscribed to it
+func (s *WebhooksPSQL) Skip(id int64) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $1, updated_at = $2 WHERE id = $3", s.namespace)
+	if _, err := s.db.Exec(query, WebhookSkipped, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("could not skip webhook event: %v, err: %w", id, err)
+	}
+	return nil
+}
+
+// Fail records the error of the delivery and sets the next attempt, the event is moved to dead letters
+// if it has no attempts left
+func (s *WebhooksPSQL) Fail(id int64, lastError string, next time.Time, dead bool) error {
+	status := WebhookPending
+	if dead {
+		status = WebhookDead
+	}
+	query := fmt.Sprintf("UPDATE %s SET status = $1, next_attempt_at = $2, updated_at = $3, "+
+		"attempts = attempts + 1, last_error = $4 WHERE id = $5", s.namespace)
+	if _, err := s.db.Exec(query, status, next.UTC(), time.Now().UTC(), lastError, id); err != nil {
+		return fmt.Errorf("could not fail webhook event: %v, err: %w", id, err)
+	}
+	return nil
+}
+
+// GetByMerchant returns the latest events of the merchant in the status
+func (s *WebhooksPSQL) GetByMerchant(merchantID string, status WebhookStatus, limit int) ([]WebhookEvent, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE merchant_id = $1 and status = $2 ORDER BY id DESC LIMIT $3",
+		webhookColumns, s.namespace)
+	rows, err := s.db.Query(query, merchantID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return rowsToWebhookEvents(rows)
+}
+
+// Replay returns the dead event of the merchant to delivery with new attempts,
+// ErrNotFound is returned if the merchant doesn't have such dead event
+func (s *WebhooksPSQL) Replay(merchantID string, id int64) error {
+	now := time.Now().UTC()
+	query := fmt.Sprintf("UPDATE %s SET status = $1, next_attempt_at = $2, updated_at = $2, attempts = 0 "+
+		"WHERE merchant_id = $3 and id = $4 and status = $5", s.namespace)
+	res, err := s.db.Exec(query, WebhookPending, now, merchantID, id, WebhookDead)
+	if err != nil {
+		return fmt.Errorf("could not replay webhook event: %v, err: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not replay webhook event: %v, err: %w", id, err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func rowsToWebhookEvents(rows *sql.Rows) ([]WebhookEvent, error) {
+	var events []WebhookEvent
+	for rows.Next() {
+		e := WebhookEvent{}
+		err := rows.Scan(&e.ID, &e.MerchantID, &e.EndpointID, &e.EventID, &e.Event, &e.Payload, &e.Status, &e.Attempts, &e.NextAttemptAt,
+			&e.LastError, &e.CreatedAt, &e.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan webhook event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read webhook events: %w", err)
+	}
+	return events, nil
+}